@@ -35,6 +35,12 @@ type Config struct {
 		}
 	}
 
+	Cart struct {
+		// HoldMinutes is how long items added to a cart are held for the
+		// shopper. Holds are off when it is 0.
+		HoldMinutes int `mapstructure:"HOLD_MINUTES"`
+	}
+
 	DB struct {
 		MySQL struct {
 			Read struct {
@@ -93,6 +99,40 @@ type Config struct {
 		}
 	}
 
+	Inventory struct {
+		// AllocationStrategy picks the locations orders ship from: priority
+		// or nearest.
+		AllocationStrategy string `mapstructure:"ALLOCATION_STRATEGY"`
+		// SplitShipments lets an item ship from several locations.
+		SplitShipments bool `mapstructure:"SPLIT_SHIPMENTS"`
+	}
+
+	Privacy struct {
+		ErasureDelayHours int `mapstructure:"ERASURE_DELAY_HOURS"`
+	}
+
+	Scim struct {
+		Token          string `mapstructure:"TOKEN"`
+		OrganizationId string `mapstructure:"ORGANIZATION_ID"`
+	}
+
+	Storage struct {
+		Driver         string `mapstructure:"DRIVER"`
+		MaxUploadBytes int64  `mapstructure:"MAX_UPLOAD_BYTES"`
+		Local          struct {
+			Path string `mapstructure:"PATH"`
+			URL  string `mapstructure:"URL"`
+		}
+		S3 struct {
+			Endpoint        string `mapstructure:"ENDPOINT"`
+			Region          string `mapstructure:"REGION"`
+			Bucket          string `mapstructure:"BUCKET"`
+			AccessKeyID     string `mapstructure:"ACCESS_KEY_ID"`
+			SecretAccessKey string `mapstructure:"SECRET_ACCESS_KEY"`
+			PublicURL       string `mapstructure:"PUBLIC_URL"`
+		}
+	}
+
 	Server struct {
 		Env      string `mapstructure:"ENV"`
 		LogLevel string `mapstructure:"LOG_LEVEL"`
@@ -102,6 +142,10 @@ type Config struct {
 			GracePeriodSeconds   int64 `mapstructure:"GRACE_PERIOD_SECONDS"`
 		}
 	}
+
+	Worker struct {
+		IntervalSeconds int `mapstructure:"INTERVAL_SECONDS"`
+	}
 }
 
 var (
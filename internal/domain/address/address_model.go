@@ -0,0 +1,186 @@
+package address
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/nuuid"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
+)
+
+type Address struct {
+	Id                uuid.UUID   `db:"id" validate:"required"`
+	UserId            uuid.UUID   `db:"user_id" validate:"required"`
+	Label             string      `db:"label" validate:"required"`
+	RecipientName     string      `db:"recipient_name" validate:"required"`
+	PhoneNumber       string      `db:"phone_number" validate:"required,e164"`
+	Line1             string      `db:"line1" validate:"required"`
+	Line2             null.String `db:"line2"`
+	City              string      `db:"city" validate:"required"`
+	Province          string      `db:"province" validate:"required"`
+	PostalCode        string      `db:"postal_code" validate:"required"`
+	CountryCode       string      `db:"country_code" validate:"required,iso3166_1_alpha2"`
+	IsDefaultShipping bool        `db:"is_default_shipping"`
+	IsDefaultBilling  bool        `db:"is_default_billing"`
+	CreatedAt         time.Time   `db:"created_at" validate:"required"`
+	UpdatedAt         time.Time   `db:"updated_at" validate:"required"`
+	DeletedAt         null.Time   `db:"deleted_at"`
+	CreatedBy         uuid.UUID   `db:"created_by" validate:"required"`
+	UpdatedBy         uuid.UUID   `db:"updated_by" validate:"required"`
+	DeletedBy         nuuid.NUUID `db:"deleted_by"`
+}
+
+type AddressResponseFormat struct {
+	Id                uuid.UUID   `json:"id"`
+	UserId            uuid.UUID   `json:"userId"`
+	Label             string      `json:"label"`
+	RecipientName     string      `json:"recipientName"`
+	PhoneNumber       string      `json:"phoneNumber"`
+	Line1             string      `json:"line1"`
+	Line2             null.String `json:"line2"`
+	City              string      `json:"city"`
+	Province          string      `json:"province"`
+	PostalCode        string      `json:"postalCode"`
+	CountryCode       string      `json:"countryCode"`
+	IsDefaultShipping bool        `json:"isDefaultShipping"`
+	IsDefaultBilling  bool        `json:"isDefaultBilling"`
+	CreatedAt         time.Time   `json:"createdAt"`
+	UpdatedAt         time.Time   `json:"updatedAt"`
+	DeletedAt         null.Time   `json:"deletedAt,omitempty"`
+	CreatedBy         uuid.UUID   `json:"createdBy"`
+	UpdatedBy         uuid.UUID   `json:"updatedBy"`
+	DeletedBy         nuuid.NUUID `json:"deletedBy,omitempty"`
+}
+
+type AddressPayload struct {
+	Label             string `json:"label" validate:"required,max=50"`
+	RecipientName     string `json:"recipientName" validate:"required,max=255"`
+	PhoneNumber       string `json:"phoneNumber" validate:"required,e164"`
+	Line1             string `json:"line1" validate:"required,max=255"`
+	Line2             string `json:"line2" validate:"max=255"`
+	City              string `json:"city" validate:"required,max=100"`
+	Province          string `json:"province" validate:"required,max=100"`
+	PostalCode        string `json:"postalCode" validate:"required,max=10"`
+	CountryCode       string `json:"countryCode" validate:"required,iso3166_1_alpha2"`
+	IsDefaultShipping bool   `json:"isDefaultShipping"`
+	IsDefaultBilling  bool   `json:"isDefaultBilling"`
+}
+
+// Kind tells which default flag of an address is being looked at.
+type Kind int
+
+const (
+	Shipping Kind = iota
+	Billing
+)
+
+// postalCodeFormats holds the postal code format of the countries we ship to.
+// Countries not listed here fall back to defaultPostalCodeFormat.
+var postalCodeFormats = map[string]*regexp.Regexp{
+	"ID": regexp.MustCompile(`^\d{5}$`),
+	"MY": regexp.MustCompile(`^\d{5}$`),
+	"SG": regexp.MustCompile(`^\d{6}$`),
+	"TH": regexp.MustCompile(`^\d{5}$`),
+	"PH": regexp.MustCompile(`^\d{4}$`),
+	"VN": regexp.MustCompile(`^\d{6}$`),
+	"AU": regexp.MustCompile(`^\d{4}$`),
+	"JP": regexp.MustCompile(`^\d{3}-\d{4}$`),
+	"US": regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+	"GB": regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}$`),
+	"NL": regexp.MustCompile(`^\d{4} ?[A-Z]{2}$`),
+}
+
+var defaultPostalCodeFormat = regexp.MustCompile(`^[A-Z0-9][A-Z0-9 -]{1,8}[A-Z0-9]$`)
+
+// ValidPostalCode reports whether postalCode is well formed for the given country.
+func ValidPostalCode(countryCode, postalCode string) bool {
+	format, ok := postalCodeFormats[strings.ToUpper(countryCode)]
+	if !ok {
+		format = defaultPostalCodeFormat
+	}
+	return format.MatchString(strings.ToUpper(postalCode))
+}
+
+func (a Address) NewFromPayload(load AddressPayload, userId, creatorId uuid.UUID) (res Address, err error) {
+	addressId, err := uuid.NewV4()
+	if err != nil {
+		return
+	}
+	res = Address{
+		Id:        addressId,
+		UserId:    userId,
+		CreatedAt: time.Now().UTC(),
+		CreatedBy: creatorId,
+	}
+	err = res.Update(load, creatorId)
+	return
+}
+
+// Update replaces the address fields with the ones from the payload.
+func (a *Address) Update(load AddressPayload, userId uuid.UUID) (err error) {
+	load.CountryCode = strings.ToUpper(load.CountryCode)
+	load.PostalCode = strings.ToUpper(strings.TrimSpace(load.PostalCode))
+	if !ValidPostalCode(load.CountryCode, load.PostalCode) {
+		err = failure.BadRequestFromString(fmt.Sprintf("invalid postal code %q for country %s", load.PostalCode, load.CountryCode))
+		return
+	}
+	a.Label = load.Label
+	a.RecipientName = load.RecipientName
+	a.PhoneNumber = load.PhoneNumber
+	a.Line1 = load.Line1
+	a.Line2 = null.NewString(load.Line2, load.Line2 != "")
+	a.City = load.City
+	a.Province = load.Province
+	a.PostalCode = load.PostalCode
+	a.CountryCode = load.CountryCode
+	a.IsDefaultShipping = load.IsDefaultShipping
+	a.IsDefaultBilling = load.IsDefaultBilling
+	a.UpdatedAt = time.Now().UTC()
+	a.UpdatedBy = userId
+	err = a.Validate()
+	return
+}
+
+func (a *Address) Validate() error {
+	validator := shared.GetValidator()
+	return validator.Struct(a)
+}
+
+func (a *Address) IsDeleted() bool {
+	return a.DeletedAt.Valid && a.DeletedBy.Valid
+}
+
+// IsDefault reports whether the address is the user's default of the given kind.
+func (a *Address) IsDefault(kind Kind) bool {
+	if kind == Billing {
+		return a.IsDefaultBilling
+	}
+	return a.IsDefaultShipping
+}
+
+func (a *Address) SoftDelete(deleter uuid.UUID) (err error) {
+	if a.IsDeleted() {
+		err = failure.Conflict("delete", "address", "already deleted")
+		return
+	}
+	a.IsDefaultShipping = false
+	a.IsDefaultBilling = false
+	a.DeletedAt = null.TimeFrom(time.Now().UTC())
+	a.DeletedBy = nuuid.From(deleter)
+	err = a.Validate()
+	return
+}
+
+func (a Address) ToResponseFormat() AddressResponseFormat {
+	return AddressResponseFormat(a)
+}
+
+func (a Address) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.ToResponseFormat())
+}
@@ -0,0 +1,157 @@
+package address
+
+import (
+	"database/sql"
+
+	"github.com/evermos/boilerplate-go/infras"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+type AddressRepository interface {
+	Create(load Address) (err error)
+	Update(load Address) (err error)
+	GetByID(id string) (res Address, err error)
+	GetAllByUserID(userId string) (res []Address, err error)
+	CountByUserID(userId string) (count int, err error)
+}
+
+type AddressRepositoryMySQL struct {
+	DB *infras.MySQLConn
+}
+
+func ProvideAddressRepositoryMySQL(db *infras.MySQLConn) *AddressRepositoryMySQL {
+	return &AddressRepositoryMySQL{DB: db}
+}
+
+func (r *AddressRepositoryMySQL) Create(load Address) (err error) {
+	return r.DB.WithTransaction(func(db *sqlx.Tx, c chan error) {
+		if err := r.txClearDefaults(db, load); err != nil {
+			c <- err
+			return
+		}
+		if err := r.txCreate(db, load); err != nil {
+			c <- err
+			return
+		}
+		c <- nil
+	})
+}
+
+func (r *AddressRepositoryMySQL) Update(load Address) (err error) {
+	return r.DB.WithTransaction(func(db *sqlx.Tx, c chan error) {
+		if err := r.txClearDefaults(db, load); err != nil {
+			c <- err
+			return
+		}
+		if err := r.txUpdate(db, load); err != nil {
+			c <- err
+			return
+		}
+		c <- nil
+	})
+}
+
+func (r *AddressRepositoryMySQL) txCreate(tx *sqlx.Tx, load Address) (err error) {
+	query := `INSERT INTO address (id,user_id,label,recipient_name,phone_number,line1,line2,city,province,postal_code,country_code,is_default_shipping,is_default_billing,created_at,created_by,updated_at,updated_by)
+	VALUES (:id,:user_id,:label,:recipient_name,:phone_number,:line1,:line2,:city,:province,:postal_code,:country_code,:is_default_shipping,:is_default_billing,:created_at,:created_by,:updated_at,:updated_by)`
+	stmt, err := tx.PrepareNamed(query)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(load)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+func (r *AddressRepositoryMySQL) txUpdate(tx *sqlx.Tx, load Address) (err error) {
+	query := `
+	UPDATE address
+	SET
+		label = :label,
+		recipient_name = :recipient_name,
+		phone_number = :phone_number,
+		line1 = :line1,
+		line2 = :line2,
+		city = :city,
+		province = :province,
+		postal_code = :postal_code,
+		country_code = :country_code,
+		is_default_shipping = :is_default_shipping,
+		is_default_billing = :is_default_billing,
+		updated_at = :updated_at,
+		updated_by = :updated_by,
+		deleted_at = :deleted_at,
+		deleted_by = :deleted_by
+	WHERE id = :id`
+	stmt, err := tx.PrepareNamed(query)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(load)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+// txClearDefaults unsets the default flags on the user's other addresses when
+// the given address takes them over, so each user has at most one default of
+// each kind.
+func (r *AddressRepositoryMySQL) txClearDefaults(tx *sqlx.Tx, load Address) (err error) {
+	if load.IsDefaultShipping {
+		_, err = tx.Exec("UPDATE address SET is_default_shipping = FALSE WHERE user_id = ? AND id <> ?", load.UserId.String(), load.Id.String())
+		if err != nil {
+			logger.ErrorWithStack(err)
+			return
+		}
+	}
+	if load.IsDefaultBilling {
+		_, err = tx.Exec("UPDATE address SET is_default_billing = FALSE WHERE user_id = ? AND id <> ?", load.UserId.String(), load.Id.String())
+		if err != nil {
+			logger.ErrorWithStack(err)
+			return
+		}
+	}
+	return
+}
+
+func (r *AddressRepositoryMySQL) GetByID(id string) (res Address, err error) {
+	err = r.DB.Read.Get(&res, "SELECT * FROM address WHERE id = ? AND deleted_at IS NULL", id)
+	if err == sql.ErrNoRows {
+		err = failure.NotFound("Address")
+		return
+	}
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+func (r *AddressRepositoryMySQL) GetAllByUserID(userId string) (res []Address, err error) {
+	err = r.DB.Read.Select(&res, "SELECT * FROM address WHERE user_id = ? AND deleted_at IS NULL ORDER BY created_at", userId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+func (r *AddressRepositoryMySQL) CountByUserID(userId string) (count int, err error) {
+	err = r.DB.Read.Get(&count, "SELECT COUNT(id) FROM address WHERE user_id = ? AND deleted_at IS NULL", userId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
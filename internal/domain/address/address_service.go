@@ -0,0 +1,108 @@
+package address
+
+import (
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/nuuid"
+	"github.com/gofrs/uuid"
+)
+
+type AddressService interface {
+	Create(load AddressPayload, userId, creatorId uuid.UUID) (res Address, err error)
+	GetAllByUserID(userId uuid.UUID) (res []Address, err error)
+	GetByID(id, userId uuid.UUID) (res Address, err error)
+	Update(load AddressPayload, id, userId, updaterId uuid.UUID) (res Address, err error)
+	DeleteByID(id, userId, deleterId uuid.UUID) (res Address, err error)
+	ResolveForCheckout(addressId nuuid.NUUID, userId uuid.UUID, kind Kind) (res nuuid.NUUID, err error)
+}
+
+type AddressServiceImpl struct {
+	Repo AddressRepository
+}
+
+func ProvideAddressServiceImpl(repo AddressRepository) *AddressServiceImpl {
+	return &AddressServiceImpl{Repo: repo}
+}
+
+func (s *AddressServiceImpl) Create(load AddressPayload, userId, creatorId uuid.UUID) (res Address, err error) {
+	count, err := s.Repo.CountByUserID(userId.String())
+	if err != nil {
+		return
+	}
+	// the first address becomes the default for everything
+	if count == 0 {
+		load.IsDefaultShipping = true
+		load.IsDefaultBilling = true
+	}
+	res, err = res.NewFromPayload(load, userId, creatorId)
+	if err != nil {
+		return
+	}
+	err = s.Repo.Create(res)
+	return
+}
+
+func (s *AddressServiceImpl) GetAllByUserID(userId uuid.UUID) (res []Address, err error) {
+	res, err = s.Repo.GetAllByUserID(userId.String())
+	return
+}
+
+func (s *AddressServiceImpl) GetByID(id, userId uuid.UUID) (res Address, err error) {
+	res, err = s.Repo.GetByID(id.String())
+	if err != nil {
+		return
+	}
+	if res.UserId != userId {
+		err = failure.NotFound("Address")
+		return
+	}
+	return
+}
+
+func (s *AddressServiceImpl) Update(load AddressPayload, id, userId, updaterId uuid.UUID) (res Address, err error) {
+	res, err = s.GetByID(id, userId)
+	if err != nil {
+		return
+	}
+	err = res.Update(load, updaterId)
+	if err != nil {
+		return
+	}
+	err = s.Repo.Update(res)
+	return
+}
+
+func (s *AddressServiceImpl) DeleteByID(id, userId, deleterId uuid.UUID) (res Address, err error) {
+	res, err = s.GetByID(id, userId)
+	if err != nil {
+		return
+	}
+	err = res.SoftDelete(deleterId)
+	if err != nil {
+		return
+	}
+	err = s.Repo.Update(res)
+	return
+}
+
+// ResolveForCheckout returns the address to use on an order. An explicitly
+// given address must belong to the user, otherwise the user's default of the
+// given kind is used. The result is null if the user has no such default.
+func (s *AddressServiceImpl) ResolveForCheckout(addressId nuuid.NUUID, userId uuid.UUID, kind Kind) (res nuuid.NUUID, err error) {
+	if addressId.Valid {
+		addr, err := s.GetByID(addressId.UUID, userId)
+		if err != nil {
+			return res, err
+		}
+		return nuuid.From(addr.Id), nil
+	}
+	addresses, err := s.Repo.GetAllByUserID(userId.String())
+	if err != nil {
+		return
+	}
+	for _, addr := range addresses {
+		if addr.IsDefault(kind) {
+			return nuuid.From(addr.Id), nil
+		}
+	}
+	return
+}
@@ -0,0 +1,36 @@
+package alert
+
+import (
+	"fmt"
+
+	"github.com/evermos/boilerplate-go/internal/domain/inventory"
+	"github.com/gofrs/uuid"
+)
+
+// Recipient is a user an alert is sent to.
+type Recipient struct {
+	UserId uuid.UUID `db:"id"`
+	Email  string    `db:"email"`
+	Name   string    `db:"name"`
+}
+
+// LowStockMessage returns the subject and body of the alert staff get
+// about the event.
+func LowStockMessage(r Recipient, e inventory.Event) (subject, body string) {
+	if e.StockAfter <= 0 {
+		subject = "Out of stock: " + e.Item
+		body = fmt.Sprintf("Hi %s, %s is out of stock.", r.Name, e.Item)
+		return
+	}
+	subject = "Low stock: " + e.Item
+	body = fmt.Sprintf("Hi %s, %s is down to %d in stock, its reorder level is %d.", r.Name, e.Item, e.StockAfter, e.ReorderLevel)
+	return
+}
+
+// RestockMessage returns the subject and body of the notification
+// customers waiting for the item of the event get.
+func RestockMessage(r Recipient, e inventory.Event) (subject, body string) {
+	subject = "Back in stock: " + e.Item
+	body = fmt.Sprintf("Hi %s, %s you asked about is back in stock.", r.Name, e.Item)
+	return
+}
@@ -0,0 +1,25 @@
+package alert_test
+
+import (
+	"testing"
+
+	"github.com/evermos/boilerplate-go/internal/domain/alert"
+	"github.com/evermos/boilerplate-go/internal/domain/inventory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLowStockMessage(t *testing.T) {
+	r := alert.Recipient{Name: "Ana"}
+
+	t.Run("tells the stock left and the reorder level", func(t *testing.T) {
+		subject, body := alert.LowStockMessage(r, inventory.Event{Item: "variant TEE-RED-M", StockAfter: 3, ReorderLevel: 5})
+		assert.Equal(t, "Low stock: variant TEE-RED-M", subject)
+		assert.Equal(t, "Hi Ana, variant TEE-RED-M is down to 3 in stock, its reorder level is 5.", body)
+	})
+
+	t.Run("tells when it ran out", func(t *testing.T) {
+		subject, body := alert.LowStockMessage(r, inventory.Event{Item: `product "Tee"`, StockAfter: 0})
+		assert.Equal(t, `Out of stock: product "Tee"`, subject)
+		assert.Equal(t, `Hi Ana, product "Tee" is out of stock.`, body)
+	})
+}
@@ -0,0 +1,42 @@
+package alert
+
+import (
+	"github.com/evermos/boilerplate-go/infras"
+	"github.com/evermos/boilerplate-go/internal/domain/group"
+	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/evermos/boilerplate-go/shared/roles"
+)
+
+type AlertRepository interface {
+	GetStaff(orgId string) (res []Recipient, err error)
+}
+
+type AlertRepositoryMySQL struct {
+	DB *infras.MySQLConn
+}
+
+func ProvideAlertRepositoryMySQL(db *infras.MySQLConn) *AlertRepositoryMySQL {
+	return &AlertRepositoryMySQL{DB: db}
+}
+
+// GetStaff returns the users who manage the products of the organization:
+// its admins and the members of groups granting products.write.
+func (r *AlertRepositoryMySQL) GetStaff(orgId string) (res []Recipient, err error) {
+	query := `SELECT u.id, u.email, COALESCE(u.name, u.username) AS name FROM user u
+	WHERE u.deleted_at IS NULL AND (
+		u.id IN (SELECT user_id FROM organization_member WHERE organization_id = ? AND role = ?)
+		OR u.id IN (
+			SELECT m.user_id FROM user_group_member m
+			JOIN user_group g ON g.id = m.group_id
+			JOIN user_group_permission p ON p.group_id = g.id
+			WHERE g.organization_id = ? AND p.permission = ?
+		)
+	)
+	ORDER BY u.email`
+	res = []Recipient{}
+	err = r.DB.Read.Select(&res, query, orgId, roles.GetStringFromRole(roles.Admin), orgId, group.PermissionProductsWrite)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+	return
+}
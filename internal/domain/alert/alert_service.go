@@ -0,0 +1,102 @@
+package alert
+
+import (
+	"encoding/json"
+
+	"github.com/evermos/boilerplate-go/internal/domain/inventory"
+	"github.com/evermos/boilerplate-go/internal/domain/preference"
+	"github.com/evermos/boilerplate-go/internal/domain/user"
+	"github.com/evermos/boilerplate-go/shared/email"
+	"github.com/evermos/boilerplate-go/shared/logger"
+)
+
+// AlertService delivers the stock events of the inventory: alerts to the
+// staff of an organization when stock runs low, and notifications to the
+// customers waiting for an item to be back in stock. Its methods process
+// the messages published on the inventory topics.
+type AlertService interface {
+	NotifyLowStock(message []byte) (err error)
+	NotifyRestock(message []byte) (err error)
+}
+
+type AlertServiceImpl struct {
+	Repo              AlertRepository
+	InventoryService  inventory.InventoryService
+	UserService       user.UserService
+	PreferenceService preference.PreferenceService
+	Mailer            email.Sender
+}
+
+func ProvideAlertServiceImpl(repo AlertRepository, inventoryService inventory.InventoryService, userService user.UserService, preferenceService preference.PreferenceService, mailer email.Sender) *AlertServiceImpl {
+	return &AlertServiceImpl{Repo: repo, InventoryService: inventoryService, UserService: userService, PreferenceService: preferenceService, Mailer: mailer}
+}
+
+// NotifyLowStock alerts the staff about the event. Alerts that cannot be
+// sent are logged and not retried, so nobody is alerted twice.
+func (s *AlertServiceImpl) NotifyLowStock(message []byte) (err error) {
+	var e inventory.Event
+	err = json.Unmarshal(message, &e)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	staff, err := s.Repo.GetStaff(e.OrganizationId.String())
+	if err != nil {
+		return
+	}
+	for _, r := range staff {
+		notify, err := s.PreferenceService.ShouldNotify(r.UserId, preference.ChannelEmail, preference.TopicLowStock)
+		if err != nil || !notify {
+			continue
+		}
+		subject, body := LowStockMessage(r, e)
+		if err := s.Mailer.Send(r.Email, subject, body); err != nil {
+			logger.ErrorWithStack(err)
+		}
+	}
+	return nil
+}
+
+// NotifyRestock notifies the customers subscribed to the item of the event
+// and fulfils their subscriptions, also of those who turned these
+// notifications off. A failure is returned after trying everyone; retrying
+// only reaches the subscriptions that are still pending.
+func (s *AlertServiceImpl) NotifyRestock(message []byte) (err error) {
+	var e inventory.Event
+	err = json.Unmarshal(message, &e)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	subs, err := s.InventoryService.GetPendingSubscriptions(e)
+	if err != nil {
+		return
+	}
+	var failed error
+	for _, sub := range subs {
+		if err := s.notifyRestock(sub, e); err != nil {
+			failed = err
+		}
+	}
+	return failed
+}
+
+func (s *AlertServiceImpl) notifyRestock(sub inventory.Subscription, e inventory.Event) (err error) {
+	notify, err := s.PreferenceService.ShouldNotify(sub.UserId, preference.ChannelEmail, preference.TopicRestock)
+	if err != nil {
+		return
+	}
+	if notify {
+		u, err := s.UserService.GetByUserID(sub.UserId)
+		if err != nil {
+			return err
+		}
+		subject, body := RestockMessage(Recipient{UserId: u.UserId, Email: u.Email, Name: u.Name}, e)
+		err = s.Mailer.Send(u.Email, subject, body)
+		if err != nil {
+			logger.ErrorWithStack(err)
+			return err
+		}
+	}
+	return s.InventoryService.MarkNotified(sub)
+}
@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 
 	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/nuuid"
 	"github.com/evermos/boilerplate-go/shared/roles"
+	"github.com/gofrs/uuid"
 )
 
 type AuthPayload struct {
@@ -16,8 +18,13 @@ type AuthPayload struct {
 }
 
 type LoginPayload struct {
-	UserName string `json:"userName" validate:"required"`
-	Password string `json:"password" validate:"required"`
+	UserName       string      `json:"userName" validate:"required"`
+	Password       string      `json:"password" validate:"required"`
+	OrganizationId nuuid.NUUID `json:"organizationId"`
+}
+
+type SwitchOrganizationPayload struct {
+	OrganizationId uuid.UUID `json:"organizationId" validate:"required"`
 }
 
 type JwtResponseFormat struct {
@@ -2,33 +2,50 @@ package auth
 
 import (
 	"github.com/evermos/boilerplate-go/configs"
+	"github.com/evermos/boilerplate-go/internal/domain/organization"
 	"github.com/evermos/boilerplate-go/internal/domain/user"
+	"github.com/evermos/boilerplate-go/shared/failure"
 	"github.com/evermos/boilerplate-go/shared/jwt"
+	"github.com/evermos/boilerplate-go/shared/roles"
+	"github.com/gofrs/uuid"
 )
 
 type AuthService interface {
-	Register(payload AuthPayload) (res JwtResponseFormat, err error)
+	Register(payload AuthPayload, orgId uuid.UUID, grantorRole string) (res JwtResponseFormat, err error)
 	Login(payload LoginPayload) (res JwtResponseFormat, err error)
+	SwitchOrganization(payload SwitchOrganizationPayload, userId uuid.UUID) (res JwtResponseFormat, err error)
 }
 
 type AuthServiceImpl struct {
-	Repo        AuthRepository
-	Config      *configs.Config
-	UserService user.UserService
+	Repo                AuthRepository
+	Config              *configs.Config
+	UserService         user.UserService
+	OrganizationService organization.OrganizationService
 }
 
-func ProvideAuthServiceImpl(repo AuthRepository, conf *configs.Config, userService user.UserService) *AuthServiceImpl {
-	return &AuthServiceImpl{Config: conf, Repo: repo, UserService: userService}
+func ProvideAuthServiceImpl(repo AuthRepository, conf *configs.Config, userService user.UserService, organizationService organization.OrganizationService) *AuthServiceImpl {
+	return &AuthServiceImpl{Config: conf, Repo: repo, UserService: userService, OrganizationService: organizationService}
 }
 
-func (s *AuthServiceImpl) Register(payload AuthPayload) (res JwtResponseFormat, err error) {
+// Register creates a user in the organization orgId and signs them in there.
+// Only admins may register users with a role above their own grantorRole.
+func (s *AuthServiceImpl) Register(payload AuthPayload, orgId uuid.UUID, grantorRole string) (res JwtResponseFormat, err error) {
+	if !roles.CanGrant(grantorRole, payload.Role) {
+		err = failure.Unauthorized("not allowed to grant the role " + payload.Role)
+		return
+	}
+
+	user, err := s.UserService.Create(user.UserPayload(payload), orgId)
+	if err != nil {
+		return
+	}
 
-	user, err := s.UserService.Create(user.UserPayload(payload))
+	member, err := s.OrganizationService.GetMember(orgId, user.UserId)
 	if err != nil {
 		return
 	}
 
-	res, err = s.createToken(user)
+	res, err = s.createToken(user, member)
 	if err != nil {
 		return
 	}
@@ -36,17 +53,33 @@ func (s *AuthServiceImpl) Register(payload AuthPayload) (res JwtResponseFormat,
 	return
 }
 
+// Login signs the user in to the requested organization, or to their home
+// organization when none is given.
 func (s *AuthServiceImpl) Login(payload LoginPayload) (res JwtResponseFormat, err error) {
 	user, err := s.UserService.GetByUserName(payload.UserName)
 	if err != nil {
 		return
 	}
+	if user.IsDeleted() {
+		err = failure.Unauthorized("account is deactivated")
+		return
+	}
 	err = user.ValidatePassword(payload.Password)
 	if err != nil {
 		return
 	}
 
-	res, err = s.createToken(user)
+	orgId := user.OrganizationId
+	if payload.OrganizationId.Valid {
+		orgId = payload.OrganizationId.UUID
+	}
+	member, err := s.OrganizationService.GetMember(orgId, user.UserId)
+	if err != nil {
+		err = failure.Unauthorized("not a member of the organization")
+		return
+	}
+
+	res, err = s.createToken(user, member)
 	if err != nil {
 		return
 	}
@@ -54,9 +87,29 @@ func (s *AuthServiceImpl) Login(payload LoginPayload) (res JwtResponseFormat, er
 	return
 }
 
-func (s *AuthServiceImpl) createToken(user user.User) (res JwtResponseFormat, err error) {
+// SwitchOrganization issues a new token for another organization the user
+// is a member of.
+func (s *AuthServiceImpl) SwitchOrganization(payload SwitchOrganizationPayload, userId uuid.UUID) (res JwtResponseFormat, err error) {
+	user, err := s.UserService.GetByUserID(userId)
+	if err != nil {
+		return
+	}
+	if user.IsDeleted() {
+		err = failure.Unauthorized("account is deactivated")
+		return
+	}
+	member, err := s.OrganizationService.GetMember(payload.OrganizationId, userId)
+	if err != nil {
+		err = failure.Unauthorized("not a member of the organization")
+		return
+	}
+	res, err = s.createToken(user, member)
+	return
+}
+
+func (s *AuthServiceImpl) createToken(user user.User, member organization.Member) (res JwtResponseFormat, err error) {
 	jwt := jwt.NewJWT(s.Config.App.JWTSecret)
-	token, err := jwt.GenerateJwt(user.UserId.String(), user.UserName, user.Role, user.CartId.String())
+	token, err := jwt.GenerateJwt(user.UserId.String(), user.UserName, member.Role, member.CartId.String(), member.OrganizationId.String())
 	if err != nil {
 		return
 	}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/evermos/boilerplate-go/internal/domain/product"
 	"github.com/evermos/boilerplate-go/shared"
 	"github.com/evermos/boilerplate-go/shared/nuuid"
 	"github.com/gofrs/uuid"
@@ -11,79 +12,105 @@ import (
 )
 
 type Cart struct {
-	Id        uuid.UUID   `db:"id" validate:"required"`
-	UserId    uuid.UUID   `db:"user_id" validate:"required"`
-	CartItems []CartItem  `db:"-"`
-	CreatedAt time.Time   `db:"created_at" validate:"required"`
-	UpdatedAt time.Time   `db:"updated_at" validate:"required"`
-	DeletedAt null.Time   `db:"deleted_at"`
-	CreatedBy uuid.UUID   `db:"created_by" validate:"required"`
-	UpdatedBy uuid.UUID   `db:"updated_by" validate:"required"`
-	DeletedBy nuuid.NUUID `db:"deleted_by"`
-}
+	Id             uuid.UUID   `db:"id" validate:"required"`
+	UserId         uuid.UUID   `db:"user_id" validate:"required"`
+	OrganizationId uuid.UUID   `db:"organization_id" validate:"required"`
+	CartItems      []CartItem  `db:"-"`
+	CreatedAt      time.Time   `db:"created_at" validate:"required"`
+	UpdatedAt      time.Time   `db:"updated_at" validate:"required"`
+	DeletedAt      null.Time   `db:"deleted_at"`
+	CreatedBy      uuid.UUID   `db:"created_by" validate:"required"`
+	UpdatedBy      uuid.UUID   `db:"updated_by" validate:"required"`
+	DeletedBy      nuuid.NUUID `db:"deleted_by"`
+}
+
+// Hold statuses of cart items. A hold keeps the item's quantity aside for
+// the shopper until it expires; items without a hold have none.
+const (
+	HoldNone    = "none"
+	HoldActive  = "active"
+	HoldExpired = "expired"
+)
 
 type CartItem struct {
 	Id        uuid.UUID   `db:"id" validate:"required"`
 	CartId    uuid.UUID   `db:"cart_id" validate:"required"`
 	ProductId uuid.UUID   `db:"product_id" validate:"required"`
+	VariantId nuuid.NUUID `db:"variant_id"`
 	Quantity  int         `db:"quantity" validate:"required"`
 	Price     float64     `db:"price" validate:"required"`
-	CreatedAt time.Time   `db:"created_at" validate:"required"`
-	UpdatedAt time.Time   `db:"updated_at" validate:"required"`
-	DeletedAt null.Time   `db:"deleted_at"`
-	CreatedBy uuid.UUID   `db:"created_by" validate:"required"`
-	UpdatedBy uuid.UUID   `db:"updated_by" validate:"required"`
-	DeletedBy nuuid.NUUID `db:"deleted_by"`
-}
-
+	// HoldStatus is null for items that were never held. Holds past
+	// HeldUntil no longer count even before they are marked expired.
+	HoldStatus null.String `db:"hold_status"`
+	HeldUntil  null.Time   `db:"held_until"`
+	CreatedAt  time.Time   `db:"created_at" validate:"required"`
+	UpdatedAt  time.Time   `db:"updated_at" validate:"required"`
+	DeletedAt  null.Time   `db:"deleted_at"`
+	CreatedBy  uuid.UUID   `db:"created_by" validate:"required"`
+	UpdatedBy  uuid.UUID   `db:"updated_by" validate:"required"`
+	DeletedBy  nuuid.NUUID `db:"deleted_by"`
+}
+
+// CartItemPayload adds a product to a cart. VariantId is required for
+// products that come in variants and must be left out otherwise.
 type CartItemPayload struct {
-	ProductId uuid.UUID `json:"productId" validate:"required"`
-	Quantity  int       `json:"quantity" validate:"required"`
+	ProductId uuid.UUID   `json:"productId" validate:"required"`
+	VariantId nuuid.NUUID `json:"variantId"`
+	Quantity  int         `json:"quantity" validate:"required"`
 }
 
 type CartPayload struct {
-	CartId uuid.UUID `json:"id" validate:"required"`
-	UserId uuid.UUID `json:"userId" validate:"required"`
+	CartId         uuid.UUID `json:"id" validate:"required"`
+	UserId         uuid.UUID `json:"userId" validate:"required"`
+	OrganizationId uuid.UUID `json:"organizationId" validate:"required"`
 }
 
 type CheckoutPayload struct {
-	CartItemsIds []string `json:"items" validate:"required"`
+	CartItemsIds      []string    `json:"items" validate:"required"`
+	ShippingAddressId nuuid.NUUID `json:"shippingAddressId"`
+	BillingAddressId  nuuid.NUUID `json:"billingAddressId"`
 }
 
 type CartResponseFormat struct {
-	Id        uuid.UUID                `json:"id" validate:"required"`
-	UserId    uuid.UUID                `json:"userId" validate:"required"`
-	CartItems []CartItemResponseFormat `json:"cartItems,omitempty"`
-	CreatedAt time.Time                `json:"createdAt" validate:"required"`
-	UpdatedAt time.Time                `json:"updatedAt" validate:"required"`
-	DeletedAt null.Time                `json:"deletedAt,omitempty"`
-	CreatedBy uuid.UUID                `json:"createdBy"`
-	UpdatedBy uuid.UUID                `json:"updatedBy"`
-	DeletedBy nuuid.NUUID              `json:"deletedBy,omitempty"`
+	Id             uuid.UUID                `json:"id" validate:"required"`
+	UserId         uuid.UUID                `json:"userId" validate:"required"`
+	OrganizationId uuid.UUID                `json:"organizationId"`
+	CartItems      []CartItemResponseFormat `json:"cartItems,omitempty"`
+	CreatedAt      time.Time                `json:"createdAt" validate:"required"`
+	UpdatedAt      time.Time                `json:"updatedAt" validate:"required"`
+	DeletedAt      null.Time                `json:"deletedAt,omitempty"`
+	CreatedBy      uuid.UUID                `json:"createdBy"`
+	UpdatedBy      uuid.UUID                `json:"updatedBy"`
+	DeletedBy      nuuid.NUUID              `json:"deletedBy,omitempty"`
 }
 
 type CartItemResponseFormat struct {
 	Id        uuid.UUID   `json:"id" validate:"required"`
 	CartId    uuid.UUID   `json:"cartId" validate:"required"`
 	ProductId uuid.UUID   `json:"productId" validate:"required"`
+	VariantId nuuid.NUUID `json:"variantId"`
 	Quantity  int         `json:"quantity" validate:"required"`
 	Price     float64     `json:"price" validate:"required"`
-	CreatedAt time.Time   `json:"createdAt" validate:"required"`
-	UpdatedAt time.Time   `json:"updatedAt" validate:"required"`
-	DeletedAt null.Time   `json:"deletedAt,omitempty"`
-	CreatedBy uuid.UUID   `json:"createdBy"`
-	UpdatedBy uuid.UUID   `json:"updatedBy"`
-	DeletedBy nuuid.NUUID `json:"deletedBy,omitempty"`
+	// HoldStatus is none, active or expired.
+	HoldStatus string      `json:"holdStatus"`
+	HeldUntil  null.Time   `json:"heldUntil"`
+	CreatedAt  time.Time   `json:"createdAt" validate:"required"`
+	UpdatedAt  time.Time   `json:"updatedAt" validate:"required"`
+	DeletedAt  null.Time   `json:"deletedAt,omitempty"`
+	CreatedBy  uuid.UUID   `json:"createdBy"`
+	UpdatedBy  uuid.UUID   `json:"updatedBy"`
+	DeletedBy  nuuid.NUUID `json:"deletedBy,omitempty"`
 }
 
 func (c Cart) NewFromPayload(load CartPayload) (res Cart, err error) {
 	res = Cart{
-		Id:        load.CartId,
-		UserId:    load.UserId,
-		CreatedAt: time.Now().UTC(),
-		CreatedBy: load.UserId,
-		UpdatedAt: time.Now().UTC(),
-		UpdatedBy: load.UserId,
+		Id:             load.CartId,
+		UserId:         load.UserId,
+		OrganizationId: load.OrganizationId,
+		CreatedAt:      time.Now().UTC(),
+		CreatedBy:      load.UserId,
+		UpdatedAt:      time.Now().UTC(),
+		UpdatedBy:      load.UserId,
 	}
 	err = res.Validate()
 	return
@@ -99,6 +126,7 @@ func (c CartItem) NewFromPayload(load CartItemPayload, cartId, userId uuid.UUID,
 		Id:        cartItemId,
 		CartId:    cartId,
 		ProductId: load.ProductId,
+		VariantId: load.VariantId,
 		Quantity:  load.Quantity,
 		Price:     cartItemPrice,
 		CreatedAt: time.Now().UTC(),
@@ -130,22 +158,38 @@ func (c *Cart) ToResponseFormat() CartResponseFormat {
 		items = append(items, item.ToResponseFormat())
 	}
 	res := CartResponseFormat{
-		Id:        c.Id,
-		UserId:    c.UserId,
-		CartItems: items,
-		CreatedAt: c.CreatedAt,
-		UpdatedAt: c.UpdatedAt,
-		DeletedAt: c.DeletedAt,
-		CreatedBy: c.CreatedBy,
-		UpdatedBy: c.UpdatedBy,
-		DeletedBy: c.DeletedBy,
+		Id:             c.Id,
+		UserId:         c.UserId,
+		OrganizationId: c.OrganizationId,
+		CartItems:      items,
+		CreatedAt:      c.CreatedAt,
+		UpdatedAt:      c.UpdatedAt,
+		DeletedAt:      c.DeletedAt,
+		CreatedBy:      c.CreatedBy,
+		UpdatedBy:      c.UpdatedBy,
+		DeletedBy:      c.DeletedBy,
 	}
 
 	return res
 }
 
 func (c CartItem) ToResponseFormat() (res CartItemResponseFormat) {
-	return CartItemResponseFormat(c)
+	return CartItemResponseFormat{
+		Id:         c.Id,
+		CartId:     c.CartId,
+		ProductId:  c.ProductId,
+		VariantId:  c.VariantId,
+		Quantity:   c.Quantity,
+		Price:      c.Price,
+		HoldStatus: c.HoldState(time.Now().UTC()),
+		HeldUntil:  c.HeldUntil,
+		CreatedAt:  c.CreatedAt,
+		UpdatedAt:  c.UpdatedAt,
+		DeletedAt:  c.DeletedAt,
+		CreatedBy:  c.CreatedBy,
+		UpdatedBy:  c.UpdatedBy,
+		DeletedBy:  c.DeletedBy,
+	}
 }
 
 func (c Cart) MarshalJSON() ([]byte, error) {
@@ -168,6 +212,44 @@ func (c *CartItem) Update(load CartItemPayload, userId uuid.UUID) {
 	c.UpdatedBy = userId
 }
 
+// Hold keeps the item's quantity aside for the shopper until the given time.
+func (c *CartItem) Hold(until time.Time) {
+	c.HoldStatus = null.StringFrom(HoldActive)
+	c.HeldUntil = null.TimeFrom(until)
+}
+
+// HoldState returns the status of the item's hold at now.
+func (c CartItem) HoldState(now time.Time) string {
+	if !c.HoldStatus.Valid {
+		return HoldNone
+	}
+	if c.HoldStatus.String == HoldActive && c.HeldUntil.Valid && c.HeldUntil.Time.After(now) {
+		return HoldActive
+	}
+	return HoldExpired
+}
+
 func (c *CartItem) Recalculate(productPrice float64) {
 	c.Price = float64(c.Quantity) * productPrice
 }
+
+// Reprice brings the items of the changed product or variant to its new
+// price for their current quantity and returns those whose price changed.
+// Items of other products or variants are left alone.
+func Reprice(items []CartItem, change product.PriceChange) (res []CartItem) {
+	res = []CartItem{}
+	for _, item := range items {
+		if item.ProductId != change.ProductId || item.VariantId != change.VariantId {
+			continue
+		}
+		before := item.Price
+		item.Recalculate(change.Price)
+		if item.Price == before {
+			continue
+		}
+		item.UpdatedAt = change.ChangedAt
+		item.UpdatedBy = change.ChangedBy
+		res = append(res, item)
+	}
+	return
+}
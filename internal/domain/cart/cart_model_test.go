@@ -0,0 +1,57 @@
+package cart_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/evermos/boilerplate-go/internal/domain/cart"
+	"github.com/evermos/boilerplate-go/internal/domain/product"
+	"github.com/evermos/boilerplate-go/shared/nuuid"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCartItemHoldState(t *testing.T) {
+	now := time.Now().UTC()
+
+	item := cart.CartItem{}
+	assert.Equal(t, cart.HoldNone, item.HoldState(now))
+
+	item.Hold(now.Add(15 * time.Minute))
+	assert.Equal(t, cart.HoldActive, item.HoldState(now))
+	assert.Equal(t, cart.HoldActive, item.ToResponseFormat().HoldStatus)
+
+	// a hold that ran out is expired before the worker gets to it
+	assert.Equal(t, cart.HoldExpired, item.HoldState(now.Add(time.Hour)))
+
+	item.HoldStatus.SetValid(cart.HoldExpired)
+	assert.Equal(t, cart.HoldExpired, item.HoldState(now))
+}
+
+func TestReprice(t *testing.T) {
+	productId, variantId := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	userId := uuid.Must(uuid.NewV4())
+	change := product.PriceChange{ProductId: productId, Price: 8, ChangedAt: time.Now().UTC(), ChangedBy: userId}
+
+	items := []cart.CartItem{
+		// priced 2 x 10 before the change, then raised to 3 units
+		{ProductId: productId, Quantity: 3, Price: 20},
+		{ProductId: productId, Quantity: 1, Price: 8},
+		{ProductId: productId, VariantId: nuuid.From(variantId), Quantity: 1, Price: 12},
+		{ProductId: uuid.Must(uuid.NewV4()), Quantity: 1, Price: 10},
+	}
+	res := cart.Reprice(items, change)
+	// items already at the price and those of variants or other products
+	// are left alone
+	assert.Len(t, res, 1)
+	assert.Equal(t, float64(24), res[0].Price)
+	assert.Equal(t, userId, res[0].UpdatedBy)
+	assert.Equal(t, float64(20), items[0].Price)
+
+	// a variant's change leaves the product's own items alone
+	change.VariantId = nuuid.From(variantId)
+	res = cart.Reprice(items, change)
+	assert.Len(t, res, 1)
+	assert.Equal(t, variantId, res[0].VariantId.UUID)
+	assert.Equal(t, float64(8), res[0].Price)
+}
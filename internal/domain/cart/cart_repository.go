@@ -2,9 +2,12 @@ package cart
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/evermos/boilerplate-go/infras"
+	"github.com/evermos/boilerplate-go/internal/domain/product"
 	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/evermos/boilerplate-go/shared/nuuid"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -16,10 +19,14 @@ type CartRepository interface {
 	CartItemExistsByID(id string) (exists bool, err error)
 	GetCartItemsByID(itemId string) (res CartItem, err error)
 	Checkout(load []string) (err error)
-	ProductExistsInCart(productId, cartId string) (exists bool, err error)
-	GetCartItemByProduct(productId, cartId string) (res CartItem, err error)
+	ProductExistsInCart(productId string, variantId nuuid.NUUID, cartId string) (exists bool, err error)
+	GetCartItemByProduct(productId string, variantId nuuid.NUUID, cartId string) (res CartItem, err error)
 	UpdateItem(item CartItem) (err error)
-	GetAllCarts(limit, offset int, sort, field string) (res []Cart, err error)
+	GetAllCarts(orgId string, limit, offset int, sort, field string) (res []Cart, err error)
+	GetCartsByUserID(userId string) (res []Cart, err error)
+	HeldQuantity(productId string, variantId nuuid.NUUID, exceptCartId string, now time.Time) (held int, err error)
+	ExpireHolds(now time.Time) (err error)
+	RepriceItems(change product.PriceChange) (err error)
 }
 
 type CartRepositoryMySQL struct {
@@ -68,8 +75,8 @@ func (r *CartRepositoryMySQL) GetCartItems(cartId string) (res []CartItem, err e
 }
 
 func (r *CartRepositoryMySQL) txCreateItem(tx *sqlx.Tx, load CartItem) (err error) {
-	query := `INSERT INTO cart_item (id,cart_id,product_id,quantity,price,created_at,created_by,updated_at,updated_by)
-	VALUES (:id,:cart_id,:product_id,:quantity,:price,:created_at,:created_by,:updated_at,:updated_by)`
+	query := `INSERT INTO cart_item (id,cart_id,product_id,variant_id,quantity,price,hold_status,held_until,created_at,created_by,updated_at,updated_by)
+	VALUES (:id,:cart_id,:product_id,:variant_id,:quantity,:price,:hold_status,:held_until,:created_at,:created_by,:updated_at,:updated_by)`
 	stmt, err := tx.PrepareNamed(query)
 	if err != nil {
 		logger.ErrorWithStack(err)
@@ -126,8 +133,10 @@ func (r *CartRepositoryMySQL) GetCartItemsByID(itemId string) (res CartItem, err
 	return
 }
 
-func (r *CartRepositoryMySQL) ProductExistsInCart(productId, cartId string) (exists bool, err error) {
-	err = r.DB.Read.Get(&exists, "SELECT COUNT(product_id) FROM cart_item WHERE product_id = ? AND cart_id = ?", productId, cartId)
+// ProductExistsInCart reports whether the cart has an item for the product
+// in the given variant, or without a variant when variantId is null.
+func (r *CartRepositoryMySQL) ProductExistsInCart(productId string, variantId nuuid.NUUID, cartId string) (exists bool, err error) {
+	err = r.DB.Read.Get(&exists, "SELECT COUNT(product_id) FROM cart_item WHERE product_id = ? AND variant_id <=> ? AND cart_id = ?", productId, variantId, cartId)
 	if err != nil {
 		logger.ErrorWithStack(err)
 		return
@@ -135,8 +144,8 @@ func (r *CartRepositoryMySQL) ProductExistsInCart(productId, cartId string) (exi
 	return
 }
 
-func (r *CartRepositoryMySQL) GetCartItemByProduct(productId, cartId string) (res CartItem, err error) {
-	err = r.DB.Read.Get(&res, "SELECT * FROM cart_item WHERE product_id = ? AND cart_id = ?", productId, cartId)
+func (r *CartRepositoryMySQL) GetCartItemByProduct(productId string, variantId nuuid.NUUID, cartId string) (res CartItem, err error) {
+	err = r.DB.Read.Get(&res, "SELECT * FROM cart_item WHERE product_id = ? AND variant_id <=> ? AND cart_id = ?", productId, variantId, cartId)
 	if err != nil {
 		logger.ErrorWithStack(err)
 		return
@@ -161,6 +170,8 @@ func (r *CartRepositoryMySQL) txUpdateItem(tx *sqlx.Tx, item CartItem) (err erro
 	SET
 		quantity = :quantity,
 		price = :price,
+		hold_status = :hold_status,
+		held_until = :held_until,
 		created_at = :created_at,
 		updated_at = :updated_at,
 		deleted_at = :deleted_at,
@@ -183,13 +194,72 @@ func (r *CartRepositoryMySQL) txUpdateItem(tx *sqlx.Tx, item CartItem) (err erro
 	return
 }
 
-func (r *CartRepositoryMySQL) GetAllCarts(limit, offset int, sort, field string) (res []Cart, err error) {
-	query := `SELECT * FROM cart `
+func (r *CartRepositoryMySQL) GetAllCarts(orgId string, limit, offset int, sort, field string) (res []Cart, err error) {
+	query := `SELECT * FROM cart WHERE organization_id = ? `
 	query += fmt.Sprintf("ORDER BY %s %s LIMIT %d OFFSET %d", field, sort, limit, offset)
-	err = r.DB.Read.Select(&res, query)
+	err = r.DB.Read.Select(&res, query, orgId)
 	if err != nil {
 		logger.ErrorWithStack(err)
 		return
 	}
 	return
 }
+
+func (r *CartRepositoryMySQL) GetCartsByUserID(userId string) (res []Cart, err error) {
+	err = r.DB.Read.Select(&res, "SELECT * FROM cart WHERE user_id = ? ORDER BY created_at", userId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+// HeldQuantity returns how much of the product, or of the variant when it is
+// valid, is held at now by carts other than exceptCartId.
+func (r *CartRepositoryMySQL) HeldQuantity(productId string, variantId nuuid.NUUID, exceptCartId string, now time.Time) (held int, err error) {
+	query := `SELECT COALESCE(SUM(quantity), 0) FROM cart_item
+	WHERE product_id = ? AND variant_id <=> ? AND cart_id <> ? AND hold_status = ? AND held_until > ?`
+	err = r.DB.Read.Get(&held, query, productId, variantId, exceptCartId, HoldActive, now)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+// ExpireHolds marks the holds that ran out by now as expired.
+func (r *CartRepositoryMySQL) ExpireHolds(now time.Time) (err error) {
+	_, err = r.DB.Write.Exec("UPDATE cart_item SET hold_status = ? WHERE hold_status = ? AND held_until <= ?", HoldExpired, HoldActive, now)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+// RepriceItems brings the items of the changed product in every cart, or
+// of the variant when it is valid, to the new price. The items are locked
+// while they are repriced and only their price is written, so quantities
+// changed meanwhile are kept and priced as they are.
+func (r *CartRepositoryMySQL) RepriceItems(change product.PriceChange) (err error) {
+	return r.DB.WithTransaction(func(db *sqlx.Tx, c chan error) {
+		items := []CartItem{}
+		err := db.Select(&items, "SELECT * FROM cart_item WHERE product_id = ? AND variant_id <=> ? AND deleted_at IS NULL FOR UPDATE",
+			change.ProductId.String(), change.VariantId)
+		if err != nil {
+			logger.ErrorWithStack(err)
+			c <- err
+			return
+		}
+		for _, item := range Reprice(items, change) {
+			_, err = db.Exec("UPDATE cart_item SET price = ?, updated_at = ?, updated_by = ? WHERE id = ?",
+				item.Price, item.UpdatedAt, item.UpdatedBy.String(), item.Id.String())
+			if err != nil {
+				logger.ErrorWithStack(err)
+				c <- err
+				return
+			}
+		}
+		c <- nil
+	})
+}
@@ -2,70 +2,209 @@ package cart
 
 import (
 	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
 
+	"github.com/evermos/boilerplate-go/configs"
+	"github.com/evermos/boilerplate-go/internal/domain/address"
 	"github.com/evermos/boilerplate-go/internal/domain/order"
 	"github.com/evermos/boilerplate-go/internal/domain/product"
+	"github.com/evermos/boilerplate-go/internal/domain/variant"
 	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/nuuid"
 	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
 )
 
 type CartService interface {
-	AddToCart(load CartItemPayload, userId, cartId uuid.UUID) (res CartItem, err error)
-	GetCart(cartId uuid.UUID) (res Cart, err error)
-	Checkout(load CheckoutPayload, cartId, userId uuid.UUID) (res order.Order, err error)
-	GetAllCarts(limit, offset int, sort, field string) (res []Cart, err error)
+	AddToCart(load CartItemPayload, userId, cartId, orgId uuid.UUID) (res CartItem, err error)
+	GetCart(cartId, orgId uuid.UUID) (res Cart, err error)
+	GetAllByUserID(userId uuid.UUID) (res []Cart, err error)
+	Checkout(load CheckoutPayload, cartId, userId, orgId uuid.UUID) (res order.Order, err error)
+	GetAllCarts(orgId uuid.UUID, limit, offset int, sort, field string) (res []Cart, err error)
+	ExpireHolds() (err error)
+	// RepriceCarts brings open carts to the prices changed since the last
+	// run.
+	RepriceCarts() (err error)
 }
 
 type CartServiceImpl struct {
 	Repo           CartRepository
+	Config         *configs.Config
 	ProductService product.ProductService
+	VariantService variant.VariantService
 	OrderService   order.OrderService
+	AddressService address.AddressService
 }
 
-func ProvideCartServiceImpl(repo CartRepository, proService product.ProductService, ordService order.OrderService) *CartServiceImpl {
-	return &CartServiceImpl{Repo: repo, ProductService: proService, OrderService: ordService}
+func ProvideCartServiceImpl(repo CartRepository, config *configs.Config, proService product.ProductService, varService variant.VariantService, ordService order.OrderService, addrService address.AddressService) *CartServiceImpl {
+	return &CartServiceImpl{Repo: repo, Config: config, ProductService: proService, VariantService: varService, OrderService: ordService, AddressService: addrService}
 }
 
-func (s *CartServiceImpl) AddToCart(load CartItemPayload, userId, cartId uuid.UUID) (res CartItem, err error) {
-	exists, err := s.Repo.CartExistsByID(cartId.String())
+// AddToCart adds the product to the cart, or more of it when the cart
+// already has it. Stock held by other carts is not available; with holds on,
+// the item is held for this cart for the configured time. Products taking
+// backorders or pre-orders can be added beyond their stock.
+func (s *CartServiceImpl) AddToCart(load CartItemPayload, userId, cartId, orgId uuid.UUID) (res CartItem, err error) {
+	_, err = s.getCart(cartId, orgId)
 	if err != nil {
 		return
 	}
-	if !exists {
-		err = failure.NotFound("Cart")
+	prod, err := s.ProductService.GetByID(load.ProductId, orgId)
+	if err != nil {
 		return
 	}
-	prod, err := s.ProductService.GetByID(load.ProductId)
+	if !prod.IsPublished(time.Now().UTC()) {
+		err = failure.Conflict("add", "product", "product is not available")
+		return
+	}
+	price, stock, err := s.priceAndStock(prod, load.VariantId, orgId)
 	if err != nil {
 		return
 	}
-	if prod.Stock < load.Quantity {
-		err = failure.BadRequest(errors.New("not enough stock available"))
+	available, waiting, err := s.availableStock(prod.Id, load.VariantId, cartId, stock)
+	if err != nil {
 		return
 	}
-	exists, err = s.ProductExistsInCart(cartId, prod.Id)
+	exists, err := s.ProductExistsInCart(cartId, prod.Id, load.VariantId)
 	if err != nil {
 		return
 	}
 	if exists {
-		res, err = s.UpdateCartItem(load, userId, cartId, prod.Id, prod.Price)
+		res, err = s.UpdateCartItem(load, userId, cartId, prod, price, available, waiting)
 		return
 	}
-	res, err = res.NewFromPayload(load, cartId, userId, prod.Price)
+	err = s.ensureOrderable(prod, load.Quantity, available, waiting)
 	if err != nil {
 		return
 	}
+	res, err = res.NewFromPayload(load, cartId, userId, price)
+	if err != nil {
+		return
+	}
+	s.hold(&res)
 	err = s.Repo.CreateItem(res)
 	return
 }
 
-func (s *CartServiceImpl) GetCart(cartId uuid.UUID) (res Cart, err error) {
-	exists, err := s.Repo.CartExistsByID(cartId.String())
+// availableStock returns how much of stock is neither held by carts other
+// than cartId nor owed to orders waiting for it, along with the units of
+// the product waiting for stock.
+func (s *CartServiceImpl) availableStock(productId uuid.UUID, variantId nuuid.NUUID, cartId uuid.UUID, stock int) (available, waiting int, err error) {
+	held, err := s.Repo.HeldQuantity(productId.String(), variantId, cartId.String(), time.Now().UTC())
+	if err != nil {
+		return
+	}
+	owed, waiting, err := s.OrderService.WaitingQuantity(productId, variantId)
+	if err != nil {
+		return
+	}
+	return stock - held - owed, waiting, nil
+}
+
+// ensureOrderable checks that quantity is available, or that the product
+// takes backorders or pre-orders and has room for quantity more units.
+func (s *CartServiceImpl) ensureOrderable(prod product.Product, quantity, available, waiting int) error {
+	if quantity <= available {
+		return nil
+	}
+	if !prod.AllowsBackorders() {
+		return failure.BadRequest(errors.New("not enough stock available"))
+	}
+	if !prod.AcceptsBackorder(quantity, waiting) {
+		return failure.BadRequestFromString("backorder limit reached")
+	}
+	return nil
+}
+
+// waitingStatus returns the status of order items of the product that wait
+// for stock.
+func waitingStatus(prod product.Product) string {
+	if prod.OrderPolicy == product.OrderPolicyPreorder {
+		return order.ItemPreordered
+	}
+	return order.ItemBackordered
+}
+
+// hold holds the item for its cart when holds are on.
+func (s *CartServiceImpl) hold(item *CartItem) {
+	if s.Config.Cart.HoldMinutes <= 0 {
+		return
+	}
+	item.Hold(time.Now().UTC().Add(time.Duration(s.Config.Cart.HoldMinutes) * time.Minute))
+}
+
+// ExpireHolds marks the holds that ran out as expired. Expired holds already
+// no longer count; this keeps their status up to date.
+func (s *CartServiceImpl) ExpireHolds() (err error) {
+	return s.Repo.ExpireHolds(time.Now().UTC())
+}
+
+func (s *CartServiceImpl) RepriceCarts() (err error) {
+	changes, err := s.ProductService.GetUnrepricedChanges()
+	if err != nil {
+		return
+	}
+	for _, change := range changes {
+		err = s.Repo.RepriceItems(change)
+		if err != nil {
+			return err
+		}
+		err = s.ProductService.MarkRepriced(change)
+		if err != nil {
+			return err
+		}
+	}
+	return
+}
+
+// priceAndStock returns what the item sells for and how much is left: the
+// variant's for products with variants, the product's own otherwise.
+func (s *CartServiceImpl) priceAndStock(prod product.Product, variantId nuuid.NUUID, orgId uuid.UUID) (price float64, stock int, err error) {
+	hasVariants, err := s.VariantService.HasVariants(prod.Id)
+	if err != nil {
+		return
+	}
+	if !variantId.Valid {
+		if hasVariants {
+			err = failure.BadRequestFromString("variantId is required, the product comes in variants")
+			return
+		}
+		return prod.Price, prod.Stock, nil
+	}
+	v, err := s.VariantService.GetByID(orgId, variantId.UUID)
+	if err != nil {
+		return
+	}
+	if v.ProductId != prod.Id {
+		err = failure.BadRequestFromString("the variant does not belong to the product")
+		return
+	}
+	return v.Price, v.Stock, nil
+}
 
+func (s *CartServiceImpl) GetCart(cartId, orgId uuid.UUID) (res Cart, err error) {
+	res, err = s.getCart(cartId, orgId)
+	if err != nil {
+		return
+	}
+	items, err := s.Repo.GetCartItems(res.Id.String())
 	if err != nil {
 		return
 	}
+	res = res.AttachItems(items)
+	return
+}
 
+// getCart returns the cart if it belongs to the organization. Carts of other
+// organizations are reported as not found.
+func (s *CartServiceImpl) getCart(cartId, orgId uuid.UUID) (res Cart, err error) {
+	exists, err := s.Repo.CartExistsByID(cartId.String())
+	if err != nil {
+		return
+	}
 	if !exists {
 		err = failure.NotFound("Cart")
 		return
@@ -74,11 +213,26 @@ func (s *CartServiceImpl) GetCart(cartId uuid.UUID) (res Cart, err error) {
 	if err != nil {
 		return
 	}
-	items, err := s.Repo.GetCartItems(res.Id.String())
+	if res.OrganizationId != orgId {
+		err = failure.NotFound("Cart")
+		return
+	}
+	return
+}
+
+// GetAllByUserID returns the user's carts in every organization.
+func (s *CartServiceImpl) GetAllByUserID(userId uuid.UUID) (res []Cart, err error) {
+	res, err = s.Repo.GetCartsByUserID(userId.String())
 	if err != nil {
 		return
 	}
-	res = res.AttachItems(items)
+	for i, cart := range res {
+		items, err := s.Repo.GetCartItems(cart.Id.String())
+		if err != nil {
+			return res, err
+		}
+		res[i] = cart.AttachItems(items)
+	}
 	return
 }
 
@@ -99,17 +253,34 @@ func (s *CartServiceImpl) GetCartItems(cartId uuid.UUID) (res []CartItem, err er
 	return
 }
 
-func (s *CartServiceImpl) Checkout(load CheckoutPayload, cartId, userId uuid.UUID) (res order.Order, err error) {
-	exists, err := s.Repo.CartExistsByID(cartId.String())
+// Checkout orders the given items of the cart. Stock is checked again, less
+// what other carts hold, and once more when the order is placed, as it may
+// have run out since the items were added. Items are charged at the current
+// price, which carts may not have been brought to yet. Items of products
+// taking backorders or pre-orders that are short wait for stock instead.
+func (s *CartServiceImpl) Checkout(load CheckoutPayload, cartId, userId, orgId uuid.UUID) (res order.Order, err error) {
+	crt, err := s.getCart(cartId, orgId)
 	if err != nil {
 		return
 	}
-	if !exists {
-		err = failure.NotFound("Cart")
+	// addresses are looked up on the cart owner, the caller may be an admin
+	shippingAddressId, err := s.AddressService.ResolveForCheckout(load.ShippingAddressId, crt.UserId, address.Shipping)
+	if err != nil {
 		return
 	}
+	billingAddressId, err := s.AddressService.ResolveForCheckout(load.BillingAddressId, crt.UserId, address.Billing)
+	if err != nil {
+		return
+	}
+	if !billingAddressId.Valid {
+		billingAddressId = shippingAddressId
+	}
 	orderItemsPayload := []order.OrderItemPayload{}
+	shortages := []string{}
+	// units of each product this checkout adds to those waiting for stock
+	backordered := map[uuid.UUID]int{}
 	var total float64
+	var exists bool
 	for _, id := range load.CartItemsIds {
 		exists, err = s.Repo.CartItemExistsByID(id)
 		if err != nil {
@@ -123,27 +294,71 @@ func (s *CartServiceImpl) Checkout(load CheckoutPayload, cartId, userId uuid.UUI
 		if err != nil {
 			return res, err
 		}
-		exists, err = s.ProductService.ExistsByID(item.ProductId)
+		if item.CartId != crt.Id {
+			err = failure.NotFound("Cart item")
+			return res, err
+		}
+		prod, err := s.ProductService.GetByID(item.ProductId, orgId)
+		if err != nil && failure.GetCode(err) != http.StatusNotFound {
+			return res, err
+		}
+		if err != nil || !prod.IsPublished(time.Now().UTC()) {
+			err = failure.Conflict("checkout", "product", item.ProductId.String()+" is no longer available")
+			return res, err
+		}
+		if item.VariantId.Valid {
+			_, err = s.VariantService.GetByID(orgId, item.VariantId.UUID)
+			if err != nil {
+				if failure.GetCode(err) == http.StatusNotFound {
+					err = failure.Conflict("checkout", "variant", item.VariantId.UUID.String()+" is no longer available")
+				}
+				return res, err
+			}
+		}
+		price, stock, err := s.priceAndStock(prod, item.VariantId, orgId)
 		if err != nil {
 			return res, err
 		}
-		if !exists {
-			err = failure.NotFound("Product")
+		item.Recalculate(price)
+		available, waiting, err := s.availableStock(item.ProductId, item.VariantId, crt.Id, stock)
+		if err != nil {
 			return res, err
 		}
+		status, expectedAt := order.ItemAllocated, null.Time{}
+		if available < item.Quantity {
+			switch {
+			case prod.AcceptsBackorder(item.Quantity, waiting+backordered[prod.Id]):
+				backordered[prod.Id] += item.Quantity
+				status, expectedAt = waitingStatus(prod), prod.ExpectedAt
+			case prod.AllowsBackorders():
+				shortages = append(shortages, fmt.Sprintf("backorder limit reached for product %q", prod.Name))
+			default:
+				shortages = append(shortages, fmt.Sprintf("insufficient stock for product %q: %d requested, %d available", prod.Name, item.Quantity, available))
+			}
+		}
 		orderItemsPayload = append(orderItemsPayload, order.OrderItemPayload{
-			ProductId: item.ProductId,
-			UserId:    userId,
-			OrderId:   res.Id,
-			Quantity:  item.Quantity,
-			Price:     item.Price,
+			ProductId:  item.ProductId,
+			VariantId:  item.VariantId,
+			UserId:     userId,
+			OrderId:    res.Id,
+			Quantity:   item.Quantity,
+			Price:      item.Price,
+			Status:     status,
+			ExpectedAt: expectedAt,
 		})
 		total += item.Price
 	}
+	if len(shortages) > 0 {
+		err = failure.Conflict("checkout", "stock", strings.Join(shortages, "; "))
+		return
+	}
 	res, err = s.OrderService.CreateOrder(order.OrderPayload{
-		UserId:     userId,
-		TotalPrice: total,
-		Status:     "pending",
+		OrganizationId:    crt.OrganizationId,
+		UserId:            userId,
+		TotalPrice:        total,
+		Status:            "pending",
+		ShippingAddressId: shippingAddressId,
+		BillingAddressId:  billingAddressId,
 	}, orderItemsPayload)
 	if err != nil {
 		return
@@ -152,8 +367,12 @@ func (s *CartServiceImpl) Checkout(load CheckoutPayload, cartId, userId uuid.UUI
 	return
 }
 
-func (s *CartServiceImpl) UpdateCartItem(load CartItemPayload, userId, cartId, productId uuid.UUID, productPrice float64) (res CartItem, err error) {
-	res, err = s.Repo.GetCartItemByProduct(productId.String(), cartId.String())
+// UpdateCartItem changes the quantity of the product in the cart by the
+// payload's. More than available cannot be added unless the product takes
+// backorders or pre-orders; taking some out is always possible. The item's
+// hold is renewed either way.
+func (s *CartServiceImpl) UpdateCartItem(load CartItemPayload, userId, cartId uuid.UUID, prod product.Product, productPrice float64, available, waiting int) (res CartItem, err error) {
+	res, err = s.Repo.GetCartItemByProduct(prod.Id.String(), load.VariantId, cartId.String())
 	if err != nil {
 		return
 	}
@@ -161,22 +380,29 @@ func (s *CartServiceImpl) UpdateCartItem(load CartItemPayload, userId, cartId, p
 		err = failure.BadRequest(errors.New("quantity cannot be less than 0"))
 		return
 	}
+	if load.Quantity > 0 {
+		err = s.ensureOrderable(prod, res.Quantity+load.Quantity, available, waiting)
+		if err != nil {
+			return
+		}
+	}
 	res.Update(load, userId)
 	res.Recalculate(productPrice)
+	s.hold(&res)
 	err = s.Repo.UpdateItem(res)
 	return
 }
 
-func (s *CartServiceImpl) ProductExistsInCart(cartId, prodId uuid.UUID) (exists bool, err error) {
-	exists, err = s.Repo.ProductExistsInCart(prodId.String(), cartId.String())
+func (s *CartServiceImpl) ProductExistsInCart(cartId, prodId uuid.UUID, variantId nuuid.NUUID) (exists bool, err error) {
+	exists, err = s.Repo.ProductExistsInCart(prodId.String(), variantId, cartId.String())
 	if err != nil {
 		return
 	}
 	return
 }
 
-func (s *CartServiceImpl) GetAllCarts(limit, offset int, sort, field string) (res []Cart, err error) {
-	res, err = s.Repo.GetAllCarts(limit, offset, sort, field)
+func (s *CartServiceImpl) GetAllCarts(orgId uuid.UUID, limit, offset int, sort, field string) (res []Cart, err error) {
+	res, err = s.Repo.GetAllCarts(orgId.String(), limit, offset, sort, field)
 	if err != nil {
 		return
 	}
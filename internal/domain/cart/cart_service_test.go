@@ -0,0 +1,57 @@
+package cart_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/evermos/boilerplate-go/internal/domain/cart"
+	"github.com/evermos/boilerplate-go/internal/domain/product"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type repriceRepository struct {
+	cart.CartRepository
+	fail     map[uuid.UUID]bool
+	repriced []uuid.UUID
+}
+
+func (r *repriceRepository) RepriceItems(change product.PriceChange) (err error) {
+	if r.fail[change.Id] {
+		return errors.New("lock wait timeout exceeded")
+	}
+	r.repriced = append(r.repriced, change.Id)
+	return
+}
+
+type priceChanges struct {
+	product.ProductService
+	changes []product.PriceChange
+	marked  []uuid.UUID
+}
+
+func (p *priceChanges) GetUnrepricedChanges() (res []product.PriceChange, err error) {
+	return p.changes, nil
+}
+
+func (p *priceChanges) MarkRepriced(change product.PriceChange) (err error) {
+	p.marked = append(p.marked, change.Id)
+	return
+}
+
+func TestRepriceCarts(t *testing.T) {
+	first, second := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	products := &priceChanges{changes: []product.PriceChange{{Id: first, Price: 8}, {Id: second, Price: 9}}}
+
+	repo := &repriceRepository{fail: map[uuid.UUID]bool{second: true}}
+	s := &cart.CartServiceImpl{Repo: repo, ProductService: products}
+	assert.Error(t, s.RepriceCarts())
+	// the change that failed stays unrepriced so the next run retries it
+	assert.Equal(t, []uuid.UUID{first}, repo.repriced)
+	assert.Equal(t, []uuid.UUID{first}, products.marked)
+
+	repo.fail = nil
+	products.changes, products.marked = products.changes[1:], nil
+	assert.NoError(t, s.RepriceCarts())
+	assert.Equal(t, []uuid.UUID{second}, products.marked)
+}
@@ -0,0 +1,205 @@
+package catalog
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/bulk"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
+)
+
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// ImportBatchSize is the number of rows processed between progress updates.
+const ImportBatchSize = 100
+
+// MaxReportedErrors caps the row errors kept on a job. Failed still counts
+// every rejected row.
+const MaxReportedErrors = 1000
+
+// ImportJob is a bulk import of products, processed in the background. Every
+// row either creates a product or replaces the product with the same SKU. On
+// a dry run rows are only validated.
+type ImportJob struct {
+	Id             uuid.UUID `db:"id" validate:"required"`
+	OrganizationId uuid.UUID `db:"organization_id" validate:"required"`
+	Status         string    `db:"status" validate:"required,oneof=queued running completed failed"`
+	Format         string    `db:"format" validate:"required,oneof=csv ndjson"`
+	DryRun         bool      `db:"dry_run"`
+	// Data is the uploaded file. It is dropped once the job is finished.
+	Data      []byte      `db:"data"`
+	Total     int         `db:"total"`
+	Processed int         `db:"processed"`
+	Valid     int         `db:"valid"`
+	Created   int         `db:"created"`
+	Updated   int         `db:"updated"`
+	Failed    int         `db:"failed"`
+	Errors    RowErrors   `db:"errors"`
+	Error     null.String `db:"error"`
+	StartedAt null.Time   `db:"started_at"`
+	// FinishedAt is set when the job completed or failed.
+	FinishedAt null.Time `db:"finished_at"`
+	CreatedAt  time.Time `db:"created_at" validate:"required"`
+	UpdatedAt  time.Time `db:"updated_at" validate:"required"`
+	CreatedBy  uuid.UUID `db:"created_by" validate:"required"`
+	UpdatedBy  uuid.UUID `db:"updated_by" validate:"required"`
+}
+
+type ImportJobResponseFormat struct {
+	Id             uuid.UUID   `json:"id"`
+	OrganizationId uuid.UUID   `json:"organizationId"`
+	Status         string      `json:"status"`
+	Format         string      `json:"format"`
+	DryRun         bool        `json:"dryRun"`
+	Total          int         `json:"total"`
+	Processed      int         `json:"processed"`
+	Valid          int         `json:"valid"`
+	Created        int         `json:"created"`
+	Updated        int         `json:"updated"`
+	Failed         int         `json:"failed"`
+	Errors         RowErrors   `json:"errors"`
+	Error          null.String `json:"error"`
+	StartedAt      null.Time   `json:"startedAt"`
+	FinishedAt     null.Time   `json:"finishedAt"`
+	CreatedAt      time.Time   `json:"createdAt"`
+	UpdatedAt      time.Time   `json:"updatedAt"`
+	CreatedBy      uuid.UUID   `json:"createdBy"`
+	UpdatedBy      uuid.UUID   `json:"updatedBy"`
+}
+
+// RowError is the reason a single row of an import was rejected.
+type RowError struct {
+	Row     int    `json:"row"`
+	Sku     string `json:"sku,omitempty"`
+	Message string `json:"message"`
+}
+
+// RowErrors is stored as a JSON column.
+type RowErrors []RowError
+
+// Scan implements the Scanner interface.
+func (e *RowErrors) Scan(value interface{}) error {
+	switch x := value.(type) {
+	case []byte:
+		return json.Unmarshal(x, e)
+	case string:
+		return json.Unmarshal([]byte(x), e)
+	case nil:
+		*e = RowErrors{}
+		return nil
+	default:
+		return fmt.Errorf("cannot scan type %T into catalog.RowErrors: %v", value, value)
+	}
+}
+
+// Value implements the driver Valuer interface.
+func (e RowErrors) Value() (driver.Value, error) {
+	if e == nil {
+		e = RowErrors{}
+	}
+	data, err := json.Marshal(e)
+	return string(data), err
+}
+
+func (j ImportJob) NewFromUpload(data []byte, format bulk.Format, dryRun bool, orgId, userId uuid.UUID) (res ImportJob, err error) {
+	if len(data) == 0 {
+		err = failure.BadRequestFromString("the file is empty")
+		return
+	}
+	id, err := uuid.NewV4()
+	if err != nil {
+		return
+	}
+	now := time.Now().UTC()
+	res = ImportJob{
+		Id:             id,
+		OrganizationId: orgId,
+		Status:         StatusQueued,
+		Format:         string(format),
+		DryRun:         dryRun,
+		Data:           data,
+		Errors:         RowErrors{},
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		CreatedBy:      userId,
+		UpdatedBy:      userId,
+	}
+	err = res.Validate()
+	return
+}
+
+func (j *ImportJob) Validate() error {
+	validator := shared.GetValidator()
+	return validator.Struct(j)
+}
+
+// Start resets the counters for a fresh run. A job picked up again after its
+// worker died starts over; rows already written are then updated rather
+// than created.
+func (j *ImportJob) Start(total int) {
+	now := time.Now().UTC()
+	j.Status = StatusRunning
+	j.Total = total
+	j.Processed, j.Valid, j.Created, j.Updated, j.Failed = 0, 0, 0, 0, 0
+	j.Errors = RowErrors{}
+	j.StartedAt = null.TimeFrom(now)
+	j.UpdatedAt = now
+}
+
+func (j *ImportJob) AddError(row int, sku string, err error) {
+	j.Failed++
+	if len(j.Errors) < MaxReportedErrors {
+		j.Errors = append(j.Errors, RowError{Row: row, Sku: sku, Message: err.Error()})
+	}
+}
+
+// Finish records the outcome of the job and drops the uploaded file.
+func (j *ImportJob) Finish(runErr error) {
+	now := time.Now().UTC()
+	j.Status = StatusCompleted
+	if runErr != nil {
+		j.Status = StatusFailed
+		j.Error = null.StringFrom(runErr.Error())
+	}
+	j.Data = nil
+	j.FinishedAt = null.TimeFrom(now)
+	j.UpdatedAt = now
+}
+
+func (j ImportJob) ToResponseFormat() ImportJobResponseFormat {
+	return ImportJobResponseFormat{
+		Id:             j.Id,
+		OrganizationId: j.OrganizationId,
+		Status:         j.Status,
+		Format:         j.Format,
+		DryRun:         j.DryRun,
+		Total:          j.Total,
+		Processed:      j.Processed,
+		Valid:          j.Valid,
+		Created:        j.Created,
+		Updated:        j.Updated,
+		Failed:         j.Failed,
+		Errors:         j.Errors,
+		Error:          j.Error,
+		StartedAt:      j.StartedAt,
+		FinishedAt:     j.FinishedAt,
+		CreatedAt:      j.CreatedAt,
+		UpdatedAt:      j.UpdatedAt,
+		CreatedBy:      j.CreatedBy,
+		UpdatedBy:      j.UpdatedBy,
+	}
+}
+
+func (j ImportJob) MarshalJSON() ([]byte, error) {
+	return json.Marshal(j.ToResponseFormat())
+}
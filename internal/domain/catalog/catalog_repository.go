@@ -0,0 +1,127 @@
+package catalog
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/evermos/boilerplate-go/infras"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/gofrs/uuid"
+)
+
+type CatalogRepository interface {
+	Create(job ImportJob) (err error)
+	GetByID(id, orgId uuid.UUID) (res ImportJob, err error)
+	Claim(now, staleBefore time.Time) (res ImportJob, err error)
+	Save(job ImportJob) (err error)
+}
+
+type CatalogRepositoryMySQL struct {
+	DB *infras.MySQLConn
+}
+
+func ProvideCatalogRepositoryMySQL(db *infras.MySQLConn) *CatalogRepositoryMySQL {
+	return &CatalogRepositoryMySQL{DB: db}
+}
+
+// jobColumns are the columns of product_import without the uploaded file,
+// which is only read when the job is processed.
+const jobColumns = `id,organization_id,status,format,dry_run,total,processed,valid,created,updated,failed,errors,error,
+	started_at,finished_at,created_at,updated_at,created_by,updated_by`
+
+// claimCandidates is the number of jobs Claim tries before giving up to
+// other workers.
+const claimCandidates = 10
+
+func (r *CatalogRepositoryMySQL) Create(job ImportJob) (err error) {
+	query := `INSERT INTO product_import (id,organization_id,status,format,dry_run,data,total,processed,valid,created,updated,failed,errors,created_at,updated_at,created_by,updated_by)
+	VALUES (:id,:organization_id,:status,:format,:dry_run,:data,:total,:processed,:valid,:created,:updated,:failed,:errors,:created_at,:updated_at,:created_by,:updated_by)`
+	_, err = r.DB.Write.NamedExec(query, job)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+func (r *CatalogRepositoryMySQL) GetByID(id, orgId uuid.UUID) (res ImportJob, err error) {
+	err = r.DB.Read.Get(&res, "SELECT "+jobColumns+" FROM product_import WHERE id = ? AND organization_id = ?", id.String(), orgId.String())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = failure.NotFound("Import")
+			return
+		}
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+// Claim marks the oldest queued job as running and returns it with its file.
+// A running job that has not been saved since staleBefore is taken over, as
+// its worker is gone. The status is only changed when nobody else changed it
+// first, so every job is claimed by one worker. It returns a not found
+// failure when there is nothing to do.
+func (r *CatalogRepositoryMySQL) Claim(now, staleBefore time.Time) (res ImportJob, err error) {
+	var candidates []ImportJob
+	err = r.DB.Read.Select(&candidates, "SELECT "+jobColumns+` FROM product_import
+		WHERE status = ? OR (status = ? AND updated_at < ?)
+		ORDER BY created_at LIMIT ?`, StatusQueued, StatusRunning, staleBefore, claimCandidates)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	for _, candidate := range candidates {
+		result, err := r.DB.Write.Exec("UPDATE product_import SET status = ?, updated_at = ? WHERE id = ? AND status = ? AND updated_at = ?",
+			StatusRunning, now, candidate.Id.String(), candidate.Status, candidate.UpdatedAt)
+		if err != nil {
+			logger.ErrorWithStack(err)
+			return res, err
+		}
+		claimed, err := result.RowsAffected()
+		if err != nil {
+			logger.ErrorWithStack(err)
+			return res, err
+		}
+		if claimed == 0 {
+			continue
+		}
+		err = r.DB.Write.Get(&res, "SELECT * FROM product_import WHERE id = ?", candidate.Id.String())
+		if err != nil {
+			logger.ErrorWithStack(err)
+		}
+		return res, err
+	}
+	err = failure.NotFound("Import")
+	return
+}
+
+// Save writes the status and progress of the job. The file is dropped once
+// the job is finished.
+func (r *CatalogRepositoryMySQL) Save(job ImportJob) (err error) {
+	query := `
+	UPDATE product_import
+	SET
+		status = :status,
+		data = IF(:finished_at IS NULL, data, NULL),
+		total = :total,
+		processed = :processed,
+		valid = :valid,
+		created = :created,
+		updated = :updated,
+		failed = :failed,
+		errors = :errors,
+		error = :error,
+		started_at = :started_at,
+		finished_at = :finished_at,
+		updated_at = :updated_at,
+		updated_by = :updated_by
+	WHERE id = :id`
+	_, err = r.DB.Write.NamedExec(query, job)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
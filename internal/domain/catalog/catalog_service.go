@@ -0,0 +1,172 @@
+package catalog
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/evermos/boilerplate-go/internal/domain/product"
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/bulk"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/gofrs/uuid"
+)
+
+// staleImportAfter is how long a running job may go without saving progress
+// before another worker takes it over.
+const staleImportAfter = 10 * time.Minute
+
+type CatalogService interface {
+	StartImport(data []byte, format bulk.Format, dryRun bool, orgId, userId uuid.UUID) (res ImportJob, err error)
+	GetImport(id, orgId uuid.UUID) (res ImportJob, err error)
+	ProcessImports() (err error)
+}
+
+type CatalogServiceImpl struct {
+	Repo           CatalogRepository
+	ProductService product.ProductService
+}
+
+func ProvideCatalogServiceImpl(repo CatalogRepository, productService product.ProductService) *CatalogServiceImpl {
+	return &CatalogServiceImpl{Repo: repo, ProductService: productService}
+}
+
+// StartImport queues the file for the background worker. Its progress is
+// polled with GetImport.
+func (s *CatalogServiceImpl) StartImport(data []byte, format bulk.Format, dryRun bool, orgId, userId uuid.UUID) (res ImportJob, err error) {
+	res, err = res.NewFromUpload(data, format, dryRun, orgId, userId)
+	if err != nil {
+		return
+	}
+	err = s.Repo.Create(res)
+	return
+}
+
+func (s *CatalogServiceImpl) GetImport(id, orgId uuid.UUID) (res ImportJob, err error) {
+	return s.Repo.GetByID(id, orgId)
+}
+
+// ProcessImports runs queued jobs one after another until none are left. A
+// job whose file cannot be read at all fails with the reason; rejected rows
+// only end up in the job's report.
+func (s *CatalogServiceImpl) ProcessImports() (err error) {
+	for {
+		now := time.Now().UTC()
+		job, err := s.Repo.Claim(now, now.Add(-staleImportAfter))
+		if failure.GetCode(err) == http.StatusNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		runErr := s.process(&job)
+		if runErr != nil {
+			logger.ErrorWithStack(runErr)
+		}
+		job.Finish(runErr)
+		if err := s.Repo.Save(job); err != nil {
+			return err
+		}
+	}
+}
+
+// record is a decoded row of an import together with its position in the
+// file, or the reason it could not be decoded.
+type record struct {
+	row  int
+	data product.Row
+	err  error
+}
+
+func (s *CatalogServiceImpl) process(job *ImportJob) (err error) {
+	records, err := decodeAll(job.Data, bulk.Format(job.Format))
+	if err != nil {
+		return
+	}
+	job.Start(len(records))
+	err = s.Repo.Save(*job)
+	if err != nil {
+		return
+	}
+	seenSkus := map[string]bool{}
+	for _, rec := range records {
+		err = rec.err
+		if err == nil {
+			err = s.importRow(job, rec.data, seenSkus)
+		}
+		if err != nil {
+			job.AddError(rec.row, rec.data.Sku, err)
+		} else {
+			job.Valid++
+		}
+		job.Processed++
+		if job.Processed%ImportBatchSize == 0 {
+			job.UpdatedAt = time.Now().UTC()
+			if err := s.Repo.Save(*job); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func decodeAll(data []byte, format bulk.Format) (records []record, err error) {
+	decoder := bulk.NewDecoder(bytes.NewReader(data), format)
+	for {
+		var row product.Row
+		err = decoder.Decode(&row)
+		if err == io.EOF {
+			return records, nil
+		}
+		if _, ok := err.(*bulk.RecordError); ok {
+			records = append(records, record{row: decoder.Row(), err: err})
+			continue
+		}
+		if err != nil {
+			return
+		}
+		records = append(records, record{row: decoder.Row(), data: row})
+	}
+}
+
+// importRow creates the product of the row or replaces the one with the same
+// SKU. Rows are validated the same way as the product endpoints; on a dry
+// run nothing else happens.
+func (s *CatalogServiceImpl) importRow(job *ImportJob, row product.Row, seenSkus map[string]bool) (err error) {
+	err = shared.GetValidator().Struct(row)
+	if err != nil {
+		return
+	}
+	if seenSkus[row.Sku] {
+		err = failure.Conflict("import", "product", "SKU appears earlier in the file")
+		return
+	}
+	seenSkus[row.Sku] = true
+	load := row.ToPayload()
+	existing, err := s.ProductService.GetBySku(row.Sku, job.OrganizationId)
+	if failure.GetCode(err) == http.StatusNotFound {
+		_, err = product.Product{}.NewFromPayload(load, job.CreatedBy, job.OrganizationId)
+		if err != nil || job.DryRun {
+			return
+		}
+		_, err = s.ProductService.Create(load, job.CreatedBy, job.OrganizationId)
+		if err == nil {
+			job.Created++
+		}
+		return
+	}
+	if err != nil {
+		return
+	}
+	err = existing.Update(load, job.CreatedBy)
+	if err != nil || job.DryRun {
+		return
+	}
+	_, err = s.ProductService.Update(load, existing.Id, job.CreatedBy, job.OrganizationId)
+	if err == nil {
+		job.Updated++
+	}
+	return
+}
@@ -0,0 +1,185 @@
+package category
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/nuuid"
+	"github.com/gofrs/uuid"
+)
+
+// Category is a node of the organization's product taxonomy. Categories
+// without a parent are the roots of the tree; siblings are ordered by
+// Position and then by name.
+type Category struct {
+	Id             uuid.UUID   `db:"id" validate:"required"`
+	OrganizationId uuid.UUID   `db:"organization_id" validate:"required"`
+	ParentId       nuuid.NUUID `db:"parent_id"`
+	Name           string      `db:"name" validate:"required,max=100"`
+	Slug           string      `db:"slug" validate:"required,max=100,slug"`
+	Position       int         `db:"position" validate:"min=0"`
+	Children       []Category  `db:"-"`
+	CreatedAt      time.Time   `db:"created_at" validate:"required"`
+	UpdatedAt      time.Time   `db:"updated_at" validate:"required"`
+	CreatedBy      uuid.UUID   `db:"created_by" validate:"required"`
+	UpdatedBy      uuid.UUID   `db:"updated_by" validate:"required"`
+}
+
+type CategoryResponseFormat struct {
+	Id             uuid.UUID   `json:"id"`
+	OrganizationId uuid.UUID   `json:"organizationId"`
+	ParentId       nuuid.NUUID `json:"parentId"`
+	Name           string      `json:"name"`
+	Slug           string      `json:"slug"`
+	Position       int         `json:"position"`
+	Children       []Category  `json:"children,omitempty"`
+	CreatedAt      time.Time   `json:"createdAt"`
+	UpdatedAt      time.Time   `json:"updatedAt"`
+	CreatedBy      uuid.UUID   `json:"createdBy"`
+	UpdatedBy      uuid.UUID   `json:"updatedBy"`
+}
+
+// CategoryPayload creates or replaces a category. The slug is derived from
+// the name when it is left empty.
+type CategoryPayload struct {
+	ParentId nuuid.NUUID `json:"parentId"`
+	Name     string      `json:"name" validate:"required,max=100"`
+	Slug     string      `json:"slug" validate:"omitempty,max=100,slug"`
+	Position int         `json:"position" validate:"min=0"`
+}
+
+// AssignmentPayload replaces the categories of a product.
+type AssignmentPayload struct {
+	CategoryIds []uuid.UUID `json:"categoryIds" validate:"max=50"`
+}
+
+// ProductCategory assigns a product to a category, as stored.
+type ProductCategory struct {
+	ProductId  uuid.UUID `db:"product_id"`
+	CategoryId uuid.UUID `db:"category_id"`
+	CreatedAt  time.Time `db:"created_at"`
+	CreatedBy  uuid.UUID `db:"created_by"`
+}
+
+func (c Category) NewFromPayload(load CategoryPayload, orgId, creatorId uuid.UUID) (res Category, err error) {
+	categoryId, err := uuid.NewV4()
+	if err != nil {
+		return
+	}
+	now := time.Now().UTC()
+	res = Category{
+		Id:             categoryId,
+		OrganizationId: orgId,
+		ParentId:       load.ParentId,
+		Name:           load.Name,
+		Slug:           slugOf(load),
+		Position:       load.Position,
+		CreatedAt:      now,
+		CreatedBy:      creatorId,
+		UpdatedAt:      now,
+		UpdatedBy:      creatorId,
+	}
+	err = res.Validate()
+	return
+}
+
+func (c *Category) Update(load CategoryPayload, updaterId uuid.UUID) (err error) {
+	if load.ParentId.Valid && load.ParentId.UUID == c.Id {
+		err = failure.BadRequestFromString("a category cannot be its own parent")
+		return
+	}
+	c.ParentId = load.ParentId
+	c.Name = load.Name
+	c.Slug = slugOf(load)
+	c.Position = load.Position
+	c.UpdatedAt = time.Now().UTC()
+	c.UpdatedBy = updaterId
+	err = c.Validate()
+	return
+}
+
+func (c *Category) Validate() error {
+	validator := shared.GetValidator()
+	return validator.Struct(c)
+}
+
+func (c Category) ToResponseFormat() CategoryResponseFormat {
+	return CategoryResponseFormat(c)
+}
+
+func (c Category) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.ToResponseFormat())
+}
+
+func slugOf(load CategoryPayload) string {
+	if load.Slug != "" {
+		return load.Slug
+	}
+	return shared.Slugify(load.Name)
+}
+
+// BuildTree nests the flat list of an organization's categories under their
+// parents and returns the roots. Categories whose parent is missing from the
+// list are treated as roots.
+func BuildTree(categories []Category) (roots []Category) {
+	children := map[uuid.UUID][]Category{}
+	known := map[uuid.UUID]bool{}
+	for _, c := range categories {
+		known[c.Id] = true
+	}
+	for _, c := range categories {
+		if c.ParentId.Valid && known[c.ParentId.UUID] {
+			children[c.ParentId.UUID] = append(children[c.ParentId.UUID], c)
+			continue
+		}
+		roots = append(roots, c)
+	}
+	var attach func(nodes []Category) []Category
+	attach = func(nodes []Category) []Category {
+		sortSiblings(nodes)
+		for i := range nodes {
+			nodes[i].Children = attach(children[nodes[i].Id])
+		}
+		return nodes
+	}
+	roots = attach(roots)
+	if roots == nil {
+		roots = []Category{}
+	}
+	return
+}
+
+// Descendants returns id followed by the ids of every category below it.
+func Descendants(categories []Category, id uuid.UUID) (res []uuid.UUID) {
+	children := map[uuid.UUID][]uuid.UUID{}
+	for _, c := range categories {
+		if c.ParentId.Valid {
+			children[c.ParentId.UUID] = append(children[c.ParentId.UUID], c.Id)
+		}
+	}
+	seen := map[uuid.UUID]bool{}
+	queue := []uuid.UUID{id}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if seen[current] {
+			continue
+		}
+		seen[current] = true
+		res = append(res, current)
+		queue = append(queue, children[current]...)
+	}
+	return
+}
+
+func sortSiblings(nodes []Category) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].Position != nodes[j].Position {
+			return nodes[i].Position < nodes[j].Position
+		}
+		return nodes[i].Name < nodes[j].Name
+	})
+}
@@ -0,0 +1,32 @@
+package category_test
+
+import (
+	"testing"
+
+	"github.com/evermos/boilerplate-go/internal/domain/category"
+	"github.com/evermos/boilerplate-go/shared/nuuid"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTree(t *testing.T) {
+	clothing := category.Category{Id: uuid.Must(uuid.NewV4()), Name: "Clothing", Position: 1}
+	shoes := category.Category{Id: uuid.Must(uuid.NewV4()), Name: "Shoes", Position: 0}
+	shirts := category.Category{Id: uuid.Must(uuid.NewV4()), ParentId: nuuid.From(clothing.Id), Name: "Shirts"}
+	polos := category.Category{Id: uuid.Must(uuid.NewV4()), ParentId: nuuid.From(shirts.Id), Name: "Polos"}
+	all := []category.Category{polos, clothing, shirts, shoes}
+
+	t.Run("BuildTree", func(t *testing.T) {
+		roots := category.BuildTree(all)
+		assert.Len(t, roots, 2)
+		assert.Equal(t, "Shoes", roots[0].Name)
+		assert.Equal(t, "Clothing", roots[1].Name)
+		assert.Equal(t, "Shirts", roots[1].Children[0].Name)
+		assert.Equal(t, "Polos", roots[1].Children[0].Children[0].Name)
+	})
+
+	t.Run("Descendants", func(t *testing.T) {
+		assert.Equal(t, []uuid.UUID{clothing.Id, shirts.Id, polos.Id}, category.Descendants(all, clothing.Id))
+		assert.Equal(t, []uuid.UUID{shoes.Id}, category.Descendants(all, shoes.Id))
+	})
+}
@@ -0,0 +1,158 @@
+package category
+
+import (
+	"database/sql"
+
+	"github.com/evermos/boilerplate-go/infras"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+type CategoryRepository interface {
+	Create(load Category) (err error)
+	Update(load Category) (err error)
+	Delete(load Category) (err error)
+	GetByID(orgId, id string) (res Category, err error)
+	GetBySlug(orgId, slug string) (res Category, err error)
+	GetAll(orgId string) (res []Category, err error)
+	ExistsBySlug(orgId, slug, excludeId string) (exists bool, err error)
+	HasChildren(id string) (exists bool, err error)
+	GetByProductID(productId string) (res []Category, err error)
+	SetProductCategories(productId string, load []ProductCategory) (err error)
+}
+
+type CategoryRepositoryMySQL struct {
+	DB *infras.MySQLConn
+}
+
+func ProvideCategoryRepositoryMySQL(db *infras.MySQLConn) *CategoryRepositoryMySQL {
+	return &CategoryRepositoryMySQL{DB: db}
+}
+
+func (r *CategoryRepositoryMySQL) Create(load Category) (err error) {
+	query := `INSERT INTO category (id,organization_id,parent_id,name,slug,position,created_at,created_by,updated_at,updated_by)
+	VALUES (:id,:organization_id,:parent_id,:name,:slug,:position,:created_at,:created_by,:updated_at,:updated_by)`
+	_, err = r.DB.Write.NamedExec(query, load)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+func (r *CategoryRepositoryMySQL) Update(load Category) (err error) {
+	query := `
+	UPDATE category
+	SET
+		parent_id = :parent_id,
+		name = :name,
+		slug = :slug,
+		position = :position,
+		updated_at = :updated_at,
+		updated_by = :updated_by
+	WHERE id = :id`
+	_, err = r.DB.Write.NamedExec(query, load)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+// Delete removes the category. Its product assignments are removed by the
+// foreign key.
+func (r *CategoryRepositoryMySQL) Delete(load Category) (err error) {
+	_, err = r.DB.Write.Exec("DELETE FROM category WHERE id = ?", load.Id.String())
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+func (r *CategoryRepositoryMySQL) GetByID(orgId, id string) (res Category, err error) {
+	err = r.DB.Read.Get(&res, "SELECT * FROM category WHERE id = ? AND organization_id = ?", id, orgId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = failure.NotFound("Category")
+			return
+		}
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+func (r *CategoryRepositoryMySQL) GetBySlug(orgId, slug string) (res Category, err error) {
+	err = r.DB.Read.Get(&res, "SELECT * FROM category WHERE slug = ? AND organization_id = ?", slug, orgId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = failure.NotFound("Category")
+			return
+		}
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+// GetAll returns every category of the organization as a flat list.
+func (r *CategoryRepositoryMySQL) GetAll(orgId string) (res []Category, err error) {
+	err = r.DB.Read.Select(&res, "SELECT * FROM category WHERE organization_id = ? ORDER BY position, name", orgId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+func (r *CategoryRepositoryMySQL) ExistsBySlug(orgId, slug, excludeId string) (exists bool, err error) {
+	err = r.DB.Read.Get(&exists, "SELECT COUNT(id) FROM category WHERE organization_id = ? AND slug = ? AND id <> ?", orgId, slug, excludeId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+func (r *CategoryRepositoryMySQL) HasChildren(id string) (exists bool, err error) {
+	err = r.DB.Read.Get(&exists, "SELECT COUNT(id) FROM category WHERE parent_id = ?", id)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+func (r *CategoryRepositoryMySQL) GetByProductID(productId string) (res []Category, err error) {
+	query := `SELECT c.* FROM category c JOIN product_category pc ON pc.category_id = c.id
+	WHERE pc.product_id = ? ORDER BY c.position, c.name`
+	err = r.DB.Read.Select(&res, query, productId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+// SetProductCategories replaces the category assignments of the product.
+func (r *CategoryRepositoryMySQL) SetProductCategories(productId string, load []ProductCategory) (err error) {
+	return r.DB.WithTransaction(func(db *sqlx.Tx, c chan error) {
+		if _, err := db.Exec("DELETE FROM product_category WHERE product_id = ?", productId); err != nil {
+			logger.ErrorWithStack(err)
+			c <- err
+			return
+		}
+		for _, assignment := range load {
+			query := `INSERT INTO product_category (product_id,category_id,created_at,created_by)
+			VALUES (:product_id,:category_id,:created_at,:created_by)`
+			if _, err := db.NamedExec(query, assignment); err != nil {
+				logger.ErrorWithStack(err)
+				c <- err
+				return
+			}
+		}
+		c <- nil
+	})
+}
@@ -0,0 +1,207 @@
+package category
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/evermos/boilerplate-go/internal/domain/product"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/gofrs/uuid"
+)
+
+type CategoryService interface {
+	Create(load CategoryPayload, orgId, creatorId uuid.UUID) (res Category, err error)
+	Update(load CategoryPayload, orgId, categoryId, updaterId uuid.UUID) (res Category, err error)
+	Delete(orgId, categoryId uuid.UUID) (res Category, err error)
+	GetByID(orgId, categoryId uuid.UUID) (res Category, err error)
+	Resolve(orgId uuid.UUID, idOrSlug string) (res Category, err error)
+	GetTree(orgId uuid.UUID) (res []Category, err error)
+	GetDescendantIDs(orgId, categoryId uuid.UUID) (res []uuid.UUID, err error)
+	GetByProductID(orgId, productId uuid.UUID) (res []Category, err error)
+	AssignProduct(load AssignmentPayload, orgId, productId, userId uuid.UUID) (res []Category, err error)
+}
+
+type CategoryServiceImpl struct {
+	Repo           CategoryRepository
+	ProductService product.ProductService
+}
+
+func ProvideCategoryServiceImpl(repo CategoryRepository, productService product.ProductService) *CategoryServiceImpl {
+	return &CategoryServiceImpl{Repo: repo, ProductService: productService}
+}
+
+func (s *CategoryServiceImpl) Create(load CategoryPayload, orgId, creatorId uuid.UUID) (res Category, err error) {
+	res, err = res.NewFromPayload(load, orgId, creatorId)
+	if err != nil {
+		return
+	}
+	err = s.ensureValidParent(res)
+	if err != nil {
+		return
+	}
+	err = s.ensureUniqueSlug(res)
+	if err != nil {
+		return
+	}
+	err = s.Repo.Create(res)
+	return
+}
+
+func (s *CategoryServiceImpl) Update(load CategoryPayload, orgId, categoryId, updaterId uuid.UUID) (res Category, err error) {
+	res, err = s.Repo.GetByID(orgId.String(), categoryId.String())
+	if err != nil {
+		return
+	}
+	err = res.Update(load, updaterId)
+	if err != nil {
+		return
+	}
+	err = s.ensureValidParent(res)
+	if err != nil {
+		return
+	}
+	err = s.ensureUniqueSlug(res)
+	if err != nil {
+		return
+	}
+	err = s.Repo.Update(res)
+	return
+}
+
+// Delete removes a category without children. Products assigned to it
+// keep their other categories.
+func (s *CategoryServiceImpl) Delete(orgId, categoryId uuid.UUID) (res Category, err error) {
+	res, err = s.Repo.GetByID(orgId.String(), categoryId.String())
+	if err != nil {
+		return
+	}
+	hasChildren, err := s.Repo.HasChildren(res.Id.String())
+	if err != nil {
+		return
+	}
+	if hasChildren {
+		err = failure.Conflict("delete", "category", "has subcategories")
+		return
+	}
+	err = s.Repo.Delete(res)
+	return
+}
+
+func (s *CategoryServiceImpl) GetByID(orgId, categoryId uuid.UUID) (res Category, err error) {
+	return s.Repo.GetByID(orgId.String(), categoryId.String())
+}
+
+// Resolve finds a category of the organization by id or by slug.
+func (s *CategoryServiceImpl) Resolve(orgId uuid.UUID, idOrSlug string) (res Category, err error) {
+	if id, parseErr := uuid.FromString(idOrSlug); parseErr == nil {
+		return s.Repo.GetByID(orgId.String(), id.String())
+	}
+	return s.Repo.GetBySlug(orgId.String(), idOrSlug)
+}
+
+// GetTree returns the root categories of the organization with their
+// subcategories nested under them.
+func (s *CategoryServiceImpl) GetTree(orgId uuid.UUID) (res []Category, err error) {
+	all, err := s.Repo.GetAll(orgId.String())
+	if err != nil {
+		return
+	}
+	res = BuildTree(all)
+	return
+}
+
+// GetDescendantIDs returns the category and every category below it, so
+// filtering by a category also matches products of its subcategories.
+func (s *CategoryServiceImpl) GetDescendantIDs(orgId, categoryId uuid.UUID) (res []uuid.UUID, err error) {
+	all, err := s.Repo.GetAll(orgId.String())
+	if err != nil {
+		return
+	}
+	res = Descendants(all, categoryId)
+	return
+}
+
+func (s *CategoryServiceImpl) GetByProductID(orgId, productId uuid.UUID) (res []Category, err error) {
+	_, err = s.ProductService.GetByID(productId, orgId)
+	if err != nil {
+		return
+	}
+	res, err = s.Repo.GetByProductID(productId.String())
+	if err != nil {
+		return
+	}
+	if res == nil {
+		res = []Category{}
+	}
+	return
+}
+
+// AssignProduct replaces the categories of the product. Every category has
+// to belong to the product's organization.
+func (s *CategoryServiceImpl) AssignProduct(load AssignmentPayload, orgId, productId, userId uuid.UUID) (res []Category, err error) {
+	_, err = s.ProductService.GetByID(productId, orgId)
+	if err != nil {
+		return
+	}
+	now := time.Now().UTC()
+	seen := map[uuid.UUID]bool{}
+	assignments := []ProductCategory{}
+	for _, categoryId := range load.CategoryIds {
+		if seen[categoryId] {
+			continue
+		}
+		seen[categoryId] = true
+		_, err = s.Repo.GetByID(orgId.String(), categoryId.String())
+		if err != nil {
+			return
+		}
+		assignments = append(assignments, ProductCategory{
+			ProductId:  productId,
+			CategoryId: categoryId,
+			CreatedAt:  now,
+			CreatedBy:  userId,
+		})
+	}
+	err = s.Repo.SetProductCategories(productId.String(), assignments)
+	if err != nil {
+		return
+	}
+	return s.GetByProductID(orgId, productId)
+}
+
+// ensureValidParent checks that the parent belongs to the same organization
+// and that moving the category under it does not create a cycle.
+func (s *CategoryServiceImpl) ensureValidParent(load Category) (err error) {
+	if !load.ParentId.Valid {
+		return
+	}
+	_, err = s.Repo.GetByID(load.OrganizationId.String(), load.ParentId.UUID.String())
+	if err != nil {
+		if failure.GetCode(err) == http.StatusNotFound {
+			err = failure.BadRequestFromString("parent category not found")
+		}
+		return
+	}
+	all, err := s.Repo.GetAll(load.OrganizationId.String())
+	if err != nil {
+		return
+	}
+	for _, id := range Descendants(all, load.Id) {
+		if id == load.ParentId.UUID {
+			err = failure.BadRequestFromString("a category cannot be moved under its own subcategory")
+			return
+		}
+	}
+	return
+}
+
+func (s *CategoryServiceImpl) ensureUniqueSlug(load Category) (err error) {
+	exists, err := s.Repo.ExistsBySlug(load.OrganizationId.String(), load.Slug, load.Id.String())
+	if err != nil {
+		return
+	}
+	if exists {
+		err = failure.Conflict("save", "category", "already exists with that slug")
+	}
+	return
+}
@@ -0,0 +1,174 @@
+package group
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
+)
+
+// Permissions that can be granted to a group. Organization admins hold all
+// of them implicitly.
+const (
+	PermissionUsersRead     = "users.read"
+	PermissionUsersWrite    = "users.write"
+	PermissionProductsWrite = "products.write"
+	PermissionCartsRead     = "carts.read"
+	PermissionPrivacyRead   = "privacy.read"
+	PermissionGroupsManage  = "groups.manage"
+)
+
+// Permissions lists every permission that can be granted.
+var Permissions = []string{
+	PermissionUsersRead,
+	PermissionUsersWrite,
+	PermissionProductsWrite,
+	PermissionCartsRead,
+	PermissionPrivacyRead,
+	PermissionGroupsManage,
+}
+
+// Group is a named set of users inside an organization, such as
+// "wholesale" or "staff". Members get the group's permissions, and other
+// domains can look up a user's groups to segment them.
+type Group struct {
+	Id             uuid.UUID   `db:"id" validate:"required"`
+	OrganizationId uuid.UUID   `db:"organization_id" validate:"required"`
+	Name           string      `db:"name" validate:"required,max=100"`
+	Description    null.String `db:"description" validate:"omitempty,max=255"`
+	Permissions    []string    `db:"-" validate:"dive,oneof=users.read users.write products.write carts.read privacy.read groups.manage"`
+	CreatedAt      time.Time   `db:"created_at" validate:"required"`
+	UpdatedAt      time.Time   `db:"updated_at" validate:"required"`
+	CreatedBy      uuid.UUID   `db:"created_by" validate:"required"`
+	UpdatedBy      uuid.UUID   `db:"updated_by" validate:"required"`
+}
+
+type GroupResponseFormat struct {
+	Id             uuid.UUID   `json:"id"`
+	OrganizationId uuid.UUID   `json:"organizationId"`
+	Name           string      `json:"name"`
+	Description    null.String `json:"description"`
+	Permissions    []string    `json:"permissions"`
+	CreatedAt      time.Time   `json:"createdAt"`
+	UpdatedAt      time.Time   `json:"updatedAt"`
+	CreatedBy      uuid.UUID   `json:"createdBy"`
+	UpdatedBy      uuid.UUID   `json:"updatedBy"`
+}
+
+type GroupPayload struct {
+	Name        string      `json:"name" validate:"required,max=100"`
+	Description null.String `json:"description" validate:"omitempty,max=255"`
+	Permissions []string    `json:"permissions" validate:"dive,oneof=users.read users.write products.write carts.read privacy.read groups.manage"`
+}
+
+// Permission is a single grant of a group, as stored.
+type Permission struct {
+	GroupId    uuid.UUID `db:"group_id"`
+	Permission string    `db:"permission"`
+}
+
+type Member struct {
+	GroupId   uuid.UUID `db:"group_id" validate:"required"`
+	UserId    uuid.UUID `db:"user_id" validate:"required"`
+	CreatedAt time.Time `db:"created_at" validate:"required"`
+	CreatedBy uuid.UUID `db:"created_by" validate:"required"`
+}
+
+type MemberResponseFormat struct {
+	GroupId   uuid.UUID `json:"groupId"`
+	UserId    uuid.UUID `json:"userId"`
+	CreatedAt time.Time `json:"createdAt"`
+	CreatedBy uuid.UUID `json:"createdBy"`
+}
+
+func (g Group) NewFromPayload(load GroupPayload, orgId, creatorId uuid.UUID) (res Group, err error) {
+	groupId, err := uuid.NewV4()
+	if err != nil {
+		return
+	}
+	now := time.Now().UTC()
+	res = Group{
+		Id:             groupId,
+		OrganizationId: orgId,
+		Name:           load.Name,
+		Description:    load.Description,
+		Permissions:    uniquePermissions(load.Permissions),
+		CreatedAt:      now,
+		CreatedBy:      creatorId,
+		UpdatedAt:      now,
+		UpdatedBy:      creatorId,
+	}
+	err = res.Validate()
+	return
+}
+
+func (g *Group) Update(load GroupPayload, updaterId uuid.UUID) (err error) {
+	g.Name = load.Name
+	g.Description = load.Description
+	g.Permissions = uniquePermissions(load.Permissions)
+	g.UpdatedAt = time.Now().UTC()
+	g.UpdatedBy = updaterId
+	err = g.Validate()
+	return
+}
+
+// HasPermission reports whether the group grants permission.
+func (g *Group) HasPermission(permission string) bool {
+	for _, p := range g.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Group) Validate() error {
+	validator := shared.GetValidator()
+	return validator.Struct(g)
+}
+
+func (g Group) ToResponseFormat() GroupResponseFormat {
+	return GroupResponseFormat(g)
+}
+
+func (g Group) MarshalJSON() ([]byte, error) {
+	return json.Marshal(g.ToResponseFormat())
+}
+
+func (m Member) NewFromUser(groupId, userId, creatorId uuid.UUID) (res Member, err error) {
+	res = Member{
+		GroupId:   groupId,
+		UserId:    userId,
+		CreatedAt: time.Now().UTC(),
+		CreatedBy: creatorId,
+	}
+	err = res.Validate()
+	return
+}
+
+func (m *Member) Validate() error {
+	validator := shared.GetValidator()
+	return validator.Struct(m)
+}
+
+func (m Member) ToResponseFormat() MemberResponseFormat {
+	return MemberResponseFormat(m)
+}
+
+func (m Member) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.ToResponseFormat())
+}
+
+func uniquePermissions(permissions []string) (res []string) {
+	res = []string{}
+	seen := map[string]bool{}
+	for _, p := range permissions {
+		if !seen[p] {
+			seen[p] = true
+			res = append(res, p)
+		}
+	}
+	return
+}
@@ -0,0 +1,237 @@
+package group
+
+import (
+	"database/sql"
+
+	"github.com/evermos/boilerplate-go/infras"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+type GroupRepository interface {
+	Create(load Group) (err error)
+	Update(load Group) (err error)
+	Delete(load Group) (err error)
+	GetByID(orgId, id string) (res Group, err error)
+	GetAll(orgId string, limit, offset int) (res []Group, err error)
+	GetByUserID(orgId, userId string) (res []Group, err error)
+	ExistsByName(orgId, name, excludeId string) (exists bool, err error)
+	HasPermission(orgId, userId, permission string) (granted bool, err error)
+	AddMember(load Member) (err error)
+	RemoveMember(groupId, userId string) (err error)
+	GetMembers(groupId string, limit, offset int) (res []Member, err error)
+}
+
+type GroupRepositoryMySQL struct {
+	DB *infras.MySQLConn
+}
+
+func ProvideGroupRepositoryMySQL(db *infras.MySQLConn) *GroupRepositoryMySQL {
+	return &GroupRepositoryMySQL{DB: db}
+}
+
+func (r *GroupRepositoryMySQL) Create(load Group) (err error) {
+	return r.DB.WithTransaction(func(db *sqlx.Tx, c chan error) {
+		query := `INSERT INTO user_group (id,organization_id,name,description,created_at,created_by,updated_at,updated_by)
+		VALUES (:id,:organization_id,:name,:description,:created_at,:created_by,:updated_at,:updated_by)`
+		if _, err := db.NamedExec(query, load); err != nil {
+			logger.ErrorWithStack(err)
+			c <- err
+			return
+		}
+		if err := r.txSetPermissions(db, load); err != nil {
+			c <- err
+			return
+		}
+		c <- nil
+	})
+}
+
+func (r *GroupRepositoryMySQL) Update(load Group) (err error) {
+	return r.DB.WithTransaction(func(db *sqlx.Tx, c chan error) {
+		query := `
+		UPDATE user_group
+		SET
+			name = :name,
+			description = :description,
+			updated_at = :updated_at,
+			updated_by = :updated_by
+		WHERE id = :id`
+		if _, err := db.NamedExec(query, load); err != nil {
+			logger.ErrorWithStack(err)
+			c <- err
+			return
+		}
+		if err := r.txSetPermissions(db, load); err != nil {
+			c <- err
+			return
+		}
+		c <- nil
+	})
+}
+
+// txSetPermissions replaces the stored grants of the group with its
+// current Permissions.
+func (r *GroupRepositoryMySQL) txSetPermissions(tx *sqlx.Tx, load Group) (err error) {
+	_, err = tx.Exec("DELETE FROM user_group_permission WHERE group_id = ?", load.Id.String())
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	for _, permission := range load.Permissions {
+		_, err = tx.Exec("INSERT INTO user_group_permission (group_id,permission) VALUES (?,?)", load.Id.String(), permission)
+		if err != nil {
+			logger.ErrorWithStack(err)
+			return
+		}
+	}
+	return
+}
+
+// Delete removes the group. Its grants and memberships are removed by the
+// foreign keys.
+func (r *GroupRepositoryMySQL) Delete(load Group) (err error) {
+	_, err = r.DB.Write.Exec("DELETE FROM user_group WHERE id = ?", load.Id.String())
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+func (r *GroupRepositoryMySQL) GetByID(orgId, id string) (res Group, err error) {
+	err = r.DB.Read.Get(&res, "SELECT * FROM user_group WHERE id = ? AND organization_id = ?", id, orgId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = failure.NotFound("Group")
+			return
+		}
+		logger.ErrorWithStack(err)
+		return
+	}
+	groups, err := r.attachPermissions([]Group{res})
+	if err != nil {
+		return
+	}
+	res = groups[0]
+	return
+}
+
+func (r *GroupRepositoryMySQL) GetAll(orgId string, limit, offset int) (res []Group, err error) {
+	err = r.DB.Read.Select(&res, "SELECT * FROM user_group WHERE organization_id = ? ORDER BY name LIMIT ? OFFSET ?", orgId, limit, offset)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	res, err = r.attachPermissions(res)
+	return
+}
+
+func (r *GroupRepositoryMySQL) GetByUserID(orgId, userId string) (res []Group, err error) {
+	query := `SELECT g.* FROM user_group g JOIN user_group_member m ON m.group_id = g.id
+	WHERE g.organization_id = ? AND m.user_id = ? ORDER BY g.name`
+	err = r.DB.Read.Select(&res, query, orgId, userId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	res, err = r.attachPermissions(res)
+	return
+}
+
+// attachPermissions loads the grants of all groups with a single query.
+func (r *GroupRepositoryMySQL) attachPermissions(groups []Group) (res []Group, err error) {
+	res = groups
+	if len(groups) == 0 {
+		return
+	}
+	ids := make([]string, 0, len(groups))
+	for i := range res {
+		res[i].Permissions = []string{}
+		ids = append(ids, res[i].Id.String())
+	}
+	query, args, err := sqlx.In("SELECT group_id, permission FROM user_group_permission WHERE group_id IN (?) ORDER BY permission", ids)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	var permissions []Permission
+	err = r.DB.Read.Select(&permissions, r.DB.Read.Rebind(query), args...)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	for _, p := range permissions {
+		for i := range res {
+			if res[i].Id == p.GroupId {
+				res[i].Permissions = append(res[i].Permissions, p.Permission)
+			}
+		}
+	}
+	return
+}
+
+func (r *GroupRepositoryMySQL) ExistsByName(orgId, name, excludeId string) (exists bool, err error) {
+	err = r.DB.Read.Get(&exists, "SELECT COUNT(id) FROM user_group WHERE organization_id = ? AND name = ? AND id <> ?", orgId, name, excludeId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+// HasPermission reports whether any group of the user in the organization
+// grants permission.
+func (r *GroupRepositoryMySQL) HasPermission(orgId, userId, permission string) (granted bool, err error) {
+	query := `SELECT COUNT(p.group_id) FROM user_group_permission p
+	JOIN user_group g ON g.id = p.group_id
+	JOIN user_group_member m ON m.group_id = g.id
+	WHERE g.organization_id = ? AND m.user_id = ? AND p.permission = ?`
+	err = r.DB.Read.Get(&granted, query, orgId, userId, permission)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+// AddMember adds the user to the group. Adding an existing member is a
+// no-op.
+func (r *GroupRepositoryMySQL) AddMember(load Member) (err error) {
+	query := `INSERT INTO user_group_member (group_id,user_id,created_at,created_by)
+	VALUES (:group_id,:user_id,:created_at,:created_by)
+	ON DUPLICATE KEY UPDATE group_id = group_id`
+	_, err = r.DB.Write.NamedExec(query, load)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+func (r *GroupRepositoryMySQL) RemoveMember(groupId, userId string) (err error) {
+	result, err := r.DB.Write.Exec("DELETE FROM user_group_member WHERE group_id = ? AND user_id = ?", groupId, userId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	if affected == 0 {
+		err = failure.NotFound("Member")
+	}
+	return
+}
+
+func (r *GroupRepositoryMySQL) GetMembers(groupId string, limit, offset int) (res []Member, err error) {
+	err = r.DB.Read.Select(&res, "SELECT * FROM user_group_member WHERE group_id = ? ORDER BY created_at LIMIT ? OFFSET ?", groupId, limit, offset)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
@@ -0,0 +1,151 @@
+package group
+
+import (
+	"github.com/evermos/boilerplate-go/internal/domain/organization"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/gofrs/uuid"
+)
+
+type GroupService interface {
+	Create(load GroupPayload, orgId, creatorId uuid.UUID) (res Group, err error)
+	Update(load GroupPayload, orgId, groupId, updaterId uuid.UUID) (res Group, err error)
+	Delete(orgId, groupId uuid.UUID) (res Group, err error)
+	GetByID(orgId, groupId uuid.UUID) (res Group, err error)
+	GetAll(orgId uuid.UUID, limit, offset int) (res []Group, err error)
+	GetByUserID(orgId, userId uuid.UUID) (res []Group, err error)
+	InGroup(orgId, userId uuid.UUID, name string) (member bool, err error)
+	HasPermission(orgId, userId uuid.UUID, permission string) (granted bool, err error)
+	AddMember(orgId, groupId, userId, creatorId uuid.UUID) (res Member, err error)
+	RemoveMember(orgId, groupId, userId uuid.UUID) (err error)
+	GetMembers(orgId, groupId uuid.UUID, limit, offset int) (res []Member, err error)
+}
+
+type GroupServiceImpl struct {
+	Repo                GroupRepository
+	OrganizationService organization.OrganizationService
+}
+
+func ProvideGroupServiceImpl(repo GroupRepository, organizationService organization.OrganizationService) *GroupServiceImpl {
+	return &GroupServiceImpl{Repo: repo, OrganizationService: organizationService}
+}
+
+func (s *GroupServiceImpl) Create(load GroupPayload, orgId, creatorId uuid.UUID) (res Group, err error) {
+	res, err = res.NewFromPayload(load, orgId, creatorId)
+	if err != nil {
+		return
+	}
+	err = s.ensureUniqueName(res)
+	if err != nil {
+		return
+	}
+	err = s.Repo.Create(res)
+	return
+}
+
+func (s *GroupServiceImpl) Update(load GroupPayload, orgId, groupId, updaterId uuid.UUID) (res Group, err error) {
+	res, err = s.Repo.GetByID(orgId.String(), groupId.String())
+	if err != nil {
+		return
+	}
+	err = res.Update(load, updaterId)
+	if err != nil {
+		return
+	}
+	err = s.ensureUniqueName(res)
+	if err != nil {
+		return
+	}
+	err = s.Repo.Update(res)
+	return
+}
+
+func (s *GroupServiceImpl) Delete(orgId, groupId uuid.UUID) (res Group, err error) {
+	res, err = s.Repo.GetByID(orgId.String(), groupId.String())
+	if err != nil {
+		return
+	}
+	err = s.Repo.Delete(res)
+	return
+}
+
+func (s *GroupServiceImpl) GetByID(orgId, groupId uuid.UUID) (res Group, err error) {
+	return s.Repo.GetByID(orgId.String(), groupId.String())
+}
+
+func (s *GroupServiceImpl) GetAll(orgId uuid.UUID, limit, offset int) (res []Group, err error) {
+	return s.Repo.GetAll(orgId.String(), limit, offset)
+}
+
+// GetByUserID returns the groups the user belongs to in the organization.
+func (s *GroupServiceImpl) GetByUserID(orgId, userId uuid.UUID) (res []Group, err error) {
+	return s.Repo.GetByUserID(orgId.String(), userId.String())
+}
+
+// InGroup reports whether the user belongs to the group called name, so
+// other domains can treat segments such as "wholesale" differently.
+func (s *GroupServiceImpl) InGroup(orgId, userId uuid.UUID, name string) (member bool, err error) {
+	groups, err := s.Repo.GetByUserID(orgId.String(), userId.String())
+	if err != nil {
+		return
+	}
+	for _, g := range groups {
+		if g.Name == name {
+			member = true
+			return
+		}
+	}
+	return
+}
+
+// HasPermission reports whether one of the user's groups grants
+// permission. Roles are not taken into account here.
+func (s *GroupServiceImpl) HasPermission(orgId, userId uuid.UUID, permission string) (granted bool, err error) {
+	return s.Repo.HasPermission(orgId.String(), userId.String(), permission)
+}
+
+// AddMember adds the user to the group. The user has to be a member of the
+// group's organization.
+func (s *GroupServiceImpl) AddMember(orgId, groupId, userId, creatorId uuid.UUID) (res Member, err error) {
+	_, err = s.Repo.GetByID(orgId.String(), groupId.String())
+	if err != nil {
+		return
+	}
+	_, err = s.OrganizationService.GetMember(orgId, userId)
+	if err != nil {
+		return
+	}
+	res, err = res.NewFromUser(groupId, userId, creatorId)
+	if err != nil {
+		return
+	}
+	err = s.Repo.AddMember(res)
+	return
+}
+
+func (s *GroupServiceImpl) RemoveMember(orgId, groupId, userId uuid.UUID) (err error) {
+	_, err = s.Repo.GetByID(orgId.String(), groupId.String())
+	if err != nil {
+		return
+	}
+	err = s.Repo.RemoveMember(groupId.String(), userId.String())
+	return
+}
+
+func (s *GroupServiceImpl) GetMembers(orgId, groupId uuid.UUID, limit, offset int) (res []Member, err error) {
+	_, err = s.Repo.GetByID(orgId.String(), groupId.String())
+	if err != nil {
+		return
+	}
+	return s.Repo.GetMembers(groupId.String(), limit, offset)
+}
+
+func (s *GroupServiceImpl) ensureUniqueName(load Group) (err error) {
+	exists, err := s.Repo.ExistsByName(load.OrganizationId.String(), load.Name, load.Id.String())
+	if err != nil {
+		return
+	}
+	if exists {
+		err = failure.Conflict("save", "group", "already exists with that name")
+	}
+	return
+}
@@ -0,0 +1,162 @@
+package inventory
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/nuuid"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
+)
+
+// Topics of the stock events published through shared.PubSub.
+const (
+	// TopicStockLow is published when the stock of an item falls to the
+	// reorder level of its product.
+	TopicStockLow = "inventory.stock.low"
+	// TopicStockReplenished is published when an item that was out of stock
+	// is in stock again.
+	TopicStockReplenished = "inventory.stock.replenished"
+)
+
+// Threshold is the reorder level of a product. Staff are alerted when the
+// stock of the product, or of one of its variants, falls to it. Products
+// without a threshold alert when they run out.
+type Threshold struct {
+	ProductId      uuid.UUID `db:"product_id" validate:"required"`
+	OrganizationId uuid.UUID `db:"organization_id" validate:"required"`
+	ReorderLevel   int       `db:"reorder_level" validate:"min=0"`
+	UpdatedAt      time.Time `db:"updated_at" validate:"required"`
+	UpdatedBy      uuid.UUID `db:"updated_by" validate:"required"`
+}
+
+type ThresholdResponseFormat struct {
+	ProductId      uuid.UUID `json:"productId"`
+	OrganizationId uuid.UUID `json:"organizationId"`
+	ReorderLevel   int       `json:"reorderLevel"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+	UpdatedBy      uuid.UUID `json:"updatedBy"`
+}
+
+// ThresholdPayload sets the reorder level of a product.
+type ThresholdPayload struct {
+	ReorderLevel int `json:"reorderLevel" validate:"min=0"`
+}
+
+// Event is the stock of a product or variant crossing a threshold. Events
+// are stored in the transaction that moves the stock and published once
+// it has been committed, so nobody is alerted about stock that never
+// moved.
+type Event struct {
+	Id             uuid.UUID   `db:"id" json:"id"`
+	OrganizationId uuid.UUID   `db:"organization_id" json:"organizationId"`
+	ProductId      uuid.UUID   `db:"product_id" json:"productId"`
+	VariantId      nuuid.NUUID `db:"variant_id" json:"variantId"`
+	// Item names the product or variant in notifications.
+	Item         string    `db:"item" json:"item"`
+	Topic        string    `db:"topic" json:"topic"`
+	ReorderLevel int       `db:"reorder_level" json:"reorderLevel"`
+	StockBefore  int       `db:"stock_before" json:"stockBefore"`
+	StockAfter   int       `db:"stock_after" json:"stockAfter"`
+	MovementId   uuid.UUID `db:"movement_id" json:"movementId"`
+	CreatedAt    time.Time `db:"created_at" json:"createdAt"`
+	PublishedAt  null.Time `db:"published_at" json:"-"`
+}
+
+// Subscription asks for a notification when a product, or one of its
+// variants, is back in stock. The first notification fulfils it;
+// subscribing again renews it.
+type Subscription struct {
+	Id             uuid.UUID   `db:"id" validate:"required"`
+	OrganizationId uuid.UUID   `db:"organization_id" validate:"required"`
+	ProductId      uuid.UUID   `db:"product_id" validate:"required"`
+	VariantId      nuuid.NUUID `db:"variant_id"`
+	UserId         uuid.UUID   `db:"user_id" validate:"required"`
+	CreatedAt      time.Time   `db:"created_at" validate:"required"`
+	NotifiedAt     null.Time   `db:"notified_at"`
+}
+
+type SubscriptionResponseFormat struct {
+	Id             uuid.UUID   `json:"id"`
+	OrganizationId uuid.UUID   `json:"organizationId"`
+	ProductId      uuid.UUID   `json:"productId"`
+	VariantId      nuuid.NUUID `json:"variantId"`
+	UserId         uuid.UUID   `json:"userId"`
+	CreatedAt      time.Time   `json:"createdAt"`
+	NotifiedAt     null.Time   `json:"notifiedAt"`
+}
+
+// SubscriptionPayload names the variant to be notified about, which is
+// required for products with variants.
+type SubscriptionPayload struct {
+	VariantId nuuid.NUUID `json:"variantId"`
+}
+
+func (t Threshold) NewFromPayload(load ThresholdPayload, orgId, productId, userId uuid.UUID) (res Threshold, err error) {
+	res = Threshold{
+		ProductId:      productId,
+		OrganizationId: orgId,
+		ReorderLevel:   load.ReorderLevel,
+		UpdatedAt:      time.Now().UTC(),
+		UpdatedBy:      userId,
+	}
+	err = res.Validate()
+	return
+}
+
+// Crossed returns the topic of the event raised by stock going from before
+// to after: stock falling to the reorder level is low, stock rising from
+// zero is replenished.
+func (t Threshold) Crossed(before, after int) (topic string, crossed bool) {
+	switch {
+	case after < before && before > t.ReorderLevel && after <= t.ReorderLevel:
+		return TopicStockLow, true
+	case after > before && before <= 0 && after > 0:
+		return TopicStockReplenished, true
+	}
+	return
+}
+
+func (t *Threshold) Validate() error {
+	validator := shared.GetValidator()
+	return validator.Struct(t)
+}
+
+func (t Threshold) ToResponseFormat() ThresholdResponseFormat {
+	return ThresholdResponseFormat(t)
+}
+
+func (t Threshold) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.ToResponseFormat())
+}
+
+func (s Subscription) NewFromPayload(load SubscriptionPayload, orgId, productId, userId uuid.UUID) (res Subscription, err error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return
+	}
+	res = Subscription{
+		Id:             id,
+		OrganizationId: orgId,
+		ProductId:      productId,
+		VariantId:      load.VariantId,
+		UserId:         userId,
+		CreatedAt:      time.Now().UTC(),
+	}
+	err = res.Validate()
+	return
+}
+
+func (s *Subscription) Validate() error {
+	validator := shared.GetValidator()
+	return validator.Struct(s)
+}
+
+func (s Subscription) ToResponseFormat() SubscriptionResponseFormat {
+	return SubscriptionResponseFormat(s)
+}
+
+func (s Subscription) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.ToResponseFormat())
+}
@@ -0,0 +1,189 @@
+package inventory
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/evermos/boilerplate-go/configs"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/nuuid"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
+	"github.com/jmoiron/sqlx"
+)
+
+// Allocation strategies, which decide the locations orders ship from.
+const (
+	// StrategyPriority prefers the locations with the lowest priority.
+	StrategyPriority = "priority"
+	// StrategyNearest prefers the locations closest to the shipping address:
+	// same postal code, then city, province and country. Locations equally
+	// close are taken by priority.
+	StrategyNearest = "nearest"
+)
+
+// Allocator decides which locations the items of an order ship from.
+type Allocator struct {
+	Strategy string
+	// Split lets an item ship from several locations when no single one has
+	// all of it. Without it such items are out of stock.
+	Split bool
+}
+
+// ProvideAllocator is the provider for Allocator. Unknown strategies fall
+// back to priority.
+func ProvideAllocator(config *configs.Config) *Allocator {
+	strategy := config.Inventory.AllocationStrategy
+	if strategy != StrategyNearest {
+		strategy = StrategyPriority
+	}
+	return &Allocator{Strategy: strategy, Split: config.Inventory.SplitShipments}
+}
+
+// Destination is where an order ships to.
+type Destination struct {
+	CountryCode string `db:"country_code"`
+	Province    string `db:"province"`
+	City        string `db:"city"`
+	PostalCode  string `db:"postal_code"`
+}
+
+// Request asks for a quantity of a product, or of one of its variants.
+type Request struct {
+	ProductId uuid.UUID
+	VariantId nuuid.NUUID
+	Quantity  int
+}
+
+// Allocation is the part of a request shipped from a location.
+type Allocation struct {
+	LocationId uuid.UUID
+	Quantity   int
+}
+
+// Rank orders the locations from the most to the least preferred for the
+// destination.
+func (a *Allocator) Rank(locations []Location, dest Destination) (res []Location) {
+	res = append([]Location{}, locations...)
+	sort.SliceStable(res, func(i, j int) bool {
+		if a.Strategy == StrategyNearest {
+			pi, pj := proximity(res[i], dest), proximity(res[j], dest)
+			if pi != pj {
+				return pi > pj
+			}
+		}
+		if res[i].Priority != res[j].Priority {
+			return res[i].Priority < res[j].Priority
+		}
+		return res[i].Code < res[j].Code
+	})
+	return
+}
+
+// Plan splits quantity over the ranked locations given their stock. The
+// first location that has all of it is taken; with Split, what the
+// locations have is taken in order instead. ok is false when that is not
+// enough, available then tells how much could have been allocated.
+func (a *Allocator) Plan(quantity int, ranked []Location, stock map[uuid.UUID]int) (res []Allocation, available int, ok bool) {
+	for _, l := range ranked {
+		if stock[l.Id] >= quantity {
+			return []Allocation{{LocationId: l.Id, Quantity: quantity}}, quantity, true
+		}
+		if a.Split {
+			available += positive(stock[l.Id])
+		} else if stock[l.Id] > available {
+			available = stock[l.Id]
+		}
+	}
+	if !a.Split || available < quantity {
+		return nil, available, false
+	}
+	left := quantity
+	for _, l := range ranked {
+		if left == 0 {
+			break
+		}
+		take := positive(stock[l.Id])
+		if take > left {
+			take = left
+		}
+		if take > 0 {
+			res = append(res, Allocation{LocationId: l.Id, Quantity: take})
+			left -= take
+		}
+	}
+	return res, quantity, true
+}
+
+// TxAllocate takes the requested quantities out of stock within tx at the
+// locations the strategy picks for the destination, recording a movement
+// per location. When stock runs short nothing is taken and the error names
+// every item that is short.
+func (a *Allocator) TxAllocate(tx *sqlx.Tx, orgId uuid.UUID, dest Destination, requests []Request, reason string, reference null.String, userId uuid.UUID) (res []Movement, err error) {
+	locations, err := txLocations(tx, orgId)
+	if err != nil {
+		return
+	}
+	ranked := a.Rank(locations, dest)
+	sorted := append([]Request{}, requests...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return requestKey(sorted[i]) < requestKey(sorted[j])
+	})
+	movements := []Movement{}
+	shortages := []string{}
+	for _, req := range sorted {
+		wanted := Movement{OrganizationId: orgId, ProductId: req.ProductId, VariantId: req.VariantId, Quantity: -req.Quantity}
+		_, item, err := txLockStock(tx, wanted)
+		if err != nil {
+			return nil, err
+		}
+		stock, err := txLevels(tx, wanted)
+		if err != nil {
+			return nil, err
+		}
+		parts, available, ok := a.Plan(req.Quantity, ranked, stock)
+		if !ok {
+			shortages = append(shortages, wanted.shortage(item, available))
+			continue
+		}
+		for _, part := range parts {
+			m, err := NewMovement(orgId, req.ProductId, req.VariantId, -part.Quantity, reason, reference, userId)
+			if err != nil {
+				return nil, err
+			}
+			m.LocationId = nuuid.From(part.LocationId)
+			movements = append(movements, m)
+		}
+	}
+	if len(shortages) > 0 {
+		return nil, failure.Conflict("move", "stock", strings.Join(shortages, "; "))
+	}
+	return TxApplyAll(tx, movements)
+}
+
+// proximity scores how close the location is to the destination.
+func proximity(l Location, dest Destination) int {
+	if !l.CountryCode.Valid || !strings.EqualFold(l.CountryCode.String, dest.CountryCode) {
+		return 0
+	}
+	switch {
+	case l.PostalCode.Valid && strings.EqualFold(l.PostalCode.String, dest.PostalCode):
+		return 4
+	case l.City.Valid && strings.EqualFold(l.City.String, dest.City):
+		return 3
+	case l.Province.Valid && strings.EqualFold(l.Province.String, dest.Province):
+		return 2
+	}
+	return 1
+}
+
+func positive(n int) int {
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+func requestKey(r Request) string {
+	return r.ProductId.String() + "/" + r.VariantId.UUID.String()
+}
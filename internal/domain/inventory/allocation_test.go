@@ -0,0 +1,65 @@
+package inventory_test
+
+import (
+	"testing"
+
+	"github.com/evermos/boilerplate-go/internal/domain/inventory"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllocator(t *testing.T) {
+	jakarta := inventory.Location{Id: uuid.Must(uuid.NewV4()), Code: "jakarta", Priority: 1,
+		City: null.StringFrom("Jakarta"), Province: null.StringFrom("DKI Jakarta"), CountryCode: null.StringFrom("ID")}
+	bandung := inventory.Location{Id: uuid.Must(uuid.NewV4()), Code: "bandung", Priority: 2,
+		City: null.StringFrom("Bandung"), Province: null.StringFrom("Jawa Barat"), CountryCode: null.StringFrom("ID")}
+	store := inventory.Location{Id: uuid.Must(uuid.NewV4()), Code: "store", Priority: 0}
+	locations := []inventory.Location{bandung, store, jakarta}
+	toBandung := inventory.Destination{CountryCode: "ID", Province: "Jawa Barat", City: "Bandung", PostalCode: "40111"}
+
+	codes := func(locations []inventory.Location) (res []string) {
+		for _, l := range locations {
+			res = append(res, l.Code)
+		}
+		return
+	}
+
+	t.Run("priority ranks by priority", func(t *testing.T) {
+		a := inventory.Allocator{Strategy: inventory.StrategyPriority}
+		assert.Equal(t, []string{"store", "jakarta", "bandung"}, codes(a.Rank(locations, toBandung)))
+	})
+
+	t.Run("nearest ranks by closeness, then priority", func(t *testing.T) {
+		a := inventory.Allocator{Strategy: inventory.StrategyNearest}
+		assert.Equal(t, []string{"bandung", "jakarta", "store"}, codes(a.Rank(locations, toBandung)))
+	})
+
+	ranked := []inventory.Location{jakarta, bandung}
+	stock := map[uuid.UUID]int{jakarta.Id: 3, bandung.Id: 5}
+
+	t.Run("takes the first location that has it all", func(t *testing.T) {
+		a := inventory.Allocator{Split: true}
+		parts, _, ok := a.Plan(4, ranked, stock)
+		assert.True(t, ok)
+		assert.Equal(t, []inventory.Allocation{{LocationId: bandung.Id, Quantity: 4}}, parts)
+	})
+
+	t.Run("splits when no location has it all", func(t *testing.T) {
+		a := inventory.Allocator{Split: true}
+		parts, _, ok := a.Plan(7, ranked, stock)
+		assert.True(t, ok)
+		assert.Equal(t, []inventory.Allocation{{LocationId: jakarta.Id, Quantity: 3}, {LocationId: bandung.Id, Quantity: 4}}, parts)
+
+		_, available, ok := a.Plan(9, ranked, stock)
+		assert.False(t, ok)
+		assert.Equal(t, 8, available)
+	})
+
+	t.Run("does not split unless allowed", func(t *testing.T) {
+		a := inventory.Allocator{}
+		_, available, ok := a.Plan(7, ranked, stock)
+		assert.False(t, ok)
+		assert.Equal(t, 5, available)
+	})
+}
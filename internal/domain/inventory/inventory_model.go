@@ -0,0 +1,198 @@
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/nuuid"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
+)
+
+// Reasons of stock movements. Orders record their own movements; the others
+// are entered by staff. A transfer is recorded as two movements, out of one
+// location and into another.
+const (
+	ReasonOrderPlaced    = "order_placed"
+	ReasonOrderCancelled = "order_cancelled"
+	ReasonAdjustment     = "adjustment"
+	ReasonRestock        = "restock"
+	ReasonReturn         = "return"
+	ReasonTransfer       = "transfer"
+)
+
+// Movement is an entry of the stock ledger: a change of the stock of a
+// product, or of one of its variants, with the reason, the actor and what
+// caused it. The ledger is append-only.
+type Movement struct {
+	Id             uuid.UUID   `db:"id" validate:"required"`
+	OrganizationId uuid.UUID   `db:"organization_id" validate:"required"`
+	ProductId      uuid.UUID   `db:"product_id" validate:"required"`
+	VariantId      nuuid.NUUID `db:"variant_id"`
+	// LocationId is where the stock moved. Movements that do not name a
+	// location are made at the organization's default location.
+	LocationId nuuid.NUUID `db:"location_id"`
+	// Quantity is the change of stock, negative when stock goes out.
+	Quantity int `db:"quantity"`
+	// StockAfter is the stock at the location right after the movement.
+	StockAfter int    `db:"stock_after"`
+	Reason     string `db:"reason" validate:"required,oneof=order_placed order_cancelled adjustment restock return transfer"`
+	// Reference identifies what caused the movement, such as the order for
+	// order movements or a delivery note for a restock.
+	Reference null.String `db:"reference" validate:"omitempty,max=255"`
+	Note      null.String `db:"note" validate:"omitempty,max=500"`
+	CreatedAt time.Time   `db:"created_at" validate:"required"`
+	CreatedBy uuid.UUID   `db:"created_by" validate:"required"`
+}
+
+type MovementResponseFormat struct {
+	Id             uuid.UUID   `json:"id"`
+	OrganizationId uuid.UUID   `json:"organizationId"`
+	ProductId      uuid.UUID   `json:"productId"`
+	VariantId      nuuid.NUUID `json:"variantId"`
+	LocationId     nuuid.NUUID `json:"locationId"`
+	Quantity       int         `json:"quantity"`
+	StockAfter     int         `json:"stockAfter"`
+	Reason         string      `json:"reason"`
+	Reference      null.String `json:"reference"`
+	Note           null.String `json:"note"`
+	CreatedAt      time.Time   `json:"createdAt"`
+	CreatedBy      uuid.UUID   `json:"createdBy"`
+}
+
+// AdjustmentPayload is a stock movement entered by staff, such as a
+// correction after a stock count, goods received or a customer return.
+type AdjustmentPayload struct {
+	// VariantId is required for products with variants, whose stock is
+	// kept per variant.
+	VariantId nuuid.NUUID `json:"variantId"`
+	// LocationId is the default location when left out.
+	LocationId nuuid.NUUID `json:"locationId"`
+	Quantity   int         `json:"quantity" validate:"required"`
+	Reason     string      `json:"reason" validate:"required,oneof=adjustment restock return"`
+	Reference  string      `json:"reference" validate:"max=255"`
+	Note       string      `json:"note" validate:"max=500"`
+}
+
+// TransferPayload moves stock of a product, or of one of its variants,
+// from one location to another.
+type TransferPayload struct {
+	VariantId      nuuid.NUUID `json:"variantId"`
+	FromLocationId uuid.UUID   `json:"fromLocationId" validate:"required"`
+	ToLocationId   uuid.UUID   `json:"toLocationId" validate:"required"`
+	Quantity       int         `json:"quantity" validate:"required,min=1"`
+	Reference      string      `json:"reference" validate:"max=255"`
+	Note           string      `json:"note" validate:"max=500"`
+}
+
+// HistoryFilter narrows down the stock history of a product.
+type HistoryFilter struct {
+	VariantId  nuuid.NUUID
+	LocationId nuuid.NUUID
+	Reason     string
+}
+
+// ValidateReason checks an optional reason filter.
+func ValidateReason(reason string) error {
+	switch reason {
+	case "", ReasonOrderPlaced, ReasonOrderCancelled, ReasonAdjustment, ReasonRestock, ReasonReturn, ReasonTransfer:
+		return nil
+	}
+	return failure.BadRequestFromString("reason must be one of order_placed, order_cancelled, adjustment, restock, return, transfer")
+}
+
+// NewMovement returns a movement of quantity units of the product, or of
+// the variant when it is valid.
+func NewMovement(orgId, productId uuid.UUID, variantId nuuid.NUUID, quantity int, reason string, reference null.String, userId uuid.UUID) (res Movement, err error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return
+	}
+	res = Movement{
+		Id:             id,
+		OrganizationId: orgId,
+		ProductId:      productId,
+		VariantId:      variantId,
+		Quantity:       quantity,
+		Reason:         reason,
+		Reference:      reference,
+		CreatedAt:      time.Now().UTC(),
+		CreatedBy:      userId,
+	}
+	err = res.Validate()
+	return
+}
+
+func (m Movement) NewFromAdjustment(load AdjustmentPayload, orgId, productId, userId uuid.UUID) (res Movement, err error) {
+	res, err = NewMovement(orgId, productId, load.VariantId, load.Quantity, load.Reason, null.NewString(load.Reference, load.Reference != ""), userId)
+	if err != nil {
+		return
+	}
+	res.LocationId = load.LocationId
+	res.Note = null.NewString(load.Note, load.Note != "")
+	err = res.Validate()
+	return
+}
+
+// NewTransfer returns the two movements of a transfer: out of the source
+// location and into the destination. Both refer to the transfer's id unless
+// the payload has a reference.
+func NewTransfer(load TransferPayload, orgId, productId, userId uuid.UUID) (res []Movement, err error) {
+	reference := null.NewString(load.Reference, load.Reference != "")
+	out, err := NewMovement(orgId, productId, load.VariantId, -load.Quantity, ReasonTransfer, reference, userId)
+	if err != nil {
+		return
+	}
+	if !reference.Valid {
+		out.Reference = null.StringFrom(out.Id.String())
+	}
+	in, err := NewMovement(orgId, productId, load.VariantId, load.Quantity, ReasonTransfer, out.Reference, userId)
+	if err != nil {
+		return
+	}
+	out.LocationId = nuuid.From(load.FromLocationId)
+	in.LocationId = nuuid.From(load.ToLocationId)
+	out.Note = null.NewString(load.Note, load.Note != "")
+	in.Note = out.Note
+	res = []Movement{out, in}
+	return
+}
+
+func (m *Movement) Validate() error {
+	validator := shared.GetValidator()
+	return validator.Struct(m)
+}
+
+// apply sets the stock after the movement given the stock before it. Stock
+// never goes below zero, for orders neither: the units of an order are taken
+// out of stock in the transaction that places it.
+func (m *Movement) apply(stock int) (err error) {
+	m.StockAfter = stock + m.Quantity
+	if m.StockAfter < 0 {
+		err = failure.Conflict("move", "stock", m.shortage("", stock))
+	}
+	return
+}
+
+// shortage describes why the movement of item cannot be made when stock
+// units are left. item names the product or variant, its id when empty.
+func (m Movement) shortage(item string, stock int) string {
+	if item == "" {
+		item = m.ProductId.String()
+		if m.VariantId.Valid {
+			item = m.VariantId.UUID.String()
+		}
+	}
+	return fmt.Sprintf("insufficient stock for %s: %d requested, %d available", item, -m.Quantity, stock)
+}
+
+func (m Movement) ToResponseFormat() MovementResponseFormat {
+	return MovementResponseFormat(m)
+}
+
+func (m Movement) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.ToResponseFormat())
+}
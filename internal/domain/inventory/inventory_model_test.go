@@ -0,0 +1,84 @@
+package inventory_test
+
+import (
+	"testing"
+
+	"github.com/evermos/boilerplate-go/internal/domain/inventory"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFromAdjustment(t *testing.T) {
+	orgId, productId, userId := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+
+	t.Run("keeps the reason, reference and note", func(t *testing.T) {
+		m, err := inventory.Movement{}.NewFromAdjustment(inventory.AdjustmentPayload{
+			Quantity:  -3,
+			Reason:    inventory.ReasonAdjustment,
+			Reference: "count-2024-03",
+			Note:      "damaged in storage",
+		}, orgId, productId, userId)
+		assert.NoError(t, err)
+		assert.Equal(t, -3, m.Quantity)
+		assert.Equal(t, inventory.ReasonAdjustment, m.Reason)
+		assert.Equal(t, "count-2024-03", m.Reference.String)
+		assert.Equal(t, "damaged in storage", m.Note.String)
+		assert.Equal(t, userId, m.CreatedBy)
+		assert.False(t, m.VariantId.Valid)
+	})
+
+	t.Run("leaves empty references out", func(t *testing.T) {
+		m, err := inventory.Movement{}.NewFromAdjustment(inventory.AdjustmentPayload{
+			Quantity: 10,
+			Reason:   inventory.ReasonRestock,
+		}, orgId, productId, userId)
+		assert.NoError(t, err)
+		assert.False(t, m.Reference.Valid)
+		assert.False(t, m.Note.Valid)
+	})
+
+	t.Run("rejects unknown reasons", func(t *testing.T) {
+		_, err := inventory.Movement{}.NewFromAdjustment(inventory.AdjustmentPayload{
+			Quantity: 1,
+			Reason:   "gift",
+		}, orgId, productId, userId)
+		assert.Error(t, err)
+	})
+}
+
+func TestValidateReason(t *testing.T) {
+	assert.NoError(t, inventory.ValidateReason(""))
+	assert.NoError(t, inventory.ValidateReason(inventory.ReasonOrderPlaced))
+	assert.Error(t, inventory.ValidateReason("gift"))
+}
+
+func TestThresholdCrossed(t *testing.T) {
+	threshold := inventory.Threshold{ReorderLevel: 5}
+
+	cases := []struct {
+		name          string
+		before, after int
+		topic         string
+		crossed       bool
+	}{
+		{"falls to the reorder level", 8, 5, inventory.TopicStockLow, true},
+		{"falls below the reorder level", 8, 2, inventory.TopicStockLow, true},
+		{"falls further when already low", 4, 2, "", false},
+		{"stays above the reorder level", 9, 6, "", false},
+		{"rises from zero", 0, 3, inventory.TopicStockReplenished, true},
+		{"rises when still in stock", 2, 6, "", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			topic, crossed := threshold.Crossed(c.before, c.after)
+			assert.Equal(t, c.crossed, crossed)
+			assert.Equal(t, c.topic, topic)
+		})
+	}
+
+	t.Run("without a reorder level, running out is low", func(t *testing.T) {
+		topic, crossed := inventory.Threshold{}.Crossed(1, 0)
+		assert.True(t, crossed)
+		assert.Equal(t, inventory.TopicStockLow, topic)
+	})
+}
@@ -0,0 +1,693 @@
+package inventory
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/evermos/boilerplate-go/infras"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/evermos/boilerplate-go/shared/nuuid"
+	"github.com/gofrs/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type InventoryRepository interface {
+	Apply(m Movement) (res Movement, err error)
+	GetByProductID(productId, orgId string, filter HistoryFilter, limit, offset int) (res []Movement, err error)
+	ProductExists(productId, orgId string) (exists bool, err error)
+	HasVariants(productId string) (has bool, err error)
+	ApplyAll(movements []Movement) (res []Movement, err error)
+	GetLevels(productId, orgId string) (res []Level, err error)
+	CreateLocation(load Location) (err error)
+	UpdateLocation(load Location) (err error)
+	GetLocations(orgId string) (res []Location, err error)
+	GetLocationByID(id, orgId string) (res Location, err error)
+	LocationCodeExists(code, orgId, excludeId string) (exists bool, err error)
+	HasDefaultLocation(orgId string) (has bool, err error)
+	LocationHasStock(id string) (has bool, err error)
+	GetThreshold(productId, orgId string) (res Threshold, err error)
+	SetThreshold(load Threshold) (err error)
+	SaveSubscription(load Subscription) (res Subscription, err error)
+	DeleteSubscription(productId, userId string, variantId nuuid.NUUID) (err error)
+	GetPendingSubscriptions(productId string, variantId nuuid.NUUID) (res []Subscription, err error)
+	MarkNotified(id string, at time.Time) (err error)
+	GetUnpublishedEvents(limit int) (res []Event, err error)
+	MarkPublished(id string, at time.Time) (err error)
+}
+
+type InventoryRepositoryMySQL struct {
+	DB *infras.MySQLConn
+}
+
+func ProvideInventoryRepositoryMySQL(db *infras.MySQLConn) *InventoryRepositoryMySQL {
+	return &InventoryRepositoryMySQL{DB: db}
+}
+
+// movementColumns are the columns of stock_movement without seq, which only
+// orders movements made within the same second.
+const movementColumns = "id,organization_id,product_id,variant_id,location_id,quantity,stock_after,reason,reference,note,created_at,created_by"
+
+const eventColumns = "id,organization_id,product_id,variant_id,item,topic,reorder_level,stock_before,stock_after,movement_id,created_at,published_at"
+
+const subscriptionColumns = "id,organization_id,product_id,variant_id,user_id,created_at,notified_at"
+
+const locationColumns = "id,organization_id,code,name,priority,city,province,postal_code,country_code,is_default,created_at,updated_at,deleted_at,created_by,updated_by,deleted_by"
+
+// Apply changes the stock and records the movement in one transaction.
+func (r *InventoryRepositoryMySQL) Apply(m Movement) (res Movement, err error) {
+	err = r.DB.WithTransaction(func(db *sqlx.Tx, c chan error) {
+		applied, err := TxApply(db, m)
+		if err != nil {
+			c <- err
+			return
+		}
+		res = applied
+		c <- nil
+	})
+	return
+}
+
+// ApplyAll applies the movements in one transaction, all or none of them.
+func (r *InventoryRepositoryMySQL) ApplyAll(movements []Movement) (res []Movement, err error) {
+	err = r.DB.WithTransaction(func(db *sqlx.Tx, c chan error) {
+		applied, err := TxApplyAll(db, movements)
+		if err != nil {
+			c <- err
+			return
+		}
+		res = applied
+		c <- nil
+	})
+	return
+}
+
+// GetByProductID returns the movements of the product and its variants,
+// newest first.
+func (r *InventoryRepositoryMySQL) GetByProductID(productId, orgId string, filter HistoryFilter, limit, offset int) (res []Movement, err error) {
+	query := "SELECT " + movementColumns + " FROM stock_movement WHERE product_id = ? AND organization_id = ?"
+	args := []interface{}{productId, orgId}
+	if filter.VariantId.Valid {
+		query += " AND variant_id = ?"
+		args = append(args, filter.VariantId.UUID.String())
+	}
+	if filter.LocationId.Valid {
+		query += " AND location_id = ?"
+		args = append(args, filter.LocationId.UUID.String())
+	}
+	if filter.Reason != "" {
+		query += " AND reason = ?"
+		args = append(args, filter.Reason)
+	}
+	query += " ORDER BY created_at DESC, seq DESC LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+	res = []Movement{}
+	err = r.DB.Read.Select(&res, query, args...)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+// ProductExists reports whether the product exists and is not deleted.
+func (r *InventoryRepositoryMySQL) ProductExists(productId, orgId string) (exists bool, err error) {
+	err = r.DB.Read.Get(&exists, "SELECT COUNT(id) FROM product WHERE id = ? AND organization_id = ? AND deleted_at IS NULL", productId, orgId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+// HasVariants reports whether the product has variants that are not
+// deleted, in which case its stock is kept per variant.
+func (r *InventoryRepositoryMySQL) HasVariants(productId string) (has bool, err error) {
+	err = r.DB.Read.Get(&has, "SELECT COUNT(id) > 0 FROM product_variant WHERE product_id = ? AND deleted_at IS NULL", productId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+// GetLevels returns the stock of the product and its variants at every
+// location of the organization that is not deleted, including locations
+// without any.
+func (r *InventoryRepositoryMySQL) GetLevels(productId, orgId string) (res []Level, err error) {
+	query := `SELECT l.id AS location_id, l.code AS location_code, l.name AS location_name, i.product_id, i.variant_id, COALESCE(s.stock, 0) AS stock
+	FROM location l
+	CROSS JOIN (
+		SELECT id AS product_id, NULL AS variant_id FROM product WHERE id = ? AND deleted_at IS NULL
+		UNION ALL
+		SELECT product_id, id FROM product_variant WHERE product_id = ? AND deleted_at IS NULL
+	) i
+	LEFT JOIN location_stock s ON s.location_id = l.id AND s.product_id = i.product_id AND s.variant_id <=> i.variant_id
+	WHERE l.organization_id = ? AND l.deleted_at IS NULL
+	ORDER BY l.priority, l.code, i.variant_id IS NOT NULL, i.variant_id`
+	res = []Level{}
+	err = r.DB.Read.Select(&res, query, productId, productId, orgId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+// CreateLocation inserts the location. A new default location takes that
+// role from the previous one.
+func (r *InventoryRepositoryMySQL) CreateLocation(load Location) (err error) {
+	return r.DB.WithTransaction(func(db *sqlx.Tx, c chan error) {
+		if err := txClearDefault(db, load); err != nil {
+			c <- err
+			return
+		}
+		query := `INSERT INTO location (` + locationColumns + `)
+		VALUES (:id,:organization_id,:code,:name,:priority,:city,:province,:postal_code,:country_code,:is_default,:created_at,:updated_at,:deleted_at,:created_by,:updated_by,:deleted_by)`
+		if _, err := db.NamedExec(query, load); err != nil {
+			logger.ErrorWithStack(err)
+			c <- err
+			return
+		}
+		c <- nil
+	})
+}
+
+// UpdateLocation saves the location. A location that became the default
+// takes that role from the previous one.
+func (r *InventoryRepositoryMySQL) UpdateLocation(load Location) (err error) {
+	return r.DB.WithTransaction(func(db *sqlx.Tx, c chan error) {
+		if err := txClearDefault(db, load); err != nil {
+			c <- err
+			return
+		}
+		query := `UPDATE location SET code = :code, name = :name, priority = :priority, city = :city, province = :province,
+		postal_code = :postal_code, country_code = :country_code, is_default = :is_default, updated_at = :updated_at,
+		updated_by = :updated_by, deleted_at = :deleted_at, deleted_by = :deleted_by
+		WHERE id = :id`
+		if _, err := db.NamedExec(query, load); err != nil {
+			logger.ErrorWithStack(err)
+			c <- err
+			return
+		}
+		c <- nil
+	})
+}
+
+func txClearDefault(tx *sqlx.Tx, load Location) (err error) {
+	if !load.IsDefault {
+		return
+	}
+	_, err = tx.Exec("UPDATE location SET is_default = 0 WHERE organization_id = ? AND id <> ?", load.OrganizationId.String(), load.Id.String())
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+	return
+}
+
+// GetLocations returns the locations of the organization that are not
+// deleted, in the order of their priority.
+func (r *InventoryRepositoryMySQL) GetLocations(orgId string) (res []Location, err error) {
+	res = []Location{}
+	err = r.DB.Read.Select(&res, "SELECT "+locationColumns+" FROM location WHERE organization_id = ? AND deleted_at IS NULL ORDER BY priority, code", orgId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+func (r *InventoryRepositoryMySQL) GetLocationByID(id, orgId string) (res Location, err error) {
+	err = r.DB.Read.Get(&res, "SELECT "+locationColumns+" FROM location WHERE id = ? AND organization_id = ? AND deleted_at IS NULL", id, orgId)
+	if err == sql.ErrNoRows {
+		err = failure.NotFound("Location")
+		return
+	}
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+// LocationCodeExists reports whether another location of the organization,
+// deleted ones included, has the code.
+func (r *InventoryRepositoryMySQL) LocationCodeExists(code, orgId, excludeId string) (exists bool, err error) {
+	err = r.DB.Read.Get(&exists, "SELECT COUNT(id) FROM location WHERE code = ? AND organization_id = ? AND id <> ?", code, orgId, excludeId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+func (r *InventoryRepositoryMySQL) HasDefaultLocation(orgId string) (has bool, err error) {
+	err = r.DB.Read.Get(&has, "SELECT COUNT(id) > 0 FROM location WHERE organization_id = ? AND is_default = 1 AND deleted_at IS NULL", orgId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+// LocationHasStock reports whether any stock is left at the location.
+func (r *InventoryRepositoryMySQL) LocationHasStock(id string) (has bool, err error) {
+	err = r.DB.Read.Get(&has, "SELECT COUNT(location_id) > 0 FROM location_stock WHERE location_id = ? AND stock <> 0", id)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+// GetThreshold returns the reorder level of the product.
+func (r *InventoryRepositoryMySQL) GetThreshold(productId, orgId string) (res Threshold, err error) {
+	err = r.DB.Read.Get(&res, "SELECT product_id, organization_id, reorder_level, updated_at, updated_by FROM stock_threshold WHERE product_id = ? AND organization_id = ?",
+		productId, orgId)
+	if err == sql.ErrNoRows {
+		err = failure.NotFound("Threshold")
+		return
+	}
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+	return
+}
+
+// SetThreshold inserts or replaces the reorder level of the product.
+func (r *InventoryRepositoryMySQL) SetThreshold(load Threshold) (err error) {
+	query := `INSERT INTO stock_threshold (product_id, organization_id, reorder_level, updated_at, updated_by)
+	VALUES (:product_id, :organization_id, :reorder_level, :updated_at, :updated_by)
+	ON DUPLICATE KEY UPDATE reorder_level = VALUES(reorder_level), updated_at = VALUES(updated_at), updated_by = VALUES(updated_by)`
+	_, err = r.DB.Write.NamedExec(query, load)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+	return
+}
+
+// SaveSubscription inserts the subscription. A user subscribing to the same
+// item again renews their subscription, which is returned.
+func (r *InventoryRepositoryMySQL) SaveSubscription(load Subscription) (res Subscription, err error) {
+	query := `INSERT INTO stock_subscription (` + subscriptionColumns + `)
+	VALUES (:id,:organization_id,:product_id,:variant_id,:user_id,:created_at,:notified_at)
+	ON DUPLICATE KEY UPDATE created_at = VALUES(created_at), notified_at = NULL`
+	_, err = r.DB.Write.NamedExec(query, load)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	err = r.DB.Write.Get(&res, "SELECT "+subscriptionColumns+" FROM stock_subscription WHERE product_id = ? AND variant_id <=> ? AND user_id = ?",
+		load.ProductId.String(), load.VariantId, load.UserId.String())
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+	return
+}
+
+func (r *InventoryRepositoryMySQL) DeleteSubscription(productId, userId string, variantId nuuid.NUUID) (err error) {
+	result, err := r.DB.Write.Exec("DELETE FROM stock_subscription WHERE product_id = ? AND variant_id <=> ? AND user_id = ?", productId, variantId, userId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	if deleted == 0 {
+		err = failure.NotFound("Subscription")
+	}
+	return
+}
+
+// GetPendingSubscriptions returns the subscriptions to the item that have
+// not been notified yet, oldest first.
+func (r *InventoryRepositoryMySQL) GetPendingSubscriptions(productId string, variantId nuuid.NUUID) (res []Subscription, err error) {
+	res = []Subscription{}
+	err = r.DB.Read.Select(&res, "SELECT "+subscriptionColumns+" FROM stock_subscription WHERE product_id = ? AND variant_id <=> ? AND notified_at IS NULL ORDER BY created_at",
+		productId, variantId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+	return
+}
+
+func (r *InventoryRepositoryMySQL) MarkNotified(id string, at time.Time) (err error) {
+	_, err = r.DB.Write.Exec("UPDATE stock_subscription SET notified_at = ? WHERE id = ?", at, id)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+	return
+}
+
+// GetUnpublishedEvents returns up to limit events that have not been
+// published yet, oldest first.
+func (r *InventoryRepositoryMySQL) GetUnpublishedEvents(limit int) (res []Event, err error) {
+	res = []Event{}
+	err = r.DB.Read.Select(&res, "SELECT "+eventColumns+" FROM stock_event WHERE published_at IS NULL ORDER BY created_at LIMIT ?", limit)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+	return
+}
+
+func (r *InventoryRepositoryMySQL) MarkPublished(id string, at time.Time) (err error) {
+	_, err = r.DB.Write.Exec("UPDATE stock_event SET published_at = ? WHERE id = ?", at, id)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+	return
+}
+
+// TxApply changes the stock of the movement's product or variant by its
+// quantity within tx and appends the movement to the ledger. The stock rows
+// stay locked until tx ends, so concurrent movements are applied one after
+// another and stock cannot be taken out twice. Every change of stock goes
+// through here.
+func TxApply(tx *sqlx.Tx, m Movement) (res Movement, err error) {
+	stock, _, err := txLock(tx, &m)
+	if err != nil {
+		return
+	}
+	err = m.apply(stock)
+	if err != nil {
+		return
+	}
+	return txRecord(tx, m)
+}
+
+// TxApplyAll applies the movements within tx, all or none of them. Rows are
+// locked in a fixed order so transactions moving the same items cannot
+// deadlock. When stock runs short the error names every item that is short,
+// not just the first one.
+func TxApplyAll(tx *sqlx.Tx, movements []Movement) (res []Movement, err error) {
+	sorted := append([]Movement{}, movements...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return lockKey(sorted[i]) < lockKey(sorted[j])
+	})
+	shortages := []string{}
+	for _, m := range sorted {
+		stock, item, err := txLock(tx, &m)
+		if err != nil {
+			return nil, err
+		}
+		if m.apply(stock) != nil {
+			shortages = append(shortages, m.shortage(item, stock))
+			continue
+		}
+		if len(shortages) > 0 {
+			continue
+		}
+		applied, err := txRecord(tx, m)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, applied)
+	}
+	if len(shortages) > 0 {
+		return nil, failure.Conflict("move", "stock", strings.Join(shortages, "; "))
+	}
+	return
+}
+
+// TxSet brings the total stock of the movement's product or variant to
+// stock, recording the difference as the movement at its location.
+// Nothing is recorded when the stock is already at that level.
+func TxSet(tx *sqlx.Tx, m Movement, stock int) (res Movement, err error) {
+	total, _, err := txLockStock(tx, m)
+	if err != nil {
+		return
+	}
+	m.Quantity = stock - total
+	if m.Quantity == 0 {
+		return m, nil
+	}
+	return TxApply(tx, m)
+}
+
+// TxReverse records the opposite of the movements of reason with the
+// reference within tx, at the same locations, as movements of newReason.
+// It returns nothing when there are no such movements.
+func TxReverse(tx *sqlx.Tx, orgId uuid.UUID, reference, reason, newReason string, userId uuid.UUID) (res []Movement, err error) {
+	original := []Movement{}
+	err = tx.Select(&original, "SELECT "+movementColumns+" FROM stock_movement WHERE organization_id = ? AND reference = ? AND reason = ?",
+		orgId.String(), reference, reason)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	movements := make([]Movement, 0, len(original))
+	for _, o := range original {
+		m, err := NewMovement(orgId, o.ProductId, o.VariantId, -o.Quantity, newReason, o.Reference, userId)
+		if err != nil {
+			return nil, err
+		}
+		m.LocationId = o.LocationId
+		movements = append(movements, m)
+	}
+	return TxApplyAll(tx, movements)
+}
+
+// txLock locks the stock rows of the movement, the total of its product or
+// variant first and then the stock at its location, which is the default
+// location when the movement has none. It returns the stock at the
+// location along with a name of the item for errors.
+func txLock(tx *sqlx.Tx, m *Movement) (stock int, item string, err error) {
+	_, item, err = txLockStock(tx, *m)
+	if err != nil {
+		return
+	}
+	if !m.LocationId.Valid {
+		loc, err := txDefaultLocation(tx, m.OrganizationId)
+		if err != nil {
+			return stock, item, err
+		}
+		m.LocationId = nuuid.From(loc.Id)
+	}
+	stock, err = txLockLevel(tx, *m)
+	return
+}
+
+// txLockStock locks the total stock of the movement's product or variant and
+// returns it along with a name for it in errors.
+func txLockStock(tx *sqlx.Tx, m Movement) (stock int, item string, err error) {
+	var row struct {
+		Stock int    `db:"stock"`
+		Name  string `db:"name"`
+	}
+	if m.VariantId.Valid {
+		err = tx.Get(&row, "SELECT stock, sku AS name FROM product_variant WHERE id = ? AND product_id = ? AND organization_id = ? FOR UPDATE",
+			m.VariantId.UUID.String(), m.ProductId.String(), m.OrganizationId.String())
+	} else {
+		err = tx.Get(&row, "SELECT stock, name FROM product WHERE id = ? AND organization_id = ? FOR UPDATE",
+			m.ProductId.String(), m.OrganizationId.String())
+	}
+	if err == sql.ErrNoRows {
+		if m.VariantId.Valid {
+			return stock, item, failure.NotFound("Variant")
+		}
+		return stock, item, failure.NotFound("Product")
+	}
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	if m.VariantId.Valid {
+		return row.Stock, "variant " + row.Name, nil
+	}
+	return row.Stock, `product "` + row.Name + `"`, nil
+}
+
+// txLockLevel locks the stock of the movement's item at its location and
+// returns it. Items get a level at a location the first time their stock
+// moves there.
+func txLockLevel(tx *sqlx.Tx, m Movement) (stock int, err error) {
+	var exists bool
+	err = tx.Get(&exists, "SELECT COUNT(id) FROM location WHERE id = ? AND organization_id = ? AND deleted_at IS NULL",
+		m.LocationId.UUID.String(), m.OrganizationId.String())
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	if !exists {
+		return stock, failure.NotFound("Location")
+	}
+	_, err = tx.Exec("INSERT IGNORE INTO location_stock (location_id, organization_id, product_id, variant_id, stock) VALUES (?, ?, ?, ?, 0)",
+		m.LocationId.UUID.String(), m.OrganizationId.String(), m.ProductId.String(), m.VariantId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	err = tx.Get(&stock, "SELECT stock FROM location_stock WHERE location_id = ? AND product_id = ? AND variant_id <=> ? FOR UPDATE",
+		m.LocationId.UUID.String(), m.ProductId.String(), m.VariantId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+	return
+}
+
+// txLevels returns the stock of the movement's item at every location that
+// has a level for it.
+func txLevels(tx *sqlx.Tx, m Movement) (res map[uuid.UUID]int, err error) {
+	var levels []struct {
+		LocationId uuid.UUID `db:"location_id"`
+		Stock      int       `db:"stock"`
+	}
+	err = tx.Select(&levels, "SELECT location_id, stock FROM location_stock WHERE product_id = ? AND variant_id <=> ? AND organization_id = ? FOR UPDATE",
+		m.ProductId.String(), m.VariantId, m.OrganizationId.String())
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	res = map[uuid.UUID]int{}
+	for _, l := range levels {
+		res[l.LocationId] = l.Stock
+	}
+	return
+}
+
+// txLocations returns the locations of the organization that are not
+// deleted. Organizations without any get their default location.
+func txLocations(tx *sqlx.Tx, orgId uuid.UUID) (res []Location, err error) {
+	err = tx.Select(&res, "SELECT "+locationColumns+" FROM location WHERE organization_id = ? AND deleted_at IS NULL", orgId.String())
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	if len(res) > 0 {
+		return
+	}
+	loc, err := txDefaultLocation(tx, orgId)
+	if err != nil {
+		return
+	}
+	return []Location{loc}, nil
+}
+
+// txDefaultLocation returns the default location of the organization,
+// creating one for organizations that have none yet.
+func txDefaultLocation(tx *sqlx.Tx, orgId uuid.UUID) (res Location, err error) {
+	query := "SELECT " + locationColumns + " FROM location WHERE organization_id = ? AND is_default = 1 AND deleted_at IS NULL LIMIT 1"
+	err = tx.Get(&res, query, orgId.String())
+	if err != sql.ErrNoRows {
+		if err != nil {
+			logger.ErrorWithStack(err)
+		}
+		return
+	}
+	id, err := uuid.NewV4()
+	if err != nil {
+		return
+	}
+	now := time.Now().UTC()
+	res = Location{
+		Id:             id,
+		OrganizationId: orgId,
+		Code:           defaultLocationCode,
+		Name:           "Main",
+		IsDefault:      true,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	// INSERT IGNORE, so transactions creating it at the same time both get
+	// the one that was created first
+	_, err = tx.NamedExec(`INSERT IGNORE INTO location (`+locationColumns+`)
+	VALUES (:id,:organization_id,:code,:name,:priority,:city,:province,:postal_code,:country_code,:is_default,:created_at,:updated_at,:deleted_at,:created_by,:updated_by,:deleted_by)`, res)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	err = tx.Get(&res, query, orgId.String())
+	if err == sql.ErrNoRows {
+		return res, failure.NotFound("Default location")
+	}
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+	return
+}
+
+func txRecord(tx *sqlx.Tx, m Movement) (res Movement, err error) {
+	if m.VariantId.Valid {
+		_, err = tx.Exec("UPDATE product_variant SET stock = stock + ? WHERE id = ?", m.Quantity, m.VariantId.UUID.String())
+	} else {
+		_, err = tx.Exec("UPDATE product SET stock = stock + ? WHERE id = ?", m.Quantity, m.ProductId.String())
+	}
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	_, err = tx.Exec("UPDATE location_stock SET stock = ? WHERE location_id = ? AND product_id = ? AND variant_id <=> ?",
+		m.StockAfter, m.LocationId.UUID.String(), m.ProductId.String(), m.VariantId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	query := `INSERT INTO stock_movement (` + movementColumns + `)
+	VALUES (:id,:organization_id,:product_id,:variant_id,:location_id,:quantity,:stock_after,:reason,:reference,:note,:created_at,:created_by)`
+	_, err = tx.NamedExec(query, m)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	err = txAlert(tx, m)
+	if err != nil {
+		return
+	}
+	return m, nil
+}
+
+// txAlert stores the event raised by the recorded movement when it takes
+// the total stock of its item across a threshold. Transfers leave the
+// total as it is and raise none.
+func txAlert(tx *sqlx.Tx, m Movement) (err error) {
+	if m.Reason == ReasonTransfer {
+		return
+	}
+	total, item, err := txLockStock(tx, m)
+	if err != nil {
+		return
+	}
+	t := Threshold{}
+	err = tx.Get(&t.ReorderLevel, "SELECT reorder_level FROM stock_threshold WHERE product_id = ?", m.ProductId.String())
+	if err != nil && err != sql.ErrNoRows {
+		logger.ErrorWithStack(err)
+		return
+	}
+	topic, crossed := t.Crossed(total-m.Quantity, total)
+	if !crossed {
+		return nil
+	}
+	id, err := uuid.NewV4()
+	if err != nil {
+		return
+	}
+	e := Event{
+		Id:             id,
+		OrganizationId: m.OrganizationId,
+		ProductId:      m.ProductId,
+		VariantId:      m.VariantId,
+		Item:           item,
+		Topic:          topic,
+		ReorderLevel:   t.ReorderLevel,
+		StockBefore:    total - m.Quantity,
+		StockAfter:     total,
+		MovementId:     m.Id,
+		CreatedAt:      m.CreatedAt,
+	}
+	_, err = tx.NamedExec(`INSERT INTO stock_event (`+eventColumns+`)
+	VALUES (:id,:organization_id,:product_id,:variant_id,:item,:topic,:reorder_level,:stock_before,:stock_after,:movement_id,:created_at,:published_at)`, e)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+	return
+}
+
+func lockKey(m Movement) string {
+	return fmt.Sprintf("%s/%s", m.ProductId, m.VariantId.UUID)
+}
@@ -0,0 +1,92 @@
+package inventory_test
+
+import (
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/evermos/boilerplate-go/infras"
+	"github.com/evermos/boilerplate-go/internal/domain/inventory"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/nuuid"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestConcurrentOrders places many orders for the same product at once and
+// checks that exactly as many units are sold as there were in stock. It runs
+// against a migrated database given by TEST_MYSQL_DSN, for example
+// "user:pass@tcp(localhost:3306)/boilerplate?parseTime=true".
+func TestConcurrentOrders(t *testing.T) {
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN is not set")
+	}
+	db := sqlx.MustConnect("mysql", dsn)
+	defer db.Close()
+	db.SetMaxOpenConns(20)
+	conn := &infras.MySQLConn{Read: db, Write: db}
+
+	const stock, buyers = 10, 50
+	orgId, productId, userId := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	db.MustExec("INSERT INTO organization (id, name, created_by, updated_by) VALUES (?, 'Stock test', ?, ?)", orgId.String(), userId.String(), userId.String())
+	db.MustExec("INSERT INTO product (id, organization_id, name, stock, price, slug, created_by, updated_by) VALUES (?, ?, 'Linen Shirt', 0, 10, ?, ?, ?)",
+		productId.String(), orgId.String(), productId.String(), userId.String(), userId.String())
+	defer func() {
+		db.MustExec("DELETE FROM stock_movement WHERE product_id = ?", productId.String())
+		db.MustExec("DELETE FROM location_stock WHERE product_id = ?", productId.String())
+		db.MustExec("DELETE FROM product WHERE id = ?", productId.String())
+		db.MustExec("DELETE FROM location WHERE organization_id = ?", orgId.String())
+		db.MustExec("DELETE FROM organization WHERE id = ?", orgId.String())
+	}()
+	restock, err := inventory.NewMovement(orgId, productId, nuuid.NUUID{}, stock, inventory.ReasonRestock, null.String{}, userId)
+	require.NoError(t, err)
+	require.NoError(t, conn.WithTransaction(func(tx *sqlx.Tx, c chan error) {
+		_, err := inventory.TxApply(tx, restock)
+		c <- err
+	}))
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	placed, shortages := 0, 0
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m, err := inventory.NewMovement(orgId, productId, nuuid.NUUID{}, -1, inventory.ReasonOrderPlaced, null.StringFrom(uuid.Must(uuid.NewV4()).String()), userId)
+			if !assert.NoError(t, err) {
+				return
+			}
+			err = conn.WithTransaction(func(tx *sqlx.Tx, c chan error) {
+				_, err := inventory.TxApplyAll(tx, []inventory.Movement{m})
+				c <- err
+			})
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				placed++
+			case failure.GetCode(err) == http.StatusConflict:
+				assert.Contains(t, err.Error(), `insufficient stock for product "Linen Shirt"`)
+				shortages++
+			default:
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, stock, placed)
+	assert.Equal(t, buyers-stock, shortages)
+	var left, recorded int
+	require.NoError(t, db.Get(&left, "SELECT stock FROM product WHERE id = ?", productId.String()))
+	require.NoError(t, db.Get(&recorded, "SELECT COUNT(id) FROM stock_movement WHERE product_id = ? AND reason = ?", productId.String(), inventory.ReasonOrderPlaced))
+	assert.Equal(t, 0, left)
+	require.NoError(t, db.Get(&left, "SELECT stock FROM location_stock WHERE product_id = ?", productId.String()))
+	assert.Equal(t, 0, left)
+	assert.Equal(t, stock, recorded)
+}
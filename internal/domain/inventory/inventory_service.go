@@ -0,0 +1,296 @@
+package inventory
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/nuuid"
+	"github.com/gofrs/uuid"
+)
+
+type InventoryService interface {
+	Adjust(load AdjustmentPayload, orgId, productId, userId uuid.UUID) (res Movement, err error)
+	GetHistory(orgId, productId uuid.UUID, filter HistoryFilter, limit, offset int) (res []Movement, err error)
+	Transfer(load TransferPayload, orgId, productId, userId uuid.UUID) (res []Movement, err error)
+	GetLevels(orgId, productId uuid.UUID) (res []Level, err error)
+	CreateLocation(load LocationPayload, orgId, userId uuid.UUID) (res Location, err error)
+	UpdateLocation(load LocationPayload, orgId, locationId, userId uuid.UUID) (res Location, err error)
+	DeleteLocation(orgId, locationId, userId uuid.UUID) (res Location, err error)
+	GetLocations(orgId uuid.UUID) (res []Location, err error)
+	GetLocation(orgId, locationId uuid.UUID) (res Location, err error)
+	GetThreshold(orgId, productId uuid.UUID) (res Threshold, err error)
+	SetThreshold(load ThresholdPayload, orgId, productId, userId uuid.UUID) (res Threshold, err error)
+	Subscribe(load SubscriptionPayload, orgId, productId, userId uuid.UUID) (res Subscription, err error)
+	Unsubscribe(orgId, productId uuid.UUID, variantId nuuid.NUUID, userId uuid.UUID) (err error)
+	GetPendingSubscriptions(e Event) (res []Subscription, err error)
+	MarkNotified(sub Subscription) (err error)
+	PublishEvents(pubsub shared.PubSub) (err error)
+}
+
+// eventBatch is the number of stock events published per run.
+const eventBatch = 100
+
+type InventoryServiceImpl struct {
+	Repo InventoryRepository
+}
+
+func ProvideInventoryServiceImpl(repo InventoryRepository) *InventoryServiceImpl {
+	return &InventoryServiceImpl{Repo: repo}
+}
+
+// Adjust records a movement entered by staff and changes the stock at its
+// location accordingly. Stock never goes below zero this way.
+func (s *InventoryServiceImpl) Adjust(load AdjustmentPayload, orgId, productId, userId uuid.UUID) (res Movement, err error) {
+	res, err = res.NewFromAdjustment(load, orgId, productId, userId)
+	if err != nil {
+		return
+	}
+	err = s.ensureItem(orgId, productId, load.VariantId)
+	if err != nil {
+		return
+	}
+	return s.Repo.Apply(res)
+}
+
+// Transfer moves stock from one location to another. The total stock of
+// the product stays the same.
+func (s *InventoryServiceImpl) Transfer(load TransferPayload, orgId, productId, userId uuid.UUID) (res []Movement, err error) {
+	if load.FromLocationId == load.ToLocationId {
+		err = failure.BadRequestFromString("stock has to be transferred to another location")
+		return
+	}
+	movements, err := NewTransfer(load, orgId, productId, userId)
+	if err != nil {
+		return
+	}
+	err = s.ensureItem(orgId, productId, load.VariantId)
+	if err != nil {
+		return
+	}
+	return s.Repo.ApplyAll(movements)
+}
+
+// GetLevels returns the stock of the product and its variants at each
+// location.
+func (s *InventoryServiceImpl) GetLevels(orgId, productId uuid.UUID) (res []Level, err error) {
+	err = s.ensureProduct(orgId, productId)
+	if err != nil {
+		return
+	}
+	return s.Repo.GetLevels(productId.String(), orgId.String())
+}
+
+// CreateLocation adds a location. The first location of an organization
+// becomes its default.
+func (s *InventoryServiceImpl) CreateLocation(load LocationPayload, orgId, userId uuid.UUID) (res Location, err error) {
+	hasDefault, err := s.Repo.HasDefaultLocation(orgId.String())
+	if err != nil {
+		return
+	}
+	if !hasDefault {
+		load.IsDefault = true
+	}
+	res, err = res.NewFromPayload(load, orgId, userId)
+	if err != nil {
+		return
+	}
+	err = s.ensureLocationCodeAvailable(res)
+	if err != nil {
+		return
+	}
+	err = s.Repo.CreateLocation(res)
+	return
+}
+
+// UpdateLocation replaces a location. The default location stays the
+// default until another location is made the default.
+func (s *InventoryServiceImpl) UpdateLocation(load LocationPayload, orgId, locationId, userId uuid.UUID) (res Location, err error) {
+	res, err = s.Repo.GetLocationByID(locationId.String(), orgId.String())
+	if err != nil {
+		return
+	}
+	if res.IsDefault && !load.IsDefault {
+		err = failure.BadRequestFromString("make another location the default instead")
+		return
+	}
+	err = res.Update(load, userId)
+	if err != nil {
+		return
+	}
+	err = s.ensureLocationCodeAvailable(res)
+	if err != nil {
+		return
+	}
+	err = s.Repo.UpdateLocation(res)
+	return
+}
+
+// DeleteLocation deletes a location without stock. Its movements stay in
+// the ledger. The default location cannot be deleted.
+func (s *InventoryServiceImpl) DeleteLocation(orgId, locationId, userId uuid.UUID) (res Location, err error) {
+	res, err = s.Repo.GetLocationByID(locationId.String(), orgId.String())
+	if err != nil {
+		return
+	}
+	if res.IsDefault {
+		err = failure.Conflict("delete", "location", "the default location cannot be deleted")
+		return
+	}
+	hasStock, err := s.Repo.LocationHasStock(res.Id.String())
+	if err != nil {
+		return
+	}
+	if hasStock {
+		err = failure.Conflict("delete", "location", "stock is left, transfer it first")
+		return
+	}
+	err = res.SoftDelete(userId)
+	if err != nil {
+		return
+	}
+	err = s.Repo.UpdateLocation(res)
+	return
+}
+
+func (s *InventoryServiceImpl) GetLocations(orgId uuid.UUID) (res []Location, err error) {
+	return s.Repo.GetLocations(orgId.String())
+}
+
+func (s *InventoryServiceImpl) GetLocation(orgId, locationId uuid.UUID) (res Location, err error) {
+	return s.Repo.GetLocationByID(locationId.String(), orgId.String())
+}
+
+// GetThreshold returns the reorder level of the product. Products without
+// one have none set.
+func (s *InventoryServiceImpl) GetThreshold(orgId, productId uuid.UUID) (res Threshold, err error) {
+	err = s.ensureProduct(orgId, productId)
+	if err != nil {
+		return
+	}
+	return s.Repo.GetThreshold(productId.String(), orgId.String())
+}
+
+// SetThreshold sets the reorder level of the product. It applies to the
+// stock moving from then on.
+func (s *InventoryServiceImpl) SetThreshold(load ThresholdPayload, orgId, productId, userId uuid.UUID) (res Threshold, err error) {
+	err = s.ensureProduct(orgId, productId)
+	if err != nil {
+		return
+	}
+	res, err = res.NewFromPayload(load, orgId, productId, userId)
+	if err != nil {
+		return
+	}
+	err = s.Repo.SetThreshold(res)
+	return
+}
+
+// Subscribe asks for a notification of the user when the product, or the
+// variant, is back in stock.
+func (s *InventoryServiceImpl) Subscribe(load SubscriptionPayload, orgId, productId, userId uuid.UUID) (res Subscription, err error) {
+	res, err = res.NewFromPayload(load, orgId, productId, userId)
+	if err != nil {
+		return
+	}
+	err = s.ensureItem(orgId, productId, load.VariantId)
+	if err != nil {
+		return
+	}
+	return s.Repo.SaveSubscription(res)
+}
+
+func (s *InventoryServiceImpl) Unsubscribe(orgId, productId uuid.UUID, variantId nuuid.NUUID, userId uuid.UUID) (err error) {
+	err = s.ensureProduct(orgId, productId)
+	if err != nil {
+		return
+	}
+	return s.Repo.DeleteSubscription(productId.String(), userId.String(), variantId)
+}
+
+// GetPendingSubscriptions returns the subscriptions to be notified about
+// the item of the event.
+func (s *InventoryServiceImpl) GetPendingSubscriptions(e Event) (res []Subscription, err error) {
+	return s.Repo.GetPendingSubscriptions(e.ProductId.String(), e.VariantId)
+}
+
+// MarkNotified fulfils the subscription.
+func (s *InventoryServiceImpl) MarkNotified(sub Subscription) (err error) {
+	return s.Repo.MarkNotified(sub.Id.String(), time.Now().UTC())
+}
+
+// PublishEvents publishes the stock events stored since the last run on
+// their topics, oldest first. An event is published once; subscribers that
+// fail retry on their own.
+func (s *InventoryServiceImpl) PublishEvents(pubsub shared.PubSub) (err error) {
+	events, err := s.Repo.GetUnpublishedEvents(eventBatch)
+	if err != nil {
+		return
+	}
+	for _, e := range events {
+		payload, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		pubsub.Publish(e.Topic, payload)
+		err = s.Repo.MarkPublished(e.Id.String(), time.Now().UTC())
+		if err != nil {
+			return err
+		}
+	}
+	return
+}
+
+func (s *InventoryServiceImpl) ensureLocationCodeAvailable(loc Location) (err error) {
+	exists, err := s.Repo.LocationCodeExists(loc.Code, loc.OrganizationId.String(), loc.Id.String())
+	if err != nil {
+		return
+	}
+	if exists {
+		err = failure.Conflict("save", "location", "code is already in use")
+	}
+	return
+}
+
+// ensureItem checks that the product exists and that a variant is given
+// exactly when its stock is kept per variant.
+func (s *InventoryServiceImpl) ensureItem(orgId, productId uuid.UUID, variantId nuuid.NUUID) (err error) {
+	err = s.ensureProduct(orgId, productId)
+	if err != nil {
+		return
+	}
+	hasVariants, err := s.Repo.HasVariants(productId.String())
+	if err != nil {
+		return
+	}
+	if hasVariants && !variantId.Valid {
+		err = failure.BadRequestFromString("variantId is required, the product's stock is kept per variant")
+	}
+	return
+}
+
+// GetHistory lists the stock movements of a product and its variants,
+// newest first.
+func (s *InventoryServiceImpl) GetHistory(orgId, productId uuid.UUID, filter HistoryFilter, limit, offset int) (res []Movement, err error) {
+	err = ValidateReason(filter.Reason)
+	if err != nil {
+		return
+	}
+	err = s.ensureProduct(orgId, productId)
+	if err != nil {
+		return
+	}
+	return s.Repo.GetByProductID(productId.String(), orgId.String(), filter, limit, offset)
+}
+
+func (s *InventoryServiceImpl) ensureProduct(orgId, productId uuid.UUID) (err error) {
+	exists, err := s.Repo.ProductExists(productId.String(), orgId.String())
+	if err != nil {
+		return
+	}
+	if !exists {
+		err = failure.NotFound("Product")
+	}
+	return
+}
@@ -0,0 +1,166 @@
+package inventory
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/nuuid"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
+)
+
+// Location is a place stock is kept, such as a warehouse or a store. Every
+// organization has one default location, which takes the stock changes
+// that do not name a location.
+type Location struct {
+	Id             uuid.UUID `db:"id" validate:"required"`
+	OrganizationId uuid.UUID `db:"organization_id" validate:"required"`
+	Code           string    `db:"code" validate:"required,max=50,slug"`
+	Name           string    `db:"name" validate:"required,max=255"`
+	// Priority orders locations when orders are allocated, lowest first.
+	Priority int `db:"priority" validate:"min=0"`
+	// The address is optional; it is what the nearest strategy compares
+	// with the shipping address.
+	City        null.String `db:"city" validate:"omitempty,max=100"`
+	Province    null.String `db:"province" validate:"omitempty,max=100"`
+	PostalCode  null.String `db:"postal_code" validate:"omitempty,max=20"`
+	CountryCode null.String `db:"country_code" validate:"omitempty,iso3166_1_alpha2"`
+	IsDefault   bool        `db:"is_default"`
+	CreatedAt   time.Time   `db:"created_at" validate:"required"`
+	UpdatedAt   time.Time   `db:"updated_at" validate:"required"`
+	DeletedAt   null.Time   `db:"deleted_at"`
+	CreatedBy   uuid.UUID   `db:"created_by" validate:"required"`
+	UpdatedBy   uuid.UUID   `db:"updated_by" validate:"required"`
+	DeletedBy   nuuid.NUUID `db:"deleted_by"`
+}
+
+type LocationResponseFormat struct {
+	Id             uuid.UUID   `json:"id"`
+	OrganizationId uuid.UUID   `json:"organizationId"`
+	Code           string      `json:"code"`
+	Name           string      `json:"name"`
+	Priority       int         `json:"priority"`
+	City           null.String `json:"city"`
+	Province       null.String `json:"province"`
+	PostalCode     null.String `json:"postalCode"`
+	CountryCode    null.String `json:"countryCode"`
+	IsDefault      bool        `json:"isDefault"`
+	CreatedAt      time.Time   `json:"createdAt"`
+	UpdatedAt      time.Time   `json:"updatedAt"`
+	DeletedAt      null.Time   `json:"deletedAt,omitempty"`
+	CreatedBy      uuid.UUID   `json:"createdBy"`
+	UpdatedBy      uuid.UUID   `json:"updatedBy"`
+	DeletedBy      nuuid.NUUID `json:"deletedBy,omitempty"`
+}
+
+// LocationPayload creates or replaces a location. Making a location the
+// default takes that role from the previous default.
+type LocationPayload struct {
+	Code        string `json:"code" validate:"required,max=50,slug"`
+	Name        string `json:"name" validate:"required,max=255"`
+	Priority    int    `json:"priority" validate:"min=0"`
+	City        string `json:"city" validate:"max=100"`
+	Province    string `json:"province" validate:"max=100"`
+	PostalCode  string `json:"postalCode" validate:"max=20"`
+	CountryCode string `json:"countryCode" validate:"omitempty,iso3166_1_alpha2"`
+	IsDefault   bool   `json:"isDefault"`
+}
+
+// Level is the stock of a product, or of one of its variants, at a
+// location.
+type Level struct {
+	LocationId   uuid.UUID   `db:"location_id"`
+	LocationCode string      `db:"location_code"`
+	LocationName string      `db:"location_name"`
+	ProductId    uuid.UUID   `db:"product_id"`
+	VariantId    nuuid.NUUID `db:"variant_id"`
+	Stock        int         `db:"stock"`
+}
+
+type LevelResponseFormat struct {
+	LocationId   uuid.UUID   `json:"locationId"`
+	LocationCode string      `json:"locationCode"`
+	LocationName string      `json:"locationName"`
+	ProductId    uuid.UUID   `json:"productId"`
+	VariantId    nuuid.NUUID `json:"variantId"`
+	Stock        int         `json:"stock"`
+}
+
+// defaultLocationCode is the code of the location created for
+// organizations that have none when their stock first changes.
+const defaultLocationCode = "main"
+
+func (l Location) NewFromPayload(load LocationPayload, orgId, userId uuid.UUID) (res Location, err error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return
+	}
+	now := time.Now().UTC()
+	res = Location{
+		Id:             id,
+		OrganizationId: orgId,
+		CreatedAt:      now,
+		CreatedBy:      userId,
+	}
+	err = res.Update(load, userId)
+	return
+}
+
+func (l *Location) Update(load LocationPayload, userId uuid.UUID) (err error) {
+	l.Code = load.Code
+	l.Name = load.Name
+	l.Priority = load.Priority
+	l.City = optional(load.City)
+	l.Province = optional(load.Province)
+	l.PostalCode = optional(load.PostalCode)
+	l.CountryCode = optional(strings.ToUpper(load.CountryCode))
+	l.IsDefault = load.IsDefault
+	l.UpdatedAt = time.Now().UTC()
+	l.UpdatedBy = userId
+	err = l.Validate()
+	return
+}
+
+func (l *Location) IsDeleted() bool {
+	return l.DeletedAt.Valid && l.DeletedBy.Valid
+}
+
+func (l *Location) SoftDelete(userId uuid.UUID) (err error) {
+	if l.IsDeleted() {
+		err = failure.Conflict("delete", "location", "already deleted")
+		return
+	}
+	l.DeletedAt = null.TimeFrom(time.Now().UTC())
+	l.DeletedBy = nuuid.From(userId)
+	err = l.Validate()
+	return
+}
+
+func (l *Location) Validate() error {
+	validator := shared.GetValidator()
+	return validator.Struct(l)
+}
+
+func (l Location) ToResponseFormat() LocationResponseFormat {
+	return LocationResponseFormat(l)
+}
+
+func (l Location) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.ToResponseFormat())
+}
+
+func (l Level) ToResponseFormat() LevelResponseFormat {
+	return LevelResponseFormat(l)
+}
+
+func (l Level) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.ToResponseFormat())
+}
+
+func optional(s string) null.String {
+	s = strings.TrimSpace(s)
+	return null.NewString(s, s != "")
+}
@@ -0,0 +1,265 @@
+package media
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"time"
+
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/imaging"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
+)
+
+// MaxImagesPerProduct bounds the gallery of a single product.
+const MaxImagesPerProduct = 20
+
+// Size is a resized rendition generated for every uploaded image.
+type Size struct {
+	Name   string
+	Width  int
+	Height int
+	// Crop cuts out the center square before scaling. Other sizes are scaled
+	// down to fit, keeping the aspect ratio.
+	Crop bool
+}
+
+// Sizes are the renditions generated for every uploaded image.
+var Sizes = []Size{
+	{Name: "thumbnail", Width: 200, Height: 200, Crop: true},
+	{Name: "medium", Width: 800, Height: 800},
+	{Name: "large", Width: 1600, Height: 1600},
+}
+
+// Render scales img to the size.
+func (s Size) Render(img image.Image) image.Image {
+	if s.Crop {
+		return imaging.Thumbnail(img, s.Width)
+	}
+	return imaging.Fit(img, s.Width, s.Height)
+}
+
+// Image is an image in the gallery of a product. The first image by position
+// is shown first; the primary image represents the product in listings.
+type Image struct {
+	Id             uuid.UUID   `db:"id" validate:"required"`
+	OrganizationId uuid.UUID   `db:"organization_id" validate:"required"`
+	ProductId      uuid.UUID   `db:"product_id" validate:"required"`
+	Position       int         `db:"position" validate:"min=0"`
+	Primary        bool        `db:"is_primary"`
+	AltText        null.String `db:"alt_text" validate:"omitempty,max=255"`
+	Key            string      `db:"storage_key" validate:"required"`
+	Url            string      `db:"url" validate:"required"`
+	ContentType    string      `db:"content_type" validate:"required"`
+	Width          int         `db:"width" validate:"min=1"`
+	Height         int         `db:"height" validate:"min=1"`
+	Renditions     []Rendition `db:"-"`
+	CreatedAt      time.Time   `db:"created_at" validate:"required"`
+	UpdatedAt      time.Time   `db:"updated_at" validate:"required"`
+	CreatedBy      uuid.UUID   `db:"created_by" validate:"required"`
+	UpdatedBy      uuid.UUID   `db:"updated_by" validate:"required"`
+}
+
+// Rendition is a resized copy of an image, see Sizes.
+type Rendition struct {
+	ImageId     uuid.UUID `db:"image_id"`
+	Size        string    `db:"size"`
+	Key         string    `db:"storage_key"`
+	Url         string    `db:"url"`
+	ContentType string    `db:"content_type"`
+	Width       int       `db:"width"`
+	Height      int       `db:"height"`
+}
+
+type ImageResponseFormat struct {
+	Id        uuid.UUID                          `json:"id"`
+	ProductId uuid.UUID                          `json:"productId"`
+	Position  int                                `json:"position"`
+	Primary   bool                               `json:"primary"`
+	AltText   null.String                        `json:"altText"`
+	Url       string                             `json:"url"`
+	Width     int                                `json:"width"`
+	Height    int                                `json:"height"`
+	Sizes     map[string]RenditionResponseFormat `json:"sizes"`
+	CreatedAt time.Time                          `json:"createdAt"`
+	UpdatedAt time.Time                          `json:"updatedAt"`
+	CreatedBy uuid.UUID                          `json:"createdBy"`
+	UpdatedBy uuid.UUID                          `json:"updatedBy"`
+}
+
+type RenditionResponseFormat struct {
+	Url    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// ImagePatchPayload changes the alt text of an image or makes it the primary
+// image. Only the fields present in the request are changed.
+type ImagePatchPayload struct {
+	// AltText is cleared by an empty string.
+	AltText *string `json:"altText" validate:"omitempty,max=255"`
+	// Primary can only be set; the previous primary image loses it.
+	Primary *bool `json:"primary"`
+}
+
+// OrderPayload lists all images of a product in their new order.
+type OrderPayload struct {
+	ImageIds []uuid.UUID `json:"imageIds" validate:"required,min=1,max=20,unique"`
+}
+
+func (i Image) NewFromUpload(img imaging.Image, altText string, orgId, productId, creatorId uuid.UUID) (res Image, err error) {
+	imageId, err := uuid.NewV4()
+	if err != nil {
+		return
+	}
+	bounds := img.Image.Bounds()
+	now := time.Now().UTC()
+	res = Image{
+		Id:             imageId,
+		OrganizationId: orgId,
+		ProductId:      productId,
+		AltText:        null.NewString(altText, altText != ""),
+		ContentType:    img.ContentType,
+		Width:          bounds.Dx(),
+		Height:         bounds.Dy(),
+		CreatedAt:      now,
+		CreatedBy:      creatorId,
+		UpdatedAt:      now,
+		UpdatedBy:      creatorId,
+	}
+	res.Key = res.keyOf("original", img.Extension())
+	return
+}
+
+// keyOf returns the storage key of the original or a rendition of the image.
+func (i *Image) keyOf(name, extension string) string {
+	return fmt.Sprintf("products/%s/images/%s/%s.%s", i.ProductId, i.Id, name, extension)
+}
+
+// Keys returns the storage keys of the image and its renditions.
+func (i *Image) Keys() (res []string) {
+	res = append(res, i.Key)
+	for _, rendition := range i.Renditions {
+		res = append(res, rendition.Key)
+	}
+	return
+}
+
+func (i *Image) Patch(load ImagePatchPayload, updaterId uuid.UUID) (err error) {
+	if load.Primary != nil && !*load.Primary {
+		err = failure.BadRequestFromString("make another image primary instead")
+		return
+	}
+	if load.AltText != nil {
+		i.AltText = null.NewString(*load.AltText, *load.AltText != "")
+	}
+	i.touch(updaterId)
+	err = i.Validate()
+	return
+}
+
+func (i *Image) touch(updaterId uuid.UUID) {
+	i.UpdatedAt = time.Now().UTC()
+	i.UpdatedBy = updaterId
+}
+
+func (i *Image) Validate() error {
+	validator := shared.GetValidator()
+	return validator.Struct(i)
+}
+
+func (i Image) ToResponseFormat() ImageResponseFormat {
+	sizes := map[string]RenditionResponseFormat{}
+	for _, rendition := range i.Renditions {
+		sizes[rendition.Size] = RenditionResponseFormat{Url: rendition.Url, Width: rendition.Width, Height: rendition.Height}
+	}
+	return ImageResponseFormat{
+		Id:        i.Id,
+		ProductId: i.ProductId,
+		Position:  i.Position,
+		Primary:   i.Primary,
+		AltText:   i.AltText,
+		Url:       i.Url,
+		Width:     i.Width,
+		Height:    i.Height,
+		Sizes:     sizes,
+		CreatedAt: i.CreatedAt,
+		UpdatedAt: i.UpdatedAt,
+		CreatedBy: i.CreatedBy,
+		UpdatedBy: i.UpdatedBy,
+	}
+}
+
+func (i Image) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.ToResponseFormat())
+}
+
+// MakePrimary makes the image with the id the only primary image of the
+// gallery. It returns the images that changed.
+func MakePrimary(images []Image, id, updaterId uuid.UUID) (changed []Image) {
+	for idx := range images {
+		primary := images[idx].Id == id
+		if images[idx].Primary != primary {
+			images[idx].Primary = primary
+			images[idx].touch(updaterId)
+			changed = append(changed, images[idx])
+		}
+	}
+	return
+}
+
+// Reorder puts the gallery in the order of ids, which has to list every
+// image exactly once. It returns the images in their new order.
+func Reorder(images []Image, ids []uuid.UUID, updaterId uuid.UUID) (res []Image, err error) {
+	if len(ids) != len(images) {
+		err = failure.BadRequestFromString("imageIds has to list every image of the product")
+		return
+	}
+	byId := map[uuid.UUID]Image{}
+	for _, img := range images {
+		byId[img.Id] = img
+	}
+	for position, id := range ids {
+		img, ok := byId[id]
+		if !ok {
+			err = failure.BadRequestFromString(fmt.Sprintf("image %s is not an image of the product", id))
+			return
+		}
+		delete(byId, id)
+		if img.Position != position {
+			img.Position = position
+			img.touch(updaterId)
+		}
+		res = append(res, img)
+	}
+	return
+}
+
+// Compact numbers the gallery from zero after an image has been removed and
+// promotes the first image when the primary one is gone. It returns the
+// images that changed.
+func Compact(images []Image, updaterId uuid.UUID) (changed []Image) {
+	hasPrimary := false
+	for _, img := range images {
+		hasPrimary = hasPrimary || img.Primary
+	}
+	for idx := range images {
+		img := &images[idx]
+		dirty := false
+		if img.Position != idx {
+			img.Position = idx
+			dirty = true
+		}
+		if !hasPrimary && idx == 0 {
+			img.Primary = true
+			dirty = true
+		}
+		if dirty {
+			img.touch(updaterId)
+			changed = append(changed, *img)
+		}
+	}
+	return
+}
@@ -0,0 +1,64 @@
+package media_test
+
+import (
+	"image"
+	"testing"
+
+	"github.com/evermos/boilerplate-go/internal/domain/media"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGallery(t *testing.T) {
+	userId := uuid.Must(uuid.NewV4())
+	gallery := func() []media.Image {
+		images := make([]media.Image, 3)
+		for i := range images {
+			images[i] = media.Image{Id: uuid.Must(uuid.NewV4()), Position: i, Primary: i == 0}
+		}
+		return images
+	}
+
+	t.Run("MakePrimary", func(t *testing.T) {
+		images := gallery()
+		changed := media.MakePrimary(images, images[2].Id, userId)
+		assert.Len(t, changed, 2)
+		assert.False(t, images[0].Primary)
+		assert.True(t, images[2].Primary)
+	})
+
+	t.Run("Reorder", func(t *testing.T) {
+		images := gallery()
+		res, err := media.Reorder(images, []uuid.UUID{images[2].Id, images[0].Id, images[1].Id}, userId)
+		assert.NoError(t, err)
+		assert.Equal(t, images[2].Id, res[0].Id)
+		assert.Equal(t, 0, res[0].Position)
+		assert.Equal(t, 2, res[2].Position)
+
+		_, err = media.Reorder(images, []uuid.UUID{images[0].Id, images[1].Id}, userId)
+		assert.Error(t, err)
+		_, err = media.Reorder(images, []uuid.UUID{images[0].Id, images[1].Id, uuid.Must(uuid.NewV4())}, userId)
+		assert.Error(t, err)
+	})
+
+	t.Run("Compact", func(t *testing.T) {
+		images := gallery()[1:]
+		changed := media.Compact(images, userId)
+		assert.Len(t, changed, 2)
+		assert.True(t, images[0].Primary)
+		assert.Equal(t, []int{0, 1}, []int{images[0].Position, images[1].Position})
+		assert.Empty(t, media.Compact(images, userId))
+	})
+}
+
+func TestSizes(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2000, 1000))
+	for _, size := range media.Sizes {
+		b := size.Render(img).Bounds()
+		assert.LessOrEqual(t, b.Dx(), size.Width, size.Name)
+		assert.LessOrEqual(t, b.Dy(), size.Height, size.Name)
+		if size.Crop {
+			assert.Equal(t, b.Dx(), b.Dy(), size.Name)
+		}
+	}
+}
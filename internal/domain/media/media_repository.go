@@ -0,0 +1,141 @@
+package media
+
+import (
+	"database/sql"
+
+	"github.com/evermos/boilerplate-go/infras"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+type MediaRepository interface {
+	Create(img Image) (err error)
+	GetByID(id, productId string) (res Image, err error)
+	GetByProductID(productId string) (res []Image, err error)
+	Save(images []Image) (err error)
+	Delete(id string) (err error)
+}
+
+type MediaRepositoryMySQL struct {
+	DB *infras.MySQLConn
+}
+
+func ProvideMediaRepositoryMySQL(db *infras.MySQLConn) *MediaRepositoryMySQL {
+	return &MediaRepositoryMySQL{DB: db}
+}
+
+func (r *MediaRepositoryMySQL) Create(img Image) (err error) {
+	return r.DB.WithTransaction(func(db *sqlx.Tx, c chan error) {
+		query := `INSERT INTO product_image (id,organization_id,product_id,position,is_primary,alt_text,storage_key,url,content_type,width,height,created_at,updated_at,created_by,updated_by)
+		VALUES (:id,:organization_id,:product_id,:position,:is_primary,:alt_text,:storage_key,:url,:content_type,:width,:height,:created_at,:updated_at,:created_by,:updated_by)`
+		if _, err := db.NamedExec(query, img); err != nil {
+			logger.ErrorWithStack(err)
+			c <- err
+			return
+		}
+		for _, rendition := range img.Renditions {
+			query := `INSERT INTO product_image_rendition (image_id,size,storage_key,url,content_type,width,height)
+			VALUES (:image_id,:size,:storage_key,:url,:content_type,:width,:height)`
+			if _, err := db.NamedExec(query, rendition); err != nil {
+				logger.ErrorWithStack(err)
+				c <- err
+				return
+			}
+		}
+		c <- nil
+	})
+}
+
+func (r *MediaRepositoryMySQL) GetByID(id, productId string) (res Image, err error) {
+	err = r.DB.Read.Get(&res, "SELECT * FROM product_image WHERE id = ? AND product_id = ?", id, productId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = failure.NotFound("Image")
+			return
+		}
+		logger.ErrorWithStack(err)
+		return
+	}
+	images := []Image{res}
+	err = r.attachRenditions(images)
+	res = images[0]
+	return
+}
+
+// GetByProductID returns the gallery of the product in display order.
+func (r *MediaRepositoryMySQL) GetByProductID(productId string) (res []Image, err error) {
+	res = []Image{}
+	err = r.DB.Read.Select(&res, "SELECT * FROM product_image WHERE product_id = ? ORDER BY position", productId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	err = r.attachRenditions(res)
+	return
+}
+
+// Save writes the position, primary flag and alt text of the images.
+func (r *MediaRepositoryMySQL) Save(images []Image) (err error) {
+	if len(images) == 0 {
+		return
+	}
+	return r.DB.WithTransaction(func(db *sqlx.Tx, c chan error) {
+		for _, img := range images {
+			query := `
+			UPDATE product_image
+			SET
+				position = :position,
+				is_primary = :is_primary,
+				alt_text = :alt_text,
+				updated_at = :updated_at,
+				updated_by = :updated_by
+			WHERE id = :id`
+			if _, err := db.NamedExec(query, img); err != nil {
+				logger.ErrorWithStack(err)
+				c <- err
+				return
+			}
+		}
+		c <- nil
+	})
+}
+
+// Delete removes the image. Its renditions go with it.
+func (r *MediaRepositoryMySQL) Delete(id string) (err error) {
+	_, err = r.DB.Write.Exec("DELETE FROM product_image WHERE id = ?", id)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+func (r *MediaRepositoryMySQL) attachRenditions(images []Image) (err error) {
+	if len(images) == 0 {
+		return
+	}
+	ids := make([]string, 0, len(images))
+	for _, img := range images {
+		ids = append(ids, img.Id.String())
+	}
+	query, args, err := sqlx.In("SELECT * FROM product_image_rendition WHERE image_id IN (?) ORDER BY width", ids)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	var renditions []Rendition
+	err = r.DB.Read.Select(&renditions, r.DB.Read.Rebind(query), args...)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	for idx := range images {
+		for _, rendition := range renditions {
+			if rendition.ImageId == images[idx].Id {
+				images[idx].Renditions = append(images[idx].Renditions, rendition)
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,194 @@
+package media
+
+import (
+	"fmt"
+
+	"github.com/evermos/boilerplate-go/internal/domain/product"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/imaging"
+	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/evermos/boilerplate-go/shared/storage"
+	"github.com/gofrs/uuid"
+)
+
+type MediaService interface {
+	GetByProductID(orgId, productId uuid.UUID) (res []Image, err error)
+	Upload(data []byte, altText string, orgId, productId, userId uuid.UUID) (res Image, err error)
+	Update(load ImagePatchPayload, orgId, productId, imageId, userId uuid.UUID) (res Image, err error)
+	Reorder(load OrderPayload, orgId, productId, userId uuid.UUID) (res []Image, err error)
+	Delete(orgId, productId, imageId, userId uuid.UUID) (res Image, err error)
+}
+
+type MediaServiceImpl struct {
+	Repo           MediaRepository
+	ProductService product.ProductService
+	Storage        storage.Storage
+}
+
+func ProvideMediaServiceImpl(repo MediaRepository, productService product.ProductService, store storage.Storage) *MediaServiceImpl {
+	return &MediaServiceImpl{Repo: repo, ProductService: productService, Storage: store}
+}
+
+func (s *MediaServiceImpl) GetByProductID(orgId, productId uuid.UUID) (res []Image, err error) {
+	_, err = s.ProductService.GetByID(productId, orgId)
+	if err != nil {
+		return
+	}
+	return s.Repo.GetByProductID(productId.String())
+}
+
+// Upload validates the uploaded image, stores it together with its resized
+// renditions and appends it to the product's gallery. The first image of a
+// product becomes its primary image.
+func (s *MediaServiceImpl) Upload(data []byte, altText string, orgId, productId, userId uuid.UUID) (res Image, err error) {
+	_, err = s.ProductService.GetByID(productId, orgId)
+	if err != nil {
+		return
+	}
+	gallery, err := s.Repo.GetByProductID(productId.String())
+	if err != nil {
+		return
+	}
+	if len(gallery) >= MaxImagesPerProduct {
+		err = failure.Conflict("upload", "image", fmt.Sprintf("a product can have at most %d images", MaxImagesPerProduct))
+		return
+	}
+	img, err := imaging.Decode(data)
+	if err != nil {
+		return
+	}
+	res, err = res.NewFromUpload(img, altText, orgId, productId, userId)
+	if err != nil {
+		return
+	}
+	res.Position = len(gallery)
+	res.Primary = len(gallery) == 0
+	res.Url = s.Storage.URL(res.Key)
+	err = res.Validate()
+	if err != nil {
+		err = failure.BadRequest(err)
+		return
+	}
+
+	err = s.Storage.Put(res.Key, img.ContentType, img.Data)
+	if err != nil {
+		return
+	}
+	for _, size := range Sizes {
+		rendered := size.Render(img.Image)
+		encoded, contentType, err := imaging.Encode(rendered, img.ContentType)
+		if err != nil {
+			s.deleteObjects(res.Keys()...)
+			return res, err
+		}
+		rendition := Rendition{
+			ImageId:     res.Id,
+			Size:        size.Name,
+			Key:         res.keyOf(size.Name, imaging.ExtensionOf(contentType)),
+			ContentType: contentType,
+			Width:       rendered.Bounds().Dx(),
+			Height:      rendered.Bounds().Dy(),
+		}
+		rendition.Url = s.Storage.URL(rendition.Key)
+		err = s.Storage.Put(rendition.Key, contentType, encoded)
+		if err != nil {
+			s.deleteObjects(res.Keys()...)
+			return res, err
+		}
+		res.Renditions = append(res.Renditions, rendition)
+	}
+
+	err = s.Repo.Create(res)
+	if err != nil {
+		s.deleteObjects(res.Keys()...)
+		return
+	}
+	return
+}
+
+// Update changes the alt text of the image or makes it the product's primary
+// image.
+func (s *MediaServiceImpl) Update(load ImagePatchPayload, orgId, productId, imageId, userId uuid.UUID) (res Image, err error) {
+	_, err = s.ProductService.GetByID(productId, orgId)
+	if err != nil {
+		return
+	}
+	res, err = s.Repo.GetByID(imageId.String(), productId.String())
+	if err != nil {
+		return
+	}
+	err = res.Patch(load, userId)
+	if err != nil {
+		return
+	}
+	changed := []Image{res}
+	if load.Primary != nil && !res.Primary {
+		gallery, err := s.Repo.GetByProductID(productId.String())
+		if err != nil {
+			return res, err
+		}
+		changed = MakePrimary(gallery, res.Id, userId)
+		for idx := range changed {
+			if changed[idx].Id == res.Id {
+				changed[idx].AltText = res.AltText
+				res = changed[idx]
+			}
+		}
+	}
+	err = s.Repo.Save(changed)
+	return
+}
+
+// Reorder puts the product's gallery in the given order.
+func (s *MediaServiceImpl) Reorder(load OrderPayload, orgId, productId, userId uuid.UUID) (res []Image, err error) {
+	_, err = s.ProductService.GetByID(productId, orgId)
+	if err != nil {
+		return
+	}
+	gallery, err := s.Repo.GetByProductID(productId.String())
+	if err != nil {
+		return
+	}
+	res, err = Reorder(gallery, load.ImageIds, userId)
+	if err != nil {
+		return
+	}
+	err = s.Repo.Save(res)
+	return
+}
+
+// Delete removes the image and its stored files. The rest of the gallery
+// closes the gap, and the first image takes over when the primary image is
+// deleted.
+func (s *MediaServiceImpl) Delete(orgId, productId, imageId, userId uuid.UUID) (res Image, err error) {
+	_, err = s.ProductService.GetByID(productId, orgId)
+	if err != nil {
+		return
+	}
+	res, err = s.Repo.GetByID(imageId.String(), productId.String())
+	if err != nil {
+		return
+	}
+	err = s.Repo.Delete(res.Id.String())
+	if err != nil {
+		return
+	}
+	s.deleteObjects(res.Keys()...)
+
+	gallery, err := s.Repo.GetByProductID(productId.String())
+	if err != nil {
+		return
+	}
+	err = s.Repo.Save(Compact(gallery, userId))
+	return
+}
+
+// deleteObjects removes stored files that are no longer referenced. Failures
+// only leave an orphaned file behind, so they are logged and not returned.
+func (s *MediaServiceImpl) deleteObjects(keys ...string) {
+	for _, key := range keys {
+		if err := s.Storage.Delete(key); err != nil {
+			logger.ErrorWithStack(err)
+		}
+	}
+}
@@ -11,40 +11,76 @@ import (
 	"github.com/guregu/null"
 )
 
+// Statuses of order items. Items of products ordered beyond stock wait for
+// it as backorders or pre-orders and are allocated once it arrives.
+const (
+	ItemAllocated   = "allocated"
+	ItemBackordered = "backordered"
+	ItemPreordered  = "preordered"
+)
+
 type Order struct {
-	Id         uuid.UUID   `db:"id" validate:"required"`
-	UserId     uuid.UUID   `db:"user_id" validate:"required"`
-	TotalPrice float64     `db:"total_price" validate:"required"`
-	Status     string      `db:"status" validate:"required"`
-	OrderItems []OrderItem `db:"-"`
-	CreatedAt  time.Time   `db:"created_at" validate:"required"`
-	UpdatedAt  time.Time   `db:"updated_at" validate:"required"`
-	DeletedAt  null.Time   `db:"deleted_at"`
-	CreatedBy  uuid.UUID   `db:"created_by" validate:"required"`
-	UpdatedBy  uuid.UUID   `db:"updated_by" validate:"required"`
-	DeletedBy  nuuid.NUUID `db:"deleted_by"`
+	Id                uuid.UUID   `db:"id" validate:"required"`
+	OrganizationId    uuid.UUID   `db:"organization_id" validate:"required"`
+	UserId            uuid.UUID   `db:"user_id" validate:"required"`
+	TotalPrice        float64     `db:"total_price" validate:"required"`
+	Status            string      `db:"status" validate:"required"`
+	ShippingAddressId nuuid.NUUID `db:"shipping_address_id"`
+	BillingAddressId  nuuid.NUUID `db:"billing_address_id"`
+	OrderItems        []OrderItem `db:"-"`
+	CreatedAt         time.Time   `db:"created_at" validate:"required"`
+	UpdatedAt         time.Time   `db:"updated_at" validate:"required"`
+	DeletedAt         null.Time   `db:"deleted_at"`
+	CreatedBy         uuid.UUID   `db:"created_by" validate:"required"`
+	UpdatedBy         uuid.UUID   `db:"updated_by" validate:"required"`
+	DeletedBy         nuuid.NUUID `db:"deleted_by"`
 }
 
 type OrderItem struct {
 	Id        uuid.UUID   `db:"id" validate:"required"`
 	OrderId   uuid.UUID   `db:"order_id" validate:"required"`
 	ProductId uuid.UUID   `db:"product_id" validate:"required"`
+	VariantId nuuid.NUUID `db:"variant_id"`
 	Quantity  int         `db:"quantity" validate:"required"`
 	Price     float64     `db:"price" validate:"required"`
-	CreatedAt time.Time   `db:"created_at" validate:"required"`
-	UpdatedAt time.Time   `db:"updated_at" validate:"required"`
-	DeletedAt null.Time   `db:"deleted_at"`
-	CreatedBy uuid.UUID   `db:"created_by" validate:"required"`
-	UpdatedBy uuid.UUID   `db:"updated_by" validate:"required"`
-	DeletedBy nuuid.NUUID `db:"deleted_by"`
+	// Status is ItemAllocated once the item is taken out of stock.
+	Status string `db:"status" validate:"oneof=allocated backordered preordered"`
+	// ExpectedAt is when stock was expected for a waiting item.
+	ExpectedAt null.Time   `db:"expected_at"`
+	CreatedAt  time.Time   `db:"created_at" validate:"required"`
+	UpdatedAt  time.Time   `db:"updated_at" validate:"required"`
+	DeletedAt  null.Time   `db:"deleted_at"`
+	CreatedBy  uuid.UUID   `db:"created_by" validate:"required"`
+	UpdatedBy  uuid.UUID   `db:"updated_by" validate:"required"`
+	DeletedBy  nuuid.NUUID `db:"deleted_by"`
 }
 
 type OrderResponseFormat struct {
+	Id                uuid.UUID   `json:"id" validate:"required"`
+	OrganizationId    uuid.UUID   `json:"organizationId"`
+	UserId            uuid.UUID   `json:"userId" validate:"required"`
+	TotalPrice        float64     `json:"totalPrice" validate:"required"`
+	Status            string      `json:"status" validate:"required"`
+	ShippingAddressId nuuid.NUUID `json:"shippingAddressId"`
+	BillingAddressId  nuuid.NUUID `json:"billingAddressId"`
+	OrderItems        []OrderItem `json:"-"`
+	CreatedAt         time.Time   `json:"createdAt" validate:"required"`
+	UpdatedAt         time.Time   `json:"updatedAt" validate:"required"`
+	DeletedAt         null.Time   `json:"deletedAt"`
+	CreatedBy         uuid.UUID   `json:"createdBy" validate:"required"`
+	UpdatedBy         uuid.UUID   `json:"updatedBy" validate:"required"`
+	DeletedBy         nuuid.NUUID `json:"deletedBy"`
+}
+
+type OrderItemResponseFormat struct {
 	Id         uuid.UUID   `json:"id" validate:"required"`
-	UserId     uuid.UUID   `json:"userId" validate:"required"`
-	TotalPrice float64     `json:"totalPrice" validate:"required"`
-	Status     string      `json:"status" validate:"required"`
-	OrderItems []OrderItem `json:"-"`
+	OrderId    uuid.UUID   `json:"orderId" validate:"required"`
+	ProductId  uuid.UUID   `json:"productId" validate:"required"`
+	VariantId  nuuid.NUUID `json:"variantId"`
+	Quantity   int         `json:"quantity" validate:"required"`
+	Price      float64     `json:"price" validate:"required"`
+	Status     string      `json:"status"`
+	ExpectedAt null.Time   `json:"expectedAt"`
 	CreatedAt  time.Time   `json:"createdAt" validate:"required"`
 	UpdatedAt  time.Time   `json:"updatedAt" validate:"required"`
 	DeletedAt  null.Time   `json:"deletedAt"`
@@ -53,32 +89,25 @@ type OrderResponseFormat struct {
 	DeletedBy  nuuid.NUUID `json:"deletedBy"`
 }
 
-type OrderItemResponseFormat struct {
-	Id        uuid.UUID   `json:"id" validate:"required"`
-	OrderId   uuid.UUID   `json:"orderId" validate:"required"`
-	ProductId uuid.UUID   `json:"productId" validate:"required"`
-	Quantity  int         `json:"quantity" validate:"required"`
-	Price     float64     `json:"price" validate:"required"`
-	CreatedAt time.Time   `json:"createdAt" validate:"required"`
-	UpdatedAt time.Time   `json:"updatedAt" validate:"required"`
-	DeletedAt null.Time   `json:"deletedAt"`
-	CreatedBy uuid.UUID   `json:"createdBy" validate:"required"`
-	UpdatedBy uuid.UUID   `json:"updatedBy" validate:"required"`
-	DeletedBy nuuid.NUUID `json:"deletedBy"`
-}
-
 type OrderPayload struct {
-	UserId     uuid.UUID
-	TotalPrice float64
-	Status     string
+	OrganizationId    uuid.UUID
+	UserId            uuid.UUID
+	TotalPrice        float64
+	Status            string
+	ShippingAddressId nuuid.NUUID
+	BillingAddressId  nuuid.NUUID
 }
 
 type OrderItemPayload struct {
 	ProductId uuid.UUID
+	VariantId nuuid.NUUID
 	UserId    uuid.UUID
 	OrderId   uuid.UUID
 	Quantity  int
 	Price     float64
+	// Status defaults to ItemAllocated.
+	Status     string
+	ExpectedAt null.Time
 }
 
 func (o Order) NewFromPayload(load OrderPayload) (res Order, err error) {
@@ -87,14 +116,17 @@ func (o Order) NewFromPayload(load OrderPayload) (res Order, err error) {
 		return
 	}
 	res = Order{
-		Id:         orderId,
-		UserId:     load.UserId,
-		TotalPrice: load.TotalPrice,
-		Status:     load.Status,
-		CreatedAt:  time.Now().UTC(),
-		CreatedBy:  load.UserId,
-		UpdatedAt:  time.Now().UTC(),
-		UpdatedBy:  load.UserId,
+		Id:                orderId,
+		OrganizationId:    load.OrganizationId,
+		UserId:            load.UserId,
+		TotalPrice:        load.TotalPrice,
+		Status:            load.Status,
+		ShippingAddressId: load.ShippingAddressId,
+		BillingAddressId:  load.BillingAddressId,
+		CreatedAt:         time.Now().UTC(),
+		CreatedBy:         load.UserId,
+		UpdatedAt:         time.Now().UTC(),
+		UpdatedBy:         load.UserId,
 	}
 	err = res.Validate()
 	return
@@ -106,15 +138,21 @@ func (o OrderItem) NewFromPayload(load OrderItemPayload) (res OrderItem, err err
 		return
 	}
 	res = OrderItem{
-		Id:        orderItemId,
-		OrderId:   load.OrderId,
-		ProductId: load.ProductId,
-		Quantity:  load.Quantity,
-		Price:     load.Price,
-		CreatedAt: time.Now().UTC(),
-		CreatedBy: load.UserId,
-		UpdatedAt: time.Now().UTC(),
-		UpdatedBy: load.UserId,
+		Id:         orderItemId,
+		OrderId:    load.OrderId,
+		ProductId:  load.ProductId,
+		VariantId:  load.VariantId,
+		Quantity:   load.Quantity,
+		Price:      load.Price,
+		Status:     load.Status,
+		ExpectedAt: load.ExpectedAt,
+		CreatedAt:  time.Now().UTC(),
+		CreatedBy:  load.UserId,
+		UpdatedAt:  time.Now().UTC(),
+		UpdatedBy:  load.UserId,
+	}
+	if res.Status == "" {
+		res.Status = ItemAllocated
 	}
 	err = res.Validate()
 	return
@@ -181,6 +219,24 @@ func (o *OrderItem) CancelOrderItem(userId uuid.UUID) (err error) {
 	return
 }
 
+// IsWaiting reports whether the item waits for stock.
+func (o *OrderItem) IsWaiting() bool {
+	return o.Status == ItemBackordered || o.Status == ItemPreordered
+}
+
+// Allocate marks the waiting item as taken out of stock.
+func (o *OrderItem) Allocate(userId uuid.UUID) (err error) {
+	if !o.IsWaiting() {
+		err = failure.Conflict("allocate", "order_item", "not waiting for stock")
+		return
+	}
+	o.Status = ItemAllocated
+	o.UpdatedAt = time.Now().UTC()
+	o.UpdatedBy = userId
+	err = o.Validate()
+	return
+}
+
 func (o *Order) isCancelled() bool {
 	return o.DeletedAt.Valid && o.DeletedBy.Valid
 }
@@ -1,31 +1,42 @@
 package order
 
 import (
+	"database/sql"
 	"fmt"
 	"strings"
 
 	"github.com/evermos/boilerplate-go/infras"
+	"github.com/evermos/boilerplate-go/internal/domain/inventory"
 	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/evermos/boilerplate-go/shared/nuuid"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
 	"github.com/jmoiron/sqlx"
 )
 
 type OrderRepository interface {
 	Create(load Order) (err error)
-	GetAll(limit, offset int, sort, field, status, userId, userRole string, cancelled bool) (res []Order, err error)
+	GetAll(orgId string, limit, offset int, sort, field, status, userId, userRole string, cancelled bool) (res []Order, err error)
 	CancelOrder(load Order) (err error)
 	GetOrderByID(orderId string) (res Order, err error)
 	ExistsByID(orderId string) (exists bool, err error)
 	GetItemsByOrderID(orderId string) (res []OrderItem, err error)
+	GetWaitingItems(after OrderItem, limit int) (res []OrderItem, err error)
+	WaitingQuantity(productId string, variantId nuuid.NUUID) (item, product int, err error)
+	Allocate(load Order, item OrderItem) (err error)
 }
 
 type OrderRepositoryMySQL struct {
-	DB *infras.MySQLConn
+	DB        *infras.MySQLConn
+	Allocator *inventory.Allocator
 }
 
-func ProvideOrderRepositoryMySQL(db *infras.MySQLConn) *OrderRepositoryMySQL {
-	return &OrderRepositoryMySQL{DB: db}
+func ProvideOrderRepositoryMySQL(db *infras.MySQLConn, allocator *inventory.Allocator) *OrderRepositoryMySQL {
+	return &OrderRepositoryMySQL{DB: db, Allocator: allocator}
 }
 
+// Create inserts the order with its items and takes their quantities out of
+// stock at the locations the allocator picks.
 func (r *OrderRepositoryMySQL) Create(load Order) (err error) {
 	return r.DB.WithTransaction(func(db *sqlx.Tx, c chan error) {
 		if err := r.txCreate(db, load); err != nil {
@@ -36,13 +47,67 @@ func (r *OrderRepositoryMySQL) Create(load Order) (err error) {
 			c <- err
 			return
 		}
+		if err := r.txAllocate(db, load); err != nil {
+			c <- err
+			return
+		}
 		c <- nil
 	})
 }
 
+// txAllocate takes the items of the order out of stock, recording a
+// movement per item and location that refers to the order. Items waiting
+// for stock are left out.
+func (r *OrderRepositoryMySQL) txAllocate(tx *sqlx.Tx, load Order) (err error) {
+	var dest inventory.Destination
+	if load.ShippingAddressId.Valid {
+		err = tx.Get(&dest, "SELECT country_code, province, city, postal_code FROM address WHERE id = ?", load.ShippingAddressId.UUID.String())
+		if err != nil && err != sql.ErrNoRows {
+			logger.ErrorWithStack(err)
+			return
+		}
+	}
+	requests := make([]inventory.Request, 0, len(load.OrderItems))
+	for _, item := range load.OrderItems {
+		if item.IsWaiting() {
+			continue
+		}
+		requests = append(requests, inventory.Request{ProductId: item.ProductId, VariantId: item.VariantId, Quantity: item.Quantity})
+	}
+	if len(requests) == 0 {
+		return
+	}
+	_, err = r.Allocator.TxAllocate(tx, load.OrganizationId, dest, requests, inventory.ReasonOrderPlaced, null.StringFrom(load.Id.String()), load.CreatedBy)
+	return
+}
+
+// txRestock puts the items of the order back in stock at the locations
+// they were taken from. Orders placed before stock was kept per location
+// have no movements to reverse; their items go to the default location,
+// except those still waiting for stock.
+func (r *OrderRepositoryMySQL) txRestock(tx *sqlx.Tx, load Order, userId uuid.UUID) (err error) {
+	reversed, err := inventory.TxReverse(tx, load.OrganizationId, load.Id.String(), inventory.ReasonOrderPlaced, inventory.ReasonOrderCancelled, userId)
+	if err != nil || len(reversed) > 0 {
+		return
+	}
+	movements := make([]inventory.Movement, 0, len(load.OrderItems))
+	for _, item := range load.OrderItems {
+		if item.IsWaiting() {
+			continue
+		}
+		m, err := inventory.NewMovement(load.OrganizationId, item.ProductId, item.VariantId, item.Quantity, inventory.ReasonOrderCancelled, null.StringFrom(load.Id.String()), userId)
+		if err != nil {
+			return err
+		}
+		movements = append(movements, m)
+	}
+	_, err = inventory.TxApplyAll(tx, movements)
+	return
+}
+
 func (r *OrderRepositoryMySQL) txCreate(tx *sqlx.Tx, load Order) (err error) {
-	query := `INSERT INTO atc_order (id,user_id,total_price,status,created_at,updated_at,created_by,updated_by) 
-	VALUES (:id,:user_id,:total_price,:status,:created_at,:updated_at,:created_by,:updated_by)`
+	query := `INSERT INTO atc_order (id,organization_id,user_id,total_price,status,shipping_address_id,billing_address_id,created_at,updated_at,created_by,updated_by) 
+	VALUES (:id,:organization_id,:user_id,:total_price,:status,:shipping_address_id,:billing_address_id,:created_at,:updated_at,:created_by,:updated_by)`
 	stmt, err := tx.PrepareNamed(query)
 	if err != nil {
 		logger.ErrorWithStack(err)
@@ -85,17 +150,20 @@ func (r *OrderRepositoryMySQL) composeBulkInsertItemQuery(load []OrderItem) (que
 	values := []string{}
 	for _, oi := range load {
 		param := map[string]interface{}{
-			"id":         oi.Id,
-			"order_id":   oi.OrderId,
-			"product_id": oi.ProductId,
-			"quantity":   oi.Quantity,
-			"price":      oi.Price,
-			"created_at": oi.CreatedAt,
-			"updated_at": oi.UpdatedAt,
-			"created_by": oi.CreatedBy,
-			"updated_by": oi.UpdatedBy,
-		}
-		q, args, err := sqlx.Named(`(:id,:order_id,:product_id,:quantity,:price,:created_at,:updated_at,:created_by,:updated_by)`, param)
+			"id":          oi.Id,
+			"order_id":    oi.OrderId,
+			"product_id":  oi.ProductId,
+			"variant_id":  oi.VariantId,
+			"quantity":    oi.Quantity,
+			"price":       oi.Price,
+			"status":      oi.Status,
+			"expected_at": oi.ExpectedAt,
+			"created_at":  oi.CreatedAt,
+			"updated_at":  oi.UpdatedAt,
+			"created_by":  oi.CreatedBy,
+			"updated_by":  oi.UpdatedBy,
+		}
+		q, args, err := sqlx.Named(`(:id,:order_id,:product_id,:variant_id,:quantity,:price,:status,:expected_at,:created_at,:updated_at,:created_by,:updated_by)`, param)
 		if err != nil {
 			return query, params, err
 		}
@@ -106,8 +174,11 @@ func (r *OrderRepositoryMySQL) composeBulkInsertItemQuery(load []OrderItem) (que
 				id,
 				order_id,
 				product_id,
+				variant_id,
 				quantity,
 				price,
+				status,
+				expected_at,
 				created_at,
 				updated_at,
 				created_by,
@@ -116,34 +187,32 @@ func (r *OrderRepositoryMySQL) composeBulkInsertItemQuery(load []OrderItem) (que
 	return
 }
 
-func (r *OrderRepositoryMySQL) GetAll(limit, offset int, sort, field, status, userId, userRole string, cancelled bool) (res []Order, err error) {
-	query := `SELECT * FROM atc_order `
-
+// GetAll lists orders of the organization. Non-admins only see their own
+// orders. An empty orgId lists across all organizations.
+func (r *OrderRepositoryMySQL) GetAll(orgId string, limit, offset int, sort, field, status, userId, userRole string, cancelled bool) (res []Order, err error) {
+	conditions := []string{}
+	args := []interface{}{}
+	if orgId != "" {
+		conditions = append(conditions, "organization_id = ?")
+		args = append(args, orgId)
+	}
 	if userRole != "admin" {
-		query += fmt.Sprintf("WHERE user_id = '%s' ", userId)
+		conditions = append(conditions, "user_id = ?")
+		args = append(args, userId)
 	}
 	if status != "" {
-		exists := strings.Contains(query, "WHERE")
-		if !exists && userRole != "admin" {
-			query += "WHERE "
-		}
-		if exists && strings.Contains(query, "user_id") {
-			query += "AND "
-		}
-		query += fmt.Sprintf("status = '%s' ", status)
+		conditions = append(conditions, "status = ?")
+		args = append(args, status)
 	}
 	if cancelled {
-		exists := strings.Contains(query, "WHERE")
-		if !exists {
-			query += `WHERE deleted_at IS NOT NULL `
-		}
-		if exists {
-			query += `AND deleted_at IS NOT NULL `
-		}
-
+		conditions = append(conditions, "deleted_at IS NOT NULL")
+	}
+	query := `SELECT * FROM atc_order `
+	if len(conditions) > 0 {
+		query += "WHERE " + strings.Join(conditions, " AND ") + " "
 	}
 	query += fmt.Sprintf("ORDER BY %s %s LIMIT %d OFFSET %d", field, sort, limit, offset)
-	err = r.DB.Read.Select(&res, query)
+	err = r.DB.Read.Select(&res, query, args...)
 	if err != nil {
 		logger.ErrorWithStack(err)
 		return
@@ -151,6 +220,8 @@ func (r *OrderRepositoryMySQL) GetAll(limit, offset int, sort, field, status, us
 	return
 }
 
+// CancelOrder marks the order and its items as cancelled and puts their
+// quantities back in stock.
 func (r *OrderRepositoryMySQL) CancelOrder(load Order) (err error) {
 	return r.DB.WithTransaction(func(db *sqlx.Tx, c chan error) {
 		if err := r.txUpdate(db, load); err != nil {
@@ -163,6 +234,10 @@ func (r *OrderRepositoryMySQL) CancelOrder(load Order) (err error) {
 				return
 			}
 		}
+		if err := r.txRestock(db, load, load.DeletedBy.UUID); err != nil {
+			c <- err
+			return
+		}
 		c <- nil
 	})
 }
@@ -200,6 +275,7 @@ func (r *OrderRepositoryMySQL) txUpdateItem(tx *sqlx.Tx, load OrderItem) (err er
 	SET
 		price = :price,
 		quantity = :quantity,
+		status = :status,
 		created_at = :created_at,
 		updated_at = :updated_at,
 		deleted_at = :deleted_at,
@@ -247,3 +323,71 @@ func (r *OrderRepositoryMySQL) GetItemsByOrderID(orderId string) (res []OrderIte
 	}
 	return
 }
+
+// GetWaitingItems returns up to limit items of orders that are not
+// cancelled which wait for stock, oldest first, starting after the item
+// after. The zero OrderItem starts from the oldest.
+func (r *OrderRepositoryMySQL) GetWaitingItems(after OrderItem, limit int) (res []OrderItem, err error) {
+	res = []OrderItem{}
+	query := `SELECT * FROM order_item WHERE status IN (?, ?) AND deleted_at IS NULL
+	AND (created_at > ? OR (created_at = ? AND id > ?)) ORDER BY created_at, id LIMIT ?`
+	err = r.DB.Read.Select(&res, query, ItemBackordered, ItemPreordered, after.CreatedAt, after.CreatedAt, after.Id.String(), limit)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+	return
+}
+
+// WaitingQuantity returns the units waiting for stock of the product's
+// item, the variant or the product itself, and of the product as a whole.
+func (r *OrderRepositoryMySQL) WaitingQuantity(productId string, variantId nuuid.NUUID) (item, product int, err error) {
+	var row struct {
+		Item    int `db:"item"`
+		Product int `db:"product"`
+	}
+	query := `SELECT COALESCE(SUM(CASE WHEN variant_id <=> ? THEN quantity END), 0) AS item, COALESCE(SUM(quantity), 0) AS product
+	FROM order_item WHERE product_id = ? AND status IN (?, ?) AND deleted_at IS NULL`
+	err = r.DB.Read.Get(&row, query, variantId, productId, ItemBackordered, ItemPreordered)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return row.Item, row.Product, nil
+}
+
+// Allocate takes the waiting item of the order out of stock and marks it
+// allocated. Nothing changes when stock is still short, or when the item
+// was cancelled or allocated since it was read.
+func (r *OrderRepositoryMySQL) Allocate(load Order, item OrderItem) (err error) {
+	return r.DB.WithTransaction(func(db *sqlx.Tx, c chan error) {
+		var current OrderItem
+		err := db.Get(&current, "SELECT * FROM order_item WHERE id = ? FOR UPDATE", item.Id.String())
+		if err == sql.ErrNoRows {
+			c <- nil
+			return
+		}
+		if err != nil {
+			logger.ErrorWithStack(err)
+			c <- err
+			return
+		}
+		if !current.IsWaiting() || current.DeletedAt.Valid {
+			c <- nil
+			return
+		}
+		item.Quantity = current.Quantity
+		load.OrderItems = []OrderItem{item}
+		if err := r.txAllocate(db, load); err != nil {
+			c <- err
+			return
+		}
+		_, err = db.Exec("UPDATE order_item SET status = ?, updated_at = ?, updated_by = ? WHERE id = ?",
+			item.Status, item.UpdatedAt, item.UpdatedBy.String(), item.Id.String())
+		if err != nil {
+			logger.ErrorWithStack(err)
+			c <- err
+			return
+		}
+		c <- nil
+	})
+}
@@ -1,16 +1,31 @@
 package order
 
 import (
+	"net/http"
+
 	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/evermos/boilerplate-go/shared/nuuid"
 	"github.com/gofrs/uuid"
 )
 
+// waitingBatch is the number of waiting items read at a time.
+const waitingBatch = 100
+
 type OrderService interface {
 	CreateOrder(load OrderPayload, itemLoads []OrderItemPayload) (res Order, err error)
 	CreateOrderItem(load OrderItemPayload) (res OrderItem, err error)
-	GetAll(limit, offset int, sort, field, status string, userId uuid.UUID, userRole string, cancelled bool) (res []Order, err error)
-	CancelOrder(orderId, userId uuid.UUID, userRole string) (res Order, err error)
-	GetByID(orderId, userId uuid.UUID) (res Order, err error)
+	// GetAll lists the orders of an organization, or of every organization
+	// when orgId is uuid.Nil.
+	GetAll(orgId uuid.UUID, limit, offset int, sort, field, status string, userId uuid.UUID, userRole string, cancelled bool) (res []Order, err error)
+	CancelOrder(orderId, userId, orgId uuid.UUID, userRole string) (res Order, err error)
+	GetByID(orderId, userId, orgId uuid.UUID) (res Order, err error)
+	// WaitingQuantity returns the units of open orders waiting for stock of
+	// the item and of the product as a whole.
+	WaitingQuantity(productId uuid.UUID, variantId nuuid.NUUID) (item, product int, err error)
+	// AllocateWaitingItems takes backordered and pre-ordered items out of
+	// stock as it arrives, oldest first.
+	AllocateWaitingItems() (err error)
 }
 
 type OrderServiceImpl struct {
@@ -49,8 +64,12 @@ func (s *OrderServiceImpl) CreateOrderItem(load OrderItemPayload) (res OrderItem
 	return
 }
 
-func (s *OrderServiceImpl) GetAll(limit, offset int, sort, field, status string, userId uuid.UUID, userRole string, cancelled bool) (res []Order, err error) {
-	res, err = s.Repo.GetAll(limit, offset, sort, field, status, userId.String(), userRole, cancelled)
+func (s *OrderServiceImpl) GetAll(orgId uuid.UUID, limit, offset int, sort, field, status string, userId uuid.UUID, userRole string, cancelled bool) (res []Order, err error) {
+	tenant := ""
+	if orgId != uuid.Nil {
+		tenant = orgId.String()
+	}
+	res, err = s.Repo.GetAll(tenant, limit, offset, sort, field, status, userId.String(), userRole, cancelled)
 	if err != nil {
 		return
 	}
@@ -67,7 +86,7 @@ func (s *OrderServiceImpl) GetAll(limit, offset int, sort, field, status string,
 	return
 }
 
-func (s *OrderServiceImpl) CancelOrder(orderId, userId uuid.UUID, userRole string) (res Order, err error) {
+func (s *OrderServiceImpl) CancelOrder(orderId, userId, orgId uuid.UUID, userRole string) (res Order, err error) {
 	exists, err := s.Repo.ExistsByID(orderId.String())
 	if err != nil {
 		return
@@ -80,6 +99,10 @@ func (s *OrderServiceImpl) CancelOrder(orderId, userId uuid.UUID, userRole strin
 	if err != nil {
 		return
 	}
+	if res.OrganizationId != orgId {
+		err = failure.NotFound("Order")
+		return
+	}
 	if userId.String() != res.UserId.String() && userRole != "admin" {
 		err = failure.Unauthorized("unauthorized, invalid credentials")
 		return
@@ -98,11 +121,15 @@ func (s *OrderServiceImpl) CancelOrder(orderId, userId uuid.UUID, userRole strin
 	return
 }
 
-func (s *OrderServiceImpl) GetByID(orderId, userId uuid.UUID) (res Order, err error) {
+func (s *OrderServiceImpl) GetByID(orderId, userId, orgId uuid.UUID) (res Order, err error) {
 	res, err = s.Repo.GetOrderByID(orderId.String())
 	if err != nil {
 		return
 	}
+	if res.OrganizationId != orgId {
+		err = failure.NotFound("Order")
+		return
+	}
 	if res.UserId != userId {
 		err = failure.Unauthorized("Invalid Credentials")
 		return
@@ -114,3 +141,49 @@ func (s *OrderServiceImpl) GetByID(orderId, userId uuid.UUID) (res Order, err er
 	res = res.AttachItems(items)
 	return
 }
+
+func (s *OrderServiceImpl) WaitingQuantity(productId uuid.UUID, variantId nuuid.NUUID) (item, product int, err error) {
+	return s.Repo.WaitingQuantity(productId.String(), variantId)
+}
+
+// AllocateWaitingItems reads every waiting item page by page, so items of
+// a product that is still short do not hold back those of other products.
+func (s *OrderServiceImpl) AllocateWaitingItems() (err error) {
+	// Once an item is short, later items of the same product or variant
+	// keep waiting so they are filled in the order they were placed.
+	short := map[string]bool{}
+	var last OrderItem
+	for {
+		items, err := s.Repo.GetWaitingItems(last, waitingBatch)
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		last = items[len(items)-1]
+		for _, item := range items {
+			key := item.ProductId.String() + "/" + item.VariantId.UUID.String()
+			if short[key] {
+				continue
+			}
+			order, err := s.Repo.GetOrderByID(item.OrderId.String())
+			if err != nil {
+				return err
+			}
+			err = item.Allocate(order.UserId)
+			if err != nil {
+				return err
+			}
+			err = s.Repo.Allocate(order, item)
+			if failure.GetCode(err) == http.StatusConflict {
+				short[key] = true
+				continue
+			}
+			if err != nil {
+				logger.ErrorWithStack(err)
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package order_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/evermos/boilerplate-go/internal/domain/order"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// waitingRepository keeps waiting items in memory and runs out of stock for
+// the products in short.
+type waitingRepository struct {
+	order.OrderRepository
+	items     []order.OrderItem
+	short     map[uuid.UUID]bool
+	attempts  map[uuid.UUID]int
+	allocated []uuid.UUID
+}
+
+func (r *waitingRepository) GetWaitingItems(after order.OrderItem, limit int) (res []order.OrderItem, err error) {
+	res = []order.OrderItem{}
+	for _, item := range r.items {
+		if !item.CreatedAt.After(after.CreatedAt) || !item.IsWaiting() {
+			continue
+		}
+		if len(res) == limit {
+			break
+		}
+		res = append(res, item)
+	}
+	return
+}
+
+func (r *waitingRepository) GetOrderByID(orderId string) (res order.Order, err error) {
+	return order.Order{Id: uuid.FromStringOrNil(orderId), UserId: uuid.Must(uuid.NewV4())}, nil
+}
+
+func (r *waitingRepository) Allocate(load order.Order, item order.OrderItem) (err error) {
+	r.attempts[item.ProductId]++
+	if r.short[item.ProductId] {
+		return failure.Conflict("allocate", "stock", "not enough stock")
+	}
+	r.allocated = append(r.allocated, item.Id)
+	return
+}
+
+func TestAllocateWaitingItems(t *testing.T) {
+	blocked, arrived := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	userId := uuid.Must(uuid.NewV4())
+	start := time.Now().UTC().Add(-time.Hour)
+	waiting := func(productId uuid.UUID, n int) order.OrderItem {
+		at := start.Add(time.Duration(n) * time.Second)
+		return order.OrderItem{Id: uuid.Must(uuid.NewV4()), OrderId: uuid.Must(uuid.NewV4()), ProductId: productId,
+			Quantity: 1, Price: 10, Status: order.ItemBackordered, CreatedAt: at, UpdatedAt: at, CreatedBy: userId, UpdatedBy: userId}
+	}
+
+	// more items of the out of stock product than are read at a time wait
+	// ahead of the one whose stock arrived
+	repo := &waitingRepository{short: map[uuid.UUID]bool{blocked: true}, attempts: map[uuid.UUID]int{}}
+	for n := 0; n < 250; n++ {
+		repo.items = append(repo.items, waiting(blocked, n))
+	}
+	filled := waiting(arrived, 250)
+	repo.items = append(repo.items, filled)
+
+	s := order.ProvideOrderServiceImpl(repo)
+	assert.NoError(t, s.AllocateWaitingItems())
+	assert.Equal(t, []uuid.UUID{filled.Id}, repo.allocated)
+	// later items of the short product keep their place in line
+	assert.Equal(t, 1, repo.attempts[blocked])
+}
@@ -0,0 +1,196 @@
+package organization
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/nuuid"
+	"github.com/evermos/boilerplate-go/shared/roles"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
+)
+
+type Organization struct {
+	Id        uuid.UUID   `db:"id" validate:"required"`
+	Name      string      `db:"name" validate:"required,max=255"`
+	CreatedAt time.Time   `db:"created_at" validate:"required"`
+	UpdatedAt time.Time   `db:"updated_at" validate:"required"`
+	DeletedAt null.Time   `db:"deleted_at"`
+	CreatedBy uuid.UUID   `db:"created_by" validate:"required"`
+	UpdatedBy uuid.UUID   `db:"updated_by" validate:"required"`
+	DeletedBy nuuid.NUUID `db:"deleted_by"`
+}
+
+type OrganizationResponseFormat struct {
+	Id        uuid.UUID   `json:"id"`
+	Name      string      `json:"name"`
+	CreatedAt time.Time   `json:"createdAt"`
+	UpdatedAt time.Time   `json:"updatedAt"`
+	DeletedAt null.Time   `json:"deletedAt,omitempty"`
+	CreatedBy uuid.UUID   `json:"createdBy"`
+	UpdatedBy uuid.UUID   `json:"updatedBy"`
+	DeletedBy nuuid.NUUID `json:"deletedBy,omitempty"`
+}
+
+type OrganizationPayload struct {
+	Name string `json:"name" validate:"required,max=255"`
+}
+
+// Member ties a user to an organization with the role the user has there.
+// Every membership has its own cart so that products of one organization
+// never end up in an order of another.
+type Member struct {
+	OrganizationId   uuid.UUID `db:"organization_id" validate:"required"`
+	OrganizationName string    `db:"organization_name"`
+	UserId           uuid.UUID `db:"user_id" validate:"required"`
+	Role             string    `db:"role" validate:"required,oneof=trainee admin"`
+	CartId           uuid.UUID `db:"cart_id" validate:"required"`
+	CreatedAt        time.Time `db:"created_at" validate:"required"`
+	UpdatedAt        time.Time `db:"updated_at" validate:"required"`
+	CreatedBy        uuid.UUID `db:"created_by" validate:"required"`
+	UpdatedBy        uuid.UUID `db:"updated_by" validate:"required"`
+}
+
+type MemberResponseFormat struct {
+	OrganizationId   uuid.UUID `json:"organizationId"`
+	OrganizationName string    `json:"organizationName"`
+	UserId           uuid.UUID `json:"userId"`
+	Role             string    `json:"role"`
+	CartId           uuid.UUID `json:"cartId"`
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+	CreatedBy        uuid.UUID `json:"createdBy"`
+	UpdatedBy        uuid.UUID `json:"updatedBy"`
+}
+
+type MemberPayload struct {
+	Role string `json:"role" validate:"required,oneof=trainee admin"`
+}
+
+// Invite asks a user to join an organization with a role. Users only become
+// members of organizations other than their home organization by accepting
+// an invite.
+type Invite struct {
+	OrganizationId   uuid.UUID `db:"organization_id" validate:"required"`
+	OrganizationName string    `db:"organization_name"`
+	UserId           uuid.UUID `db:"user_id" validate:"required"`
+	Role             string    `db:"role" validate:"required,oneof=trainee admin"`
+	CreatedAt        time.Time `db:"created_at" validate:"required"`
+	CreatedBy        uuid.UUID `db:"created_by" validate:"required"`
+}
+
+type InviteResponseFormat struct {
+	OrganizationId   uuid.UUID `json:"organizationId"`
+	OrganizationName string    `json:"organizationName"`
+	UserId           uuid.UUID `json:"userId"`
+	Role             string    `json:"role"`
+	CreatedAt        time.Time `json:"createdAt"`
+	CreatedBy        uuid.UUID `json:"createdBy"`
+}
+
+func (o Organization) NewFromPayload(load OrganizationPayload, creatorId uuid.UUID) (res Organization, err error) {
+	orgId, err := uuid.NewV4()
+	if err != nil {
+		return
+	}
+	now := time.Now().UTC()
+	res = Organization{
+		Id:        orgId,
+		Name:      load.Name,
+		CreatedAt: now,
+		CreatedBy: creatorId,
+		UpdatedAt: now,
+		UpdatedBy: creatorId,
+	}
+	err = res.Validate()
+	return
+}
+
+func (o *Organization) Validate() error {
+	validator := shared.GetValidator()
+	return validator.Struct(o)
+}
+
+func (o Organization) ToResponseFormat() OrganizationResponseFormat {
+	return OrganizationResponseFormat(o)
+}
+
+func (o Organization) MarshalJSON() ([]byte, error) {
+	return json.Marshal(o.ToResponseFormat())
+}
+
+func (m Member) NewFromPayload(load MemberPayload, orgId, userId, creatorId uuid.UUID) (res Member, err error) {
+	cartId, err := uuid.NewV4()
+	if err != nil {
+		return
+	}
+	now := time.Now().UTC()
+	res = Member{
+		OrganizationId: orgId,
+		UserId:         userId,
+		Role:           load.Role,
+		CartId:         cartId,
+		CreatedAt:      now,
+		CreatedBy:      creatorId,
+		UpdatedAt:      now,
+		UpdatedBy:      creatorId,
+	}
+	err = res.Validate()
+	return
+}
+
+func (m *Member) Update(load MemberPayload, updaterId uuid.UUID) (err error) {
+	m.Role = load.Role
+	m.UpdatedAt = time.Now().UTC()
+	m.UpdatedBy = updaterId
+	err = m.Validate()
+	return
+}
+
+func (m *Member) IsAdmin() bool {
+	return m.Role == roles.GetStringFromRole(roles.Admin)
+}
+
+func (m *Member) Validate() error {
+	validator := shared.GetValidator()
+	return validator.Struct(m)
+}
+
+func (m Member) ToResponseFormat() MemberResponseFormat {
+	return MemberResponseFormat(m)
+}
+
+func (m Member) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.ToResponseFormat())
+}
+
+func (i Invite) NewFromPayload(load MemberPayload, orgId, userId, creatorId uuid.UUID) (res Invite, err error) {
+	res = Invite{
+		OrganizationId: orgId,
+		UserId:         userId,
+		Role:           load.Role,
+		CreatedAt:      time.Now().UTC(),
+		CreatedBy:      creatorId,
+	}
+	err = res.Validate()
+	return
+}
+
+// Accept returns the membership the invite grants its user.
+func (i Invite) Accept() (res Member, err error) {
+	return Member{}.NewFromPayload(MemberPayload{Role: i.Role}, i.OrganizationId, i.UserId, i.UserId)
+}
+
+func (i *Invite) Validate() error {
+	validator := shared.GetValidator()
+	return validator.Struct(i)
+}
+
+func (i Invite) ToResponseFormat() InviteResponseFormat {
+	return InviteResponseFormat(i)
+}
+
+func (i Invite) MarshalJSON() ([]byte, error) {
+	return json.Marshal(i.ToResponseFormat())
+}
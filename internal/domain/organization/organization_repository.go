@@ -0,0 +1,266 @@
+package organization
+
+import (
+	"database/sql"
+
+	"github.com/evermos/boilerplate-go/infras"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// memberColumns selects a membership together with its organization's name.
+const memberColumns = `SELECT m.organization_id, o.name AS organization_name, m.user_id, m.role, m.cart_id, m.created_at, m.updated_at, m.created_by, m.updated_by
+	FROM organization_member m JOIN organization o ON o.id = m.organization_id `
+
+// inviteColumns selects an invite together with its organization's name.
+const inviteColumns = `SELECT i.organization_id, o.name AS organization_name, i.user_id, i.role, i.created_at, i.created_by
+	FROM organization_invite i JOIN organization o ON o.id = i.organization_id `
+
+type OrganizationRepository interface {
+	Create(load Organization, owner Member) (err error)
+	GetByID(id string) (res Organization, err error)
+	CreateMember(load Member) (err error)
+	UpdateMember(load Member) (err error)
+	DeleteMember(load Member) (err error)
+	GetMember(orgId, userId string) (res Member, err error)
+	GetMembers(orgId string, limit, offset int) (res []Member, err error)
+	GetMembershipsByUserID(userId string) (res []Member, err error)
+	CountAdmins(orgId string) (count int, err error)
+	SaveInvite(load Invite) (err error)
+	GetInvite(orgId, userId string) (res Invite, err error)
+	GetInvitesByUserID(userId string) (res []Invite, err error)
+	DeleteInvite(load Invite) (err error)
+	AcceptInvite(load Invite, member Member) (err error)
+}
+
+type OrganizationRepositoryMySQL struct {
+	DB *infras.MySQLConn
+}
+
+func ProvideOrganizationRepositoryMySQL(db *infras.MySQLConn) *OrganizationRepositoryMySQL {
+	return &OrganizationRepositoryMySQL{DB: db}
+}
+
+// Create inserts the organization and makes owner its first member.
+func (r *OrganizationRepositoryMySQL) Create(load Organization, owner Member) (err error) {
+	return r.DB.WithTransaction(func(db *sqlx.Tx, c chan error) {
+		if err := r.txCreate(db, load); err != nil {
+			c <- err
+			return
+		}
+		if err := r.txCreateMember(db, owner); err != nil {
+			c <- err
+			return
+		}
+		c <- nil
+	})
+}
+
+func (r *OrganizationRepositoryMySQL) txCreate(tx *sqlx.Tx, load Organization) (err error) {
+	query := `INSERT INTO organization (id,name,created_at,created_by,updated_at,updated_by)
+	VALUES (:id,:name,:created_at,:created_by,:updated_at,:updated_by)`
+	stmt, err := tx.PrepareNamed(query)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(load)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+func (r *OrganizationRepositoryMySQL) GetByID(id string) (res Organization, err error) {
+	err = r.DB.Read.Get(&res, "SELECT * FROM organization WHERE id = ? AND deleted_at IS NULL", id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = failure.NotFound("Organization")
+			return
+		}
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+func (r *OrganizationRepositoryMySQL) CreateMember(load Member) (err error) {
+	return r.DB.WithTransaction(func(db *sqlx.Tx, c chan error) {
+		if err := r.txCreateMember(db, load); err != nil {
+			c <- err
+			return
+		}
+		c <- nil
+	})
+}
+
+// txCreateMember inserts the membership along with the member's cart for
+// the organization.
+func (r *OrganizationRepositoryMySQL) txCreateMember(tx *sqlx.Tx, load Member) (err error) {
+	query := `INSERT INTO cart (id,user_id,organization_id,created_at,created_by,updated_at,updated_by)
+	VALUES (:cart_id,:user_id,:organization_id,:created_at,:created_by,:updated_at,:updated_by)`
+	_, err = tx.NamedExec(query, load)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	query = `INSERT INTO organization_member (organization_id,user_id,role,cart_id,created_at,created_by,updated_at,updated_by)
+	VALUES (:organization_id,:user_id,:role,:cart_id,:created_at,:created_by,:updated_at,:updated_by)`
+	_, err = tx.NamedExec(query, load)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+func (r *OrganizationRepositoryMySQL) UpdateMember(load Member) (err error) {
+	query := `
+	UPDATE organization_member
+	SET
+		role = :role,
+		updated_at = :updated_at,
+		updated_by = :updated_by
+	WHERE organization_id = :organization_id AND user_id = :user_id`
+	_, err = r.DB.Write.NamedExec(query, load)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+// DeleteMember removes the membership. The member's cart and groups in the
+// organization go with it; orders placed from it are kept.
+func (r *OrganizationRepositoryMySQL) DeleteMember(load Member) (err error) {
+	return r.DB.WithTransaction(func(db *sqlx.Tx, c chan error) {
+		_, err := db.Exec("DELETE FROM organization_member WHERE organization_id = ? AND user_id = ?", load.OrganizationId.String(), load.UserId.String())
+		if err != nil {
+			logger.ErrorWithStack(err)
+			c <- err
+			return
+		}
+		_, err = db.Exec("DELETE m FROM user_group_member m JOIN user_group g ON g.id = m.group_id WHERE g.organization_id = ? AND m.user_id = ?", load.OrganizationId.String(), load.UserId.String())
+		if err != nil {
+			logger.ErrorWithStack(err)
+			c <- err
+			return
+		}
+		_, err = db.Exec("DELETE FROM cart WHERE id = ?", load.CartId.String())
+		if err != nil {
+			logger.ErrorWithStack(err)
+			c <- err
+			return
+		}
+		c <- nil
+	})
+}
+
+func (r *OrganizationRepositoryMySQL) GetMember(orgId, userId string) (res Member, err error) {
+	err = r.DB.Read.Get(&res, memberColumns+"WHERE m.organization_id = ? AND m.user_id = ? AND o.deleted_at IS NULL", orgId, userId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = failure.NotFound("Member")
+			return
+		}
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+func (r *OrganizationRepositoryMySQL) GetMembers(orgId string, limit, offset int) (res []Member, err error) {
+	err = r.DB.Read.Select(&res, memberColumns+"WHERE m.organization_id = ? ORDER BY m.created_at LIMIT ? OFFSET ?", orgId, limit, offset)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+func (r *OrganizationRepositoryMySQL) GetMembershipsByUserID(userId string) (res []Member, err error) {
+	err = r.DB.Read.Select(&res, memberColumns+"WHERE m.user_id = ? AND o.deleted_at IS NULL ORDER BY m.created_at", userId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+func (r *OrganizationRepositoryMySQL) CountAdmins(orgId string) (count int, err error) {
+	err = r.DB.Read.Get(&count, "SELECT COUNT(user_id) FROM organization_member WHERE organization_id = ? AND role = 'admin'", orgId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+// SaveInvite inserts the invite, or renews the pending one of the user.
+func (r *OrganizationRepositoryMySQL) SaveInvite(load Invite) (err error) {
+	query := `INSERT INTO organization_invite (organization_id,user_id,role,created_at,created_by)
+	VALUES (:organization_id,:user_id,:role,:created_at,:created_by)
+	ON DUPLICATE KEY UPDATE role = VALUES(role), created_at = VALUES(created_at), created_by = VALUES(created_by)`
+	_, err = r.DB.Write.NamedExec(query, load)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+	return
+}
+
+func (r *OrganizationRepositoryMySQL) GetInvite(orgId, userId string) (res Invite, err error) {
+	err = r.DB.Read.Get(&res, inviteColumns+"WHERE i.organization_id = ? AND i.user_id = ? AND o.deleted_at IS NULL", orgId, userId)
+	if err == sql.ErrNoRows {
+		err = failure.NotFound("Invite")
+		return
+	}
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+	return
+}
+
+func (r *OrganizationRepositoryMySQL) GetInvitesByUserID(userId string) (res []Invite, err error) {
+	res = []Invite{}
+	err = r.DB.Read.Select(&res, inviteColumns+"WHERE i.user_id = ? AND o.deleted_at IS NULL ORDER BY i.created_at", userId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+	return
+}
+
+func (r *OrganizationRepositoryMySQL) DeleteInvite(load Invite) (err error) {
+	_, err = r.DB.Write.Exec("DELETE FROM organization_invite WHERE organization_id = ? AND user_id = ?", load.OrganizationId.String(), load.UserId.String())
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+	return
+}
+
+// AcceptInvite replaces the invite by the membership it grants.
+func (r *OrganizationRepositoryMySQL) AcceptInvite(load Invite, member Member) (err error) {
+	return r.DB.WithTransaction(func(db *sqlx.Tx, c chan error) {
+		res, err := db.Exec("DELETE FROM organization_invite WHERE organization_id = ? AND user_id = ?", load.OrganizationId.String(), load.UserId.String())
+		if err != nil {
+			logger.ErrorWithStack(err)
+			c <- err
+			return
+		}
+		// the invite may have been revoked since it was read
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			if err == nil {
+				err = failure.NotFound("Invite")
+			}
+			c <- err
+			return
+		}
+		if err := r.txCreateMember(db, member); err != nil {
+			c <- err
+			return
+		}
+		c <- nil
+	})
+}
@@ -0,0 +1,183 @@
+package organization
+
+import (
+	"net/http"
+
+	"github.com/evermos/boilerplate-go/internal/domain/user"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/roles"
+	"github.com/gofrs/uuid"
+)
+
+type OrganizationService interface {
+	Create(load OrganizationPayload, creatorId uuid.UUID) (res Organization, err error)
+	GetByID(orgId uuid.UUID) (res Organization, err error)
+	GetMember(orgId, userId uuid.UUID) (res Member, err error)
+	GetMembers(orgId uuid.UUID, limit, offset int) (res []Member, err error)
+	GetMembershipsByUserID(userId uuid.UUID) (res []Member, err error)
+	SetMember(load MemberPayload, orgId, userId, updaterId uuid.UUID) (res Member, err error)
+	RemoveMember(orgId, userId uuid.UUID) (res Member, err error)
+	Invite(load MemberPayload, orgId, userId, inviterId uuid.UUID) (res Invite, err error)
+	GetInvitesByUserID(userId uuid.UUID) (res []Invite, err error)
+	AcceptInvite(orgId, userId uuid.UUID) (res Member, err error)
+	DeleteInvite(orgId, userId uuid.UUID) (res Invite, err error)
+}
+
+type OrganizationServiceImpl struct {
+	Repo        OrganizationRepository
+	UserService user.UserService
+}
+
+func ProvideOrganizationServiceImpl(repo OrganizationRepository, userService user.UserService) *OrganizationServiceImpl {
+	return &OrganizationServiceImpl{Repo: repo, UserService: userService}
+}
+
+// Create creates an organization with the creator as its admin.
+func (s *OrganizationServiceImpl) Create(load OrganizationPayload, creatorId uuid.UUID) (res Organization, err error) {
+	res, err = res.NewFromPayload(load, creatorId)
+	if err != nil {
+		return
+	}
+	owner, err := Member{}.NewFromPayload(MemberPayload{Role: roles.GetStringFromRole(roles.Admin)}, res.Id, creatorId, creatorId)
+	if err != nil {
+		return
+	}
+	err = s.Repo.Create(res, owner)
+	return
+}
+
+func (s *OrganizationServiceImpl) GetByID(orgId uuid.UUID) (res Organization, err error) {
+	return s.Repo.GetByID(orgId.String())
+}
+
+func (s *OrganizationServiceImpl) GetMember(orgId, userId uuid.UUID) (res Member, err error) {
+	return s.Repo.GetMember(orgId.String(), userId.String())
+}
+
+func (s *OrganizationServiceImpl) GetMembers(orgId uuid.UUID, limit, offset int) (res []Member, err error) {
+	return s.Repo.GetMembers(orgId.String(), limit, offset)
+}
+
+func (s *OrganizationServiceImpl) GetMembershipsByUserID(userId uuid.UUID) (res []Member, err error) {
+	return s.Repo.GetMembershipsByUserID(userId.String())
+}
+
+// SetMember changes the role of an existing member. Users are added by
+// inviting them, see Invite.
+func (s *OrganizationServiceImpl) SetMember(load MemberPayload, orgId, userId, updaterId uuid.UUID) (res Member, err error) {
+	res, err = s.Repo.GetMember(orgId.String(), userId.String())
+	if err != nil {
+		return
+	}
+	if res.IsAdmin() && load.Role != res.Role {
+		err = s.ensureAnotherAdmin(orgId)
+		if err != nil {
+			return
+		}
+	}
+	err = res.Update(load, updaterId)
+	if err != nil {
+		return
+	}
+	err = s.Repo.UpdateMember(res)
+	return
+}
+
+// Invite invites the user to the organization with the role, or renews the
+// pending invite. The user joins only by accepting it.
+func (s *OrganizationServiceImpl) Invite(load MemberPayload, orgId, userId, inviterId uuid.UUID) (res Invite, err error) {
+	_, err = s.Repo.GetMember(orgId.String(), userId.String())
+	if err == nil {
+		err = failure.Conflict("invite", "member", "the user is already a member")
+		return
+	}
+	if failure.GetCode(err) != http.StatusNotFound {
+		return
+	}
+	_, err = s.UserService.GetByUserID(userId)
+	if err != nil {
+		return
+	}
+	res, err = res.NewFromPayload(load, orgId, userId, inviterId)
+	if err != nil {
+		return
+	}
+	err = s.Repo.SaveInvite(res)
+	if err != nil {
+		return
+	}
+	res, err = s.Repo.GetInvite(orgId.String(), userId.String())
+	return
+}
+
+// GetInvitesByUserID returns the invites waiting for the user.
+func (s *OrganizationServiceImpl) GetInvitesByUserID(userId uuid.UUID) (res []Invite, err error) {
+	return s.Repo.GetInvitesByUserID(userId.String())
+}
+
+// AcceptInvite makes the user a member of the organization with the role
+// they were invited with.
+func (s *OrganizationServiceImpl) AcceptInvite(orgId, userId uuid.UUID) (res Member, err error) {
+	invite, err := s.Repo.GetInvite(orgId.String(), userId.String())
+	if err != nil {
+		return
+	}
+	res, err = invite.Accept()
+	if err != nil {
+		return
+	}
+	err = s.Repo.AcceptInvite(invite, res)
+	if err != nil {
+		return
+	}
+	res, err = s.Repo.GetMember(orgId.String(), userId.String())
+	return
+}
+
+// DeleteInvite drops the invite, declined by the user or revoked by an
+// admin.
+func (s *OrganizationServiceImpl) DeleteInvite(orgId, userId uuid.UUID) (res Invite, err error) {
+	res, err = s.Repo.GetInvite(orgId.String(), userId.String())
+	if err != nil {
+		return
+	}
+	err = s.Repo.DeleteInvite(res)
+	return
+}
+
+// RemoveMember takes the user out of the organization. Users cannot leave
+// the organization they were created in, and every organization keeps at
+// least one admin.
+func (s *OrganizationServiceImpl) RemoveMember(orgId, userId uuid.UUID) (res Member, err error) {
+	res, err = s.Repo.GetMember(orgId.String(), userId.String())
+	if err != nil {
+		return
+	}
+	member, err := s.UserService.GetByUserID(userId)
+	if err != nil {
+		return
+	}
+	if member.OrganizationId == orgId {
+		err = failure.Conflict("remove", "member", "users cannot leave their home organization")
+		return
+	}
+	if res.IsAdmin() {
+		err = s.ensureAnotherAdmin(orgId)
+		if err != nil {
+			return
+		}
+	}
+	err = s.Repo.DeleteMember(res)
+	return
+}
+
+func (s *OrganizationServiceImpl) ensureAnotherAdmin(orgId uuid.UUID) (err error) {
+	count, err := s.Repo.CountAdmins(orgId.String())
+	if err != nil {
+		return
+	}
+	if count <= 1 {
+		err = failure.Conflict("update", "member", "an organization needs at least one admin")
+	}
+	return
+}
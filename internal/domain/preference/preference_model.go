@@ -0,0 +1,208 @@
+package preference
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/gofrs/uuid"
+)
+
+type Type string
+
+const (
+	TypeString     Type = "string"
+	TypeBool       Type = "bool"
+	TypeStringList Type = "stringList"
+)
+
+// Keys of the known preferences.
+const (
+	KeyLocale                = "locale"
+	KeyCurrency              = "currency"
+	KeyMarketingOptIn        = "marketing.optIn"
+	KeyNotificationChannels  = "notifications.channels"
+	KeyNotificationOrders    = "notifications.orders"
+	KeyNotificationSecurity  = "notifications.security"
+	KeyNotificationMarketing = "notifications.marketing"
+	KeyNotificationLowStock  = "notifications.lowStock"
+	KeyNotificationRestock   = "notifications.backInStock"
+)
+
+// Notification channels.
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+	ChannelPush  = "push"
+)
+
+// Notification topics other services pass to ShouldNotify.
+const (
+	TopicSecurity  = "security"
+	TopicOrders    = "orders"
+	TopicMarketing = "marketing"
+	TopicLowStock  = "lowStock"
+	TopicRestock   = "backInStock"
+)
+
+// Definition describes an allowed key, its type, default and validation.
+type Definition struct {
+	Key         string      `json:"key"`
+	Type        Type        `json:"type"`
+	Default     interface{} `json:"default"`
+	Allowed     []string    `json:"allowed,omitempty"`
+	Description string      `json:"description"`
+	validate    string
+}
+
+// Schema lists every preference a user can set. The locale defaults to the
+// locale of the user's profile.
+var Schema = []Definition{
+	{Key: KeyLocale, Type: TypeString, Default: nil, validate: "locale", Description: "Language for notifications, defaults to the profile locale"},
+	{Key: KeyCurrency, Type: TypeString, Default: "IDR", Allowed: []string{"IDR", "USD", "SGD", "MYR"}, validate: "oneof=IDR USD SGD MYR", Description: "Currency prices are shown in"},
+	{Key: KeyMarketingOptIn, Type: TypeBool, Default: false, Description: "Whether the user agreed to receive marketing"},
+	{Key: KeyNotificationChannels, Type: TypeStringList, Default: []string{ChannelEmail}, Allowed: []string{ChannelEmail, ChannelSMS, ChannelPush}, validate: "dive,oneof=email sms push", Description: "Channels notifications may be sent through"},
+	{Key: KeyNotificationOrders, Type: TypeBool, Default: true, Description: "Notify about order updates"},
+	{Key: KeyNotificationSecurity, Type: TypeBool, Default: true, Description: "Notify about sign-ins and account changes"},
+	{Key: KeyNotificationMarketing, Type: TypeBool, Default: true, Description: "Notify about promotions, only if marketing.optIn is set"},
+	{Key: KeyNotificationLowStock, Type: TypeBool, Default: true, Description: "Notify staff when products run low on stock"},
+	{Key: KeyNotificationRestock, Type: TypeBool, Default: true, Description: "Notify when products asked about are back in stock"},
+}
+
+// Lookup returns the definition of key.
+func Lookup(key string) (res Definition, ok bool) {
+	for _, d := range Schema {
+		if d.Key == key {
+			return d, true
+		}
+	}
+	return
+}
+
+// Parse decodes and validates a value for the preference.
+func (d Definition) Parse(raw json.RawMessage) (res interface{}, err error) {
+	switch d.Type {
+	case TypeString:
+		var v string
+		err = json.Unmarshal(raw, &v)
+		res = v
+	case TypeBool:
+		var v bool
+		err = json.Unmarshal(raw, &v)
+		res = v
+	case TypeStringList:
+		v := []string{}
+		err = json.Unmarshal(raw, &v)
+		res = v
+	}
+	if err != nil {
+		err = failure.BadRequestFromString(d.Key + " must be of type " + string(d.Type))
+		return
+	}
+	if d.validate != "" {
+		err = shared.GetValidator().Var(res, d.validate)
+		if err != nil {
+			err = failure.BadRequestFromString(d.Key + " has an invalid value")
+		}
+	}
+	return
+}
+
+// Preference is a single value a user has set. Value holds the JSON
+// encoding of the typed value.
+type Preference struct {
+	UserId    uuid.UUID `db:"user_id" validate:"required"`
+	Key       string    `db:"preference_key" validate:"required"`
+	Value     string    `db:"value" validate:"required"`
+	UpdatedAt time.Time `db:"updated_at" validate:"required"`
+	UpdatedBy uuid.UUID `db:"updated_by" validate:"required"`
+}
+
+func (p Preference) NewFromValue(userId uuid.UUID, key string, value interface{}, updaterId uuid.UUID) (res Preference, err error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	res = Preference{
+		UserId:    userId,
+		Key:       key,
+		Value:     string(encoded),
+		UpdatedAt: time.Now().UTC(),
+		UpdatedBy: updaterId,
+	}
+	err = res.Validate()
+	return
+}
+
+func (p *Preference) Validate() error {
+	validator := shared.GetValidator()
+	return validator.Struct(p)
+}
+
+// Preferences are the effective values of all keys, defaults included.
+type Preferences map[string]interface{}
+
+// Defaults returns the default of every key. localeDefault fills in the
+// locale.
+func Defaults(localeDefault string) Preferences {
+	res := Preferences{}
+	for _, d := range Schema {
+		res[d.Key] = d.Default
+	}
+	res[KeyLocale] = localeDefault
+	return res
+}
+
+// Apply overrides the defaults with the stored values. Values of keys that
+// are no longer in the schema, or no longer valid, are ignored.
+func (p Preferences) Apply(stored []Preference) {
+	for _, s := range stored {
+		d, ok := Lookup(s.Key)
+		if !ok {
+			continue
+		}
+		value, err := d.Parse(json.RawMessage(s.Value))
+		if err != nil {
+			continue
+		}
+		p[s.Key] = value
+	}
+}
+
+func (p Preferences) Bool(key string) bool {
+	v, _ := p[key].(bool)
+	return v
+}
+
+func (p Preferences) Strings(key string) []string {
+	v, _ := p[key].([]string)
+	return v
+}
+
+// ShouldNotify reports whether the user wants notifications about topic
+// through channel.
+func (p Preferences) ShouldNotify(channel, topic string) bool {
+	enabled := false
+	for _, c := range p.Strings(KeyNotificationChannels) {
+		if c == channel {
+			enabled = true
+		}
+	}
+	if !enabled {
+		return false
+	}
+	switch topic {
+	case TopicSecurity:
+		return p.Bool(KeyNotificationSecurity)
+	case TopicOrders:
+		return p.Bool(KeyNotificationOrders)
+	case TopicMarketing:
+		return p.Bool(KeyMarketingOptIn) && p.Bool(KeyNotificationMarketing)
+	case TopicLowStock:
+		return p.Bool(KeyNotificationLowStock)
+	case TopicRestock:
+		return p.Bool(KeyNotificationRestock)
+	}
+	return false
+}
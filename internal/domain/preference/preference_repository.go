@@ -0,0 +1,54 @@
+package preference
+
+import (
+	"github.com/evermos/boilerplate-go/infras"
+	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+type PreferenceRepository interface {
+	GetByUserID(userId string) (res []Preference, err error)
+	Save(userId string, set []Preference, reset []string) (err error)
+}
+
+type PreferenceRepositoryMySQL struct {
+	DB *infras.MySQLConn
+}
+
+func ProvidePreferenceRepositoryMySQL(db *infras.MySQLConn) *PreferenceRepositoryMySQL {
+	return &PreferenceRepositoryMySQL{DB: db}
+}
+
+func (r *PreferenceRepositoryMySQL) GetByUserID(userId string) (res []Preference, err error) {
+	err = r.DB.Read.Select(&res, "SELECT * FROM user_preference WHERE user_id = ?", userId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+// Save upserts the values in set and deletes the keys in reset, so they go
+// back to their defaults.
+func (r *PreferenceRepositoryMySQL) Save(userId string, set []Preference, reset []string) (err error) {
+	return r.DB.WithTransaction(func(db *sqlx.Tx, c chan error) {
+		query := `INSERT INTO user_preference (user_id,preference_key,value,updated_at,updated_by)
+		VALUES (:user_id,:preference_key,:value,:updated_at,:updated_by)
+		ON DUPLICATE KEY UPDATE value = VALUES(value), updated_at = VALUES(updated_at), updated_by = VALUES(updated_by)`
+		for _, p := range set {
+			if _, err := db.NamedExec(query, p); err != nil {
+				logger.ErrorWithStack(err)
+				c <- err
+				return
+			}
+		}
+		for _, key := range reset {
+			if _, err := db.Exec("DELETE FROM user_preference WHERE user_id = ? AND preference_key = ?", userId, key); err != nil {
+				logger.ErrorWithStack(err)
+				c <- err
+				return
+			}
+		}
+		c <- nil
+	})
+}
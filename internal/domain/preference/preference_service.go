@@ -0,0 +1,100 @@
+package preference
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/evermos/boilerplate-go/internal/domain/user"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/gofrs/uuid"
+)
+
+// PreferenceService stores user settings. Services that notify users call
+// ShouldNotify first.
+type PreferenceService interface {
+	GetSchema() []Definition
+	Get(userId uuid.UUID) (res Preferences, err error)
+	Update(load map[string]json.RawMessage, userId, updaterId uuid.UUID) (res Preferences, err error)
+	ShouldNotify(userId uuid.UUID, channel, topic string) (notify bool, err error)
+}
+
+type PreferenceServiceImpl struct {
+	Repo        PreferenceRepository
+	UserService user.UserService
+}
+
+func ProvidePreferenceServiceImpl(repo PreferenceRepository, userService user.UserService) *PreferenceServiceImpl {
+	return &PreferenceServiceImpl{Repo: repo, UserService: userService}
+}
+
+func (s *PreferenceServiceImpl) GetSchema() []Definition {
+	return Schema
+}
+
+func (s *PreferenceServiceImpl) Get(userId uuid.UUID) (res Preferences, err error) {
+	u, err := s.UserService.GetByUserID(userId)
+	if err != nil {
+		return
+	}
+	stored, err := s.Repo.GetByUserID(userId.String())
+	if err != nil {
+		return
+	}
+	res = Defaults(u.Locale)
+	res.Apply(stored)
+	return
+}
+
+// Update sets the keys in load. A null value resets the key to its
+// default. Nothing is saved if any key is unknown or invalid.
+func (s *PreferenceServiceImpl) Update(load map[string]json.RawMessage, userId, updaterId uuid.UUID) (res Preferences, err error) {
+	_, err = s.UserService.GetByUserID(userId)
+	if err != nil {
+		return
+	}
+	keys := make([]string, 0, len(load))
+	for key := range load {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	set := []Preference{}
+	reset := []string{}
+	for _, key := range keys {
+		d, ok := Lookup(key)
+		if !ok {
+			err = failure.BadRequestFromString("unknown preference " + key)
+			return
+		}
+		raw := load[key]
+		if string(raw) == "null" {
+			reset = append(reset, key)
+			continue
+		}
+		value, err := d.Parse(raw)
+		if err != nil {
+			return res, err
+		}
+		p, err := Preference{}.NewFromValue(userId, key, value, updaterId)
+		if err != nil {
+			return res, err
+		}
+		set = append(set, p)
+	}
+	err = s.Repo.Save(userId.String(), set, reset)
+	if err != nil {
+		return
+	}
+	return s.Get(userId)
+}
+
+// ShouldNotify reports whether the user wants to be notified about topic
+// through channel.
+func (s *PreferenceServiceImpl) ShouldNotify(userId uuid.UUID, channel, topic string) (notify bool, err error) {
+	prefs, err := s.Get(userId)
+	if err != nil {
+		return
+	}
+	notify = prefs.ShouldNotify(channel, topic)
+	return
+}
@@ -0,0 +1,147 @@
+package privacy
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/evermos/boilerplate-go/internal/domain/address"
+	"github.com/evermos/boilerplate-go/internal/domain/cart"
+	"github.com/evermos/boilerplate-go/internal/domain/group"
+	"github.com/evermos/boilerplate-go/internal/domain/order"
+	"github.com/evermos/boilerplate-go/internal/domain/organization"
+	"github.com/evermos/boilerplate-go/internal/domain/preference"
+	"github.com/evermos/boilerplate-go/internal/domain/user"
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
+)
+
+const (
+	StatusScheduled = "scheduled"
+	StatusCompleted = "completed"
+	StatusCancelled = "cancelled"
+	StatusFailed    = "failed"
+)
+
+// SortFields are the columns erasure requests can be sorted by.
+var SortFields = []string{"id", "status", "scheduled_for", "created_at", "updated_at"}
+
+// ErasureRequest is a user's request to have their personal data erased.
+// It is carried out once ScheduledFor has passed, giving the user time to
+// change their mind.
+type ErasureRequest struct {
+	Id           uuid.UUID   `db:"id" validate:"required"`
+	UserId       uuid.UUID   `db:"user_id" validate:"required"`
+	Status       string      `db:"status" validate:"required,oneof=scheduled completed cancelled failed"`
+	ScheduledFor time.Time   `db:"scheduled_for" validate:"required"`
+	ProcessedAt  null.Time   `db:"processed_at"`
+	Error        null.String `db:"error"`
+	CreatedAt    time.Time   `db:"created_at" validate:"required"`
+	UpdatedAt    time.Time   `db:"updated_at" validate:"required"`
+	CreatedBy    uuid.UUID   `db:"created_by" validate:"required"`
+	UpdatedBy    uuid.UUID   `db:"updated_by" validate:"required"`
+}
+
+type ErasureRequestResponseFormat struct {
+	Id           uuid.UUID   `json:"id"`
+	UserId       uuid.UUID   `json:"userId"`
+	Status       string      `json:"status"`
+	ScheduledFor time.Time   `json:"scheduledFor"`
+	ProcessedAt  null.Time   `json:"processedAt"`
+	Error        null.String `json:"error"`
+	CreatedAt    time.Time   `json:"createdAt"`
+	UpdatedAt    time.Time   `json:"updatedAt"`
+	CreatedBy    uuid.UUID   `json:"createdBy"`
+	UpdatedBy    uuid.UUID   `json:"updatedBy"`
+}
+
+// DataExport bundles everything stored about a user in the formats the API
+// returns them in. Authentication is done with stateless JWTs, so there are
+// no sessions to include.
+type DataExport struct {
+	GeneratedAt     time.Time                           `json:"generatedAt"`
+	Profile         user.UserResponseFormat             `json:"profile"`
+	Organizations   []organization.MemberResponseFormat `json:"organizations"`
+	Groups          []group.GroupResponseFormat         `json:"groups"`
+	Preferences     preference.Preferences              `json:"preferences"`
+	Addresses       []address.AddressResponseFormat     `json:"addresses"`
+	Carts           []cart.CartResponseFormat           `json:"carts"`
+	Orders          []OrderExport                       `json:"orders"`
+	ErasureRequests []ErasureRequestResponseFormat      `json:"erasureRequests"`
+}
+
+// OrderExport is an order together with its items, which the order's own
+// JSON format leaves out.
+type OrderExport struct {
+	Order order.OrderResponseFormat       `json:"order"`
+	Items []order.OrderItemResponseFormat `json:"items"`
+}
+
+// ValidateStatus checks an optional status filter.
+func ValidateStatus(status string) error {
+	switch status {
+	case "", StatusScheduled, StatusCompleted, StatusCancelled, StatusFailed:
+		return nil
+	}
+	return failure.BadRequestFromString("status must be one of scheduled, completed, cancelled, failed")
+}
+
+func (e ErasureRequest) NewFromUser(userId, requesterId uuid.UUID, delay time.Duration) (res ErasureRequest, err error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return
+	}
+	now := time.Now().UTC()
+	res = ErasureRequest{
+		Id:           id,
+		UserId:       userId,
+		Status:       StatusScheduled,
+		ScheduledFor: now.Add(delay),
+		CreatedAt:    now,
+		CreatedBy:    requesterId,
+		UpdatedAt:    now,
+		UpdatedBy:    requesterId,
+	}
+	err = res.Validate()
+	return
+}
+
+func (e *ErasureRequest) Validate() error {
+	validator := shared.GetValidator()
+	return validator.Struct(e)
+}
+
+func (e *ErasureRequest) Cancel(userId uuid.UUID) (err error) {
+	if e.Status != StatusScheduled {
+		err = failure.Conflict("cancel", "erasure request", "already "+e.Status)
+		return
+	}
+	e.Status = StatusCancelled
+	e.UpdatedAt = time.Now().UTC()
+	e.UpdatedBy = userId
+	err = e.Validate()
+	return
+}
+
+// Finish records the outcome of carrying out the request.
+func (e *ErasureRequest) Finish(runErr error) {
+	now := time.Now().UTC()
+	e.Status = StatusCompleted
+	e.Error = null.String{}
+	if runErr != nil {
+		e.Status = StatusFailed
+		e.Error = null.StringFrom(runErr.Error())
+	}
+	e.ProcessedAt = null.TimeFrom(now)
+	e.UpdatedAt = now
+	e.UpdatedBy = e.UserId
+}
+
+func (e ErasureRequest) ToResponseFormat() ErasureRequestResponseFormat {
+	return ErasureRequestResponseFormat(e)
+}
+
+func (e ErasureRequest) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.ToResponseFormat())
+}
@@ -0,0 +1,240 @@
+package privacy
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/evermos/boilerplate-go/infras"
+	"github.com/evermos/boilerplate-go/internal/domain/user"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/gofrs/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type PrivacyRepository interface {
+	Create(load ErasureRequest) (err error)
+	Update(load ErasureRequest) (err error)
+	GetByID(id uuid.UUID) (res ErasureRequest, err error)
+	GetPendingByUserID(userId uuid.UUID) (res ErasureRequest, err error)
+	GetAllByUserID(userId uuid.UUID) (res []ErasureRequest, err error)
+	GetAll(orgId, status string, limit, offset int, sort, field string) (res []ErasureRequest, err error)
+	GetDue(now time.Time, limit int) (res []ErasureRequest, err error)
+	Erase(load user.User, request ErasureRequest) (err error)
+}
+
+type PrivacyRepositoryMySQL struct {
+	DB *infras.MySQLConn
+}
+
+func ProvidePrivacyRepositoryMySQL(db *infras.MySQLConn) *PrivacyRepositoryMySQL {
+	return &PrivacyRepositoryMySQL{DB: db}
+}
+
+func (r *PrivacyRepositoryMySQL) Create(load ErasureRequest) (err error) {
+	query := `INSERT INTO erasure_request (id,user_id,status,scheduled_for,processed_at,error,created_at,created_by,updated_at,updated_by)
+	VALUES (:id,:user_id,:status,:scheduled_for,:processed_at,:error,:created_at,:created_by,:updated_at,:updated_by)`
+	_, err = r.DB.Write.NamedExec(query, load)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+func (r *PrivacyRepositoryMySQL) Update(load ErasureRequest) (err error) {
+	return r.DB.WithTransaction(func(db *sqlx.Tx, c chan error) {
+		if err := r.txUpdate(db, load); err != nil {
+			c <- err
+			return
+		}
+		c <- nil
+	})
+}
+
+func (r *PrivacyRepositoryMySQL) txUpdate(tx *sqlx.Tx, load ErasureRequest) (err error) {
+	query := `
+	UPDATE erasure_request
+	SET
+		status = :status,
+		scheduled_for = :scheduled_for,
+		processed_at = :processed_at,
+		error = :error,
+		updated_at = :updated_at,
+		updated_by = :updated_by
+	WHERE id = :id`
+	stmt, err := tx.PrepareNamed(query)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(load)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+func (r *PrivacyRepositoryMySQL) GetByID(id uuid.UUID) (res ErasureRequest, err error) {
+	err = r.DB.Read.Get(&res, "SELECT * FROM erasure_request WHERE id = ?", id.String())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = failure.NotFound("erasure request")
+			return
+		}
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+func (r *PrivacyRepositoryMySQL) GetPendingByUserID(userId uuid.UUID) (res ErasureRequest, err error) {
+	err = r.DB.Read.Get(&res, "SELECT * FROM erasure_request WHERE user_id = ? AND status = ? ORDER BY created_at DESC LIMIT 1", userId.String(), StatusScheduled)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = failure.NotFound("erasure request")
+			return
+		}
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+func (r *PrivacyRepositoryMySQL) GetAllByUserID(userId uuid.UUID) (res []ErasureRequest, err error) {
+	err = r.DB.Read.Select(&res, "SELECT * FROM erasure_request WHERE user_id = ? ORDER BY created_at", userId.String())
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+// GetAll returns the requests of the members of the organization.
+func (r *PrivacyRepositoryMySQL) GetAll(orgId, status string, limit, offset int, sort, field string) (res []ErasureRequest, err error) {
+	query := "SELECT * FROM erasure_request WHERE EXISTS (SELECT 1 FROM organization_member m WHERE m.user_id = erasure_request.user_id AND m.organization_id = ?)"
+	args := []interface{}{orgId}
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY " + field + " " + sort + " LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+	err = r.DB.Read.Select(&res, query, args...)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+// GetDue returns scheduled requests whose retention delay has passed.
+func (r *PrivacyRepositoryMySQL) GetDue(now time.Time, limit int) (res []ErasureRequest, err error) {
+	err = r.DB.Read.Select(&res, "SELECT * FROM erasure_request WHERE status = ? AND scheduled_for <= ? ORDER BY scheduled_for LIMIT ?", StatusScheduled, now, limit)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+// Erase writes the anonymized user, scrubs the user's addresses, empties the
+// carts and completes the request in a single transaction. Addresses are kept
+// as rows because orders reference them; only the country and province are
+// left in place for tax records.
+func (r *PrivacyRepositoryMySQL) Erase(load user.User, request ErasureRequest) (err error) {
+	return r.DB.WithTransaction(func(db *sqlx.Tx, c chan error) {
+		if err := r.txEraseUser(db, load); err != nil {
+			c <- err
+			return
+		}
+		if err := r.txEraseAddresses(db, load); err != nil {
+			c <- err
+			return
+		}
+		if _, err := db.Exec("DELETE FROM cart_item WHERE cart_id IN (SELECT id FROM cart WHERE user_id = ?)", load.UserId.String()); err != nil {
+			logger.ErrorWithStack(err)
+			c <- err
+			return
+		}
+		if _, err := db.Exec("DELETE FROM user_group_member WHERE user_id = ?", load.UserId.String()); err != nil {
+			logger.ErrorWithStack(err)
+			c <- err
+			return
+		}
+		if _, err := db.Exec("DELETE FROM user_preference WHERE user_id = ?", load.UserId.String()); err != nil {
+			logger.ErrorWithStack(err)
+			c <- err
+			return
+		}
+		if err := r.txUpdate(db, request); err != nil {
+			c <- err
+			return
+		}
+		c <- nil
+	})
+}
+
+func (r *PrivacyRepositoryMySQL) txEraseUser(tx *sqlx.Tx, load user.User) (err error) {
+	query := `
+	UPDATE user
+	SET
+		email = :email,
+		username = :username,
+		name = :name,
+		password = :password,
+		phone_number = :phone_number,
+		locale = :locale,
+		timezone = :timezone,
+		email_verified_at = :email_verified_at,
+		email_verification_token = :email_verification_token,
+		avatar_key = :avatar_key,
+		avatar_thumbnail_key = :avatar_thumbnail_key,
+		avatar_url = :avatar_url,
+		avatar_thumbnail_url = :avatar_thumbnail_url,
+		updated_at = :updated_at,
+		updated_by = :updated_by,
+		deleted_at = :deleted_at,
+		deleted_by = :deleted_by
+	WHERE id = :id`
+	stmt, err := tx.PrepareNamed(query)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(load)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+func (r *PrivacyRepositoryMySQL) txEraseAddresses(tx *sqlx.Tx, load user.User) (err error) {
+	query := `
+	UPDATE address
+	SET
+		label = 'erased',
+		recipient_name = 'Erased User',
+		phone_number = '',
+		line1 = '',
+		line2 = NULL,
+		city = '',
+		postal_code = '',
+		is_default_shipping = FALSE,
+		is_default_billing = FALSE,
+		updated_at = ?,
+		updated_by = ?,
+		deleted_at = COALESCE(deleted_at, ?),
+		deleted_by = COALESCE(deleted_by, ?)
+	WHERE user_id = ?`
+	_, err = tx.Exec(query, load.Updated_at, load.Updated_by.String(), load.Updated_at, load.Updated_by.String(), load.UserId.String())
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
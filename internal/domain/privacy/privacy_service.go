@@ -0,0 +1,254 @@
+package privacy
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/evermos/boilerplate-go/configs"
+	"github.com/evermos/boilerplate-go/internal/domain/address"
+	"github.com/evermos/boilerplate-go/internal/domain/cart"
+	"github.com/evermos/boilerplate-go/internal/domain/group"
+	"github.com/evermos/boilerplate-go/internal/domain/order"
+	"github.com/evermos/boilerplate-go/internal/domain/organization"
+	"github.com/evermos/boilerplate-go/internal/domain/preference"
+	"github.com/evermos/boilerplate-go/internal/domain/user"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/evermos/boilerplate-go/shared/roles"
+	"github.com/evermos/boilerplate-go/shared/storage"
+	"github.com/gofrs/uuid"
+)
+
+// exportPageSize is the number of orders fetched at a time while exporting.
+const exportPageSize = 100
+
+// erasureBatchSize is the number of due requests handled per run.
+const erasureBatchSize = 50
+
+type PrivacyService interface {
+	Export(userId uuid.UUID) (res DataExport, err error)
+	RequestErasure(userId, requesterId uuid.UUID) (res ErasureRequest, err error)
+	CancelErasure(userId, requesterId uuid.UUID) (res ErasureRequest, err error)
+	GetErasuresByUserID(userId uuid.UUID) (res []ErasureRequest, err error)
+	GetAllErasures(orgId uuid.UUID, status string, limit, offset int, sort, field string) (res []ErasureRequest, err error)
+	ProcessDueErasures() (err error)
+}
+
+type PrivacyServiceImpl struct {
+	Repo                PrivacyRepository
+	Config              *configs.Config
+	UserService         user.UserService
+	OrganizationService organization.OrganizationService
+	GroupService        group.GroupService
+	PreferenceService   preference.PreferenceService
+	AddressService      address.AddressService
+	CartService         cart.CartService
+	OrderService        order.OrderService
+	Storage             storage.Storage
+}
+
+func ProvidePrivacyServiceImpl(repo PrivacyRepository, config *configs.Config, userService user.UserService, organizationService organization.OrganizationService, groupService group.GroupService, preferenceService preference.PreferenceService, addressService address.AddressService, cartService cart.CartService, orderService order.OrderService, storage storage.Storage) *PrivacyServiceImpl {
+	return &PrivacyServiceImpl{
+		Repo:                repo,
+		Config:              config,
+		UserService:         userService,
+		OrganizationService: organizationService,
+		GroupService:        groupService,
+		PreferenceService:   preferenceService,
+		AddressService:      addressService,
+		CartService:         cartService,
+		OrderService:        orderService,
+		Storage:             storage,
+	}
+}
+
+// Export collects everything stored about a user into a single document.
+func (s *PrivacyServiceImpl) Export(userId uuid.UUID) (res DataExport, err error) {
+	profile, err := s.UserService.GetByUserID(userId)
+	if err != nil {
+		return
+	}
+	memberships, err := s.OrganizationService.GetMembershipsByUserID(userId)
+	if err != nil {
+		return
+	}
+	groups := []group.Group{}
+	for _, m := range memberships {
+		orgGroups, err := s.GroupService.GetByUserID(m.OrganizationId, userId)
+		if err != nil {
+			return res, err
+		}
+		groups = append(groups, orgGroups...)
+	}
+	preferences, err := s.PreferenceService.Get(userId)
+	if err != nil {
+		return
+	}
+	addresses, err := s.AddressService.GetAllByUserID(userId)
+	if err != nil {
+		return
+	}
+	carts, err := s.CartService.GetAllByUserID(userId)
+	if err != nil {
+		return
+	}
+	orders, err := s.exportOrders(userId)
+	if err != nil {
+		return
+	}
+	erasures, err := s.Repo.GetAllByUserID(userId)
+	if err != nil {
+		return
+	}
+
+	res = DataExport{
+		GeneratedAt:     time.Now().UTC(),
+		Profile:         profile.ToResponseFormat(),
+		Organizations:   []organization.MemberResponseFormat{},
+		Groups:          []group.GroupResponseFormat{},
+		Preferences:     preferences,
+		Addresses:       []address.AddressResponseFormat{},
+		Carts:           []cart.CartResponseFormat{},
+		Orders:          orders,
+		ErasureRequests: []ErasureRequestResponseFormat{},
+	}
+	for _, m := range memberships {
+		res.Organizations = append(res.Organizations, m.ToResponseFormat())
+	}
+	for _, g := range groups {
+		res.Groups = append(res.Groups, g.ToResponseFormat())
+	}
+	for _, a := range addresses {
+		res.Addresses = append(res.Addresses, a.ToResponseFormat())
+	}
+	for _, c := range carts {
+		res.Carts = append(res.Carts, c.ToResponseFormat())
+	}
+	for _, e := range erasures {
+		res.ErasureRequests = append(res.ErasureRequests, e.ToResponseFormat())
+	}
+	return
+}
+
+func (s *PrivacyServiceImpl) exportOrders(userId uuid.UUID) (res []OrderExport, err error) {
+	role := roles.GetStringFromRole(roles.Trainee)
+	for offset := 0; ; offset += exportPageSize {
+		orders, err := s.OrderService.GetAll(uuid.Nil, exportPageSize, offset, "ASC", "created_at", "", userId, role, false)
+		if err != nil {
+			return res, err
+		}
+		for _, o := range orders {
+			export := OrderExport{Order: o.ToResponseFormat(), Items: []order.OrderItemResponseFormat{}}
+			for _, item := range o.OrderItems {
+				export.Items = append(export.Items, item.ToResponseFormat())
+			}
+			res = append(res, export)
+		}
+		if len(orders) < exportPageSize {
+			return res, nil
+		}
+	}
+}
+
+// RequestErasure schedules the user's data to be erased once the configured
+// retention delay has passed. Until then the request can be cancelled.
+func (s *PrivacyServiceImpl) RequestErasure(userId, requesterId uuid.UUID) (res ErasureRequest, err error) {
+	_, err = s.UserService.GetByUserID(userId)
+	if err != nil {
+		return
+	}
+	_, err = s.Repo.GetPendingByUserID(userId)
+	if err == nil {
+		err = failure.Conflict("create", "erasure request", "already scheduled")
+		return
+	}
+	if failure.GetCode(err) != http.StatusNotFound {
+		return
+	}
+
+	delay := time.Duration(s.Config.Privacy.ErasureDelayHours) * time.Hour
+	res, err = res.NewFromUser(userId, requesterId, delay)
+	if err != nil {
+		return
+	}
+	err = s.Repo.Create(res)
+	if err != nil {
+		return
+	}
+	return
+}
+
+func (s *PrivacyServiceImpl) CancelErasure(userId, requesterId uuid.UUID) (res ErasureRequest, err error) {
+	res, err = s.Repo.GetPendingByUserID(userId)
+	if err != nil {
+		return
+	}
+	err = res.Cancel(requesterId)
+	if err != nil {
+		return
+	}
+	err = s.Repo.Update(res)
+	if err != nil {
+		return
+	}
+	return
+}
+
+func (s *PrivacyServiceImpl) GetErasuresByUserID(userId uuid.UUID) (res []ErasureRequest, err error) {
+	return s.Repo.GetAllByUserID(userId)
+}
+
+func (s *PrivacyServiceImpl) GetAllErasures(orgId uuid.UUID, status string, limit, offset int, sort, field string) (res []ErasureRequest, err error) {
+	return s.Repo.GetAll(orgId.String(), status, limit, offset, sort, field)
+}
+
+// ProcessDueErasures carries out every request whose retention delay has
+// passed. A request that fails is marked as failed with the reason so an
+// admin can look into it; the remaining requests are still processed.
+func (s *PrivacyServiceImpl) ProcessDueErasures() (err error) {
+	due, err := s.Repo.GetDue(time.Now().UTC(), erasureBatchSize)
+	if err != nil {
+		return
+	}
+	for _, request := range due {
+		runErr := s.erase(request)
+		if runErr == nil {
+			continue
+		}
+		logger.ErrorWithStack(runErr)
+		request.Finish(runErr)
+		if err := s.Repo.Update(request); err != nil {
+			return err
+		}
+	}
+	return
+}
+
+func (s *PrivacyServiceImpl) erase(request ErasureRequest) (err error) {
+	target, err := s.UserService.GetByUserID(request.UserId)
+	if err != nil {
+		return
+	}
+	keys := []string{target.AvatarKey.String, target.ThumbnailKey.String}
+	err = target.Anonymize(request.UserId)
+	if err != nil {
+		return
+	}
+	request.Finish(nil)
+	err = s.Repo.Erase(target, request)
+	if err != nil {
+		return
+	}
+
+	// Blobs are removed after the commit so a failed erasure never leaves a
+	// profile pointing at missing files. A leftover blob is only logged.
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if err := s.Storage.Delete(key); err != nil {
+			logger.ErrorWithStack(err)
+		}
+	}
+	return nil
+}
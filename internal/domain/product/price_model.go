@@ -0,0 +1,284 @@
+package product
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/nuuid"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
+)
+
+// Statuses of price schedules. A schedule is active between its start and
+// its end; schedules without an end change the price for good and end as
+// soon as they start.
+const (
+	ScheduleScheduled = "scheduled"
+	ScheduleActive    = "active"
+	ScheduleEnded     = "ended"
+	ScheduleCancelled = "cancelled"
+)
+
+// PriceChange is an entry in the price history of a product or one of its
+// variants. Changes are recorded in the transaction that writes the price,
+// and open carts are repriced from them once committed.
+type PriceChange struct {
+	Id             uuid.UUID   `db:"id" validate:"required"`
+	OrganizationId uuid.UUID   `db:"organization_id" validate:"required"`
+	ProductId      uuid.UUID   `db:"product_id" validate:"required"`
+	VariantId      nuuid.NUUID `db:"variant_id"`
+	Price          float64     `db:"price" validate:"required,gt=0"`
+	CompareAtPrice null.Float  `db:"compare_at_price"`
+	// PreviousPrice is null for the price the product or variant was
+	// created with.
+	PreviousPrice null.Float `db:"previous_price"`
+	// ScheduleId is the schedule that made the change, if any.
+	ScheduleId nuuid.NUUID `db:"schedule_id"`
+	ChangedAt  time.Time   `db:"changed_at" validate:"required"`
+	ChangedBy  uuid.UUID   `db:"changed_by" validate:"required"`
+	// RepricedAt is set once open carts have been brought to the price.
+	RepricedAt null.Time `db:"repriced_at"`
+}
+
+type PriceChangeResponseFormat struct {
+	Id             uuid.UUID   `json:"id"`
+	OrganizationId uuid.UUID   `json:"organizationId"`
+	ProductId      uuid.UUID   `json:"productId"`
+	VariantId      nuuid.NUUID `json:"variantId"`
+	Price          float64     `json:"price"`
+	CompareAtPrice null.Float  `json:"compareAtPrice"`
+	PreviousPrice  null.Float  `json:"previousPrice"`
+	ScheduleId     nuuid.NUUID `json:"scheduleId"`
+	ChangedAt      time.Time   `json:"changedAt"`
+	ChangedBy      uuid.UUID   `json:"changedBy"`
+	RepricedAt     null.Time   `json:"repricedAt"`
+}
+
+// PriceSchedule changes the price of a product at StartsAt and, when it has
+// an end, restores the previous price at EndsAt. Schedules of a product do
+// not overlap.
+type PriceSchedule struct {
+	Id             uuid.UUID  `db:"id" validate:"required"`
+	OrganizationId uuid.UUID  `db:"organization_id" validate:"required"`
+	ProductId      uuid.UUID  `db:"product_id" validate:"required"`
+	Price          float64    `db:"price" validate:"required,gt=0"`
+	CompareAtPrice null.Float `db:"compare_at_price"`
+	StartsAt       time.Time  `db:"starts_at" validate:"required"`
+	EndsAt         null.Time  `db:"ends_at"`
+	// Status is one of ScheduleScheduled, ScheduleActive, ScheduleEnded and
+	// ScheduleCancelled.
+	Status string `db:"status" validate:"oneof=scheduled active ended cancelled"`
+	// PreviousPrice and PreviousCompareAtPrice are the product's when the
+	// schedule started.
+	PreviousPrice          null.Float `db:"previous_price"`
+	PreviousCompareAtPrice null.Float `db:"previous_compare_at_price"`
+	CreatedAt              time.Time  `db:"created_at" validate:"required"`
+	CreatedBy              uuid.UUID  `db:"created_by" validate:"required"`
+	UpdatedAt              time.Time  `db:"updated_at" validate:"required"`
+	UpdatedBy              uuid.UUID  `db:"updated_by" validate:"required"`
+}
+
+type PriceScheduleResponseFormat struct {
+	Id                     uuid.UUID  `json:"id"`
+	OrganizationId         uuid.UUID  `json:"organizationId"`
+	ProductId              uuid.UUID  `json:"productId"`
+	Price                  float64    `json:"price"`
+	CompareAtPrice         null.Float `json:"compareAtPrice"`
+	StartsAt               time.Time  `json:"startsAt"`
+	EndsAt                 null.Time  `json:"endsAt"`
+	Status                 string     `json:"status"`
+	PreviousPrice          null.Float `json:"previousPrice"`
+	PreviousCompareAtPrice null.Float `json:"previousCompareAtPrice"`
+	CreatedAt              time.Time  `json:"createdAt"`
+	CreatedBy              uuid.UUID  `json:"createdBy"`
+	UpdatedAt              time.Time  `json:"updatedAt"`
+	UpdatedBy              uuid.UUID  `json:"updatedBy"`
+}
+
+// PriceSchedulePayload schedules a price. Without EndsAt the price stays
+// until it is changed again.
+type PriceSchedulePayload struct {
+	Price          float64    `json:"price" validate:"required,gt=0"`
+	CompareAtPrice null.Float `json:"compareAtPrice"`
+	StartsAt       time.Time  `json:"startsAt" validate:"required"`
+	EndsAt         null.Time  `json:"endsAt"`
+}
+
+// NewPriceChange records the product's price, or the variant's when
+// variantId is valid, changing from previous.
+func NewPriceChange(orgId, productId uuid.UUID, variantId nuuid.NUUID, price float64, compareAtPrice, previous null.Float, scheduleId nuuid.NUUID, userId uuid.UUID) (res PriceChange, err error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return
+	}
+	res = PriceChange{
+		Id:             id,
+		OrganizationId: orgId,
+		ProductId:      productId,
+		VariantId:      variantId,
+		Price:          price,
+		CompareAtPrice: compareAtPrice,
+		PreviousPrice:  previous,
+		ScheduleId:     scheduleId,
+		ChangedAt:      time.Now().UTC(),
+		ChangedBy:      userId,
+	}
+	err = res.Validate()
+	return
+}
+
+func (c *PriceChange) Validate() error {
+	validator := shared.GetValidator()
+	return validator.Struct(c)
+}
+
+func (c PriceChange) ToResponseFormat() PriceChangeResponseFormat {
+	return PriceChangeResponseFormat(c)
+}
+
+func (c PriceChange) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.ToResponseFormat())
+}
+
+func (s PriceSchedule) NewFromPayload(load PriceSchedulePayload, prod Product, userId uuid.UUID, now time.Time) (res PriceSchedule, err error) {
+	if !load.StartsAt.After(now) {
+		err = failure.BadRequestFromString("startsAt must be in the future")
+		return
+	}
+	if load.EndsAt.Valid && !load.EndsAt.Time.After(load.StartsAt) {
+		err = failure.BadRequestFromString("endsAt must be after startsAt")
+		return
+	}
+	err = checkCompareAtPrice(load.Price, load.CompareAtPrice)
+	if err != nil {
+		return
+	}
+	id, err := uuid.NewV4()
+	if err != nil {
+		return
+	}
+	res = PriceSchedule{
+		Id:             id,
+		OrganizationId: prod.OrganizationId,
+		ProductId:      prod.Id,
+		Price:          load.Price,
+		CompareAtPrice: load.CompareAtPrice,
+		StartsAt:       load.StartsAt.UTC(),
+		EndsAt:         utc(load.EndsAt),
+		Status:         ScheduleScheduled,
+		CreatedAt:      now,
+		CreatedBy:      userId,
+		UpdatedAt:      now,
+		UpdatedBy:      userId,
+	}
+	err = res.Validate()
+	return
+}
+
+// IsPending reports whether the schedule is yet to start or to end.
+func (s *PriceSchedule) IsPending() bool {
+	return s.Status == ScheduleScheduled || s.Status == ScheduleActive
+}
+
+// Overlaps reports whether the windows of both schedules overlap. Windows
+// without an end run forever.
+func (s *PriceSchedule) Overlaps(other PriceSchedule) bool {
+	startsBeforeOtherEnds := !other.EndsAt.Valid || s.StartsAt.Before(other.EndsAt.Time)
+	endsAfterOtherStarts := !s.EndsAt.Valid || s.EndsAt.Time.After(other.StartsAt)
+	return startsBeforeOtherEnds && endsAfterOtherStarts
+}
+
+// IsDue reports whether the schedule is to start or to end at now.
+func (s *PriceSchedule) IsDue(now time.Time) bool {
+	switch s.Status {
+	case ScheduleScheduled:
+		return !s.StartsAt.After(now)
+	case ScheduleActive:
+		return s.EndsAt.Valid && !s.EndsAt.Time.After(now)
+	}
+	return false
+}
+
+// Start gives prod the price of the schedule, remembering the one it had.
+func (s *PriceSchedule) Start(prod *Product, now time.Time) (err error) {
+	if s.Status != ScheduleScheduled {
+		err = failure.Conflict("start", "price schedule", "not scheduled")
+		return
+	}
+	s.PreviousPrice = null.FloatFrom(prod.Price)
+	s.PreviousCompareAtPrice = prod.CompareAtPrice
+	s.Status = ScheduleActive
+	if !s.EndsAt.Valid {
+		s.Status = ScheduleEnded
+	}
+	s.UpdatedAt = now
+	prod.Price = s.Price
+	prod.CompareAtPrice = s.CompareAtPrice
+	prod.Updated_at = now
+	prod.Updated_by = s.UpdatedBy
+	err = s.Validate()
+	if err != nil {
+		return
+	}
+	err = prod.Validate()
+	return
+}
+
+// End restores the price prod had before the schedule started. A price
+// changed by hand in the meantime is kept.
+func (s *PriceSchedule) End(prod *Product, now time.Time) (err error) {
+	if s.Status != ScheduleActive {
+		err = failure.Conflict("end", "price schedule", "not active")
+		return
+	}
+	s.Status = ScheduleEnded
+	s.UpdatedAt = now
+	if prod.Price == s.Price && prod.CompareAtPrice == s.CompareAtPrice && s.PreviousPrice.Valid {
+		prod.Price = s.PreviousPrice.Float64
+		prod.CompareAtPrice = s.PreviousCompareAtPrice
+		prod.Updated_at = now
+		prod.Updated_by = s.UpdatedBy
+	}
+	err = s.Validate()
+	if err != nil {
+		return
+	}
+	err = prod.Validate()
+	return
+}
+
+// Cancel drops a schedule that has not started.
+func (s *PriceSchedule) Cancel(userId uuid.UUID) (err error) {
+	if s.Status != ScheduleScheduled {
+		err = failure.Conflict("cancel", "price schedule", "only schedules that have not started can be cancelled")
+		return
+	}
+	s.Status = ScheduleCancelled
+	s.UpdatedAt = time.Now().UTC()
+	s.UpdatedBy = userId
+	err = s.Validate()
+	return
+}
+
+func (s *PriceSchedule) Validate() error {
+	validator := shared.GetValidator()
+	return validator.Struct(s)
+}
+
+func (s PriceSchedule) ToResponseFormat() PriceScheduleResponseFormat {
+	return PriceScheduleResponseFormat(s)
+}
+
+func (s PriceSchedule) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.ToResponseFormat())
+}
+
+// checkCompareAtPrice checks that compareAtPrice, if any, is above price.
+func checkCompareAtPrice(price float64, compareAtPrice null.Float) error {
+	if compareAtPrice.Valid && compareAtPrice.Float64 <= price {
+		return failure.BadRequestFromString("compareAtPrice must be greater than price")
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package product
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/guregu/null"
+)
+
+// Row is the flat representation of a product used by bulk imports and
+// exports, so an export can be edited and imported again. Products are
+// matched by Sku.
+type Row struct {
+	Sku             string        `json:"sku" validate:"required,max=64"`
+	Name            string        `json:"name"`
+	Description     null.String   `json:"description"`
+	Stock           int           `json:"stock"`
+	Price           float64       `json:"price"`
+	CompareAtPrice  null.Float    `json:"compareAtPrice"`
+	Slug            string        `json:"slug"`
+	SeoTitle        null.String   `json:"seoTitle"`
+	MetaDescription null.String   `json:"metaDescription"`
+	Status          string        `json:"status"`
+	PublishAt       null.Time     `json:"publishAt"`
+	OrderPolicy     string        `json:"orderPolicy"`
+	ExpectedAt      null.Time     `json:"expectedAt"`
+	BackorderLimit  null.Int      `json:"backorderLimit"`
+	Attributes      AttributeList `json:"attributes"`
+}
+
+func (p Product) ToRow() Row {
+	return Row{
+		Sku:             p.Sku.String,
+		Name:            p.Name,
+		Description:     p.Description,
+		Stock:           p.Stock,
+		Price:           p.Price,
+		CompareAtPrice:  p.CompareAtPrice,
+		Slug:            p.Slug,
+		SeoTitle:        p.SeoTitle,
+		MetaDescription: p.MetaDescription,
+		Status:          p.Status,
+		PublishAt:       p.PublishAt,
+		OrderPolicy:     p.OrderPolicy,
+		ExpectedAt:      p.ExpectedAt,
+		BackorderLimit:  p.BackorderLimit,
+		Attributes:      AttributeList(p.Attributes),
+	}
+}
+
+// ToPayload returns the row as a replacement of the whole product.
+func (r Row) ToPayload() ProductPayload {
+	return ProductPayload{
+		Name:            r.Name,
+		Sku:             r.Sku,
+		Description:     r.Description,
+		Stock:           r.Stock,
+		Price:           r.Price,
+		CompareAtPrice:  r.CompareAtPrice,
+		Slug:            r.Slug,
+		SeoTitle:        r.SeoTitle,
+		MetaDescription: r.MetaDescription,
+		Status:          r.Status,
+		PublishAt:       r.PublishAt,
+		OrderPolicy:     r.OrderPolicy,
+		ExpectedAt:      r.ExpectedAt,
+		BackorderLimit:  r.BackorderLimit,
+		Attributes:      r.Attributes,
+	}
+}
+
+// AttributeList holds the attributes of a row. In CSV they are written as
+// name=value pairs separated by semicolons, e.g. "brand=acme;material=linen";
+// in NDJSON they are an object, though the CSV form is accepted as well.
+type AttributeList map[string]string
+
+func (l AttributeList) String() string {
+	names := make([]string, 0, len(l))
+	for name := range l {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, name+"="+l[name])
+	}
+	return strings.Join(pairs, ";")
+}
+
+func (l *AttributeList) UnmarshalText(text []byte) error {
+	res := AttributeList{}
+	for _, pair := range strings.Split(string(text), ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("attribute %q must be written as name=value", pair)
+		}
+		res[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	*l = res
+	return nil
+}
+
+func (l *AttributeList) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		return l.UnmarshalText([]byte(text))
+	}
+	var res map[string]string
+	if err := json.Unmarshal(data, &res); err != nil {
+		return err
+	}
+	*l = res
+	return nil
+}
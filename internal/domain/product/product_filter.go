@@ -0,0 +1,194 @@
+package product
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
+)
+
+// SortFields are the columns products can be sorted by.
+var SortFields = []string{"id", "name", "stock", "price", "created_at", "updated_at"}
+
+// maxAttributeFilters bounds how many attributes a listing can filter on.
+const maxAttributeFilters = 10
+
+// Filter narrows down product listings.
+type Filter struct {
+	// OrganizationId limits the results to the organization of the caller's
+	// token.
+	OrganizationId uuid.UUID
+	// Query is a search query, see ParseQuery. Results are then ordered by
+	// relevance.
+	Query string
+	// CategoryIds matches products assigned to any of the categories.
+	CategoryIds []uuid.UUID
+	MinPrice    null.Float
+	MaxPrice    null.Float
+	// InStock matches products that can be bought right now: with stock
+	// left, or with a variant that has stock left.
+	InStock bool
+	// Attributes matches products that have, for every attribute, one of the
+	// listed values.
+	Attributes map[string][]string
+	// Status matches products in the publication state.
+	Status string
+	// PublishedOnly leaves out products that are not live, see
+	// Product.IsPublished. Customers only ever see live products.
+	PublishedOnly bool
+}
+
+func (f Filter) Validate() error {
+	if f.MinPrice.Valid && f.MinPrice.Float64 < 0 || f.MaxPrice.Valid && f.MaxPrice.Float64 < 0 {
+		return failure.BadRequestFromString("prices cannot be negative")
+	}
+	if f.MinPrice.Valid && f.MaxPrice.Valid && f.MaxPrice.Float64 < f.MinPrice.Float64 {
+		return failure.BadRequestFromString("max_price is below min_price")
+	}
+	if f.Status != "" && f.Status != StatusDraft && f.Status != StatusPublished && f.Status != StatusArchived {
+		return failure.BadRequestFromString("unknown status " + f.Status)
+	}
+	if len(f.Attributes) > maxAttributeFilters {
+		return failure.BadRequestFromString("too many attribute filters")
+	}
+	return nil
+}
+
+// Facets summarizes the products matching a filter so a storefront can show
+// how many results each refinement gives. Each facet ignores its own filter:
+// the price range and in-stock count are computed without the price and
+// stock filters, categories without the category filter, and the values of
+// an attribute without the filter on that attribute.
+type Facets struct {
+	Total      int                     `json:"total"`
+	Price      PriceFacet              `json:"price"`
+	InStock    int                     `json:"inStock"`
+	Categories []CategoryFacet         `json:"categories"`
+	Attributes map[string][]ValueFacet `json:"attributes"`
+}
+
+type PriceFacet struct {
+	Min null.Float `json:"min" db:"min_price"`
+	Max null.Float `json:"max" db:"max_price"`
+}
+
+type CategoryFacet struct {
+	CategoryId uuid.UUID `json:"categoryId" db:"category_id"`
+	Count      int       `json:"count" db:"count"`
+}
+
+type ValueFacet struct {
+	Value string `json:"value" db:"value"`
+	Count int    `json:"count" db:"count"`
+}
+
+// ProductAttribute is a single attribute of a product, as stored.
+type ProductAttribute struct {
+	ProductId uuid.UUID `db:"product_id"`
+	Name      string    `db:"name"`
+	Value     string    `db:"value"`
+}
+
+// conditions renders the filter as SQL conditions on the product table
+// aliased p. IN clauses are left for sqlx.In to expand.
+func (f Filter) conditions() (query string, args []interface{}, err error) {
+	conditions := []string{"p.organization_id = ?", "p.deleted_at IS NULL"}
+	args = []interface{}{f.OrganizationId.String()}
+	if f.Query != "" {
+		q, err := ParseQuery(f.Query)
+		if err != nil {
+			return query, args, err
+		}
+		conditions = append(conditions, "MATCH(p.name, p.description) AGAINST (? IN BOOLEAN MODE)")
+		args = append(args, q.BooleanMode())
+	}
+	if len(f.CategoryIds) > 0 {
+		ids := make([]string, 0, len(f.CategoryIds))
+		for _, id := range f.CategoryIds {
+			ids = append(ids, id.String())
+		}
+		conditions = append(conditions, "p.id IN (SELECT product_id FROM product_category WHERE category_id IN (?))")
+		args = append(args, ids)
+	}
+	if f.MinPrice.Valid {
+		conditions = append(conditions, "p.price >= ?")
+		args = append(args, f.MinPrice.Float64)
+	}
+	if f.MaxPrice.Valid {
+		conditions = append(conditions, "p.price <= ?")
+		args = append(args, f.MaxPrice.Float64)
+	}
+	if f.InStock {
+		conditions = append(conditions, inStockCondition)
+	}
+	if f.Status != "" {
+		conditions = append(conditions, "p.status = ?")
+		args = append(args, f.Status)
+	}
+	if f.PublishedOnly {
+		conditions = append(conditions, "p.status = ? AND p.publish_at <= ?")
+		args = append(args, StatusPublished, time.Now().UTC())
+	}
+	for _, name := range f.attributeNames() {
+		conditions = append(conditions, "p.id IN (SELECT product_id FROM product_attribute WHERE name = ? AND value IN (?))")
+		args = append(args, name, f.Attributes[name])
+	}
+	query = strings.Join(conditions, " AND ")
+	return
+}
+
+// inStockCondition matches products with stock left. Products with variants
+// are in stock when one of their variants is.
+const inStockCondition = `(EXISTS (SELECT 1 FROM product_variant v WHERE v.product_id = p.id AND v.deleted_at IS NULL AND v.stock > 0)
+	OR (p.stock > 0 AND NOT EXISTS (SELECT 1 FROM product_variant v WHERE v.product_id = p.id AND v.deleted_at IS NULL)))`
+
+// matches applies the price, stock and attribute filters in memory. Variants
+// are not known here, so the product's own stock is used.
+func (f Filter) matches(prod Product) bool {
+	if f.MinPrice.Valid && prod.Price < f.MinPrice.Float64 || f.MaxPrice.Valid && prod.Price > f.MaxPrice.Float64 {
+		return false
+	}
+	if f.InStock && prod.Stock <= 0 {
+		return false
+	}
+	if f.Status != "" && prod.Status != f.Status || f.PublishedOnly && !prod.IsPublished(time.Now().UTC()) {
+		return false
+	}
+	for name, values := range f.Attributes {
+		found := false
+		for _, value := range values {
+			if prod.Attributes[name] == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (f Filter) attributeNames() (res []string) {
+	for name := range f.Attributes {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return
+}
+
+// withoutAttribute returns a copy of the filter that no longer filters on
+// the attribute.
+func (f Filter) withoutAttribute(name string) Filter {
+	attributes := map[string][]string{}
+	for other, values := range f.Attributes {
+		if other != name {
+			attributes[other] = values
+		}
+	}
+	f.Attributes = attributes
+	return f
+}
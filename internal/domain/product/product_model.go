@@ -2,19 +2,74 @@ package product
 
 import (
 	"encoding/json"
+	"strings"
 	"time"
 
 	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/failure"
 	"github.com/evermos/boilerplate-go/shared/nuuid"
 	"github.com/gofrs/uuid"
 	"github.com/guregu/null"
 )
 
+// Publication states of a product. Only published products are shown to
+// customers and can be added to carts.
+const (
+	StatusDraft     = "draft"
+	StatusPublished = "published"
+	StatusArchived  = "archived"
+)
+
+// Order policies, which decide whether a product can be ordered beyond its
+// stock. Items ordered beyond stock wait for it and are taken out of stock
+// as it arrives, oldest orders first.
+const (
+	// OrderPolicyStock sells only what is in stock.
+	OrderPolicyStock = "stock"
+	// OrderPolicyBackorder takes orders for a product that ran out.
+	OrderPolicyBackorder = "backorder"
+	// OrderPolicyPreorder takes orders for a product that is yet to be
+	// released, expected at ExpectedAt.
+	OrderPolicyPreorder = "preorder"
+)
+
 type Product struct {
-	Id         uuid.UUID   `db:"id" validate:"required"`
-	Name       string      `db:"name" validate:"required"`
-	Stock      int         `db:"stock" validate:"required"`
-	Price      float64     `db:"price" validate:"required"`
+	Id             uuid.UUID   `db:"id" validate:"required"`
+	OrganizationId uuid.UUID   `db:"organization_id" validate:"required"`
+	Name           string      `db:"name" validate:"required,max=255"`
+	Description    null.String `db:"description" validate:"omitempty,max=5000"`
+	Stock          int         `db:"stock" validate:"min=0"`
+	Price          float64     `db:"price" validate:"required,gt=0"`
+	// CompareAtPrice is the price shown struck through next to Price, such
+	// as the regular price during a sale. It is greater than Price.
+	CompareAtPrice null.Float `db:"compare_at_price"`
+	// Sku is the merchant's own code for the product, unique within the
+	// organization. Imports match products by it.
+	Sku null.String `db:"sku" validate:"omitempty,max=64"`
+	// Slug identifies the product in URLs. It is unique within the
+	// organization and generated from the name unless given.
+	Slug            string      `db:"slug" validate:"required,slug,max=255"`
+	SeoTitle        null.String `db:"seo_title" validate:"omitempty,max=255"`
+	MetaDescription null.String `db:"meta_description" validate:"omitempty,max=500"`
+	// Status is one of StatusDraft, StatusPublished and StatusArchived. A
+	// published product with PublishAt in the future is scheduled and goes
+	// live at that time.
+	Status    string    `db:"status" validate:"oneof=draft published archived"`
+	PublishAt null.Time `db:"publish_at"`
+	// OrderPolicy is one of OrderPolicyStock, OrderPolicyBackorder and
+	// OrderPolicyPreorder.
+	OrderPolicy string `db:"order_policy" validate:"oneof=stock backorder preorder"`
+	// ExpectedAt is when stock is expected for backorders and pre-orders;
+	// pre-orders require it.
+	ExpectedAt null.Time `db:"expected_at"`
+	// BackorderLimit caps the units of the product waiting for stock at any
+	// time. Without it there is no cap.
+	BackorderLimit null.Int `db:"backorder_limit"`
+	// Attributes are free-form properties such as brand or material, keyed
+	// by a slug.
+	Attributes map[string]string `db:"-" validate:"max=20,dive,keys,slug,max=50,endkeys,required,max=100"`
+	// Images is the product's gallery, managed by the media package.
+	Images     []Image     `db:"-"`
 	Created_at time.Time   `db:"created_at" validate:"required"`
 	Updated_at time.Time   `db:"updated_at" validate:"required"`
 	Deleted_at null.Time   `db:"deleted_at"`
@@ -24,25 +79,107 @@ type Product struct {
 }
 
 type ProductResponseFormat struct {
-	Id         uuid.UUID   `json:"id" validate:"true"`
-	Name       string      `json:"name" validate:"true"`
-	Stock      int         `json:"stock" validate:"true"`
-	Price      float64     `json:"price" validate:"true"`
-	Created_at time.Time   `json:"createdAt" validate:"required"`
-	Updated_at time.Time   `json:"updatedAt" validate:"required"`
-	Deleted_at null.Time   `json:"deletedAt,omitempty"`
-	Created_by uuid.UUID   `json:"createdBy"`
-	Updated_by uuid.UUID   `json:"updatedBy"`
-	Deleted_by nuuid.NUUID `json:"deletedBy,omitempty"`
+	Id              uuid.UUID         `json:"id" validate:"true"`
+	OrganizationId  uuid.UUID         `json:"organizationId"`
+	Name            string            `json:"name" validate:"true"`
+	Description     null.String       `json:"description"`
+	Stock           int               `json:"stock" validate:"true"`
+	Price           float64           `json:"price" validate:"true"`
+	CompareAtPrice  null.Float        `json:"compareAtPrice"`
+	Sku             null.String       `json:"sku"`
+	Slug            string            `json:"slug"`
+	SeoTitle        null.String       `json:"seoTitle"`
+	MetaDescription null.String       `json:"metaDescription"`
+	Status          string            `json:"status"`
+	PublishAt       null.Time         `json:"publishAt"`
+	OrderPolicy     string            `json:"orderPolicy"`
+	ExpectedAt      null.Time         `json:"expectedAt"`
+	BackorderLimit  null.Int          `json:"backorderLimit"`
+	Attributes      map[string]string `json:"attributes"`
+	Images          []Image           `json:"images"`
+	Created_at      time.Time         `json:"createdAt" validate:"required"`
+	Updated_at      time.Time         `json:"updatedAt" validate:"required"`
+	Deleted_at      null.Time         `json:"deletedAt,omitempty"`
+	Created_by      uuid.UUID         `json:"createdBy"`
+	Updated_by      uuid.UUID         `json:"updatedBy"`
+	Deleted_by      nuuid.NUUID       `json:"deletedBy,omitempty"`
+}
+
+// Image is a product image as shown with the product: the original and the
+// URLs of its resized renditions by size name.
+type Image struct {
+	Id        uuid.UUID         `db:"id" json:"id"`
+	ProductId uuid.UUID         `db:"product_id" json:"-"`
+	Url       string            `db:"url" json:"url"`
+	AltText   null.String       `db:"alt_text" json:"altText"`
+	Primary   bool              `db:"is_primary" json:"primary"`
+	Sizes     map[string]string `db:"-" json:"sizes"`
+}
+
+// ImageSize is the URL of a resized rendition of an image, as stored.
+type ImageSize struct {
+	ImageId uuid.UUID `db:"image_id"`
+	Size    string    `db:"size"`
+	Url     string    `db:"url"`
 }
 
 type ProductPayload struct {
-	Name  string  `json:"name"`
-	Stock int     `json:"stock"`
-	Price float64 `json:"price"`
+	Name        string      `json:"name" validate:"required,max=255"`
+	Description null.String `json:"description" validate:"omitempty,max=5000"`
+	Stock       int         `json:"stock" validate:"min=0"`
+	Price       float64     `json:"price" validate:"required,gt=0"`
+	// CompareAtPrice is removed when left out on replacement.
+	CompareAtPrice null.Float `json:"compareAtPrice"`
+	// Sku is kept on replacement when left out.
+	Sku string `json:"sku" validate:"omitempty,max=64"`
+	// Slug defaults to one generated from the name on creation and is kept
+	// on replacement.
+	Slug            string      `json:"slug" validate:"omitempty,slug,max=255"`
+	SeoTitle        null.String `json:"seoTitle" validate:"omitempty,max=255"`
+	MetaDescription null.String `json:"metaDescription" validate:"omitempty,max=500"`
+	// Status defaults to draft on creation and is kept on replacement.
+	Status string `json:"status" validate:"omitempty,oneof=draft published archived"`
+	// PublishAt schedules the publication of a published product; it
+	// defaults to now.
+	PublishAt null.Time `json:"publishAt"`
+	// OrderPolicy defaults to stock on creation and is kept on replacement.
+	OrderPolicy    string    `json:"orderPolicy" validate:"omitempty,oneof=stock backorder preorder"`
+	ExpectedAt     null.Time `json:"expectedAt"`
+	BackorderLimit null.Int  `json:"backorderLimit"`
+	// Attributes replaces all attributes of the product.
+	Attributes map[string]string `json:"attributes" validate:"max=20,dive,keys,slug,max=50,endkeys,required,max=100"`
+}
+
+// ProductPatchPayload is a partial update of a product. Only the fields
+// present in the request are changed.
+type ProductPatchPayload struct {
+	Name *string `json:"name" validate:"omitempty,min=1,max=255"`
+	// Description is cleared by an empty string.
+	Description *string  `json:"description" validate:"omitempty,max=5000"`
+	Stock       *int     `json:"stock" validate:"omitempty,min=0"`
+	Price       *float64 `json:"price" validate:"omitempty,gt=0"`
+	// CompareAtPrice is removed by 0.
+	CompareAtPrice *float64 `json:"compareAtPrice" validate:"omitempty,min=0"`
+	// Sku is cleared by an empty string.
+	Sku  *string `json:"sku" validate:"omitempty,max=64"`
+	Slug *string `json:"slug" validate:"omitempty,slug,max=255"`
+	// SeoTitle and MetaDescription are cleared by an empty string.
+	SeoTitle        *string `json:"seoTitle" validate:"omitempty,max=255"`
+	MetaDescription *string `json:"metaDescription" validate:"omitempty,max=500"`
+	Status          *string `json:"status" validate:"omitempty,oneof=draft published archived"`
+	// PublishAt reschedules a published product, or schedules the product
+	// when it is published in the same request.
+	PublishAt   *time.Time `json:"publishAt"`
+	OrderPolicy *string    `json:"orderPolicy" validate:"omitempty,oneof=stock backorder preorder"`
+	ExpectedAt  *time.Time `json:"expectedAt"`
+	// BackorderLimit is removed by 0.
+	BackorderLimit *int `json:"backorderLimit" validate:"omitempty,min=0"`
+	// Attributes are merged into the product's attributes. An empty value
+	// removes the attribute.
+	Attributes map[string]string `json:"attributes" validate:"max=20,dive,keys,slug,max=50,endkeys,max=100"`
 }
 
-func (p Product) NewFromPayload(load ProductPayload, userId uuid.UUID) (res Product, err error) {
+func (p Product) NewFromPayload(load ProductPayload, userId, orgId uuid.UUID) (res Product, err error) {
 
 	prodId, err := uuid.NewV4()
 
@@ -51,19 +188,261 @@ func (p Product) NewFromPayload(load ProductPayload, userId uuid.UUID) (res Prod
 	}
 
 	res = Product{
-		Id:         prodId,
-		Name:       load.Name,
-		Stock:      load.Stock,
-		Price:      load.Price,
-		Created_at: time.Now().UTC(),
-		Created_by: userId,
-		Updated_at: time.Now().UTC(),
-		Updated_by: userId,
+		Id:              prodId,
+		OrganizationId:  orgId,
+		Name:            load.Name,
+		Sku:             null.NewString(load.Sku, load.Sku != ""),
+		Description:     sanitized(load.Description.String),
+		Slug:            load.Slug,
+		SeoTitle:        load.SeoTitle,
+		MetaDescription: load.MetaDescription,
+		Status:          StatusDraft,
+		OrderPolicy:     OrderPolicyStock,
+		ExpectedAt:      utc(load.ExpectedAt),
+		BackorderLimit:  load.BackorderLimit,
+		Attributes:      attributesOf(load.Attributes),
+		Stock:           load.Stock,
+		Price:           load.Price,
+		CompareAtPrice:  load.CompareAtPrice,
+		Created_at:      time.Now().UTC(),
+		Created_by:      userId,
+		Updated_at:      time.Now().UTC(),
+		Updated_by:      userId,
+	}
+	if res.Slug == "" {
+		res.Slug = SlugOf(load.Name)
+	}
+	if load.Status != "" {
+		err = res.SetStatus(load.Status, load.PublishAt)
+		if err != nil {
+			return
+		}
+	}
+	if load.OrderPolicy != "" {
+		res.OrderPolicy = load.OrderPolicy
+	}
+	err = res.checkOrderPolicy()
+	if err != nil {
+		return
+	}
+	err = res.checkCompareAtPrice()
+	if err != nil {
+		return
 	}
 	err = res.Validate()
 	return
 }
 
+func (p *Product) Update(load ProductPayload, userId uuid.UUID) (err error) {
+	if p.IsDeleted() {
+		err = failure.Conflict("update", "product", "product is deleted")
+		return
+	}
+	p.Attributes = attributesOf(load.Attributes)
+	p.ExpectedAt = utc(load.ExpectedAt)
+	p.BackorderLimit = load.BackorderLimit
+	p.CompareAtPrice = load.CompareAtPrice
+	patch := ProductPatchPayload{
+		Name:            &load.Name,
+		Description:     &load.Description.String,
+		Stock:           &load.Stock,
+		Price:           &load.Price,
+		SeoTitle:        &load.SeoTitle.String,
+		MetaDescription: &load.MetaDescription.String,
+	}
+	if load.Sku != "" {
+		patch.Sku = &load.Sku
+	}
+	if load.Slug != "" {
+		patch.Slug = &load.Slug
+	}
+	if load.Status != "" {
+		patch.Status = &load.Status
+	}
+	if load.PublishAt.Valid {
+		patch.PublishAt = &load.PublishAt.Time
+	}
+	if load.OrderPolicy != "" {
+		patch.OrderPolicy = &load.OrderPolicy
+	}
+	return p.Patch(patch, userId)
+}
+
+func (p *Product) Patch(load ProductPatchPayload, userId uuid.UUID) (err error) {
+	if p.IsDeleted() {
+		err = failure.Conflict("update", "product", "product is deleted")
+		return
+	}
+	if load.Name != nil {
+		p.Name = *load.Name
+	}
+	if load.Sku != nil {
+		p.Sku = null.NewString(*load.Sku, *load.Sku != "")
+	}
+	if load.Description != nil {
+		p.Description = sanitized(*load.Description)
+	}
+	if load.Stock != nil {
+		p.Stock = *load.Stock
+	}
+	if load.Price != nil {
+		p.Price = *load.Price
+	}
+	if load.CompareAtPrice != nil {
+		p.CompareAtPrice = null.NewFloat(*load.CompareAtPrice, *load.CompareAtPrice != 0)
+	}
+	if load.Slug != nil {
+		p.Slug = *load.Slug
+	}
+	if load.SeoTitle != nil {
+		p.SeoTitle = null.NewString(*load.SeoTitle, *load.SeoTitle != "")
+	}
+	if load.MetaDescription != nil {
+		p.MetaDescription = null.NewString(*load.MetaDescription, *load.MetaDescription != "")
+	}
+	if load.Status != nil || load.PublishAt != nil {
+		status := p.Status
+		if load.Status != nil {
+			status = *load.Status
+		}
+		err = p.SetStatus(status, null.TimeFromPtr(load.PublishAt))
+		if err != nil {
+			return
+		}
+	}
+	if load.OrderPolicy != nil {
+		if *load.OrderPolicy == OrderPolicyStock {
+			p.ExpectedAt = null.Time{}
+			p.BackorderLimit = null.Int{}
+		}
+		p.OrderPolicy = *load.OrderPolicy
+	}
+	if load.ExpectedAt != nil {
+		p.ExpectedAt = null.TimeFrom(load.ExpectedAt.UTC())
+	}
+	if load.BackorderLimit != nil {
+		p.BackorderLimit = null.NewInt(int64(*load.BackorderLimit), *load.BackorderLimit != 0)
+	}
+	err = p.checkOrderPolicy()
+	if err != nil {
+		return
+	}
+	err = p.checkCompareAtPrice()
+	if err != nil {
+		return
+	}
+	if p.Attributes == nil {
+		p.Attributes = map[string]string{}
+	}
+	for name, value := range load.Attributes {
+		if value == "" {
+			delete(p.Attributes, name)
+			continue
+		}
+		p.Attributes[name] = value
+	}
+	p.Updated_at = time.Now().UTC()
+	p.Updated_by = userId
+	err = p.Validate()
+	return
+}
+
+// SetStatus moves the product to status. Publishing makes the product live
+// at publishAt, or right away without one; a product that is already
+// published keeps its publication time unless a new one is given.
+func (p *Product) SetStatus(status string, publishAt null.Time) (err error) {
+	if publishAt.Valid && status != StatusPublished {
+		err = failure.BadRequestFromString("publishAt only applies to published products")
+		return
+	}
+	switch status {
+	case StatusPublished:
+		if publishAt.Valid {
+			p.PublishAt = null.TimeFrom(publishAt.Time.UTC())
+		} else if p.Status != StatusPublished || !p.PublishAt.Valid {
+			p.PublishAt = null.TimeFrom(time.Now().UTC())
+		}
+	case StatusDraft, StatusArchived:
+		p.PublishAt = null.Time{}
+	default:
+		err = failure.BadRequestFromString("unknown status " + status)
+		return
+	}
+	p.Status = status
+	return
+}
+
+// IsPublished reports whether the product is live at the given time: it is
+// published and its publication time has come.
+func (p *Product) IsPublished(at time.Time) bool {
+	return p.Status == StatusPublished && p.PublishAt.Valid && !p.PublishAt.Time.After(at)
+}
+
+// AllowsBackorders reports whether the product can be ordered beyond its
+// stock, as a backorder or a pre-order.
+func (p *Product) AllowsBackorders() bool {
+	return p.OrderPolicy == OrderPolicyBackorder || p.OrderPolicy == OrderPolicyPreorder
+}
+
+// AcceptsBackorder reports whether quantity more units can wait for stock
+// with waiting units already waiting.
+func (p *Product) AcceptsBackorder(quantity, waiting int) bool {
+	if !p.AllowsBackorders() {
+		return false
+	}
+	return !p.BackorderLimit.Valid || int64(waiting+quantity) <= p.BackorderLimit.Int64
+}
+
+// checkOrderPolicy checks that the expected date and the limit are only
+// set for backorders and pre-orders, that pre-orders have a date and that
+// the limit is positive.
+func (p *Product) checkOrderPolicy() error {
+	if !p.AllowsBackorders() && (p.ExpectedAt.Valid || p.BackorderLimit.Valid) {
+		return failure.BadRequestFromString("expectedAt and backorderLimit only apply to backorders and pre-orders")
+	}
+	if p.OrderPolicy == OrderPolicyPreorder && !p.ExpectedAt.Valid {
+		return failure.BadRequestFromString("expectedAt is required for pre-orders")
+	}
+	if p.BackorderLimit.Valid && p.BackorderLimit.Int64 < 1 {
+		return failure.BadRequestFromString("backorderLimit must be at least 1")
+	}
+	return nil
+}
+
+// checkCompareAtPrice checks that the compare-at price is above the price.
+func (p *Product) checkCompareAtPrice() error {
+	return checkCompareAtPrice(p.Price, p.CompareAtPrice)
+}
+
+// IsDeleted reports whether the product has been soft deleted.
+func (p *Product) IsDeleted() bool {
+	return p.Deleted_at.Valid && p.Deleted_by.Valid
+}
+
+func (p *Product) SoftDelete(userId uuid.UUID) (err error) {
+	if p.IsDeleted() {
+		err = failure.Conflict("delete", "product", "already deleted")
+		return
+	}
+	p.Deleted_at = null.TimeFrom(time.Now().UTC())
+	p.Deleted_by = nuuid.From(userId)
+	err = p.Validate()
+	return
+}
+
+func (p *Product) Restore(userId uuid.UUID) (err error) {
+	if !p.IsDeleted() {
+		err = failure.Conflict("restore", "product", "not deleted")
+		return
+	}
+	p.Deleted_at = null.Time{}
+	p.Deleted_by = nuuid.NUUID{}
+	p.Updated_at = time.Now().UTC()
+	p.Updated_by = userId
+	err = p.Validate()
+	return
+}
+
 func (p *Product) Validate() error {
 	validator := shared.GetValidator()
 	return validator.Struct(p)
@@ -73,6 +452,45 @@ func (p Product) ToResponseFormat() ProductResponseFormat {
 	return ProductResponseFormat(p)
 }
 
-func (p *Product) MarshalJSON() ([]byte, error) {
+func (p Product) MarshalJSON() ([]byte, error) {
 	return json.Marshal(p.ToResponseFormat())
 }
+
+// SlugOf generates the slug of a product from its name.
+func SlugOf(name string) string {
+	slug := shared.Slugify(name)
+	if len(slug) > maxGeneratedSlugLength {
+		slug = strings.TrimRight(slug[:maxGeneratedSlugLength], "-")
+	}
+	if slug == "" {
+		return "product"
+	}
+	return slug
+}
+
+// maxGeneratedSlugLength leaves room for the suffix that makes a generated
+// slug unique.
+const maxGeneratedSlugLength = 240
+
+// utc returns t in UTC, as times are stored.
+func utc(t null.Time) null.Time {
+	if !t.Valid {
+		return t
+	}
+	return null.TimeFrom(t.Time.UTC())
+}
+
+// sanitized cleans up a description, see shared.SanitizeHTML. Descriptions
+// that are empty after cleaning are cleared.
+func sanitized(description string) null.String {
+	clean := shared.SanitizeHTML(description)
+	return null.NewString(clean, clean != "")
+}
+
+func attributesOf(attributes map[string]string) (res map[string]string) {
+	res = map[string]string{}
+	for name, value := range attributes {
+		res[name] = value
+	}
+	return
+}
@@ -0,0 +1,265 @@
+package product_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/evermos/boilerplate-go/internal/domain/product"
+	"github.com/evermos/boilerplate-go/shared/bulk"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProductPublication(t *testing.T) {
+	userId, orgId := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	now := time.Now().UTC()
+
+	t.Run("new products are drafts with a generated slug", func(t *testing.T) {
+		prod, err := product.Product{}.NewFromPayload(product.ProductPayload{
+			Name:        "Men's Linen Shirt",
+			Description: null.StringFrom(`**Soft** <script>alert(1)</script><p onclick="x()">linen</p>`),
+			Price:       10,
+		}, userId, orgId)
+		assert.NoError(t, err)
+		assert.Equal(t, product.StatusDraft, prod.Status)
+		assert.Equal(t, "men-s-linen-shirt", prod.Slug)
+		assert.Equal(t, "**Soft** <p>linen</p>", prod.Description.String)
+		assert.False(t, prod.IsPublished(now))
+	})
+
+	t.Run("scheduled publication", func(t *testing.T) {
+		prod := product.Product{Status: product.StatusDraft}
+		assert.NoError(t, prod.SetStatus(product.StatusPublished, null.TimeFrom(now.Add(time.Hour))))
+		assert.False(t, prod.IsPublished(now))
+		assert.True(t, prod.IsPublished(now.Add(2*time.Hour)))
+
+		assert.NoError(t, prod.SetStatus(product.StatusArchived, null.Time{}))
+		assert.False(t, prod.PublishAt.Valid)
+		assert.Error(t, prod.SetStatus(product.StatusDraft, null.TimeFrom(now)))
+
+		assert.NoError(t, prod.SetStatus(product.StatusPublished, null.Time{}))
+		assert.True(t, prod.IsPublished(time.Now().UTC()))
+	})
+
+	t.Run("SlugOf", func(t *testing.T) {
+		assert.Equal(t, "product", product.SlugOf("!!!"))
+		assert.LessOrEqual(t, len(product.SlugOf(strings.Repeat("ab ", 100))), 240)
+	})
+}
+
+func TestRow(t *testing.T) {
+	userId, orgId := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	prod, err := product.Product{}.NewFromPayload(product.ProductPayload{
+		Name:        "Linen Shirt",
+		Sku:         "SHIRT-1",
+		Description: null.StringFrom("Soft linen"),
+		Price:       10,
+		Stock:       3,
+		Attributes:  map[string]string{"material": "linen", "brand": "acme"},
+	}, userId, orgId)
+	assert.NoError(t, err)
+
+	for _, format := range []bulk.Format{bulk.CSV, bulk.NDJSON} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			encoder := bulk.NewEncoder(&buf, format)
+			assert.NoError(t, encoder.Encode(prod.ToRow()))
+			assert.NoError(t, encoder.Flush())
+			if format == bulk.CSV {
+				assert.Contains(t, buf.String(), "SHIRT-1,Linen Shirt,Soft linen,3,10,,linen-shirt,,,draft,,stock,,,brand=acme;material=linen\n")
+			}
+
+			var row product.Row
+			assert.NoError(t, bulk.NewDecoder(&buf, format).Decode(&row))
+			assert.Equal(t, prod.ToRow(), row)
+			assert.Equal(t, "SHIRT-1", row.ToPayload().Sku)
+		})
+	}
+
+	t.Run("attributes in NDJSON may use the CSV form", func(t *testing.T) {
+		var row product.Row
+		assert.NoError(t, bulk.NewDecoder(strings.NewReader(`{"sku":"A","attributes":"brand=acme"}`), bulk.NDJSON).Decode(&row))
+		assert.Equal(t, product.AttributeList{"brand": "acme"}, row.Attributes)
+		assert.Error(t, bulk.NewDecoder(strings.NewReader(`{"sku":"A","attributes":"brand"}`), bulk.NDJSON).Decode(&row))
+	})
+}
+
+func TestProductOrderPolicy(t *testing.T) {
+	userId, orgId := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	expected := time.Now().UTC().Add(30 * 24 * time.Hour)
+	load := product.ProductPayload{Name: "Linen Shirt", Price: 10}
+
+	t.Run("products are sold from stock by default", func(t *testing.T) {
+		prod, err := product.Product{}.NewFromPayload(load, userId, orgId)
+		assert.NoError(t, err)
+		assert.Equal(t, product.OrderPolicyStock, prod.OrderPolicy)
+		assert.False(t, prod.AllowsBackorders())
+	})
+
+	t.Run("pre-orders require an expected date", func(t *testing.T) {
+		preorder := load
+		preorder.OrderPolicy = product.OrderPolicyPreorder
+		_, err := product.Product{}.NewFromPayload(preorder, userId, orgId)
+		assert.Error(t, err)
+
+		preorder.ExpectedAt = null.TimeFrom(expected)
+		preorder.BackorderLimit = null.IntFrom(50)
+		prod, err := product.Product{}.NewFromPayload(preorder, userId, orgId)
+		assert.NoError(t, err)
+		assert.True(t, prod.AllowsBackorders())
+		assert.Equal(t, int64(50), prod.BackorderLimit.Int64)
+	})
+
+	t.Run("the limit has to be positive", func(t *testing.T) {
+		backorder := load
+		backorder.OrderPolicy = product.OrderPolicyBackorder
+		backorder.BackorderLimit = null.IntFrom(0)
+		_, err := product.Product{}.NewFromPayload(backorder, userId, orgId)
+		assert.Error(t, err)
+	})
+
+	t.Run("the date and limit only apply beyond stock", func(t *testing.T) {
+		stocked := load
+		stocked.ExpectedAt = null.TimeFrom(expected)
+		_, err := product.Product{}.NewFromPayload(stocked, userId, orgId)
+		assert.Error(t, err)
+	})
+
+	t.Run("going back to stock clears the date and limit", func(t *testing.T) {
+		backorder := load
+		backorder.OrderPolicy = product.OrderPolicyBackorder
+		backorder.ExpectedAt = null.TimeFrom(expected)
+		backorder.BackorderLimit = null.IntFrom(5)
+		prod, err := product.Product{}.NewFromPayload(backorder, userId, orgId)
+		assert.NoError(t, err)
+
+		policy := product.OrderPolicyStock
+		assert.NoError(t, prod.Patch(product.ProductPatchPayload{OrderPolicy: &policy}, userId))
+		assert.False(t, prod.ExpectedAt.Valid)
+		assert.False(t, prod.BackorderLimit.Valid)
+	})
+
+	t.Run("backorders are taken up to the limit", func(t *testing.T) {
+		backorder := load
+		backorder.OrderPolicy = product.OrderPolicyBackorder
+		backorder.BackorderLimit = null.IntFrom(5)
+		prod, err := product.Product{}.NewFromPayload(backorder, userId, orgId)
+		assert.NoError(t, err)
+		assert.True(t, prod.AcceptsBackorder(2, 3))
+		assert.False(t, prod.AcceptsBackorder(3, 3))
+
+		stocked, err := product.Product{}.NewFromPayload(load, userId, orgId)
+		assert.NoError(t, err)
+		assert.False(t, stocked.AcceptsBackorder(1, 0))
+	})
+}
+
+func TestPriceSchedule(t *testing.T) {
+	userId, orgId := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	now := time.Now().UTC()
+	prod, err := product.Product{}.NewFromPayload(product.ProductPayload{Name: "Linen Shirt", Price: 10}, userId, orgId)
+	assert.NoError(t, err)
+
+	sale := product.PriceSchedulePayload{
+		Price:          8,
+		CompareAtPrice: null.FloatFrom(10),
+		StartsAt:       now.Add(time.Hour),
+		EndsAt:         null.TimeFrom(now.Add(48 * time.Hour)),
+	}
+
+	t.Run("windows must be in the future and in order", func(t *testing.T) {
+		past := sale
+		past.StartsAt = now.Add(-time.Hour)
+		_, err := product.PriceSchedule{}.NewFromPayload(past, prod, userId, now)
+		assert.Error(t, err)
+
+		backwards := sale
+		backwards.EndsAt = null.TimeFrom(now)
+		_, err = product.PriceSchedule{}.NewFromPayload(backwards, prod, userId, now)
+		assert.Error(t, err)
+
+		cheaper := sale
+		cheaper.CompareAtPrice = null.FloatFrom(8)
+		_, err = product.PriceSchedule{}.NewFromPayload(cheaper, prod, userId, now)
+		assert.Error(t, err)
+	})
+
+	t.Run("a sale starts and ends", func(t *testing.T) {
+		schedule, err := product.PriceSchedule{}.NewFromPayload(sale, prod, userId, now)
+		assert.NoError(t, err)
+		assert.False(t, schedule.IsDue(now))
+
+		started := now.Add(2 * time.Hour)
+		assert.True(t, schedule.IsDue(started))
+		p := prod
+		assert.NoError(t, schedule.Start(&p, started))
+		assert.Equal(t, product.ScheduleActive, schedule.Status)
+		assert.Equal(t, float64(8), p.Price)
+		assert.Equal(t, null.FloatFrom(10), p.CompareAtPrice)
+
+		ended := now.Add(72 * time.Hour)
+		assert.True(t, schedule.IsDue(ended))
+		assert.NoError(t, schedule.End(&p, ended))
+		assert.Equal(t, product.ScheduleEnded, schedule.Status)
+		assert.Equal(t, float64(10), p.Price)
+		assert.False(t, p.CompareAtPrice.Valid)
+	})
+
+	t.Run("a price changed during the sale is kept", func(t *testing.T) {
+		schedule, err := product.PriceSchedule{}.NewFromPayload(sale, prod, userId, now)
+		assert.NoError(t, err)
+		p := prod
+		assert.NoError(t, schedule.Start(&p, now.Add(2*time.Hour)))
+		price := 9.0
+		assert.NoError(t, p.Patch(product.ProductPatchPayload{Price: &price}, userId))
+		assert.NoError(t, schedule.End(&p, now.Add(72*time.Hour)))
+		assert.Equal(t, 9.0, p.Price)
+	})
+
+	t.Run("schedules without an end change the price for good", func(t *testing.T) {
+		permanent := sale
+		permanent.EndsAt = null.Time{}
+		schedule, err := product.PriceSchedule{}.NewFromPayload(permanent, prod, userId, now)
+		assert.NoError(t, err)
+		p := prod
+		assert.NoError(t, schedule.Start(&p, now.Add(2*time.Hour)))
+		assert.Equal(t, product.ScheduleEnded, schedule.Status)
+		assert.Error(t, schedule.Cancel(userId))
+	})
+
+	t.Run("windows overlap", func(t *testing.T) {
+		schedule, err := product.PriceSchedule{}.NewFromPayload(sale, prod, userId, now)
+		assert.NoError(t, err)
+		later := sale
+		later.StartsAt = sale.EndsAt.Time
+		later.EndsAt = null.Time{}
+		other, err := product.PriceSchedule{}.NewFromPayload(later, prod, userId, now)
+		assert.NoError(t, err)
+		assert.False(t, schedule.Overlaps(other))
+		assert.False(t, other.Overlaps(schedule))
+
+		later.StartsAt = sale.EndsAt.Time.Add(-time.Minute)
+		other, err = product.PriceSchedule{}.NewFromPayload(later, prod, userId, now)
+		assert.NoError(t, err)
+		assert.True(t, schedule.Overlaps(other))
+		assert.True(t, other.Overlaps(schedule))
+	})
+}
+
+func TestProductCompareAtPrice(t *testing.T) {
+	userId, orgId := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	load := product.ProductPayload{Name: "Linen Shirt", Price: 10, CompareAtPrice: null.FloatFrom(8)}
+	_, err := product.Product{}.NewFromPayload(load, userId, orgId)
+	assert.Error(t, err)
+
+	load.CompareAtPrice = null.FloatFrom(12)
+	prod, err := product.Product{}.NewFromPayload(load, userId, orgId)
+	assert.NoError(t, err)
+
+	none := 0.0
+	assert.NoError(t, prod.Patch(product.ProductPatchPayload{CompareAtPrice: &none}, userId))
+	assert.False(t, prod.CompareAtPrice.Valid)
+}
@@ -3,18 +3,40 @@ package product
 import (
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/evermos/boilerplate-go/infras"
+	"github.com/evermos/boilerplate-go/internal/domain/inventory"
 	"github.com/evermos/boilerplate-go/shared/failure"
 	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/evermos/boilerplate-go/shared/nuuid"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
 	"github.com/jmoiron/sqlx"
 )
 
 type ProductRepository interface {
 	Create(prod Product) (err error)
-	GetAll(limit, offset int, sort, field, productTitle string) (res []Product, err error)
-	ExistsByID(id string) (exists bool, err error)
-	GetByID(id string) (res Product, err error)
+	GetAll(filter Filter, limit, offset int, sort, field string) (res []Product, err error)
+	GetAllAfter(filter Filter, afterId string, limit int) (res []Product, err error)
+	ExistsByID(id, orgId string) (exists bool, err error)
+	GetByID(id, orgId string) (res Product, err error)
+	GetBySlug(slug, orgId string) (res Product, err error)
+	SlugExists(slug, orgId, excludeId string) (exists bool, err error)
+	GetBySku(sku, orgId string) (res Product, err error)
+	SkuExists(sku, orgId, excludeId string) (exists bool, err error)
+	Update(prod Product) (err error)
+	UpdateWithStock(prod Product) (err error)
+	GetFacets(filter Filter) (res Facets, err error)
+	GetPriceHistory(productId, orgId string, limit, offset int) (res []PriceChange, total int, err error)
+	GetUnrepricedChanges(limit int) (res []PriceChange, err error)
+	MarkRepriced(id string, at time.Time) (err error)
+	CreatePriceSchedule(load PriceSchedule) (err error)
+	GetPriceSchedules(productId, orgId string) (res []PriceSchedule, err error)
+	GetPriceSchedule(id, productId, orgId string) (res PriceSchedule, err error)
+	UpdatePriceSchedule(load PriceSchedule) (err error)
+	GetDuePriceSchedules(now time.Time, limit int) (res []PriceSchedule, err error)
+	ApplyPriceSchedule(prod Product, schedule PriceSchedule) (err error)
 }
 
 type ProductRepositoryMySQL struct {
@@ -25,19 +47,42 @@ func ProvideProductRepositoryMySQL(db *infras.MySQLConn) *ProductRepositoryMySQL
 	return &ProductRepositoryMySQL{DB: db}
 }
 
+const priceChangeColumns = "id,organization_id,product_id,variant_id,price,compare_at_price,previous_price,schedule_id,changed_at,changed_by,repriced_at"
+
+const priceScheduleColumns = "id,organization_id,product_id,price,compare_at_price,starts_at,ends_at,status,previous_price,previous_compare_at_price,created_at,created_by,updated_at,updated_by"
+
+// Create inserts the product without stock and records its initial stock
+// as a movement and its initial price in the price history.
 func (r *ProductRepositoryMySQL) Create(prod Product) (err error) {
 	return r.DB.WithTransaction(func(db *sqlx.Tx, c chan error) {
 		if err := r.txCreate(db, prod); err != nil {
 			c <- err
 			return
 		}
+		change, err := NewPriceChange(prod.OrganizationId, prod.Id, nuuid.NUUID{}, prod.Price, prod.CompareAtPrice, null.Float{}, nuuid.NUUID{}, prod.Created_by)
+		if err != nil {
+			c <- err
+			return
+		}
+		if err := TxRecordPrice(db, change); err != nil {
+			c <- err
+			return
+		}
+		if err := r.txSetAttributes(db, prod); err != nil {
+			c <- err
+			return
+		}
+		if err := r.txSetStock(db, prod); err != nil {
+			c <- err
+			return
+		}
 		c <- nil
 	})
 }
 
 func (r *ProductRepositoryMySQL) txCreate(tx *sqlx.Tx, prod Product) (err error) {
-	query := `INSERT INTO product (id,name,stock,price,created_at,created_by,updated_at,updated_by)
-    VALUES (:id,:name,:stock,:price,:created_at,:created_by,:updated_at,:updated_by)`
+	query := `INSERT INTO product (id,organization_id,name,sku,description,stock,price,compare_at_price,slug,seo_title,meta_description,status,publish_at,order_policy,expected_at,backorder_limit,created_at,created_by,updated_at,updated_by)
+    VALUES (:id,:organization_id,:name,:sku,:description,0,:price,:compare_at_price,:slug,:seo_title,:meta_description,:status,:publish_at,:order_policy,:expected_at,:backorder_limit,:created_at,:created_by,:updated_at,:updated_by)`
 
 	stmt, err := tx.PrepareNamed(query)
 
@@ -56,26 +101,130 @@ func (r *ProductRepositoryMySQL) txCreate(tx *sqlx.Tx, prod Product) (err error)
 	return
 }
 
-func (r *ProductRepositoryMySQL) GetAll(limit, offset int, sort, field, productTitle string) (res []Product, err error) {
-	query := `SELECT * FROM product `
+// GetAll lists products without a search query, see ProductSearcher for
+// searching. field has to be one of SortFields.
+func (r *ProductRepositoryMySQL) GetAll(filter Filter, limit, offset int, sort, field string) (res []Product, err error) {
+	filter.Query = ""
+	conditions, args, err := filter.conditions()
+	if err != nil {
+		return
+	}
+	query := `SELECT p.* FROM product p WHERE ` + conditions +
+		fmt.Sprintf(" ORDER BY p.%s %s LIMIT %d OFFSET %d", field, sort, limit, offset)
+	query, args, err = sqlx.In(query, args...)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	err = r.DB.Read.Select(&res, r.DB.Read.Rebind(query), args...)
+
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+
+	err = attachDetails(r.DB.Read, res)
+	return
+}
+
+// GetAllAfter returns up to limit products matching the filter with an id
+// greater than afterId, in id order.
+func (r *ProductRepositoryMySQL) GetAllAfter(filter Filter, afterId string, limit int) (res []Product, err error) {
+	filter.Query = ""
+	conditions, args, err := filter.conditions()
+	if err != nil {
+		return
+	}
+	args = append(args, afterId, limit)
+	query, args, err := sqlx.In(`SELECT p.* FROM product p WHERE `+conditions+` AND p.id > ? ORDER BY p.id LIMIT ?`, args...)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	err = r.DB.Read.Select(&res, r.DB.Read.Rebind(query), args...)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	err = attachDetails(r.DB.Read, res)
+	return
+}
+
+func (r *ProductRepositoryMySQL) ExistsByID(id, orgId string) (exists bool, err error) {
+	err = r.DB.Read.Get(&exists, "SELECT COUNT(id) FROM product WHERE id = ? AND organization_id = ? AND deleted_at IS NULL", id, orgId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+// GetByID returns the product, including soft deleted ones.
+func (r *ProductRepositoryMySQL) GetByID(id, orgId string) (res Product, err error) {
+	err = r.DB.Read.Get(&res, "SELECT * FROM product WHERE id = ? AND organization_id = ?", id, orgId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = failure.NotFound("Product")
+			return
+		}
+		logger.ErrorWithStack(err)
+		return
+	}
+	products := []Product{res}
+	err = attachDetails(r.DB.Read, products)
+	res = products[0]
+	return
+}
 
-	if productTitle != "" {
-		query += `WHERE name `
-		query += fmt.Sprintf("COLLATE UTF8_GENERAL_CI LIKE '%%%s%%' ", productTitle)
+// GetBySlug returns the product with the slug, excluding soft deleted ones.
+func (r *ProductRepositoryMySQL) GetBySlug(slug, orgId string) (res Product, err error) {
+	err = r.DB.Read.Get(&res, "SELECT * FROM product WHERE slug = ? AND organization_id = ? AND deleted_at IS NULL", slug, orgId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = failure.NotFound("Product")
+			return
+		}
+		logger.ErrorWithStack(err)
+		return
 	}
-	query += fmt.Sprintf("ORDER BY %s %s LIMIT %d OFFSET %d", field, sort, limit, offset)
-	err = r.DB.Read.Select(&res, query)
+	products := []Product{res}
+	err = attachDetails(r.DB.Read, products)
+	res = products[0]
+	return
+}
 
+// SlugExists reports whether another product of the organization, deleted or
+// not, has the slug.
+func (r *ProductRepositoryMySQL) SlugExists(slug, orgId, excludeId string) (exists bool, err error) {
+	err = r.DB.Read.Get(&exists, "SELECT COUNT(id) FROM product WHERE slug = ? AND organization_id = ? AND id != ?", slug, orgId, excludeId)
 	if err != nil {
 		logger.ErrorWithStack(err)
 		return
 	}
+	return
+}
 
+// GetBySku returns the product with the SKU, including soft deleted ones.
+func (r *ProductRepositoryMySQL) GetBySku(sku, orgId string) (res Product, err error) {
+	err = r.DB.Read.Get(&res, "SELECT * FROM product WHERE sku = ? AND organization_id = ?", sku, orgId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = failure.NotFound("Product")
+			return
+		}
+		logger.ErrorWithStack(err)
+		return
+	}
+	products := []Product{res}
+	err = attachDetails(r.DB.Read, products)
+	res = products[0]
 	return
 }
 
-func (r *ProductRepositoryMySQL) ExistsByID(id string) (exists bool, err error) {
-	err = r.DB.Read.Get(&exists, "SELECT COUNT(id) FROM product WHERE id = ?", id)
+// SkuExists reports whether another product of the organization, deleted or
+// not, has the SKU.
+func (r *ProductRepositoryMySQL) SkuExists(sku, orgId, excludeId string) (exists bool, err error) {
+	err = r.DB.Read.Get(&exists, "SELECT COUNT(id) FROM product WHERE sku = ? AND organization_id = ? AND id != ?", sku, orgId, excludeId)
 	if err != nil {
 		logger.ErrorWithStack(err)
 		return
@@ -83,12 +232,469 @@ func (r *ProductRepositoryMySQL) ExistsByID(id string) (exists bool, err error)
 	return
 }
 
-func (r *ProductRepositoryMySQL) GetByID(id string) (res Product, err error) {
-	err = r.DB.Read.Get(&res, "SELECT * FROM product WHERE id = ?", id)
-	if err != nil && err == sql.ErrNoRows {
-		err = failure.NotFound("foo")
+// Update writes the product except for its stock, which only changes
+// through stock movements. A change of price is recorded in the price
+// history.
+func (r *ProductRepositoryMySQL) Update(prod Product) (err error) {
+	return r.update(prod, false)
+}
+
+// UpdateWithStock writes the product and records the change of its stock as
+// an adjustment.
+func (r *ProductRepositoryMySQL) UpdateWithStock(prod Product) (err error) {
+	return r.update(prod, true)
+}
+
+func (r *ProductRepositoryMySQL) update(prod Product, withStock bool) (err error) {
+	return r.DB.WithTransaction(func(db *sqlx.Tx, c chan error) {
+		if err := r.txRecordPrice(db, prod, nuuid.NUUID{}); err != nil {
+			c <- err
+			return
+		}
+		query := `
+		UPDATE product
+		SET
+			name = :name,
+			sku = :sku,
+			description = :description,
+			price = :price,
+			compare_at_price = :compare_at_price,
+			slug = :slug,
+			seo_title = :seo_title,
+			meta_description = :meta_description,
+			status = :status,
+			publish_at = :publish_at,
+			order_policy = :order_policy,
+			expected_at = :expected_at,
+			backorder_limit = :backorder_limit,
+			updated_at = :updated_at,
+			updated_by = :updated_by,
+			deleted_at = :deleted_at,
+			deleted_by = :deleted_by
+		WHERE id = :id AND organization_id = :organization_id`
+		if _, err := db.NamedExec(query, prod); err != nil {
+			logger.ErrorWithStack(err)
+			c <- err
+			return
+		}
+		if err := r.txSetAttributes(db, prod); err != nil {
+			c <- err
+			return
+		}
+		if withStock {
+			if err := r.txSetStock(db, prod); err != nil {
+				c <- err
+				return
+			}
+		}
+		c <- nil
+	})
+}
+
+// txRecordPrice records the price of the product in its history when it
+// differs from the stored one, which stays locked until tx ends.
+func (r *ProductRepositoryMySQL) txRecordPrice(tx *sqlx.Tx, prod Product, scheduleId nuuid.NUUID) (err error) {
+	var stored struct {
+		Price          float64    `db:"price"`
+		CompareAtPrice null.Float `db:"compare_at_price"`
+	}
+	err = tx.Get(&stored, "SELECT price, compare_at_price FROM product WHERE id = ? FOR UPDATE", prod.Id.String())
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	if stored.Price == prod.Price && stored.CompareAtPrice == prod.CompareAtPrice {
+		return
+	}
+	change, err := NewPriceChange(prod.OrganizationId, prod.Id, nuuid.NUUID{}, prod.Price, prod.CompareAtPrice, null.FloatFrom(stored.Price), scheduleId, prod.Updated_by)
+	if err != nil {
+		return
+	}
+	return TxRecordPrice(tx, change)
+}
+
+// txSetStock brings the stock to the product's, see inventory.TxSet.
+func (r *ProductRepositoryMySQL) txSetStock(tx *sqlx.Tx, prod Product) (err error) {
+	m, err := inventory.NewMovement(prod.OrganizationId, prod.Id, nuuid.NUUID{}, 0, inventory.ReasonAdjustment, null.String{}, prod.Updated_by)
+	if err != nil {
+		return
+	}
+	_, err = inventory.TxSet(tx, m, prod.Stock)
+	return
+}
+
+// txSetAttributes replaces the attributes of the product.
+func (r *ProductRepositoryMySQL) txSetAttributes(tx *sqlx.Tx, prod Product) (err error) {
+	_, err = tx.Exec("DELETE FROM product_attribute WHERE product_id = ?", prod.Id.String())
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	for name, value := range prod.Attributes {
+		attribute := ProductAttribute{ProductId: prod.Id, Name: name, Value: value}
+		_, err = tx.NamedExec("INSERT INTO product_attribute (product_id,name,value) VALUES (:product_id,:name,:value)", attribute)
+		if err != nil {
+			logger.ErrorWithStack(err)
+			return
+		}
+	}
+	return
+}
+
+// GetFacets counts the products matching the filter per refinement, see
+// Facets.
+func (r *ProductRepositoryMySQL) GetFacets(filter Filter) (res Facets, err error) {
+	err = r.get(filter, `SELECT COUNT(*) FROM product p WHERE %s`, &res.Total)
+	if err != nil {
+		return
+	}
+
+	unpriced := filter
+	unpriced.MinPrice, unpriced.MaxPrice, unpriced.InStock = null.Float{}, null.Float{}, false
+	err = r.get(unpriced, `SELECT MIN(p.price) AS min_price, MAX(p.price) AS max_price FROM product p WHERE %s`, &res.Price)
+	if err != nil {
+		return
+	}
+	err = r.get(unpriced, `SELECT COUNT(*) FROM product p WHERE %s AND `+inStockCondition, &res.InStock)
+	if err != nil {
+		return
+	}
+
+	uncategorized := filter
+	uncategorized.CategoryIds = nil
+	res.Categories = []CategoryFacet{}
+	err = r.selectFacets(uncategorized, `SELECT pc.category_id, COUNT(*) AS count
+		FROM product p JOIN product_category pc ON pc.product_id = p.id
+		WHERE %s GROUP BY pc.category_id ORDER BY count DESC`, &res.Categories)
+	if err != nil {
+		return
+	}
+
+	res.Attributes = map[string][]ValueFacet{}
+	var names []string
+	err = r.selectFacets(filter, `SELECT DISTINCT pa.name
+		FROM product p JOIN product_attribute pa ON pa.product_id = p.id
+		WHERE %s ORDER BY pa.name`, &names)
+	if err != nil {
+		return
+	}
+	for _, name := range filter.attributeNames() {
+		if !containsString(names, name) {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		values := []ValueFacet{}
+		err = r.selectFacets(filter.withoutAttribute(name), `SELECT pa.value, COUNT(*) AS count
+			FROM product p JOIN product_attribute pa ON pa.product_id = p.id
+			WHERE %s AND pa.name = ? GROUP BY pa.value ORDER BY count DESC, pa.value`, &values, name)
+		if err != nil {
+			return
+		}
+		res.Attributes[name] = values
+	}
+	return
+}
+
+// get runs a single row query, with the filter's conditions in place of %s.
+func (r *ProductRepositoryMySQL) get(filter Filter, query string, dest interface{}, args ...interface{}) (err error) {
+	query, args, err = r.facetQuery(filter, query, args)
+	if err != nil {
+		return
+	}
+	err = r.DB.Read.Get(dest, query, args...)
+	if err != nil {
 		logger.ErrorWithStack(err)
 		return
 	}
 	return
 }
+
+// selectFacets runs a query with the filter's conditions in place of %s.
+func (r *ProductRepositoryMySQL) selectFacets(filter Filter, query string, dest interface{}, args ...interface{}) (err error) {
+	query, args, err = r.facetQuery(filter, query, args)
+	if err != nil {
+		return
+	}
+	err = r.DB.Read.Select(dest, query, args...)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+func (r *ProductRepositoryMySQL) facetQuery(filter Filter, query string, extra []interface{}) (string, []interface{}, error) {
+	conditions, args, err := filter.conditions()
+	if err != nil {
+		return query, args, err
+	}
+	query, args, err = sqlx.In(fmt.Sprintf(query, conditions), append(args, extra...)...)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return query, args, err
+	}
+	return r.DB.Read.Rebind(query), args, nil
+}
+
+// attachDetails loads the attributes and images of the products in place.
+func attachDetails(db *sqlx.DB, products []Product) (err error) {
+	err = attachAttributes(db, products)
+	if err != nil {
+		return
+	}
+	return attachImages(db, products)
+}
+
+// attachAttributes loads the attributes of the products in place.
+func attachAttributes(db *sqlx.DB, products []Product) (err error) {
+	if len(products) == 0 {
+		return
+	}
+	ids := make([]string, 0, len(products))
+	for _, prod := range products {
+		ids = append(ids, prod.Id.String())
+	}
+	query, args, err := sqlx.In("SELECT * FROM product_attribute WHERE product_id IN (?)", ids)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	var attributes []ProductAttribute
+	err = db.Select(&attributes, db.Rebind(query), args...)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	byProduct := map[uuid.UUID]map[string]string{}
+	for _, attribute := range attributes {
+		if byProduct[attribute.ProductId] == nil {
+			byProduct[attribute.ProductId] = map[string]string{}
+		}
+		byProduct[attribute.ProductId][attribute.Name] = attribute.Value
+	}
+	for i := range products {
+		products[i].Attributes = byProduct[products[i].Id]
+		if products[i].Attributes == nil {
+			products[i].Attributes = map[string]string{}
+		}
+	}
+	return
+}
+
+// attachImages loads the galleries of the products in place.
+func attachImages(db *sqlx.DB, products []Product) (err error) {
+	if len(products) == 0 {
+		return
+	}
+	ids := make([]string, 0, len(products))
+	for _, prod := range products {
+		ids = append(ids, prod.Id.String())
+	}
+	query, args, err := sqlx.In(`SELECT id, product_id, url, alt_text, is_primary FROM product_image
+		WHERE product_id IN (?) ORDER BY position`, ids)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	var images []Image
+	err = db.Select(&images, db.Rebind(query), args...)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	sizes := []ImageSize{}
+	if len(images) > 0 {
+		imageIds := make([]string, 0, len(images))
+		for _, img := range images {
+			imageIds = append(imageIds, img.Id.String())
+		}
+		query, args, err = sqlx.In("SELECT image_id, size, url FROM product_image_rendition WHERE image_id IN (?)", imageIds)
+		if err != nil {
+			logger.ErrorWithStack(err)
+			return
+		}
+		err = db.Select(&sizes, db.Rebind(query), args...)
+		if err != nil {
+			logger.ErrorWithStack(err)
+			return
+		}
+	}
+	for idx := range images {
+		images[idx].Sizes = map[string]string{}
+		for _, size := range sizes {
+			if size.ImageId == images[idx].Id {
+				images[idx].Sizes[size.Size] = size.Url
+			}
+		}
+	}
+	for idx := range products {
+		products[idx].Images = []Image{}
+		for _, img := range images {
+			if img.ProductId == products[idx].Id {
+				products[idx].Images = append(products[idx].Images, img)
+			}
+		}
+	}
+	return
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPriceHistory returns the price changes of the product and its
+// variants, newest first, and how many there are in all.
+func (r *ProductRepositoryMySQL) GetPriceHistory(productId, orgId string, limit, offset int) (res []PriceChange, total int, err error) {
+	err = r.DB.Read.Get(&total, "SELECT COUNT(id) FROM price_change WHERE product_id = ? AND organization_id = ?", productId, orgId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	res = []PriceChange{}
+	err = r.DB.Read.Select(&res, "SELECT "+priceChangeColumns+" FROM price_change WHERE product_id = ? AND organization_id = ? ORDER BY seq DESC LIMIT ? OFFSET ?",
+		productId, orgId, limit, offset)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+	return
+}
+
+// GetUnrepricedChanges returns up to limit price changes that open carts
+// have not been brought to yet, oldest first.
+func (r *ProductRepositoryMySQL) GetUnrepricedChanges(limit int) (res []PriceChange, err error) {
+	res = []PriceChange{}
+	err = r.DB.Read.Select(&res, "SELECT "+priceChangeColumns+" FROM price_change WHERE repriced_at IS NULL ORDER BY seq LIMIT ?", limit)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+	return
+}
+
+func (r *ProductRepositoryMySQL) MarkRepriced(id string, at time.Time) (err error) {
+	_, err = r.DB.Write.Exec("UPDATE price_change SET repriced_at = ? WHERE id = ?", at, id)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+	return
+}
+
+func (r *ProductRepositoryMySQL) CreatePriceSchedule(load PriceSchedule) (err error) {
+	_, err = r.DB.Write.NamedExec(`INSERT INTO price_schedule (`+priceScheduleColumns+`)
+	VALUES (:id,:organization_id,:product_id,:price,:compare_at_price,:starts_at,:ends_at,:status,:previous_price,:previous_compare_at_price,:created_at,:created_by,:updated_at,:updated_by)`, load)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+	return
+}
+
+// GetPriceSchedules returns the schedules of the product by start.
+func (r *ProductRepositoryMySQL) GetPriceSchedules(productId, orgId string) (res []PriceSchedule, err error) {
+	res = []PriceSchedule{}
+	err = r.DB.Read.Select(&res, "SELECT "+priceScheduleColumns+" FROM price_schedule WHERE product_id = ? AND organization_id = ? ORDER BY starts_at",
+		productId, orgId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+	return
+}
+
+func (r *ProductRepositoryMySQL) GetPriceSchedule(id, productId, orgId string) (res PriceSchedule, err error) {
+	err = r.DB.Read.Get(&res, "SELECT "+priceScheduleColumns+" FROM price_schedule WHERE id = ? AND product_id = ? AND organization_id = ?",
+		id, productId, orgId)
+	if err == sql.ErrNoRows {
+		err = failure.NotFound("Price schedule")
+		return
+	}
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+	return
+}
+
+func (r *ProductRepositoryMySQL) UpdatePriceSchedule(load PriceSchedule) (err error) {
+	return r.DB.WithTransaction(func(db *sqlx.Tx, c chan error) {
+		if err := r.txUpdatePriceSchedule(db, load); err != nil {
+			c <- err
+			return
+		}
+		c <- nil
+	})
+}
+
+func (r *ProductRepositoryMySQL) txUpdatePriceSchedule(tx *sqlx.Tx, load PriceSchedule) (err error) {
+	query := `
+	UPDATE price_schedule
+	SET
+		status = :status,
+		previous_price = :previous_price,
+		previous_compare_at_price = :previous_compare_at_price,
+		updated_at = :updated_at,
+		updated_by = :updated_by
+	WHERE id = :id`
+	_, err = tx.NamedExec(query, load)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+	return
+}
+
+// GetDuePriceSchedules returns up to limit schedules that are to start or
+// to end at now, earliest first.
+func (r *ProductRepositoryMySQL) GetDuePriceSchedules(now time.Time, limit int) (res []PriceSchedule, err error) {
+	res = []PriceSchedule{}
+	query := `SELECT ` + priceScheduleColumns + ` FROM price_schedule
+	WHERE (status = ? AND starts_at <= ?) OR (status = ? AND ends_at <= ?)
+	ORDER BY starts_at LIMIT ?`
+	err = r.DB.Read.Select(&res, query, ScheduleScheduled, now, ScheduleActive, now, limit)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+	return
+}
+
+// ApplyPriceSchedule writes the price the schedule gave the product, or
+// restored, together with the schedule. Nothing else of the product is
+// written, so edits made meanwhile are kept.
+func (r *ProductRepositoryMySQL) ApplyPriceSchedule(prod Product, schedule PriceSchedule) (err error) {
+	return r.DB.WithTransaction(func(db *sqlx.Tx, c chan error) {
+		if err := r.txRecordPrice(db, prod, nuuid.From(schedule.Id)); err != nil {
+			c <- err
+			return
+		}
+		query := `
+		UPDATE product
+		SET
+			price = :price,
+			compare_at_price = :compare_at_price,
+			updated_at = :updated_at,
+			updated_by = :updated_by
+		WHERE id = :id AND organization_id = :organization_id`
+		if _, err := db.NamedExec(query, prod); err != nil {
+			logger.ErrorWithStack(err)
+			c <- err
+			return
+		}
+		if err := r.txUpdatePriceSchedule(db, schedule); err != nil {
+			c <- err
+			return
+		}
+		c <- nil
+	})
+}
+
+// TxRecordPrice appends the change to the price history within tx. Every
+// change of the price of a product or variant goes through here.
+func TxRecordPrice(tx *sqlx.Tx, change PriceChange) (err error) {
+	_, err = tx.NamedExec(`INSERT INTO price_change (`+priceChangeColumns+`)
+	VALUES (:id,:organization_id,:product_id,:variant_id,:price,:compare_at_price,:previous_price,:schedule_id,:changed_at,:changed_by,:repriced_at)`, change)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+	return
+}
@@ -0,0 +1,113 @@
+package product
+
+import (
+	"github.com/evermos/boilerplate-go/infras"
+	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/gofrs/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// ProductSearcher finds products matching a search query, most relevant
+// first, along with the number of matches across all pages. It is kept apart from ProductRepository so the search can move to
+// an external engine; such an engine is kept up to date through Index and
+// Remove.
+type ProductSearcher interface {
+	Search(q Query, filter Filter, limit, offset int) (res []SearchHit, total int, err error)
+	Index(prod Product) (err error)
+	Remove(id uuid.UUID) (err error)
+}
+
+// SearchHit is a product matching a search query. Highlights holds the
+// matching fields with the matches wrapped in <em> tags.
+type SearchHit struct {
+	Product    Product           `json:"product"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+// NewSearchHit builds the hit for a product with its highlights.
+func NewSearchHit(prod Product, score float64, q Query) SearchHit {
+	highlights := map[string]string{}
+	if name, ok := q.Highlight(prod.Name); ok {
+		highlights["name"] = name
+	}
+	if prod.Description.Valid {
+		if description, ok := q.Highlight(prod.Description.String); ok {
+			highlights["description"] = description
+		}
+	}
+	return SearchHit{Product: prod, Score: score, Highlights: highlights}
+}
+
+// ProductSearchMySQL searches the FULLTEXT index on the product table, which
+// MySQL keeps up to date by itself.
+type ProductSearchMySQL struct {
+	DB *infras.MySQLConn
+}
+
+func ProvideProductSearchMySQL(db *infras.MySQLConn) *ProductSearchMySQL {
+	return &ProductSearchMySQL{DB: db}
+}
+
+type searchRow struct {
+	Product
+	Score float64 `db:"score"`
+}
+
+func (s *ProductSearchMySQL) Search(q Query, filter Filter, limit, offset int) (res []SearchHit, total int, err error) {
+	against := q.BooleanMode()
+	filter.Query = ""
+	conditions, args, err := filter.conditions()
+	if err != nil {
+		return
+	}
+	countQuery, countArgs, err := sqlx.In(`SELECT COUNT(p.id) FROM product p
+	WHERE `+conditions+` AND MATCH(p.name, p.description) AGAINST (? IN BOOLEAN MODE)`, append(append([]interface{}{}, args...), against)...)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	err = s.DB.Read.Get(&total, s.DB.Read.Rebind(countQuery), countArgs...)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	query := `SELECT p.*, MATCH(p.name, p.description) AGAINST (? IN BOOLEAN MODE) AS score FROM product p
+	WHERE ` + conditions + ` AND MATCH(p.name, p.description) AGAINST (? IN BOOLEAN MODE)
+	ORDER BY score DESC, p.name LIMIT ? OFFSET ?`
+	args = append(append([]interface{}{against}, args...), against, limit, offset)
+	query, args, err = sqlx.In(query, args...)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	var rows []searchRow
+	err = s.DB.Read.Select(&rows, s.DB.Read.Rebind(query), args...)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	products := make([]Product, 0, len(rows))
+	for _, row := range rows {
+		products = append(products, row.Product)
+	}
+	err = attachDetails(s.DB.Read, products)
+	if err != nil {
+		return
+	}
+	res = make([]SearchHit, 0, len(rows))
+	for i, row := range rows {
+		res = append(res, NewSearchHit(products[i], row.Score, q))
+	}
+	return
+}
+
+// Index is a no-op, the FULLTEXT index follows the table.
+func (s *ProductSearchMySQL) Index(prod Product) (err error) {
+	return
+}
+
+// Remove is a no-op, the FULLTEXT index follows the table.
+func (s *ProductSearchMySQL) Remove(id uuid.UUID) (err error) {
+	return
+}
@@ -0,0 +1,141 @@
+package product
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gofrs/uuid"
+)
+
+// ProductSearchMemory is an in-memory ProductSearcher for tests and local
+// development. Products are only found after being indexed.
+type ProductSearchMemory struct {
+	mu         sync.RWMutex
+	products   map[uuid.UUID]Product
+	categories map[uuid.UUID][]uuid.UUID
+}
+
+func NewProductSearchMemory() *ProductSearchMemory {
+	return &ProductSearchMemory{
+		products:   map[uuid.UUID]Product{},
+		categories: map[uuid.UUID][]uuid.UUID{},
+	}
+}
+
+func (s *ProductSearchMemory) Index(prod Product) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.products[prod.Id] = prod
+	return
+}
+
+func (s *ProductSearchMemory) Remove(id uuid.UUID) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.products, id)
+	delete(s.categories, id)
+	return
+}
+
+// SetCategories records the categories of a product for category filters.
+func (s *ProductSearchMemory) SetCategories(productId uuid.UUID, categoryIds []uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.categories[productId] = categoryIds
+}
+
+func (s *ProductSearchMemory) Search(q Query, filter Filter, limit, offset int) (res []SearchHit, total int, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	hits := []SearchHit{}
+	for _, prod := range s.products {
+		if prod.OrganizationId != filter.OrganizationId || prod.IsDeleted() || !s.inCategories(prod.Id, filter.CategoryIds) || !filter.matches(prod) {
+			continue
+		}
+		score, ok := memoryScore(q, prod)
+		if !ok {
+			continue
+		}
+		hits = append(hits, NewSearchHit(prod, score, q))
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Product.Name < hits[j].Product.Name
+	})
+	total = len(hits)
+	res = []SearchHit{}
+	if offset < len(hits) {
+		end := offset + limit
+		if end > len(hits) {
+			end = len(hits)
+		}
+		res = hits[offset:end]
+	}
+	return
+}
+
+func (s *ProductSearchMemory) inCategories(productId uuid.UUID, categoryIds []uuid.UUID) bool {
+	if len(categoryIds) == 0 {
+		return true
+	}
+	for _, assigned := range s.categories[productId] {
+		for _, id := range categoryIds {
+			if assigned == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// memoryScore mirrors the boolean mode search: long terms and phrases are
+// required, excluded words must not appear. Matches in the name weigh more
+// than matches in the description.
+func memoryScore(q Query, prod Product) (score float64, ok bool) {
+	name := splitWords(prod.Name)
+	description := splitWords(prod.Description.String)
+	for _, excluded := range q.Excluded {
+		if containsWords(name, excluded) || containsWords(description, excluded) {
+			return
+		}
+	}
+	for _, term := range q.Terms {
+		matches := 2*countPrefix(name, term) + countPrefix(description, term)
+		if matches == 0 && len([]rune(term)) >= minRequiredTermLength {
+			return
+		}
+		score += float64(matches)
+	}
+	for _, phrase := range q.Phrases {
+		inName, inDescription := containsWords(name, phrase), containsWords(description, phrase)
+		if !inName && !inDescription {
+			return
+		}
+		if inName {
+			score += 4
+		}
+		if inDescription {
+			score += 2
+		}
+	}
+	ok = score > 0
+	return
+}
+
+func countPrefix(words []string, term string) (count int) {
+	for _, word := range words {
+		if strings.HasPrefix(word, term) {
+			count++
+		}
+	}
+	return
+}
+
+// containsWords reports whether the space separated phrase appears in words
+// as a whole.
+func containsWords(words []string, phrase string) bool {
+	return strings.Contains(" "+strings.Join(words, " ")+" ", " "+phrase+" ")
+}
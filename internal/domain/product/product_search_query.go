@@ -0,0 +1,161 @@
+package product
+
+import (
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/evermos/boilerplate-go/shared/failure"
+)
+
+// maxQueryParts bounds how many terms and phrases a search query can have.
+const maxQueryParts = 10
+
+// minRequiredTermLength is the shortest term the full-text index stores.
+// Shorter terms still count towards relevance but are not required.
+const minRequiredTermLength = 3
+
+// Query is a parsed search query. Terms match words starting with them,
+// phrases match the words in order, and products matching anything in
+// Excluded are left out.
+type Query struct {
+	Terms    []string
+	Phrases  []string
+	Excluded []string
+}
+
+// ParseQuery parses a search query such as
+//
+//	red shirt "slim fit" -polo -"v neck"
+//
+// Words are matched as prefixes, quoted text as a phrase, and a leading
+// minus excludes the word or phrase. Punctuation inside words is treated as
+// a word break.
+func ParseQuery(raw string) (q Query, err error) {
+	rest := strings.TrimSpace(raw)
+	for rest != "" {
+		excluded := false
+		if rest[0] == '-' {
+			excluded = true
+			rest = rest[1:]
+		}
+		var token string
+		phrase := false
+		if strings.HasPrefix(rest, `"`) {
+			phrase = true
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				token, rest = rest[1:], ""
+			} else {
+				token, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			end := strings.IndexFunc(rest, unicode.IsSpace)
+			if end < 0 {
+				token, rest = rest, ""
+			} else {
+				token, rest = rest[:end], rest[end:]
+			}
+		}
+		rest = strings.TrimSpace(rest)
+
+		words := splitWords(token)
+		switch {
+		case len(words) == 0:
+			continue
+		case excluded:
+			q.Excluded = append(q.Excluded, strings.Join(words, " "))
+		case phrase && len(words) > 1:
+			q.Phrases = append(q.Phrases, strings.Join(words, " "))
+		default:
+			q.Terms = append(q.Terms, words...)
+		}
+	}
+	if len(q.Terms) == 0 && len(q.Phrases) == 0 {
+		err = failure.BadRequestFromString("search query needs at least one word that is not excluded")
+		return
+	}
+	if len(q.Terms)+len(q.Phrases)+len(q.Excluded) > maxQueryParts {
+		err = failure.BadRequestFromString("search query has too many words")
+		return
+	}
+	return
+}
+
+// BooleanMode renders the query for MySQL's MATCH ... AGAINST in boolean
+// mode.
+func (q Query) BooleanMode() string {
+	parts := []string{}
+	for _, term := range q.Terms {
+		if len([]rune(term)) < minRequiredTermLength {
+			parts = append(parts, term+"*")
+			continue
+		}
+		parts = append(parts, "+"+term+"*")
+	}
+	for _, phrase := range q.Phrases {
+		parts = append(parts, `+"`+phrase+`"`)
+	}
+	for _, excluded := range q.Excluded {
+		if strings.Contains(excluded, " ") {
+			parts = append(parts, `-"`+excluded+`"`)
+			continue
+		}
+		parts = append(parts, "-"+excluded)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Highlight returns text, HTML escaped, with the parts matching the query
+// wrapped in <em> tags. It reports whether anything matched.
+func (q Query) Highlight(text string) (res string, matched bool) {
+	pattern := q.pattern()
+	if pattern == nil {
+		return html.EscapeString(text), false
+	}
+	var b strings.Builder
+	last := 0
+	for _, loc := range pattern.FindAllStringIndex(text, -1) {
+		b.WriteString(html.EscapeString(text[last:loc[0]]))
+		b.WriteString("<em>")
+		b.WriteString(html.EscapeString(text[loc[0]:loc[1]]))
+		b.WriteString("</em>")
+		last = loc[1]
+		matched = true
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return b.String(), matched
+}
+
+// pattern matches the phrases and the words starting with a term, longest
+// first so phrases win over their own words.
+func (q Query) pattern() *regexp.Regexp {
+	alternatives := []string{}
+	for _, phrase := range q.Phrases {
+		words := strings.Split(phrase, " ")
+		for i := range words {
+			words[i] = regexp.QuoteMeta(words[i])
+		}
+		alternatives = append(alternatives, `\b`+strings.Join(words, `\W+`)+`\b`)
+	}
+	for _, term := range q.Terms {
+		alternatives = append(alternatives, `\b`+regexp.QuoteMeta(term)+`\w*`)
+	}
+	if len(alternatives) == 0 {
+		return nil
+	}
+	sort.SliceStable(alternatives, func(i, j int) bool {
+		return len(alternatives[i]) > len(alternatives[j])
+	})
+	return regexp.MustCompile(`(?i)` + strings.Join(alternatives, "|"))
+}
+
+// splitWords lowercases token and splits it on anything that is not a
+// letter or digit, which also strips the full-text operators.
+func splitWords(token string) []string {
+	return strings.FieldsFunc(strings.ToLower(token), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,127 @@
+package product_test
+
+import (
+	"testing"
+
+	"github.com/evermos/boilerplate-go/internal/domain/product"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQuery(t *testing.T) {
+	t.Run("TermsPhrasesAndExclusions", func(t *testing.T) {
+		q, err := product.ParseQuery(`Red shirt "Slim  Fit" -polo -"v-neck" +x`)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"red", "shirt", "x"}, q.Terms)
+		assert.Equal(t, []string{"slim fit"}, q.Phrases)
+		assert.Equal(t, []string{"polo", "v neck"}, q.Excluded)
+		assert.Equal(t, `+red* +shirt* x* +"slim fit" -polo -"v neck"`, q.BooleanMode())
+	})
+
+	t.Run("OperatorsAreStripped", func(t *testing.T) {
+		q, err := product.ParseQuery(`shirt') OR 1=1 -- ~*@>`)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"shirt", "or", "1", "1"}, q.Terms)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, raw := range []string{"", "   ", "-polo", `"" -"v neck"`, "a b c d e f g h i j k"} {
+			_, err := product.ParseQuery(raw)
+			assert.Error(t, err, raw)
+		}
+	})
+
+	t.Run("Highlight", func(t *testing.T) {
+		q, _ := product.ParseQuery(`shirt "slim fit"`)
+		res, matched := q.Highlight("Slim fit <b>Shirts</b> & more")
+		assert.True(t, matched)
+		assert.Equal(t, "<em>Slim fit</em> &lt;b&gt;<em>Shirts</em>&lt;/b&gt; &amp; more", res)
+
+		_, matched = q.Highlight("Trousers")
+		assert.False(t, matched)
+	})
+}
+
+func TestProductSearchMemory(t *testing.T) {
+	orgId := uuid.Must(uuid.NewV4())
+	searcher := product.NewProductSearchMemory()
+	newProduct := func(name, description string) product.Product {
+		prod := product.Product{Id: uuid.Must(uuid.NewV4()), OrganizationId: orgId, Name: name, Description: null.NewString(description, description != "")}
+		assert.NoError(t, searcher.Index(prod))
+		return prod
+	}
+	shirt := newProduct("Red Shirt", "A slim fit shirt")
+	polo := newProduct("Red Polo Shirt", "")
+	newProduct("Blue Trousers", "Goes well with a red shirt")
+	other := newProduct("Red Shirt", "")
+	other.OrganizationId = uuid.Must(uuid.NewV4())
+	assert.NoError(t, searcher.Index(other))
+
+	search := func(raw string, filter product.Filter) (names []string) {
+		q, err := product.ParseQuery(raw)
+		assert.NoError(t, err)
+		filter.OrganizationId = orgId
+		hits, _, err := searcher.Search(q, filter, 10, 0)
+		assert.NoError(t, err)
+		for _, hit := range hits {
+			names = append(names, hit.Product.Name)
+		}
+		return
+	}
+
+	assert.Equal(t, []string{"Red Shirt", "Red Polo Shirt", "Blue Trousers"}, search("red shirt", product.Filter{}))
+	assert.Equal(t, []string{"Red Shirt", "Blue Trousers"}, search("red shirt -polo", product.Filter{}))
+	assert.Equal(t, []string{"Red Shirt"}, search(`"slim fit"`, product.Filter{}))
+
+	q, err := product.ParseQuery("red shirt")
+	assert.NoError(t, err)
+	hits, total, err := searcher.Search(q, product.Filter{OrganizationId: orgId}, 1, 1)
+	assert.NoError(t, err)
+	assert.Len(t, hits, 1)
+	assert.Equal(t, 3, total)
+
+	categoryId := uuid.Must(uuid.NewV4())
+	searcher.SetCategories(polo.Id, []uuid.UUID{categoryId})
+	assert.Equal(t, []string{"Red Polo Shirt"}, search("shirt", product.Filter{CategoryIds: []uuid.UUID{categoryId}}))
+
+	assert.NoError(t, searcher.Remove(shirt.Id))
+	assert.Equal(t, []string{"Red Polo Shirt", "Blue Trousers"}, search("red shirt", product.Filter{}))
+}
+
+func TestFilter(t *testing.T) {
+	t.Run("validate", func(t *testing.T) {
+		assert.NoError(t, product.Filter{MinPrice: null.FloatFrom(10), MaxPrice: null.FloatFrom(10)}.Validate())
+		assert.Error(t, product.Filter{MinPrice: null.FloatFrom(20), MaxPrice: null.FloatFrom(10)}.Validate())
+		assert.Error(t, product.Filter{MinPrice: null.FloatFrom(-1)}.Validate())
+	})
+
+	t.Run("memory search", func(t *testing.T) {
+		orgId := uuid.Must(uuid.NewV4())
+		searcher := product.NewProductSearchMemory()
+		newProduct := func(name string, price float64, stock int, attributes map[string]string) {
+			prod := product.Product{Id: uuid.Must(uuid.NewV4()), OrganizationId: orgId, Name: name, Price: price, Stock: stock, Attributes: attributes}
+			assert.NoError(t, searcher.Index(prod))
+		}
+		newProduct("Cotton Shirt", 10, 5, map[string]string{"brand": "acme", "material": "cotton"})
+		newProduct("Linen Shirt", 30, 0, map[string]string{"brand": "acme", "material": "linen"})
+		newProduct("Silk Shirt", 50, 2, map[string]string{"brand": "other", "material": "silk"})
+
+		search := func(filter product.Filter) (names []string) {
+			q, err := product.ParseQuery("shirt")
+			assert.NoError(t, err)
+			filter.OrganizationId = orgId
+			hits, _, err := searcher.Search(q, filter, 10, 0)
+			assert.NoError(t, err)
+			for _, hit := range hits {
+				names = append(names, hit.Product.Name)
+			}
+			return
+		}
+
+		assert.Equal(t, []string{"Cotton Shirt", "Linen Shirt"}, search(product.Filter{MaxPrice: null.FloatFrom(30)}))
+		assert.Equal(t, []string{"Cotton Shirt", "Silk Shirt"}, search(product.Filter{InStock: true}))
+		assert.Equal(t, []string{"Linen Shirt", "Silk Shirt"}, search(product.Filter{Attributes: map[string][]string{"material": {"linen", "silk"}}}))
+		assert.Equal(t, []string{"Linen Shirt"}, search(product.Filter{Attributes: map[string][]string{"material": {"linen", "silk"}, "brand": {"acme"}}}))
+	})
+}
@@ -1,28 +1,64 @@
 package product
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/logger"
 	"github.com/gofrs/uuid"
 )
 
 type ProductService interface {
-	Create(load ProductPayload, userId uuid.UUID) (res Product, err error)
-	GetAll(limit, offset int, sort, field, productTitle string) (res []Product, err error)
-	GetByID(id uuid.UUID) (res Product, err error)
-	ExistsByID(id uuid.UUID) (exists bool, err error)
+	Create(load ProductPayload, userId, orgId uuid.UUID) (res Product, err error)
+	GetAll(filter Filter, limit, offset int, sort, field string) (res []Product, err error)
+	Search(filter Filter, limit, offset int) (res []SearchHit, total int, err error)
+	GetFacets(filter Filter) (res Facets, err error)
+	Export(filter Filter, fn func(prod Product) error) (err error)
+	GetByID(id, orgId uuid.UUID) (res Product, err error)
+	Resolve(idOrSlug string, orgId uuid.UUID) (res Product, err error)
+	GetBySku(sku string, orgId uuid.UUID) (res Product, err error)
+	ExistsByID(id, orgId uuid.UUID) (exists bool, err error)
+	Update(load ProductPayload, id, userId, orgId uuid.UUID) (res Product, err error)
+	Patch(load ProductPatchPayload, id, userId, orgId uuid.UUID) (res Product, err error)
+	Delete(id, userId, orgId uuid.UUID) (res Product, err error)
+	Restore(id, userId, orgId uuid.UUID) (res Product, err error)
+	GetPriceHistory(id, orgId uuid.UUID, limit, offset int) (res []PriceChange, total int, err error)
+	GetPriceSchedules(id, orgId uuid.UUID) (res []PriceSchedule, err error)
+	SchedulePrice(load PriceSchedulePayload, id, userId, orgId uuid.UUID) (res PriceSchedule, err error)
+	CancelPriceSchedule(scheduleId, id, userId, orgId uuid.UUID) (res PriceSchedule, err error)
+	// ApplyPriceSchedules starts and ends the price schedules that are due.
+	ApplyPriceSchedules() (err error)
+	// GetUnrepricedChanges returns the price changes open carts are yet to
+	// be brought to, oldest first.
+	GetUnrepricedChanges() (res []PriceChange, err error)
+	MarkRepriced(change PriceChange) (err error)
 }
 
 type ProductServiceImpl struct {
-	Repo ProductRepository
+	Repo     ProductRepository
+	Searcher ProductSearcher
 }
 
-func ProvideProductServiceImpl(repo ProductRepository) *ProductServiceImpl {
-	return &ProductServiceImpl{Repo: repo}
+func ProvideProductServiceImpl(repo ProductRepository, searcher ProductSearcher) *ProductServiceImpl {
+	return &ProductServiceImpl{Repo: repo, Searcher: searcher}
 }
 
-func (s *ProductServiceImpl) Create(load ProductPayload, userId uuid.UUID) (res Product, err error) {
+func (s *ProductServiceImpl) Create(load ProductPayload, userId, orgId uuid.UUID) (res Product, err error) {
 
-	res, err = res.NewFromPayload(load, userId)
+	res, err = res.NewFromPayload(load, userId, orgId)
+	if err != nil {
+		return
+	}
+	if load.Slug == "" {
+		res.Slug, err = s.uniqueSlug(res)
+	} else {
+		err = s.ensureSlugAvailable(res)
+	}
+	if err != nil {
+		return
+	}
+	err = s.ensureSkuAvailable(res)
 	if err != nil {
 		return
 	}
@@ -30,19 +66,92 @@ func (s *ProductServiceImpl) Create(load ProductPayload, userId uuid.UUID) (res
 	if err != nil {
 		return
 	}
+	s.index(res)
 	return
 }
 
-func (s *ProductServiceImpl) GetAll(limit, offset int, sort, field, productTitle string) (res []Product, err error) {
-	res, err = s.Repo.GetAll(limit, offset, sort, field, productTitle)
+// GetAll lists products. With a search query they come most relevant first
+// and sort and field are ignored.
+func (s *ProductServiceImpl) GetAll(filter Filter, limit, offset int, sort, field string) (res []Product, err error) {
+	err = filter.Validate()
+	if err != nil {
+		return
+	}
+	if filter.Query != "" {
+		hits, _, err := s.Search(filter, limit, offset)
+		if err != nil {
+			return res, err
+		}
+		res = make([]Product, 0, len(hits))
+		for _, hit := range hits {
+			res = append(res, hit.Product)
+		}
+		return res, nil
+	}
+	res, err = s.Repo.GetAll(filter, limit, offset, sort, field)
 	if err != nil {
 		return
 	}
 	return
 }
 
-func (s *ProductServiceImpl) GetByID(id uuid.UUID) (res Product, err error) {
-	exists, err := s.Repo.ExistsByID(id.String())
+// Search finds the products matching filter.Query, most relevant first,
+// with the matches highlighted, and counts all of them.
+func (s *ProductServiceImpl) Search(filter Filter, limit, offset int) (res []SearchHit, total int, err error) {
+	err = filter.Validate()
+	if err != nil {
+		return
+	}
+	q, err := ParseQuery(filter.Query)
+	if err != nil {
+		return
+	}
+	return s.Searcher.Search(q, filter, limit, offset)
+}
+
+// GetFacets counts the products matching the filter per price range, stock,
+// category and attribute value.
+func (s *ProductServiceImpl) GetFacets(filter Filter) (res Facets, err error) {
+	err = filter.Validate()
+	if err != nil {
+		return
+	}
+	return s.Repo.GetFacets(filter)
+}
+
+// Export calls fn for every product matching the filter, in id order,
+// reading exportBatchSize products at a time. A search query is ignored.
+func (s *ProductServiceImpl) Export(filter Filter, fn func(prod Product) error) (err error) {
+	err = filter.Validate()
+	if err != nil {
+		return
+	}
+	afterId := ""
+	for {
+		products, err := s.Repo.GetAllAfter(filter, afterId, exportBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, prod := range products {
+			if err := fn(prod); err != nil {
+				return err
+			}
+		}
+		if len(products) < exportBatchSize {
+			return nil
+		}
+		afterId = products[len(products)-1].Id.String()
+	}
+}
+
+// exportBatchSize is the number of products Export reads at a time.
+const exportBatchSize = 100
+
+// priceBatchSize caps the schedules and price changes handled in one run.
+const priceBatchSize = 100
+
+func (s *ProductServiceImpl) GetByID(id, orgId uuid.UUID) (res Product, err error) {
+	exists, err := s.Repo.ExistsByID(id.String(), orgId.String())
 
 	if err != nil {
 		return
@@ -53,15 +162,294 @@ func (s *ProductServiceImpl) GetByID(id uuid.UUID) (res Product, err error) {
 		return
 	}
 
-	res, err = s.Repo.GetByID(id.String())
+	res, err = s.Repo.GetByID(id.String(), orgId.String())
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// Resolve finds a product by id or by slug. Deleted products are not found.
+func (s *ProductServiceImpl) Resolve(idOrSlug string, orgId uuid.UUID) (res Product, err error) {
+	if id, err := uuid.FromString(idOrSlug); err == nil {
+		return s.GetByID(id, orgId)
+	}
+	return s.Repo.GetBySlug(idOrSlug, orgId.String())
+}
+
+// GetBySku returns the product with the SKU, including deleted ones.
+func (s *ProductServiceImpl) GetBySku(sku string, orgId uuid.UUID) (res Product, err error) {
+	return s.Repo.GetBySku(sku, orgId.String())
+}
+
+// Update replaces the product. Its stock is set to the given level, with the
+// difference recorded as an adjustment.
+func (s *ProductServiceImpl) Update(load ProductPayload, id, userId, orgId uuid.UUID) (res Product, err error) {
+	res, err = s.GetByID(id, orgId)
+	if err != nil {
+		return
+	}
+	slug, sku := res.Slug, res.Sku
+	err = res.Update(load, userId)
+	if err != nil {
+		return
+	}
+	if res.Slug != slug {
+		err = s.ensureSlugAvailable(res)
+		if err != nil {
+			return
+		}
+	}
+	if res.Sku != sku {
+		err = s.ensureSkuAvailable(res)
+		if err != nil {
+			return
+		}
+	}
+	err = s.Repo.UpdateWithStock(res)
+	if err != nil {
+		return
+	}
+	s.index(res)
+	return
+}
+
+func (s *ProductServiceImpl) Patch(load ProductPatchPayload, id, userId, orgId uuid.UUID) (res Product, err error) {
+	res, err = s.GetByID(id, orgId)
+	if err != nil {
+		return
+	}
+	slug, sku := res.Slug, res.Sku
+	err = res.Patch(load, userId)
+	if err != nil {
+		return
+	}
+	if res.Slug != slug {
+		err = s.ensureSlugAvailable(res)
+		if err != nil {
+			return
+		}
+	}
+	if res.Sku != sku {
+		err = s.ensureSkuAvailable(res)
+		if err != nil {
+			return
+		}
+	}
+	if load.Stock != nil {
+		err = s.Repo.UpdateWithStock(res)
+	} else {
+		err = s.Repo.Update(res)
+	}
+	if err != nil {
+		return
+	}
+	s.index(res)
+	return
+}
+
+// Delete soft deletes the product. It disappears from listings and can no
+// longer be added to carts or checked out, while past orders keep it.
+func (s *ProductServiceImpl) Delete(id, userId, orgId uuid.UUID) (res Product, err error) {
+	res, err = s.GetByID(id, orgId)
+	if err != nil {
+		return
+	}
+	err = res.SoftDelete(userId)
+	if err != nil {
+		return
+	}
+	err = s.Repo.Update(res)
+	if err != nil {
+		return
+	}
+	if err := s.Searcher.Remove(res.Id); err != nil {
+		logger.ErrorWithStack(err)
+	}
+	return
+}
+
+func (s *ProductServiceImpl) Restore(id, userId, orgId uuid.UUID) (res Product, err error) {
+	res, err = s.Repo.GetByID(id.String(), orgId.String())
+	if err != nil {
+		return
+	}
+	err = res.Restore(userId)
+	if err != nil {
+		return
+	}
+	err = s.Repo.Update(res)
+	if err != nil {
+		return
+	}
+	s.index(res)
+	return
+}
+
+// ExistsByID reports whether the product exists and is not deleted.
+func (s *ProductServiceImpl) ExistsByID(id, orgId uuid.UUID) (exists bool, err error) {
+	exists, err = s.Repo.ExistsByID(id.String(), orgId.String())
+	return
+}
+
+// uniqueSlug makes the generated slug of the product unique within its
+// organization by appending a number, or a part of the product's id when
+// the first few numbers are taken as well.
+func (s *ProductServiceImpl) uniqueSlug(prod Product) (slug string, err error) {
+	for i := 1; i <= maxSlugSuffix; i++ {
+		slug = prod.Slug
+		if i > 1 {
+			slug = fmt.Sprintf("%s-%d", prod.Slug, i)
+		}
+		exists, err := s.Repo.SlugExists(slug, prod.OrganizationId.String(), prod.Id.String())
+		if err != nil {
+			return slug, err
+		}
+		if !exists {
+			return slug, nil
+		}
+	}
+	return prod.Slug + "-" + prod.Id.String()[:8], nil
+}
+
+// maxSlugSuffix is the highest number uniqueSlug tries.
+const maxSlugSuffix = 10
+
+func (s *ProductServiceImpl) ensureSlugAvailable(prod Product) (err error) {
+	exists, err := s.Repo.SlugExists(prod.Slug, prod.OrganizationId.String(), prod.Id.String())
+	if err != nil {
+		return
+	}
+	if exists {
+		err = failure.Conflict("save", "product", "slug "+prod.Slug+" is taken")
+	}
+	return
+}
+
+func (s *ProductServiceImpl) ensureSkuAvailable(prod Product) (err error) {
+	if !prod.Sku.Valid {
+		return
+	}
+	exists, err := s.Repo.SkuExists(prod.Sku.String, prod.OrganizationId.String(), prod.Id.String())
+	if err != nil {
+		return
+	}
+	if exists {
+		err = failure.Conflict("save", "product", "SKU "+prod.Sku.String+" is taken")
+	}
+	return
+}
+
+// index hands the product to the searcher. The database stays the source of
+// truth, so a failure is logged rather than failing the write; the next
+// write of the product indexes it again.
+func (s *ProductServiceImpl) index(prod Product) {
+	if err := s.Searcher.Index(prod); err != nil {
+		logger.ErrorWithStack(err)
+	}
+}
+
+// GetPriceHistory lists the price changes of the product and its variants,
+// newest first, and counts all of them.
+func (s *ProductServiceImpl) GetPriceHistory(id, orgId uuid.UUID, limit, offset int) (res []PriceChange, total int, err error) {
+	_, err = s.GetByID(id, orgId)
+	if err != nil {
+		return
+	}
+	return s.Repo.GetPriceHistory(id.String(), orgId.String(), limit, offset)
+}
+
+func (s *ProductServiceImpl) GetPriceSchedules(id, orgId uuid.UUID) (res []PriceSchedule, err error) {
+	_, err = s.GetByID(id, orgId)
+	if err != nil {
+		return
+	}
+	return s.Repo.GetPriceSchedules(id.String(), orgId.String())
+}
+
+// SchedulePrice schedules a price for the product. The window cannot
+// overlap those of its other schedules that are yet to start or end.
+func (s *ProductServiceImpl) SchedulePrice(load PriceSchedulePayload, id, userId, orgId uuid.UUID) (res PriceSchedule, err error) {
+	prod, err := s.GetByID(id, orgId)
+	if err != nil {
+		return
+	}
+	if prod.IsDeleted() {
+		err = failure.Conflict("schedule", "price", "product is deleted")
+		return
+	}
+	res, err = res.NewFromPayload(load, prod, userId, time.Now().UTC())
 	if err != nil {
 		return
 	}
+	schedules, err := s.Repo.GetPriceSchedules(id.String(), orgId.String())
+	if err != nil {
+		return
+	}
+	for _, other := range schedules {
+		if other.IsPending() && res.Overlaps(other) {
+			err = failure.Conflict("schedule", "price", fmt.Sprintf("overlaps the schedule starting at %s", other.StartsAt.Format(time.RFC3339)))
+			return
+		}
+	}
+	err = s.Repo.CreatePriceSchedule(res)
+	return
+}
 
+// CancelPriceSchedule cancels a schedule that has not started.
+func (s *ProductServiceImpl) CancelPriceSchedule(scheduleId, id, userId, orgId uuid.UUID) (res PriceSchedule, err error) {
+	res, err = s.Repo.GetPriceSchedule(scheduleId.String(), id.String(), orgId.String())
+	if err != nil {
+		return
+	}
+	err = res.Cancel(userId)
+	if err != nil {
+		return
+	}
+	err = s.Repo.UpdatePriceSchedule(res)
 	return
 }
 
-func (s *ProductServiceImpl) ExistsByID(id uuid.UUID) (exists bool, err error) {
-	exists, err = s.Repo.ExistsByID(id.String())
+func (s *ProductServiceImpl) ApplyPriceSchedules() (err error) {
+	now := time.Now().UTC()
+	schedules, err := s.Repo.GetDuePriceSchedules(now, priceBatchSize)
+	if err != nil {
+		return
+	}
+	for _, schedule := range schedules {
+		prod, err := s.Repo.GetByID(schedule.ProductId.String(), schedule.OrganizationId.String())
+		if err != nil {
+			return err
+		}
+		// a window that passed while nobody was looking starts and ends
+		// in one go
+		if schedule.Status == ScheduleScheduled {
+			err = schedule.Start(&prod, now)
+			if err != nil {
+				return err
+			}
+		}
+		if schedule.IsDue(now) {
+			err = schedule.End(&prod, now)
+			if err != nil {
+				return err
+			}
+		}
+		err = s.Repo.ApplyPriceSchedule(prod, schedule)
+		if err != nil {
+			return err
+		}
+		s.index(prod)
+	}
 	return
 }
+
+func (s *ProductServiceImpl) GetUnrepricedChanges() (res []PriceChange, err error) {
+	return s.Repo.GetUnrepricedChanges(priceBatchSize)
+}
+
+// MarkRepriced records that open carts have been brought to the change.
+func (s *ProductServiceImpl) MarkRepriced(change PriceChange) (err error) {
+	return s.Repo.MarkRepriced(change.Id.String(), time.Now().UTC())
+}
@@ -0,0 +1,364 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/evermos/boilerplate-go/internal/domain/user"
+)
+
+// Condition is a single attribute comparison of a SCIM filter.
+type Condition struct {
+	Attribute string
+	Operator  string
+	Value     string
+}
+
+// ParseFilter parses the subset of the SCIM filter grammar made of
+// comparisons joined by "and", e.g. `userName eq "jane" and active eq true`.
+// Attributes and operators are lower-cased.
+func ParseFilter(expr string) (res []Condition, err error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return
+	}
+	for i := 0; i < len(tokens); {
+		if len(res) > 0 {
+			if strings.ToLower(tokens[i]) != "and" {
+				err = invalidFilter("only \"and\" is supported between comparisons")
+				return
+			}
+			i++
+		}
+		if i+1 >= len(tokens) {
+			err = invalidFilter("incomplete comparison")
+			return
+		}
+		cond := Condition{Attribute: normalizeAttribute(tokens[i]), Operator: strings.ToLower(tokens[i+1])}
+		i += 2
+		if cond.Operator != "pr" {
+			if i >= len(tokens) {
+				err = invalidFilter("missing value for " + cond.Attribute)
+				return
+			}
+			cond.Value = tokens[i]
+			i++
+		}
+		res = append(res, cond)
+	}
+	return
+}
+
+// tokenize splits the filter on whitespace, keeping quoted strings whole
+// and unquoting them.
+func tokenize(expr string) (tokens []string, err error) {
+	var current strings.Builder
+	inQuotes, quoted := false, false
+	flush := func() {
+		if current.Len() > 0 || quoted {
+			tokens = append(tokens, current.String())
+		}
+		current.Reset()
+		quoted = false
+	}
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case inQuotes && c == '\\' && i+1 < len(expr):
+			i++
+			current.WriteByte(expr[i])
+		case c == '"':
+			inQuotes = !inQuotes
+			quoted = true
+		case !inQuotes && (c == ' ' || c == '\t'):
+			flush()
+		case !inQuotes && (c == '(' || c == ')' || c == '['):
+			err = invalidFilter("grouping and complex attribute filters are not supported")
+			return
+		default:
+			current.WriteByte(c)
+		}
+	}
+	if inQuotes {
+		err = invalidFilter("unterminated string")
+		return
+	}
+	flush()
+	return
+}
+
+// normalizeAttribute lower-cases an attribute path and drops the core
+// schema prefix.
+func normalizeAttribute(attribute string) string {
+	attribute = strings.ToLower(attribute)
+	for _, schema := range []string{SchemaUser, SchemaGroup} {
+		attribute = strings.TrimPrefix(attribute, strings.ToLower(schema)+":")
+	}
+	return attribute
+}
+
+// ToUserFilter maps conditions onto a user filter. Text attributes support
+// eq and co, but not both in the same filter.
+func ToUserFilter(conditions []Condition) (res user.Filter, err error) {
+	operator := ""
+	for _, c := range conditions {
+		if c.Attribute == "active" {
+			if c.Operator != "eq" {
+				err = invalidFilter("active only supports eq")
+				return
+			}
+			res.Status = user.StatusDeleted
+			if strings.EqualFold(c.Value, "true") {
+				res.Status = user.StatusActive
+			}
+			continue
+		}
+		if c.Operator != "eq" && c.Operator != "co" {
+			err = invalidFilter("operator " + c.Operator + " is not supported")
+			return
+		}
+		if operator != "" && operator != c.Operator {
+			err = invalidFilter("eq and co cannot be combined")
+			return
+		}
+		operator = c.Operator
+		switch c.Attribute {
+		case "username":
+			res.UserName = c.Value
+		case "emails", "emails.value":
+			res.Email = c.Value
+		case "name.formatted", "displayname":
+			res.Name = c.Value
+		default:
+			err = invalidFilter("attribute " + c.Attribute + " cannot be filtered on")
+			return
+		}
+	}
+	res.Exact = operator == "eq"
+	return
+}
+
+// Apply applies one PATCH operation to the user.
+func (u *User) Apply(op PatchOperation) (err error) {
+	name := strings.ToLower(op.Op)
+	if name != "add" && name != "replace" && name != "remove" {
+		return NewError(http.StatusBadRequest, "invalidSyntax", "unknown operation "+op.Op)
+	}
+	if op.Path != "" {
+		return u.apply(name, normalizePath(op.Path), op.Value)
+	}
+	var values map[string]json.RawMessage
+	if err = json.Unmarshal(op.Value, &values); err != nil {
+		return NewError(http.StatusBadRequest, "invalidValue", "value must be an object when no path is given")
+	}
+	for path, value := range values {
+		if err = u.apply(name, normalizePath(path), value); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (u *User) apply(op, path string, value json.RawMessage) (err error) {
+	if op == "remove" {
+		switch path {
+		case "phonenumbers", "phonenumbers.value":
+			u.PhoneNumbers = []MultiValue{}
+		case "locale":
+			u.Locale = user.DefaultLocale
+		case "timezone":
+			u.Timezone = user.DefaultTimezone
+		case "externalid", "roles", "roles.value":
+		default:
+			err = NewError(http.StatusBadRequest, "mutability", path+" cannot be removed")
+		}
+		return
+	}
+	switch path {
+	case "active":
+		var active bool
+		active, err = decodeBool(value)
+		u.Active = &active
+	case "username":
+		err = decode(value, &u.UserName)
+	case "displayname":
+		err = decode(value, &u.DisplayName)
+	case "name":
+		err = decode(value, &u.Name)
+	case "name.formatted":
+		err = decode(value, &u.Name.Formatted)
+	case "name.givenname":
+		err = decode(value, &u.Name.GivenName)
+		u.Name.Formatted = ""
+	case "name.familyname":
+		err = decode(value, &u.Name.FamilyName)
+		u.Name.Formatted = ""
+	case "emails":
+		err = decode(value, &u.Emails)
+	case "emails.value":
+		var email string
+		err = decode(value, &email)
+		u.Emails = []MultiValue{{Value: email, Type: "work", Primary: true}}
+	case "phonenumbers":
+		err = decode(value, &u.PhoneNumbers)
+	case "phonenumbers.value":
+		var phone string
+		err = decode(value, &phone)
+		u.PhoneNumbers = []MultiValue{{Value: phone, Type: "work", Primary: true}}
+	case "roles":
+		err = decode(value, &u.Roles)
+	case "roles.value":
+		var role string
+		err = decode(value, &role)
+		u.Roles = []MultiValue{{Value: role, Primary: true}}
+	case "locale":
+		err = decode(value, &u.Locale)
+	case "timezone":
+		err = decode(value, &u.Timezone)
+	case "password":
+		err = decode(value, &u.Password)
+	case "externalid":
+	default:
+		err = NewError(http.StatusBadRequest, "invalidPath", "unsupported attribute "+path)
+	}
+	return
+}
+
+// Apply applies one PATCH operation to the group.
+func (g *Group) Apply(op PatchOperation) (err error) {
+	name := strings.ToLower(op.Op)
+	if name != "add" && name != "replace" && name != "remove" {
+		return NewError(http.StatusBadRequest, "invalidSyntax", "unknown operation "+op.Op)
+	}
+	if op.Path == "" {
+		var values map[string]json.RawMessage
+		if err = json.Unmarshal(op.Value, &values); err != nil {
+			return NewError(http.StatusBadRequest, "invalidValue", "value must be an object when no path is given")
+		}
+		for path, value := range values {
+			if err = g.apply(name, path, value); err != nil {
+				return
+			}
+		}
+		return
+	}
+	return g.apply(name, op.Path, op.Value)
+}
+
+func (g *Group) apply(op, path string, value json.RawMessage) (err error) {
+	// members[value eq "id"] addresses a single member
+	if open := strings.Index(path, "["); open > 0 && strings.HasSuffix(path, "]") {
+		if op != "remove" || normalizeAttribute(path[:open]) != "members" {
+			return NewError(http.StatusBadRequest, "invalidPath", "unsupported path "+path)
+		}
+		conditions, err := ParseFilter(path[open+1 : len(path)-1])
+		if err != nil {
+			return err
+		}
+		if len(conditions) != 1 || conditions[0].Attribute != "value" || conditions[0].Operator != "eq" {
+			return NewError(http.StatusBadRequest, "invalidFilter", "members can only be selected by value eq")
+		}
+		g.removeMembers([]MultiValue{{Value: conditions[0].Value}})
+		return nil
+	}
+	switch normalizeAttribute(path) {
+	case "displayname":
+		if op == "remove" {
+			return NewError(http.StatusBadRequest, "mutability", "displayName cannot be removed")
+		}
+		err = decode(value, &g.DisplayName)
+	case "members":
+		var members []MultiValue
+		if len(value) > 0 {
+			if err = decode(value, &members); err != nil {
+				return
+			}
+		}
+		switch op {
+		case "add":
+			g.addMembers(members)
+		case "replace":
+			g.Members = []MultiValue{}
+			g.addMembers(members)
+		case "remove":
+			if len(value) == 0 {
+				g.Members = []MultiValue{}
+				return
+			}
+			g.removeMembers(members)
+		}
+	case "externalid":
+	default:
+		err = NewError(http.StatusBadRequest, "invalidPath", "unsupported attribute "+path)
+	}
+	return
+}
+
+func (g *Group) addMembers(members []MultiValue) {
+	for _, m := range members {
+		if !g.HasMember(m.Value) {
+			g.Members = append(g.Members, MultiValue{Value: m.Value})
+		}
+	}
+}
+
+func (g *Group) removeMembers(members []MultiValue) {
+	kept := []MultiValue{}
+	for _, current := range g.Members {
+		removed := false
+		for _, m := range members {
+			if strings.EqualFold(current.Value, m.Value) {
+				removed = true
+			}
+		}
+		if !removed {
+			kept = append(kept, current)
+		}
+	}
+	g.Members = kept
+}
+
+func (g *Group) HasMember(userId string) bool {
+	for _, m := range g.Members {
+		if strings.EqualFold(m.Value, userId) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizePath turns a user attribute path such as
+// `emails[type eq "work"].value` into `emails.value`.
+func normalizePath(path string) string {
+	if open := strings.Index(path, "["); open >= 0 {
+		if end := strings.Index(path, "]"); end > open {
+			path = path[:open] + path[end+1:]
+		}
+	}
+	return normalizeAttribute(path)
+}
+
+func decode(value json.RawMessage, target interface{}) error {
+	if err := json.Unmarshal(value, target); err != nil {
+		return NewError(http.StatusBadRequest, "invalidValue", err.Error())
+	}
+	return nil
+}
+
+// decodeBool accepts JSON booleans as well as "true" and "false" strings,
+// which some identity providers send.
+func decodeBool(value json.RawMessage) (res bool, err error) {
+	if json.Unmarshal(value, &res) == nil {
+		return
+	}
+	var s string
+	if json.Unmarshal(value, &s) == nil && (strings.EqualFold(s, "true") || strings.EqualFold(s, "false")) {
+		return strings.EqualFold(s, "true"), nil
+	}
+	return false, NewError(http.StatusBadRequest, "invalidValue", "expected a boolean")
+}
+
+func invalidFilter(detail string) error {
+	return NewError(http.StatusBadRequest, "invalidFilter", detail)
+}
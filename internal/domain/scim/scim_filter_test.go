@@ -0,0 +1,74 @@
+package scim_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/evermos/boilerplate-go/internal/domain/scim"
+	"github.com/evermos/boilerplate-go/internal/domain/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFilter(t *testing.T) {
+	t.Run("Comparisons", func(t *testing.T) {
+		conditions, err := scim.ParseFilter(`userName eq "jane \"j\" doe" and urn:ietf:params:scim:schemas:core:2.0:User:active eq true`)
+		assert.NoError(t, err)
+		assert.Equal(t, []scim.Condition{
+			{Attribute: "username", Operator: "eq", Value: `jane "j" doe`},
+			{Attribute: "active", Operator: "eq", Value: "true"},
+		}, conditions)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, filter := range []string{`userName eq`, `userName eq "a" or email eq "b"`, `emails[type eq "work"]`, `userName eq "a`} {
+			_, err := scim.ParseFilter(filter)
+			assert.Error(t, err, filter)
+		}
+	})
+
+	t.Run("ToUserFilter", func(t *testing.T) {
+		conditions, _ := scim.ParseFilter(`emails.value eq "jane@example.com" and active eq false`)
+		filter, err := scim.ToUserFilter(conditions)
+		assert.NoError(t, err)
+		assert.Equal(t, "jane@example.com", filter.Email)
+		assert.Equal(t, user.StatusDeleted, filter.Status)
+		assert.True(t, filter.Exact)
+
+		conditions, _ = scim.ParseFilter(`userName eq "jane" and name.formatted co "Doe"`)
+		_, err = scim.ToUserFilter(conditions)
+		assert.Error(t, err)
+	})
+}
+
+func TestUserApply(t *testing.T) {
+	u := scim.User{UserName: "jane", Name: scim.Name{Formatted: "Jane Doe"}}
+	ops := []scim.PatchOperation{
+		{Op: "Replace", Value: json.RawMessage(`{"active":"False","displayName":"JD"}`)},
+		{Op: "replace", Path: `emails[type eq "work"].value`, Value: json.RawMessage(`"jane@example.com"`)},
+		{Op: "replace", Path: "name.givenName", Value: json.RawMessage(`"Janet"`)},
+	}
+	for _, op := range ops {
+		assert.NoError(t, u.Apply(op))
+	}
+	assert.False(t, *u.Active)
+	assert.Equal(t, "jane@example.com", u.PrimaryEmail())
+	assert.Equal(t, "Janet", u.FullName())
+
+	assert.Error(t, u.Apply(scim.PatchOperation{Op: "replace", Path: "nickName", Value: json.RawMessage(`"j"`)}))
+	assert.Error(t, u.Apply(scim.PatchOperation{Op: "remove", Path: "userName"}))
+}
+
+func TestGroupApply(t *testing.T) {
+	g := scim.Group{DisplayName: "staff", Members: []scim.MultiValue{{Value: "a"}}}
+	assert.NoError(t, g.Apply(scim.PatchOperation{Op: "add", Path: "members", Value: json.RawMessage(`[{"value":"b"},{"value":"a"}]`)}))
+	assert.Equal(t, []scim.MultiValue{{Value: "a"}, {Value: "b"}}, g.Members)
+
+	assert.NoError(t, g.Apply(scim.PatchOperation{Op: "remove", Path: `members[value eq "a"]`}))
+	assert.Equal(t, []scim.MultiValue{{Value: "b"}}, g.Members)
+
+	assert.NoError(t, g.Apply(scim.PatchOperation{Op: "replace", Value: json.RawMessage(`{"displayName":"wholesale"}`)}))
+	assert.Equal(t, "wholesale", g.DisplayName)
+
+	assert.NoError(t, g.Apply(scim.PatchOperation{Op: "remove", Path: "members"}))
+	assert.Empty(t, g.Members)
+}
@@ -0,0 +1,273 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/evermos/boilerplate-go/internal/domain/group"
+	"github.com/evermos/boilerplate-go/internal/domain/user"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	SchemaUser         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup        = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaListResponse = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp      = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError        = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+const (
+	ResourceTypeUser  = "User"
+	ResourceTypeGroup = "Group"
+)
+
+// Pagination defaults. SCIM pages are addressed by a 1-based startIndex and
+// a count.
+const (
+	DefaultCount = 100
+	MaxCount     = 500
+)
+
+// ActorId is recorded as the creator or updater of everything changed
+// through SCIM.
+var ActorId = uuid.NewV5(uuid.NamespaceURL, "urn:ietf:params:scim")
+
+type Meta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location"`
+}
+
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// MultiValue is an entry of a multi-valued attribute such as emails or
+// group members.
+type MultiValue struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// User is the SCIM representation of a user. Roles hold the user's role in
+// the provisioned organization. ExternalId is accepted but not stored.
+type User struct {
+	Schemas      []string     `json:"schemas"`
+	Id           string       `json:"id,omitempty"`
+	ExternalId   string       `json:"externalId,omitempty"`
+	UserName     string       `json:"userName"`
+	Name         Name         `json:"name"`
+	DisplayName  string       `json:"displayName,omitempty"`
+	Emails       []MultiValue `json:"emails,omitempty"`
+	PhoneNumbers []MultiValue `json:"phoneNumbers,omitempty"`
+	Locale       string       `json:"locale,omitempty"`
+	Timezone     string       `json:"timezone,omitempty"`
+	Active       *bool        `json:"active,omitempty"`
+	Password     string       `json:"password,omitempty"`
+	Roles        []MultiValue `json:"roles,omitempty"`
+	Groups       []MultiValue `json:"groups,omitempty"`
+	Meta         *Meta        `json:"meta,omitempty"`
+}
+
+type Group struct {
+	Schemas     []string     `json:"schemas"`
+	Id          string       `json:"id,omitempty"`
+	DisplayName string       `json:"displayName"`
+	Members     []MultiValue `json:"members"`
+	Meta        *Meta        `json:"meta,omitempty"`
+}
+
+type ListResponse struct {
+	Schemas      []string      `json:"schemas"`
+	TotalResults int           `json:"totalResults"`
+	StartIndex   int           `json:"startIndex"`
+	ItemsPerPage int           `json:"itemsPerPage"`
+	Resources    []interface{} `json:"Resources"`
+}
+
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Error is a SCIM error response. It is also used as an error value so
+// services can pick the scimType.
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+	Status   string   `json:"status"`
+	code     int
+}
+
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+func (e *Error) Code() int {
+	return e.code
+}
+
+func NewError(code int, scimType, detail string) *Error {
+	return &Error{
+		Schemas:  []string{SchemaError},
+		ScimType: scimType,
+		Detail:   detail,
+		Status:   strconv.Itoa(code),
+		code:     code,
+	}
+}
+
+// ErrorFrom turns any error into a SCIM error, keeping the status code of
+// failures.
+func ErrorFrom(err error) *Error {
+	var scimErr *Error
+	if errors.As(err, &scimErr) {
+		return scimErr
+	}
+	code := failure.GetCode(err)
+	scimType := ""
+	switch code {
+	case http.StatusConflict:
+		scimType = "uniqueness"
+	case http.StatusBadRequest:
+		scimType = "invalidValue"
+	}
+	return NewError(code, scimType, err.Error())
+}
+
+// Page returns the limit and offset for a SCIM startIndex and count.
+func Page(startIndex, count int) (limit, offset int) {
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	if count < 0 {
+		count = 0
+	}
+	if count > MaxCount {
+		count = MaxCount
+	}
+	return count, startIndex - 1
+}
+
+func NewListResponse(resources []interface{}, total, startIndex int) ListResponse {
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	if resources == nil {
+		resources = []interface{}{}
+	}
+	return ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
+
+// UserFrom converts a user and the role it has in the provisioned
+// organization.
+func UserFrom(u user.User, role string, groups []group.Group, baseURL string) User {
+	active := !u.IsDeleted()
+	res := User{
+		Schemas:     []string{SchemaUser},
+		Id:          u.UserId.String(),
+		UserName:    u.UserName,
+		Name:        Name{Formatted: u.Name},
+		DisplayName: u.Name,
+		Emails:      []MultiValue{{Value: u.Email, Type: "work", Primary: true}},
+		Locale:      u.Locale,
+		Timezone:    u.Timezone,
+		Active:      &active,
+		Roles:       []MultiValue{{Value: role, Primary: true}},
+		Groups:      []MultiValue{},
+		Meta: &Meta{
+			ResourceType: ResourceTypeUser,
+			Created:      u.Created_at,
+			LastModified: u.Updated_at,
+			Location:     baseURL + "/Users/" + u.UserId.String(),
+		},
+	}
+	if u.PhoneNumber.Valid {
+		res.PhoneNumbers = []MultiValue{{Value: u.PhoneNumber.String, Type: "work", Primary: true}}
+	}
+	for _, g := range groups {
+		res.Groups = append(res.Groups, MultiValue{Value: g.Id.String(), Display: g.Name})
+	}
+	return res
+}
+
+// GroupFrom converts a group and the ids of its members.
+func GroupFrom(g group.Group, members []group.Member, baseURL string) Group {
+	res := Group{
+		Schemas:     []string{SchemaGroup},
+		Id:          g.Id.String(),
+		DisplayName: g.Name,
+		Members:     []MultiValue{},
+		Meta: &Meta{
+			ResourceType: ResourceTypeGroup,
+			Created:      g.CreatedAt,
+			LastModified: g.UpdatedAt,
+			Location:     baseURL + "/Groups/" + g.Id.String(),
+		},
+	}
+	for _, m := range members {
+		res.Members = append(res.Members, MultiValue{Value: m.UserId.String()})
+	}
+	return res
+}
+
+// FullName is the single name the user has here.
+func (u User) FullName() string {
+	if u.Name.Formatted != "" {
+		return u.Name.Formatted
+	}
+	if full := strings.TrimSpace(u.Name.GivenName + " " + u.Name.FamilyName); full != "" {
+		return full
+	}
+	return u.DisplayName
+}
+
+// PrimaryEmail is the primary email, or the first one if none is marked.
+func (u User) PrimaryEmail() string {
+	return primary(u.Emails)
+}
+
+func (u User) PrimaryPhoneNumber() string {
+	return primary(u.PhoneNumbers)
+}
+
+// Role is the requested role in the organization, empty if none was sent.
+func (u User) Role() string {
+	return primary(u.Roles)
+}
+
+func primary(values []MultiValue) string {
+	for _, v := range values {
+		if v.Primary {
+			return v.Value
+		}
+	}
+	if len(values) > 0 {
+		return values[0].Value
+	}
+	return ""
+}
@@ -0,0 +1,464 @@
+package scim
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/evermos/boilerplate-go/configs"
+	"github.com/evermos/boilerplate-go/internal/domain/group"
+	"github.com/evermos/boilerplate-go/internal/domain/organization"
+	"github.com/evermos/boilerplate-go/internal/domain/user"
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/roles"
+	"github.com/gofrs/uuid"
+)
+
+// ScimService maps SCIM resources onto the users and groups of the
+// organization configured in SCIM.ORGANIZATION_ID. Deactivating or deleting
+// a user soft deletes it, so it keeps showing up with active set to false.
+type ScimService interface {
+	GetUsers(filter string, startIndex, count int) (res ListResponse, err error)
+	GetUser(id uuid.UUID) (res User, err error)
+	CreateUser(load User) (res User, err error)
+	ReplaceUser(id uuid.UUID, load User) (res User, err error)
+	PatchUser(id uuid.UUID, load PatchRequest) (res User, err error)
+	DeleteUser(id uuid.UUID) (err error)
+	GetGroups(filter string, startIndex, count int) (res ListResponse, err error)
+	GetGroup(id uuid.UUID) (res Group, err error)
+	CreateGroup(load Group) (res Group, err error)
+	ReplaceGroup(id uuid.UUID, load Group) (res Group, err error)
+	PatchGroup(id uuid.UUID, load PatchRequest) (res Group, err error)
+	DeleteGroup(id uuid.UUID) (err error)
+}
+
+type ScimServiceImpl struct {
+	Config              *configs.Config
+	UserService         user.UserService
+	OrganizationService organization.OrganizationService
+	GroupService        group.GroupService
+}
+
+func ProvideScimServiceImpl(config *configs.Config, userService user.UserService, organizationService organization.OrganizationService, groupService group.GroupService) *ScimServiceImpl {
+	return &ScimServiceImpl{
+		Config:              config,
+		UserService:         userService,
+		OrganizationService: organizationService,
+		GroupService:        groupService,
+	}
+}
+
+// groupPageSize is the page size used to read all groups or members.
+const groupPageSize = 100
+
+func (s *ScimServiceImpl) GetUsers(filter string, startIndex, count int) (res ListResponse, err error) {
+	orgId, err := s.orgId()
+	if err != nil {
+		return
+	}
+	conditions, err := ParseFilter(filter)
+	if err != nil {
+		return
+	}
+	userFilter, err := ToUserFilter(conditions)
+	if err != nil {
+		return
+	}
+	userFilter.OrganizationId = orgId
+	limit, offset := Page(startIndex, count)
+	users, total, err := s.UserService.GetAll(userFilter, limit, offset, "ASC", "created_at")
+	if err != nil {
+		return
+	}
+	resources := []interface{}{}
+	for _, u := range users {
+		resource, err := s.toUser(orgId, u)
+		if err != nil {
+			return res, err
+		}
+		resources = append(resources, resource)
+	}
+	res = NewListResponse(resources, total, startIndex)
+	return
+}
+
+func (s *ScimServiceImpl) GetUser(id uuid.UUID) (res User, err error) {
+	orgId, err := s.orgId()
+	if err != nil {
+		return
+	}
+	u, err := s.getUser(orgId, id)
+	if err != nil {
+		return
+	}
+	return s.toUser(orgId, u)
+}
+
+// CreateUser provisions a user in the organization. Users provisioned
+// without a password get a random one and are expected to sign in through
+// the identity provider.
+func (s *ScimServiceImpl) CreateUser(load User) (res User, err error) {
+	orgId, err := s.orgId()
+	if err != nil {
+		return
+	}
+	password := load.Password
+	if password == "" {
+		password, err = randomPassword()
+		if err != nil {
+			return
+		}
+	}
+	role := load.Role()
+	if role == "" {
+		role = roles.GetStringFromRole(roles.Trainee)
+	}
+	payload := user.UserPayload{
+		Email:    load.PrimaryEmail(),
+		UserName: load.UserName,
+		Name:     load.FullName(),
+		Password: password,
+		Role:     role,
+	}
+	err = shared.GetValidator().Struct(payload)
+	if err != nil {
+		err = NewError(http.StatusBadRequest, "invalidValue", err.Error())
+		return
+	}
+	created, err := s.UserService.Create(payload, orgId)
+	if err != nil {
+		return
+	}
+	return s.replaceUser(orgId, created, load)
+}
+
+func (s *ScimServiceImpl) ReplaceUser(id uuid.UUID, load User) (res User, err error) {
+	orgId, err := s.orgId()
+	if err != nil {
+		return
+	}
+	current, err := s.getUser(orgId, id)
+	if err != nil {
+		return
+	}
+	return s.replaceUser(orgId, current, load)
+}
+
+// PatchUser applies the operations to the current representation of the
+// user and saves the result like a PUT would.
+func (s *ScimServiceImpl) PatchUser(id uuid.UUID, load PatchRequest) (res User, err error) {
+	orgId, err := s.orgId()
+	if err != nil {
+		return
+	}
+	current, err := s.getUser(orgId, id)
+	if err != nil {
+		return
+	}
+	desired, err := s.toUser(orgId, current)
+	if err != nil {
+		return
+	}
+	for _, op := range load.Operations {
+		err = desired.Apply(op)
+		if err != nil {
+			return
+		}
+	}
+	return s.replaceUser(orgId, current, desired)
+}
+
+func (s *ScimServiceImpl) DeleteUser(id uuid.UUID) (err error) {
+	orgId, err := s.orgId()
+	if err != nil {
+		return
+	}
+	current, err := s.getUser(orgId, id)
+	if err != nil {
+		return
+	}
+	if current.IsDeleted() {
+		return
+	}
+	_, err = s.UserService.DeleteByID(id, ActorId)
+	return
+}
+
+// replaceUser brings the stored user in line with desired. Reactivation
+// happens before the profile is saved and deactivation after, as deleted
+// users cannot be edited.
+func (s *ScimServiceImpl) replaceUser(orgId uuid.UUID, current user.User, desired User) (res User, err error) {
+	active := desired.Active == nil || *desired.Active
+	if active && current.IsDeleted() {
+		current, err = s.UserService.Restore(current.UserId, ActorId)
+		if err != nil {
+			return
+		}
+	}
+	if !current.IsDeleted() {
+		current, err = s.updateProfile(current, desired)
+		if err != nil {
+			return
+		}
+	}
+	if role := desired.Role(); role != "" {
+		_, err = s.OrganizationService.SetMember(organization.MemberPayload{Role: role}, orgId, current.UserId, ActorId)
+		if err != nil {
+			return
+		}
+	}
+	if !active && !current.IsDeleted() {
+		current, err = s.UserService.DeleteByID(current.UserId, ActorId)
+		if err != nil {
+			return
+		}
+	}
+	return s.toUser(orgId, current)
+}
+
+func (s *ScimServiceImpl) updateProfile(current user.User, desired User) (res user.User, err error) {
+	name := desired.FullName()
+	userName := desired.UserName
+	email := desired.PrimaryEmail()
+	phone := desired.PrimaryPhoneNumber()
+	payload := user.ProfilePayload{
+		Name:        &name,
+		UserName:    &userName,
+		Email:       &email,
+		PhoneNumber: &phone,
+		UpdatedAt:   current.Updated_at,
+	}
+	if desired.Locale != "" {
+		payload.Locale = &desired.Locale
+	}
+	if desired.Timezone != "" {
+		payload.Timezone = &desired.Timezone
+	}
+	if name == current.Name && strings.EqualFold(userName, current.UserName) && strings.EqualFold(email, current.Email) &&
+		phone == current.PhoneNumber.String && (payload.Locale == nil || *payload.Locale == current.Locale) &&
+		(payload.Timezone == nil || *payload.Timezone == current.Timezone) {
+		return current, nil
+	}
+	err = shared.GetValidator().Struct(payload)
+	if err != nil {
+		err = NewError(http.StatusBadRequest, "invalidValue", err.Error())
+		return
+	}
+	return s.UserService.UpdateProfile(payload, current.UserId, ActorId)
+}
+
+func (s *ScimServiceImpl) GetGroups(filter string, startIndex, count int) (res ListResponse, err error) {
+	orgId, err := s.orgId()
+	if err != nil {
+		return
+	}
+	conditions, err := ParseFilter(filter)
+	if err != nil {
+		return
+	}
+	displayName := ""
+	for _, c := range conditions {
+		if c.Attribute != "displayname" || c.Operator != "eq" {
+			err = invalidFilter("groups can only be filtered by displayName eq")
+			return
+		}
+		displayName = c.Value
+	}
+	groups := []group.Group{}
+	for offset := 0; ; offset += groupPageSize {
+		page, err := s.GroupService.GetAll(orgId, groupPageSize, offset)
+		if err != nil {
+			return res, err
+		}
+		for _, g := range page {
+			if displayName == "" || strings.EqualFold(g.Name, displayName) {
+				groups = append(groups, g)
+			}
+		}
+		if len(page) < groupPageSize {
+			break
+		}
+	}
+	limit, offset := Page(startIndex, count)
+	resources := []interface{}{}
+	for i := offset; i < len(groups) && i < offset+limit; i++ {
+		resource, err := s.toGroup(orgId, groups[i])
+		if err != nil {
+			return res, err
+		}
+		resources = append(resources, resource)
+	}
+	res = NewListResponse(resources, len(groups), startIndex)
+	return
+}
+
+func (s *ScimServiceImpl) GetGroup(id uuid.UUID) (res Group, err error) {
+	orgId, err := s.orgId()
+	if err != nil {
+		return
+	}
+	g, err := s.GroupService.GetByID(orgId, id)
+	if err != nil {
+		return
+	}
+	return s.toGroup(orgId, g)
+}
+
+func (s *ScimServiceImpl) CreateGroup(load Group) (res Group, err error) {
+	orgId, err := s.orgId()
+	if err != nil {
+		return
+	}
+	created, err := s.GroupService.Create(group.GroupPayload{Name: load.DisplayName}, orgId, ActorId)
+	if err != nil {
+		return
+	}
+	return s.replaceGroup(orgId, created, Group{Members: []MultiValue{}}, load)
+}
+
+func (s *ScimServiceImpl) ReplaceGroup(id uuid.UUID, load Group) (res Group, err error) {
+	orgId, err := s.orgId()
+	if err != nil {
+		return
+	}
+	g, err := s.GroupService.GetByID(orgId, id)
+	if err != nil {
+		return
+	}
+	current, err := s.toGroup(orgId, g)
+	if err != nil {
+		return
+	}
+	return s.replaceGroup(orgId, g, current, load)
+}
+
+func (s *ScimServiceImpl) PatchGroup(id uuid.UUID, load PatchRequest) (res Group, err error) {
+	orgId, err := s.orgId()
+	if err != nil {
+		return
+	}
+	g, err := s.GroupService.GetByID(orgId, id)
+	if err != nil {
+		return
+	}
+	current, err := s.toGroup(orgId, g)
+	if err != nil {
+		return
+	}
+	desired := current
+	desired.Members = append([]MultiValue{}, current.Members...)
+	for _, op := range load.Operations {
+		err = desired.Apply(op)
+		if err != nil {
+			return
+		}
+	}
+	return s.replaceGroup(orgId, g, current, desired)
+}
+
+func (s *ScimServiceImpl) DeleteGroup(id uuid.UUID) (err error) {
+	orgId, err := s.orgId()
+	if err != nil {
+		return
+	}
+	_, err = s.GroupService.Delete(orgId, id)
+	return
+}
+
+// replaceGroup renames the group and adds or removes members until they
+// match desired. Permissions and description are not managed through SCIM
+// and are kept.
+func (s *ScimServiceImpl) replaceGroup(orgId uuid.UUID, g group.Group, current, desired Group) (res Group, err error) {
+	if desired.DisplayName != "" && desired.DisplayName != g.Name {
+		g, err = s.GroupService.Update(group.GroupPayload{Name: desired.DisplayName, Description: g.Description, Permissions: g.Permissions}, orgId, g.Id, ActorId)
+		if err != nil {
+			return
+		}
+	}
+	for _, m := range desired.Members {
+		if current.HasMember(m.Value) {
+			continue
+		}
+		userId, err := uuid.FromString(m.Value)
+		if err != nil {
+			return res, NewError(http.StatusBadRequest, "invalidValue", "invalid member "+m.Value)
+		}
+		_, err = s.GroupService.AddMember(orgId, g.Id, userId, ActorId)
+		if err != nil {
+			return res, err
+		}
+	}
+	for _, m := range current.Members {
+		if desired.HasMember(m.Value) {
+			continue
+		}
+		err = s.GroupService.RemoveMember(orgId, g.Id, uuid.FromStringOrNil(m.Value))
+		if err != nil {
+			return
+		}
+	}
+	return s.toGroup(orgId, g)
+}
+
+// getUser returns the user if it is a member of the organization.
+func (s *ScimServiceImpl) getUser(orgId, id uuid.UUID) (res user.User, err error) {
+	_, err = s.OrganizationService.GetMember(orgId, id)
+	if err != nil {
+		if failure.GetCode(err) == http.StatusNotFound {
+			err = failure.NotFound("User")
+		}
+		return
+	}
+	return s.UserService.GetByUserID(id)
+}
+
+func (s *ScimServiceImpl) toUser(orgId uuid.UUID, u user.User) (res User, err error) {
+	member, err := s.OrganizationService.GetMember(orgId, u.UserId)
+	if err != nil {
+		return
+	}
+	groups, err := s.GroupService.GetByUserID(orgId, u.UserId)
+	if err != nil {
+		return
+	}
+	res = UserFrom(u, member.Role, groups, s.baseURL())
+	return
+}
+
+func (s *ScimServiceImpl) toGroup(orgId uuid.UUID, g group.Group) (res Group, err error) {
+	members := []group.Member{}
+	for offset := 0; ; offset += groupPageSize {
+		page, err := s.GroupService.GetMembers(orgId, g.Id, groupPageSize, offset)
+		if err != nil {
+			return res, err
+		}
+		members = append(members, page...)
+		if len(page) < groupPageSize {
+			break
+		}
+	}
+	res = GroupFrom(g, members, s.baseURL())
+	return
+}
+
+func (s *ScimServiceImpl) orgId() (orgId uuid.UUID, err error) {
+	orgId, err = uuid.FromString(s.Config.Scim.OrganizationId)
+	if err != nil {
+		err = failure.InternalError(err)
+	}
+	return
+}
+
+func (s *ScimServiceImpl) baseURL() string {
+	return strings.TrimSuffix(s.Config.App.URL, "/") + "/scim/v2"
+}
+
+func randomPassword() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
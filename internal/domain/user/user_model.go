@@ -1,6 +1,9 @@
 package user
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"time"
@@ -17,37 +20,57 @@ import (
 )
 
 type User struct {
-	UserId     uuid.UUID   `db:"id" validate:"required"`
-	Email      string      `db:"email" validate:"required"`
-	UserName   string      `db:"username" validate:"required"`
-	Name       string      `db:"name" validate:"required"`
-	Password   string      `db:"password" validate:"required"`
-	Role       string      `db:"role" validate:"required"`
-	CartId     uuid.UUID   `db:"cart_id" validate:"required"`
-	Cart       cart.Cart   `db:"-"`
-	Created_at time.Time   `db:"created_at" validate:"required"`
-	Updated_at time.Time   `db:"updated_at" validate:"required"`
-	Deleted_at null.Time   `db:"deleted_at"`
-	Created_by uuid.UUID   `db:"created_by"`
-	Updated_by uuid.UUID   `db:"updated_by"`
-	Deleted_by nuuid.NUUID `db:"deleted_by"`
+	UserId            uuid.UUID   `db:"id" validate:"required"`
+	Email             string      `db:"email" validate:"required"`
+	UserName          string      `db:"username" validate:"required"`
+	Name              string      `db:"name" validate:"required"`
+	Password          string      `db:"password" validate:"required"`
+	Role              string      `db:"role" validate:"required"`
+	CartId            uuid.UUID   `db:"cart_id" validate:"required"`
+	OrganizationId    uuid.UUID   `db:"organization_id" validate:"required"`
+	Cart              cart.Cart   `db:"-"`
+	PhoneNumber       null.String `db:"phone_number"`
+	Locale            string      `db:"locale" validate:"required,locale"`
+	Timezone          string      `db:"timezone" validate:"required,timezone"`
+	EmailVerifiedAt   null.Time   `db:"email_verified_at"`
+	EmailVerification null.String `db:"email_verification_token"`
+	AvatarKey         null.String `db:"avatar_key"`
+	ThumbnailKey      null.String `db:"avatar_thumbnail_key"`
+	AvatarURL         null.String `db:"avatar_url"`
+	ThumbnailURL      null.String `db:"avatar_thumbnail_url"`
+	Created_at        time.Time   `db:"created_at" validate:"required"`
+	Updated_at        time.Time   `db:"updated_at" validate:"required"`
+	Deleted_at        null.Time   `db:"deleted_at"`
+	Created_by        uuid.UUID   `db:"created_by"`
+	Updated_by        uuid.UUID   `db:"updated_by"`
+	Deleted_by        nuuid.NUUID `db:"deleted_by"`
 }
 
 type UserResponseFormat struct {
-	UserId     uuid.UUID   `json:"id" validate:"required"`
-	Email      string      `json:"email" validate:"required"`
-	UserName   string      `json:"userName" validate:"required"`
-	Name       string      `json:"name" validate:"required"`
-	Password   string      `json:"-" validate:"required"`
-	Role       string      `json:"role" validate:"required"`
-	CartId     uuid.UUID   `json:"cartId" validate:"required"`
-	Cart       cart.Cart   `json:"-"`
-	Created_at time.Time   `json:"createdAt" validate:"required"`
-	Updated_at time.Time   `json:"updatedAt" validate:"required"`
-	Deleted_at null.Time   `json:"deletedAt"`
-	Created_by uuid.UUID   `json:"createdBy"`
-	Updated_by uuid.UUID   `json:"updatedBy"`
-	Deleted_by nuuid.NUUID `json:"deletedBy"`
+	UserId            uuid.UUID   `json:"id" validate:"required"`
+	Email             string      `json:"email" validate:"required"`
+	UserName          string      `json:"userName" validate:"required"`
+	Name              string      `json:"name" validate:"required"`
+	Password          string      `json:"-" validate:"required"`
+	Role              string      `json:"role" validate:"required"`
+	CartId            uuid.UUID   `json:"cartId" validate:"required"`
+	OrganizationId    uuid.UUID   `json:"organizationId"`
+	Cart              cart.Cart   `json:"-"`
+	PhoneNumber       null.String `json:"phoneNumber"`
+	Locale            string      `json:"locale"`
+	Timezone          string      `json:"timezone"`
+	EmailVerifiedAt   null.Time   `json:"emailVerifiedAt"`
+	EmailVerification null.String `json:"-"`
+	AvatarKey         null.String `json:"-"`
+	ThumbnailKey      null.String `json:"-"`
+	AvatarURL         null.String `json:"avatarUrl"`
+	ThumbnailURL      null.String `json:"avatarThumbnailUrl"`
+	Created_at        time.Time   `json:"createdAt" validate:"required"`
+	Updated_at        time.Time   `json:"updatedAt" validate:"required"`
+	Deleted_at        null.Time   `json:"deletedAt"`
+	Created_by        uuid.UUID   `json:"createdBy"`
+	Updated_by        uuid.UUID   `json:"updatedBy"`
+	Deleted_by        nuuid.NUUID `json:"deletedBy"`
 }
 
 type UserPayload struct {
@@ -58,7 +81,9 @@ type UserPayload struct {
 	Role     string `json:"role" validate:"required"`
 }
 
-func (u User) NewFromPayload(payload UserPayload) (res User, err error) {
+// NewFromPayload creates a user whose home organization is orgId. The user
+// becomes a member of it with the role from the payload.
+func (u User) NewFromPayload(payload UserPayload, orgId uuid.UUID) (res User, err error) {
 	userId, err := uuid.NewV4()
 	if err != nil {
 		return
@@ -72,7 +97,7 @@ func (u User) NewFromPayload(payload UserPayload) (res User, err error) {
 	if err != nil {
 		return
 	}
-	newCart, err := u.Cart.NewFromPayload(cart.CartPayload{CartId: cartId, UserId: userId})
+	newCart, err := u.Cart.NewFromPayload(cart.CartPayload{CartId: cartId, UserId: userId, OrganizationId: orgId})
 	if err != nil {
 		return
 	}
@@ -81,19 +106,27 @@ func (u User) NewFromPayload(payload UserPayload) (res User, err error) {
 		err = failure.BadRequest(errors.New("invalid email"))
 		return
 	}
+	token, err := newVerificationToken()
+	if err != nil {
+		return
+	}
 	res = User{
-		UserId:     userId,
-		Email:      payload.Email,
-		UserName:   payload.UserName,
-		Name:       payload.Name,
-		Password:   hashedPass,
-		Role:       userRole,
-		CartId:     cartId,
-		Cart:       newCart,
-		Created_at: time.Now().UTC(),
-		Created_by: userId,
-		Updated_at: time.Now().UTC(),
-		Updated_by: userId,
+		UserId:            userId,
+		Email:             payload.Email,
+		UserName:          payload.UserName,
+		Name:              payload.Name,
+		Password:          hashedPass,
+		Role:              userRole,
+		CartId:            cartId,
+		OrganizationId:    orgId,
+		Cart:              newCart,
+		Locale:            DefaultLocale,
+		Timezone:          DefaultTimezone,
+		EmailVerification: null.StringFrom(token),
+		Created_at:        time.Now().UTC(),
+		Created_by:        userId,
+		Updated_at:        time.Now().UTC(),
+		Updated_by:        userId,
 	}
 	err = res.Validate()
 	return
@@ -103,6 +136,134 @@ type NamePayload struct {
 	Name string `json:"name" validate:"required"`
 }
 
+// ImportBatchSize is the number of users inserted per transaction on import.
+const ImportBatchSize = 100
+
+// ImportReport describes the outcome of a bulk import. On a dry run nothing
+// is written and Imported stays zero.
+type ImportReport struct {
+	DryRun   bool             `json:"dryRun"`
+	Total    int              `json:"total"`
+	Valid    int              `json:"valid"`
+	Imported int              `json:"imported"`
+	Failed   int              `json:"failed"`
+	Errors   []ImportRowError `json:"errors"`
+}
+
+// ImportRowError is the reason a single row of an import was rejected.
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+func (r *ImportReport) AddError(row int, err error) {
+	r.Failed++
+	r.Errors = append(r.Errors, ImportRowError{Row: row, Message: err.Error()})
+}
+
+// ExportRow is the flat representation of a user used by exports.
+type ExportRow struct {
+	Id              string    `json:"id"`
+	Email           string    `json:"email"`
+	UserName        string    `json:"userName"`
+	Name            string    `json:"name"`
+	Role            string    `json:"role"`
+	PhoneNumber     string    `json:"phoneNumber"`
+	Locale          string    `json:"locale"`
+	Timezone        string    `json:"timezone"`
+	EmailVerifiedAt null.Time `json:"emailVerifiedAt"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+	DeletedAt       null.Time `json:"deletedAt"`
+}
+
+func (u User) ToExportRow() ExportRow {
+	return ExportRow{
+		Id:              u.UserId.String(),
+		Email:           u.Email,
+		UserName:        u.UserName,
+		Name:            u.Name,
+		Role:            u.Role,
+		PhoneNumber:     u.PhoneNumber.String,
+		Locale:          u.Locale,
+		Timezone:        u.Timezone,
+		EmailVerifiedAt: u.EmailVerifiedAt,
+		CreatedAt:       u.Created_at,
+		UpdatedAt:       u.Updated_at,
+		DeletedAt:       u.Deleted_at,
+	}
+}
+
+// ProfilePayload is a partial update of a user's profile. Only the fields
+// present in the request are changed. UpdatedAt must carry the updatedAt
+// value the client last read, so concurrent edits are detected.
+type ProfilePayload struct {
+	Name        *string   `json:"name" validate:"omitempty,min=1,max=255"`
+	UserName    *string   `json:"userName" validate:"omitempty,min=3,max=255"`
+	Email       *string   `json:"email" validate:"omitempty,email,max=255"`
+	PhoneNumber *string   `json:"phoneNumber" validate:"omitempty,e164"`
+	Locale      *string   `json:"locale" validate:"omitempty,locale"`
+	Timezone    *string   `json:"timezone" validate:"omitempty,timezone"`
+	UpdatedAt   time.Time `json:"updatedAt" validate:"required"`
+}
+
+type VerifyEmailPayload struct {
+	Token string `json:"token" validate:"required"`
+}
+
+const (
+	DefaultLocale   = "en"
+	DefaultTimezone = "UTC"
+)
+
+const (
+	StatusActive  = "active"
+	StatusDeleted = "deleted"
+)
+
+// SortFields are the columns users can be sorted by.
+var SortFields = []string{"id", "email", "username", "name", "role", "created_at", "updated_at"}
+
+// Filter narrows down the users returned by GetAll. Empty fields match
+// every user, text fields match substrings unless Exact is set.
+type Filter struct {
+	// OrganizationId limits the results to members of the organization. It
+	// comes from the caller's token, never from the query string.
+	OrganizationId uuid.UUID
+	Search         string
+	Email          string
+	UserName       string
+	Name           string
+	Role           string
+	Status         string
+	Verified       *bool
+	CreatedFrom    time.Time
+	CreatedTo      time.Time
+	// Exact makes Email, UserName and Name match whole values.
+	Exact bool
+}
+
+func (f Filter) Validate() error {
+	if f.Role != "" && roles.GetRoleFromString(f.Role) < 0 {
+		return failure.BadRequestFromString("unknown role " + f.Role)
+	}
+	if f.Status != "" && f.Status != StatusActive && f.Status != StatusDeleted {
+		return failure.BadRequestFromString("status must be active or deleted")
+	}
+	if !f.CreatedFrom.IsZero() && !f.CreatedTo.IsZero() && f.CreatedTo.Before(f.CreatedFrom) {
+		return failure.BadRequestFromString("createdTo is before createdFrom")
+	}
+	return nil
+}
+
+func newVerificationToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
 func (u *User) ValidatePassword(loginPass string) error {
 	return encrypt.ComparePasswords(u.Password, loginPass)
 }
@@ -111,6 +272,129 @@ func (u *User) UpdateName(payload NamePayload) {
 	u.Name = payload.Name
 }
 
+// UsernameChanged reports whether the payload asks for a different username.
+func (p ProfilePayload) UsernameChanged(u User) bool {
+	return p.UserName != nil && *p.UserName != u.UserName
+}
+
+// EmailChanged reports whether the payload asks for a different email,
+// a change of case included.
+func (p ProfilePayload) EmailChanged(u User) bool {
+	return p.Email != nil && *p.Email != u.Email
+}
+
+// UpdateProfile applies the fields present in the payload. Changing the email
+// marks it as unverified and issues a new verification token.
+func (u *User) UpdateProfile(payload ProfilePayload, updater uuid.UUID) (err error) {
+	if u.IsDeleted() {
+		err = failure.Conflict("update", "user", "user is deleted")
+		return
+	}
+	if !u.Updated_at.Equal(payload.UpdatedAt) {
+		err = failure.Conflict("update", "user", "modified by another request, reload and try again")
+		return
+	}
+	if payload.EmailChanged(*u) {
+		var token string
+		token, err = newVerificationToken()
+		if err != nil {
+			return
+		}
+		u.Email = *payload.Email
+		u.EmailVerifiedAt = null.Time{}
+		u.EmailVerification = null.StringFrom(token)
+	}
+	if payload.Name != nil {
+		u.Name = *payload.Name
+	}
+	if payload.UserName != nil {
+		u.UserName = *payload.UserName
+	}
+	if payload.PhoneNumber != nil {
+		u.PhoneNumber = null.NewString(*payload.PhoneNumber, *payload.PhoneNumber != "")
+	}
+	if payload.Locale != nil {
+		u.Locale = *payload.Locale
+	}
+	if payload.Timezone != nil {
+		u.Timezone = *payload.Timezone
+	}
+	// MySQL TIMESTAMP columns keep whole seconds, truncate so the value the
+	// client reads back matches the stored one.
+	u.Updated_at = time.Now().UTC().Truncate(time.Second)
+	u.Updated_by = updater
+	err = u.Validate()
+	return
+}
+
+// SetAvatar points the user at a newly stored avatar and its thumbnail.
+func (u *User) SetAvatar(avatarKey, avatarURL, thumbnailKey, thumbnailURL string, updater uuid.UUID) {
+	u.AvatarKey = null.StringFrom(avatarKey)
+	u.AvatarURL = null.StringFrom(avatarURL)
+	u.ThumbnailKey = null.StringFrom(thumbnailKey)
+	u.ThumbnailURL = null.StringFrom(thumbnailURL)
+	u.Updated_at = time.Now().UTC().Truncate(time.Second)
+	u.Updated_by = updater
+}
+
+// RemoveAvatar clears the user's avatar.
+func (u *User) RemoveAvatar(updater uuid.UUID) {
+	u.AvatarKey = null.String{}
+	u.AvatarURL = null.String{}
+	u.ThumbnailKey = null.String{}
+	u.ThumbnailURL = null.String{}
+	u.Updated_at = time.Now().UTC().Truncate(time.Second)
+	u.Updated_by = updater
+}
+
+// Anonymize replaces every piece of personal data with a placeholder derived
+// from the user id, so that orders and other records keep a valid owner. The
+// password is replaced with a random one that nobody knows.
+func (u *User) Anonymize(updater uuid.UUID) (err error) {
+	secret, err := newVerificationToken()
+	if err != nil {
+		return
+	}
+	hashedPass, err := encrypt.HashPassword(secret)
+	if err != nil {
+		return
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	u.Email = u.UserId.String() + "@erased.invalid"
+	u.UserName = "erased-" + u.UserId.String()
+	u.Name = "Erased User"
+	u.Password = hashedPass
+	u.PhoneNumber = null.String{}
+	u.Locale = DefaultLocale
+	u.Timezone = DefaultTimezone
+	u.EmailVerifiedAt = null.Time{}
+	u.EmailVerification = null.String{}
+	u.RemoveAvatar(updater)
+	u.Updated_at = now
+	u.Updated_by = updater
+	if !u.IsDeleted() {
+		u.Deleted_at = null.TimeFrom(now)
+		u.Deleted_by = nuuid.From(updater)
+	}
+	err = u.Validate()
+	return
+}
+
+// VerifyEmail marks the current email as verified if the token matches.
+func (u *User) VerifyEmail(token string) (err error) {
+	if u.EmailVerifiedAt.Valid {
+		err = failure.Conflict("verify", "email", "already verified")
+		return
+	}
+	if !u.EmailVerification.Valid || subtle.ConstantTimeCompare([]byte(u.EmailVerification.String), []byte(token)) != 1 {
+		err = failure.BadRequestFromString("invalid verification token")
+		return
+	}
+	u.EmailVerifiedAt = null.TimeFrom(time.Now().UTC())
+	u.EmailVerification = null.String{}
+	return
+}
+
 func (u User) ToResponseFormat() UserResponseFormat {
 	return UserResponseFormat(u)
 }
@@ -124,12 +408,27 @@ func (u *User) Validate() (err error) {
 	return validator.Struct(u)
 }
 
-func (u *User) isDeleted() bool {
+// IsDeleted reports whether the user has been soft deleted.
+func (u *User) IsDeleted() bool {
 	return u.Deleted_at.Valid && u.Deleted_by.Valid
 }
 
+// Restore undoes a soft delete.
+func (u *User) Restore(updater uuid.UUID) (err error) {
+	if !u.IsDeleted() {
+		err = failure.Conflict("restore", "user", "not deleted")
+		return
+	}
+	u.Deleted_at = null.Time{}
+	u.Deleted_by = nuuid.NUUID{}
+	u.Updated_at = time.Now().UTC().Truncate(time.Second)
+	u.Updated_by = updater
+	err = u.Validate()
+	return
+}
+
 func (u *User) SoftDelete(deleter uuid.UUID) (err error) {
-	if u.isDeleted() {
+	if u.IsDeleted() {
 		err = failure.Conflict("delete", "user", "already deleted")
 		return
 	}
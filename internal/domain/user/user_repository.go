@@ -2,6 +2,8 @@ package user
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/evermos/boilerplate-go/infras"
 	"github.com/evermos/boilerplate-go/shared/failure"
@@ -12,13 +14,17 @@ import (
 
 type UserRepository interface {
 	Create(user User) (err error)
+	CreateBatch(users []User) (err error)
+	GetAllAfter(filter Filter, afterId string, limit int) (res []User, err error)
 	ExistsByID(userId uuid.UUID) (exists bool, err error)
 	ExistsByUserName(userName string) (exists bool, err error)
 	ExistsByEmail(email string) (exists bool, err error)
 	GetByUserId(userId uuid.UUID) (user User, err error)
 	GetByUserName(userName string) (user User, err error)
 	Update(user User) (err error)
-	GetAll(limit, offset int, sort, field string) (res []User, err error)
+	UpdateIfUnmodified(user User, lastUpdatedAt time.Time) (err error)
+	UpdateAvatar(user User) (err error)
+	GetAll(filter Filter, limit, offset int, sort, field string) (res []User, total int, err error)
 }
 
 type UserRepositoryMySQL struct {
@@ -39,6 +45,37 @@ func (r *UserRepositoryMySQL) Create(user User) (err error) {
 	})
 }
 
+// CreateBatch inserts all users in a single transaction.
+func (r *UserRepositoryMySQL) CreateBatch(users []User) (err error) {
+	return r.DB.WithTransaction(func(db *sqlx.Tx, c chan error) {
+		for _, user := range users {
+			if err := r.txCreate(db, user); err != nil {
+				c <- err
+				return
+			}
+		}
+		c <- nil
+	})
+}
+
+// GetAllAfter returns up to limit users ordered by id, starting after the
+// given id. It is used to walk through every user without OFFSET.
+func (r *UserRepositoryMySQL) GetAllAfter(filter Filter, afterId string, limit int) (res []User, err error) {
+	where, args := r.composeFilter(filter)
+	if where == "" {
+		where = "WHERE id > ? "
+	} else {
+		where += "AND id > ? "
+	}
+	args = append(args, afterId, limit)
+	err = r.DB.Read.Select(&res, "SELECT * FROM user "+where+"ORDER BY id LIMIT ?", args...)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
 func (r *UserRepositoryMySQL) ExistsByID(userId uuid.UUID) (exists bool, err error) {
 
 	err = r.DB.Read.Get(&exists, "SELECT COUNT(id) FROM user WHERE id = ?", userId.String())
@@ -96,8 +133,8 @@ func (r *UserRepositoryMySQL) GetByUserName(userName string) (user User, err err
 }
 
 func (r *UserRepositoryMySQL) txCreate(tx *sqlx.Tx, payload User) (err error) {
-	query := `insert into user (id,email,username,name,password,role,cart_id,created_at,created_by,updated_at,updated_by)
-    VALUES (:id,:email,:username,:name,:password,:role,:cart_id,:created_at,:created_by,:updated_at,:updated_by)`
+	query := `insert into user (id,email,username,name,password,role,cart_id,organization_id,phone_number,locale,timezone,email_verified_at,email_verification_token,created_at,created_by,updated_at,updated_by)
+    VALUES (:id,:email,:username,:name,:password,:role,:cart_id,:organization_id,:phone_number,:locale,:timezone,:email_verified_at,:email_verification_token,:created_at,:created_by,:updated_at,:updated_by)`
 
 	stmt, err := tx.PrepareNamed(query)
 	if err != nil {
@@ -111,6 +148,14 @@ func (r *UserRepositoryMySQL) txCreate(tx *sqlx.Tx, payload User) (err error) {
 		logger.ErrorWithStack(err)
 		return
 	}
+	// the cart itself is created by the create_cart_on_user_insert trigger
+	query = `INSERT INTO organization_member (organization_id,user_id,role,cart_id,created_at,created_by,updated_at,updated_by)
+	VALUES (:organization_id,:id,:role,:cart_id,:created_at,:created_by,:updated_at,:updated_by)`
+	_, err = tx.NamedExec(query, payload)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
 	return
 }
 
@@ -128,10 +173,20 @@ func (r *UserRepositoryMySQL) txUpdate(tx *sqlx.Tx, payload User) (err error) {
 	query := `UPDATE user
 	SET 
 		id = :id,
+		email = :email,
 		username = :username,
 		name = :name,
 		password = :password,
 		role =  :role,
+		phone_number = :phone_number,
+		locale = :locale,
+		timezone = :timezone,
+		email_verified_at = :email_verified_at,
+		email_verification_token = :email_verification_token,
+		avatar_key = :avatar_key,
+		avatar_thumbnail_key = :avatar_thumbnail_key,
+		avatar_url = :avatar_url,
+		avatar_thumbnail_url = :avatar_thumbnail_url,
 		created_at = :created_at,
 		created_by = :created_by,
 		updated_at = :updated_at,
@@ -156,13 +211,178 @@ func (r *UserRepositoryMySQL) txUpdate(tx *sqlx.Tx, payload User) (err error) {
 	return
 }
 
-func (r *UserRepositoryMySQL) GetAll(limit, offset int, sort, field string) (res []User, err error) {
-	query := `SELECT * FROM user `
+// UpdateAvatar writes only the avatar of the user, so profile changes made
+// since the user was read are kept.
+func (r *UserRepositoryMySQL) UpdateAvatar(user User) (err error) {
+	query := `UPDATE user
+	SET
+		avatar_key = :avatar_key,
+		avatar_thumbnail_key = :avatar_thumbnail_key,
+		avatar_url = :avatar_url,
+		avatar_thumbnail_url = :avatar_thumbnail_url,
+		updated_at = :updated_at,
+		updated_by = :updated_by
+	WHERE id = :id`
+	_, err = r.DB.Write.NamedExec(query, user)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+	return
+}
+
+// UpdateIfUnmodified updates the user only if its updated_at still equals
+// lastUpdatedAt, returning a conflict when another request got there first.
+func (r *UserRepositoryMySQL) UpdateIfUnmodified(user User, lastUpdatedAt time.Time) (err error) {
+	return r.DB.WithTransaction(func(db *sqlx.Tx, c chan error) {
+		if err := r.txUpdateIfUnmodified(db, user, lastUpdatedAt); err != nil {
+			c <- err
+			return
+		}
+		c <- nil
+	})
+}
+
+func (r *UserRepositoryMySQL) txUpdateIfUnmodified(tx *sqlx.Tx, payload User, lastUpdatedAt time.Time) (err error) {
+	query := `UPDATE user
+	SET
+		email = :email,
+		username = :username,
+		name = :name,
+		phone_number = :phone_number,
+		locale = :locale,
+		timezone = :timezone,
+		email_verified_at = :email_verified_at,
+		email_verification_token = :email_verification_token,
+		updated_at = :updated_at,
+		updated_by = :updated_by
+	WHERE id = :id AND updated_at = :last_updated_at`
+	args := map[string]interface{}{
+		"id":                       payload.UserId,
+		"email":                    payload.Email,
+		"username":                 payload.UserName,
+		"name":                     payload.Name,
+		"phone_number":             payload.PhoneNumber,
+		"locale":                   payload.Locale,
+		"timezone":                 payload.Timezone,
+		"email_verified_at":        payload.EmailVerifiedAt,
+		"email_verification_token": payload.EmailVerification,
+		"updated_at":               payload.Updated_at,
+		"updated_by":               payload.Updated_by,
+		"last_updated_at":          lastUpdatedAt,
+	}
+	result, err := tx.NamedExec(query, args)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	if affected == 0 {
+		err = failure.Conflict("update", "user", "modified by another request, reload and try again")
+		return
+	}
+	return
+}
+
+func (r *UserRepositoryMySQL) GetAll(filter Filter, limit, offset int, sort, field string) (res []User, total int, err error) {
+	where, args := r.composeFilter(filter)
+	err = r.DB.Read.Get(&total, "SELECT COUNT(id) FROM user "+where, args...)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	// field and sort are checked against a whitelist by the caller
+	query := "SELECT * FROM user " + where
 	query += fmt.Sprintf("ORDER BY %s %s LIMIT %d OFFSET %d", field, sort, limit, offset)
-	err = r.DB.Read.Select(&res, query)
+	err = r.DB.Read.Select(&res, query, args...)
 	if err != nil {
 		logger.ErrorWithStack(err)
 		return
 	}
 	return
 }
+
+func (r *UserRepositoryMySQL) textCondition(column string, exact bool) string {
+	if exact {
+		return column + " = ?"
+	}
+	return column + " LIKE ?"
+}
+
+func (r *UserRepositoryMySQL) textArg(value string, exact bool) string {
+	if exact {
+		return value
+	}
+	return containsPattern(value)
+}
+
+// composeFilter turns the filter into a WHERE clause with placeholders.
+func (r *UserRepositoryMySQL) composeFilter(filter Filter) (where string, args []interface{}) {
+	conditions := []string{}
+	if filter.OrganizationId != uuid.Nil {
+		// inside an organization the role that counts is the member's role
+		member := "EXISTS (SELECT 1 FROM organization_member m WHERE m.user_id = user.id AND m.organization_id = ?"
+		args = append(args, filter.OrganizationId.String())
+		if filter.Role != "" {
+			member += " AND m.role = ?"
+			args = append(args, filter.Role)
+		}
+		conditions = append(conditions, member+")")
+	}
+	if filter.Search != "" {
+		like := containsPattern(filter.Search)
+		conditions = append(conditions, "(email LIKE ? OR username LIKE ? OR name LIKE ?)")
+		args = append(args, like, like, like)
+	}
+	if filter.Email != "" {
+		conditions = append(conditions, r.textCondition("email", filter.Exact))
+		args = append(args, r.textArg(filter.Email, filter.Exact))
+	}
+	if filter.UserName != "" {
+		conditions = append(conditions, r.textCondition("username", filter.Exact))
+		args = append(args, r.textArg(filter.UserName, filter.Exact))
+	}
+	if filter.Name != "" {
+		conditions = append(conditions, r.textCondition("name", filter.Exact))
+		args = append(args, r.textArg(filter.Name, filter.Exact))
+	}
+	if filter.Role != "" && filter.OrganizationId == uuid.Nil {
+		conditions = append(conditions, "role = ?")
+		args = append(args, filter.Role)
+	}
+	switch filter.Status {
+	case StatusActive:
+		conditions = append(conditions, "deleted_at IS NULL")
+	case StatusDeleted:
+		conditions = append(conditions, "deleted_at IS NOT NULL")
+	}
+	if filter.Verified != nil {
+		if *filter.Verified {
+			conditions = append(conditions, "email_verified_at IS NOT NULL")
+		} else {
+			conditions = append(conditions, "email_verified_at IS NULL")
+		}
+	}
+	if !filter.CreatedFrom.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, filter.CreatedTo)
+	}
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ") + " "
+	}
+	return
+}
+
+// containsPattern builds a LIKE pattern matching s anywhere, escaping the
+// LIKE wildcards in s itself.
+func containsPattern(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+	return "%" + s + "%"
+}
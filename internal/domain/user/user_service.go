@@ -1,28 +1,51 @@
 package user
 
 import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/bulk"
+	"github.com/evermos/boilerplate-go/shared/email"
 	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/imaging"
+	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/evermos/boilerplate-go/shared/roles"
+	"github.com/evermos/boilerplate-go/shared/storage"
 	"github.com/gofrs/uuid"
 )
 
 type UserService interface {
 	GetByUserName(userName string) (user User, err error)
-	Create(load UserPayload) (user User, err error)
+	Create(load UserPayload, orgId uuid.UUID) (user User, err error)
 	UpdateName(payload NamePayload, userId uuid.UUID) (user User, err error)
+	UpdateProfile(payload ProfilePayload, userId, updaterId uuid.UUID) (user User, err error)
+	VerifyEmail(payload VerifyEmailPayload, userId uuid.UUID) (user User, err error)
+	UpdateAvatar(data []byte, userId, updaterId uuid.UUID) (user User, err error)
+	DeleteAvatar(userId, updaterId uuid.UUID) (user User, err error)
+	Import(data io.Reader, format bulk.Format, dryRun bool, orgId uuid.UUID, grantorRole string) (report ImportReport, err error)
+	Export(filter Filter, fn func(user User) error) (err error)
 	DeleteByID(userId, userDeleter uuid.UUID) (user User, err error)
-	GetAll(limit, offset int, sort, field string) (res []User, err error)
+	Restore(userId, updaterId uuid.UUID) (user User, err error)
+	GetAll(filter Filter, limit, offset int, sort, field string) (res []User, total int, err error)
 	GetByUserID(userId uuid.UUID) (user User, err error)
 }
 
 type UserServiceImpl struct {
-	Repo UserRepository
+	Repo    UserRepository
+	Mailer  email.Sender
+	Storage storage.Storage
 }
 
-func ProvideUserServiceImpl(repo UserRepository) *UserServiceImpl {
-	return &UserServiceImpl{Repo: repo}
+func ProvideUserServiceImpl(repo UserRepository, mailer email.Sender, store storage.Storage) *UserServiceImpl {
+	return &UserServiceImpl{Repo: repo, Mailer: mailer, Storage: store}
 }
 
-func (s *UserServiceImpl) Create(load UserPayload) (user User, err error) {
+// AvatarThumbnailSize is the width and height of generated avatar thumbnails.
+const AvatarThumbnailSize = 128
+
+func (s *UserServiceImpl) Create(load UserPayload, orgId uuid.UUID) (user User, err error) {
 	exists, err := s.Repo.ExistsByUserName(load.UserName)
 	if err != nil {
 		return
@@ -39,7 +62,7 @@ func (s *UserServiceImpl) Create(load UserPayload) (user User, err error) {
 		err = failure.Conflict("create", "user", "already exists with that email")
 		return
 	}
-	user, err = user.NewFromPayload(load)
+	user, err = user.NewFromPayload(load, orgId)
 	if err != nil {
 		return
 	}
@@ -47,6 +70,7 @@ func (s *UserServiceImpl) Create(load UserPayload) (user User, err error) {
 	if err != nil {
 		return
 	}
+	s.sendVerificationEmail(user)
 	return
 }
 
@@ -89,6 +113,278 @@ func (s *UserServiceImpl) UpdateName(payload NamePayload, userId uuid.UUID) (use
 	return
 }
 
+func (s *UserServiceImpl) UpdateProfile(payload ProfilePayload, userId, updaterId uuid.UUID) (user User, err error) {
+	user, err = s.GetByUserID(userId)
+	if err != nil {
+		return
+	}
+	if payload.UsernameChanged(user) {
+		exists, err := s.Repo.ExistsByUserName(*payload.UserName)
+		if err != nil {
+			return user, err
+		}
+		if exists {
+			return user, failure.Conflict("update", "user", "already exists with that username")
+		}
+	}
+	emailChanged := payload.EmailChanged(user)
+	// the user's own address is found again when only its case changes
+	if emailChanged && !strings.EqualFold(*payload.Email, user.Email) {
+		exists, err := s.Repo.ExistsByEmail(*payload.Email)
+		if err != nil {
+			return user, err
+		}
+		if exists {
+			return user, failure.Conflict("update", "user", "already exists with that email")
+		}
+	}
+	lastUpdatedAt := user.Updated_at
+	err = user.UpdateProfile(payload, updaterId)
+	if err != nil {
+		return
+	}
+	err = s.Repo.UpdateIfUnmodified(user, lastUpdatedAt)
+	if err != nil {
+		return
+	}
+	if emailChanged {
+		s.sendVerificationEmail(user)
+	}
+	return
+}
+
+func (s *UserServiceImpl) VerifyEmail(payload VerifyEmailPayload, userId uuid.UUID) (user User, err error) {
+	user, err = s.GetByUserID(userId)
+	if err != nil {
+		return
+	}
+	err = user.VerifyEmail(payload.Token)
+	if err != nil {
+		return
+	}
+	err = s.Repo.Update(user)
+	return
+}
+
+// UpdateAvatar validates the uploaded image, stores it together with a square
+// thumbnail and replaces the user's previous avatar.
+func (s *UserServiceImpl) UpdateAvatar(data []byte, userId, updaterId uuid.UUID) (user User, err error) {
+	user, err = s.GetByUserID(userId)
+	if err != nil {
+		return
+	}
+	img, err := imaging.Decode(data)
+	if err != nil {
+		return
+	}
+	thumb, thumbType, err := imaging.Encode(imaging.Thumbnail(img.Image, AvatarThumbnailSize), img.ContentType)
+	if err != nil {
+		return
+	}
+	uploadId, err := uuid.NewV4()
+	if err != nil {
+		return
+	}
+	prefix := fmt.Sprintf("avatars/%s/%s", userId, uploadId)
+	avatarKey := prefix + "." + img.Extension()
+	thumbKey := prefix + "_thumb." + imaging.ExtensionOf(thumbType)
+	err = s.Storage.Put(avatarKey, img.ContentType, img.Data)
+	if err != nil {
+		return
+	}
+	err = s.Storage.Put(thumbKey, thumbType, thumb)
+	if err != nil {
+		s.deleteObjects(avatarKey)
+		return
+	}
+	previous := []string{user.AvatarKey.String, user.ThumbnailKey.String}
+	user.SetAvatar(avatarKey, s.Storage.URL(avatarKey), thumbKey, s.Storage.URL(thumbKey), updaterId)
+	err = s.Repo.UpdateAvatar(user)
+	if err != nil {
+		s.deleteObjects(avatarKey, thumbKey)
+		return
+	}
+	s.deleteObjects(previous...)
+	return
+}
+
+func (s *UserServiceImpl) DeleteAvatar(userId, updaterId uuid.UUID) (user User, err error) {
+	user, err = s.GetByUserID(userId)
+	if err != nil {
+		return
+	}
+	if !user.AvatarKey.Valid {
+		err = failure.NotFound("avatar")
+		return
+	}
+	previous := []string{user.AvatarKey.String, user.ThumbnailKey.String}
+	user.RemoveAvatar(updaterId)
+	err = s.Repo.UpdateAvatar(user)
+	if err != nil {
+		return
+	}
+	s.deleteObjects(previous...)
+	return
+}
+
+// Import creates a user in the organization orgId for every valid row of
+// data. Rows are validated the same way as registrations, including username
+// and email uniqueness within the file, rows with a role above grantorRole
+// are rejected, and the rest are inserted in batches of ImportBatchSize.
+func (s *UserServiceImpl) Import(data io.Reader, format bulk.Format, dryRun bool, orgId uuid.UUID, grantorRole string) (report ImportReport, err error) {
+	report = ImportReport{DryRun: dryRun, Errors: []ImportRowError{}}
+	decoder := bulk.NewDecoder(data, format)
+	seenUserNames := map[string]bool{}
+	seenEmails := map[string]bool{}
+	batch := []User{}
+	batchRows := []int{}
+	for {
+		var load UserPayload
+		err = decoder.Decode(&load)
+		if err == io.EOF {
+			err = nil
+			break
+		}
+		if _, ok := err.(*bulk.RecordError); ok {
+			report.Total++
+			report.AddError(decoder.Row(), err)
+			continue
+		}
+		if err != nil {
+			err = failure.BadRequest(err)
+			return
+		}
+		report.Total++
+		user, rowErr := s.validateImportRow(load, orgId, grantorRole, seenUserNames, seenEmails)
+		if rowErr != nil {
+			report.AddError(decoder.Row(), rowErr)
+			continue
+		}
+		report.Valid++
+		if dryRun {
+			continue
+		}
+		batch = append(batch, user)
+		batchRows = append(batchRows, decoder.Row())
+		if len(batch) == ImportBatchSize {
+			s.importBatch(&report, batch, batchRows)
+			batch, batchRows = []User{}, []int{}
+		}
+	}
+	if len(batch) > 0 {
+		s.importBatch(&report, batch, batchRows)
+	}
+	return
+}
+
+func (s *UserServiceImpl) validateImportRow(load UserPayload, orgId uuid.UUID, grantorRole string, seenUserNames, seenEmails map[string]bool) (user User, err error) {
+	err = shared.GetValidator().Struct(load)
+	if err != nil {
+		return
+	}
+	if roles.GetRoleFromString(load.Role) < 0 {
+		err = failure.BadRequestFromString("unknown role " + load.Role)
+		return
+	}
+	if !roles.CanGrant(grantorRole, load.Role) {
+		err = failure.Unauthorized("not allowed to grant the role " + load.Role)
+		return
+	}
+	emailKey := strings.ToLower(load.Email)
+	if seenUserNames[load.UserName] {
+		err = failure.Conflict("import", "user", "username appears earlier in the file")
+		return
+	}
+	if seenEmails[emailKey] {
+		err = failure.Conflict("import", "user", "email appears earlier in the file")
+		return
+	}
+	seenUserNames[load.UserName] = true
+	seenEmails[emailKey] = true
+	exists, err := s.Repo.ExistsByUserName(load.UserName)
+	if err != nil {
+		return
+	}
+	if exists {
+		err = failure.Conflict("import", "user", "already exists with that username")
+		return
+	}
+	exists, err = s.Repo.ExistsByEmail(load.Email)
+	if err != nil {
+		return
+	}
+	if exists {
+		err = failure.Conflict("import", "user", "already exists with that email")
+		return
+	}
+	user, err = user.NewFromPayload(load, orgId)
+	return
+}
+
+// importBatch inserts a batch in one transaction. If it fails every row in
+// the batch is reported, as none of them were written.
+func (s *UserServiceImpl) importBatch(report *ImportReport, batch []User, rows []int) {
+	err := s.Repo.CreateBatch(batch)
+	if err != nil {
+		for _, row := range rows {
+			report.AddError(row, fmt.Errorf("batch insert failed: %v", err))
+		}
+		return
+	}
+	report.Imported += len(batch)
+	for _, user := range batch {
+		s.sendVerificationEmail(user)
+	}
+}
+
+// Export calls fn for every user matching the filter, in id order, reading
+// ImportBatchSize users at a time.
+func (s *UserServiceImpl) Export(filter Filter, fn func(user User) error) (err error) {
+	err = filter.Validate()
+	if err != nil {
+		return
+	}
+	afterId := ""
+	for {
+		users, err := s.Repo.GetAllAfter(filter, afterId, ImportBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			if err := fn(user); err != nil {
+				return err
+			}
+		}
+		if len(users) < ImportBatchSize {
+			return nil
+		}
+		afterId = users[len(users)-1].UserId.String()
+	}
+}
+
+// deleteObjects removes stored files that are no longer referenced. Failures
+// only leave an orphaned file behind, so they are logged and not returned.
+func (s *UserServiceImpl) deleteObjects(keys ...string) {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if err := s.Storage.Delete(key); err != nil {
+			logger.ErrorWithStack(err)
+		}
+	}
+}
+
+// sendVerificationEmail mails the user their verification token. The user
+// is already saved by then, so a failure is logged rather than failing the
+// write.
+func (s *UserServiceImpl) sendVerificationEmail(user User) {
+	body := fmt.Sprintf("Hi %s, use this token to verify your email address: %s", user.Name, user.EmailVerification.String)
+	if err := s.Mailer.Send(user.Email, "Verify your email address", body); err != nil {
+		logger.ErrorWithStack(err)
+	}
+}
+
 func (s *UserServiceImpl) DeleteByID(userId, userDeleter uuid.UUID) (user User, err error) {
 	exists, err := s.Repo.ExistsByID(userId)
 	if err != nil {
@@ -114,8 +410,26 @@ func (s *UserServiceImpl) DeleteByID(userId, userDeleter uuid.UUID) (user User,
 	return
 }
 
-func (s *UserServiceImpl) GetAll(limit, offset int, sort, field string) (res []User, err error) {
-	res, err = s.Repo.GetAll(limit, offset, sort, field)
+// Restore reactivates a soft deleted user.
+func (s *UserServiceImpl) Restore(userId, updaterId uuid.UUID) (user User, err error) {
+	user, err = s.GetByUserID(userId)
+	if err != nil {
+		return
+	}
+	err = user.Restore(updaterId)
+	if err != nil {
+		return
+	}
+	err = s.Repo.Update(user)
+	return
+}
+
+func (s *UserServiceImpl) GetAll(filter Filter, limit, offset int, sort, field string) (res []User, total int, err error) {
+	err = filter.Validate()
+	if err != nil {
+		return
+	}
+	res, total, err = s.Repo.GetAll(filter, limit, offset, sort, field)
 	if err != nil {
 		return
 	}
@@ -0,0 +1,256 @@
+package variant
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/nuuid"
+	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
+)
+
+// Option is a dimension a product comes in, such as size or color, with the
+// values it can take.
+type Option struct {
+	Id        uuid.UUID `db:"id" validate:"required"`
+	ProductId uuid.UUID `db:"product_id" validate:"required"`
+	Name      string    `db:"name" validate:"required,max=50"`
+	Position  int       `db:"position" validate:"min=0"`
+	Values    []string  `db:"-" validate:"required,min=1,max=100,unique,dive,required,max=50"`
+	CreatedAt time.Time `db:"created_at" validate:"required"`
+	CreatedBy uuid.UUID `db:"created_by" validate:"required"`
+}
+
+type OptionResponseFormat struct {
+	Id        uuid.UUID `json:"id"`
+	ProductId uuid.UUID `json:"productId"`
+	Name      string    `json:"name"`
+	Position  int       `json:"position"`
+	Values    []string  `json:"values"`
+	CreatedAt time.Time `json:"createdAt"`
+	CreatedBy uuid.UUID `json:"createdBy"`
+}
+
+// OptionValue is a single value of an option, as stored.
+type OptionValue struct {
+	OptionId uuid.UUID `db:"option_id"`
+	Value    string    `db:"value"`
+	Position int       `db:"position"`
+}
+
+type OptionPayload struct {
+	Name   string   `json:"name" validate:"required,max=50"`
+	Values []string `json:"values" validate:"required,min=1,max=100,unique,dive,required,max=50"`
+}
+
+// OptionsPayload replaces the options of a product, in display order.
+type OptionsPayload struct {
+	Options []OptionPayload `json:"options" validate:"max=5,dive"`
+}
+
+// Variant is a purchasable version of a product, such as the shirt in size
+// M and color red. It has its own SKU, price and stock; the product's own
+// price and stock are only used for products without variants.
+type Variant struct {
+	Id             uuid.UUID         `db:"id" validate:"required"`
+	OrganizationId uuid.UUID         `db:"organization_id" validate:"required"`
+	ProductId      uuid.UUID         `db:"product_id" validate:"required"`
+	Sku            string            `db:"sku" validate:"required,max=64"`
+	Price          float64           `db:"price" validate:"required,gt=0"`
+	Stock          int               `db:"stock" validate:"min=0"`
+	Options        map[string]string `db:"-"`
+	CreatedAt      time.Time         `db:"created_at" validate:"required"`
+	UpdatedAt      time.Time         `db:"updated_at" validate:"required"`
+	DeletedAt      null.Time         `db:"deleted_at"`
+	CreatedBy      uuid.UUID         `db:"created_by" validate:"required"`
+	UpdatedBy      uuid.UUID         `db:"updated_by" validate:"required"`
+	DeletedBy      nuuid.NUUID       `db:"deleted_by"`
+}
+
+type VariantResponseFormat struct {
+	Id             uuid.UUID         `json:"id"`
+	OrganizationId uuid.UUID         `json:"organizationId"`
+	ProductId      uuid.UUID         `json:"productId"`
+	Sku            string            `json:"sku"`
+	Price          float64           `json:"price"`
+	Stock          int               `json:"stock"`
+	Options        map[string]string `json:"options"`
+	CreatedAt      time.Time         `json:"createdAt"`
+	UpdatedAt      time.Time         `json:"updatedAt"`
+	DeletedAt      null.Time         `json:"deletedAt"`
+	CreatedBy      uuid.UUID         `json:"createdBy"`
+	UpdatedBy      uuid.UUID         `json:"updatedBy"`
+	DeletedBy      nuuid.NUUID       `json:"deletedBy"`
+}
+
+// VariantOption is the value of one option of a variant, as stored.
+type VariantOption struct {
+	VariantId uuid.UUID `db:"variant_id"`
+	Name      string    `db:"name"`
+	Value     string    `db:"value"`
+}
+
+// VariantPayload creates or replaces a variant. Options has a value for
+// every option of the product, e.g. {"size": "M", "color": "red"}.
+type VariantPayload struct {
+	Sku     string            `json:"sku" validate:"required,max=64"`
+	Price   float64           `json:"price" validate:"required,gt=0"`
+	Stock   int               `json:"stock" validate:"min=0"`
+	Options map[string]string `json:"options"`
+}
+
+func (o Option) NewFromPayload(load OptionPayload, productId, creatorId uuid.UUID, position int) (res Option, err error) {
+	optionId, err := uuid.NewV4()
+	if err != nil {
+		return
+	}
+	res = Option{
+		Id:        optionId,
+		ProductId: productId,
+		Name:      strings.ToLower(load.Name),
+		Position:  position,
+		Values:    load.Values,
+		CreatedAt: time.Now().UTC(),
+		CreatedBy: creatorId,
+	}
+	err = res.Validate()
+	return
+}
+
+func (o *Option) Validate() error {
+	validator := shared.GetValidator()
+	return validator.Struct(o)
+}
+
+func (o *Option) HasValue(value string) bool {
+	for _, v := range o.Values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (o Option) ToResponseFormat() OptionResponseFormat {
+	return OptionResponseFormat(o)
+}
+
+func (o Option) MarshalJSON() ([]byte, error) {
+	return json.Marshal(o.ToResponseFormat())
+}
+
+func (v Variant) NewFromPayload(load VariantPayload, orgId, productId, creatorId uuid.UUID) (res Variant, err error) {
+	variantId, err := uuid.NewV4()
+	if err != nil {
+		return
+	}
+	now := time.Now().UTC()
+	res = Variant{
+		Id:             variantId,
+		OrganizationId: orgId,
+		ProductId:      productId,
+		Sku:            load.Sku,
+		Price:          load.Price,
+		Stock:          load.Stock,
+		Options:        normalizeOptions(load.Options),
+		CreatedAt:      now,
+		CreatedBy:      creatorId,
+		UpdatedAt:      now,
+		UpdatedBy:      creatorId,
+	}
+	err = res.Validate()
+	return
+}
+
+func (v *Variant) Update(load VariantPayload, updaterId uuid.UUID) (err error) {
+	if v.IsDeleted() {
+		err = failure.Conflict("update", "variant", "variant is deleted")
+		return
+	}
+	v.Sku = load.Sku
+	v.Price = load.Price
+	v.Stock = load.Stock
+	v.Options = normalizeOptions(load.Options)
+	v.UpdatedAt = time.Now().UTC()
+	v.UpdatedBy = updaterId
+	err = v.Validate()
+	return
+}
+
+// IsDeleted reports whether the variant has been soft deleted.
+func (v *Variant) IsDeleted() bool {
+	return v.DeletedAt.Valid && v.DeletedBy.Valid
+}
+
+func (v *Variant) SoftDelete(userId uuid.UUID) (err error) {
+	if v.IsDeleted() {
+		err = failure.Conflict("delete", "variant", "already deleted")
+		return
+	}
+	v.DeletedAt = null.TimeFrom(time.Now().UTC())
+	v.DeletedBy = nuuid.From(userId)
+	err = v.Validate()
+	return
+}
+
+// MatchOptions checks that the variant has exactly one allowed value for
+// every option of its product.
+func (v *Variant) MatchOptions(options []Option) (err error) {
+	if len(v.Options) != len(options) {
+		err = failure.BadRequestFromString("a variant needs a value for each of the product's options")
+		return
+	}
+	for _, option := range options {
+		value, ok := v.Options[option.Name]
+		if !ok {
+			err = failure.BadRequestFromString("missing value for option " + option.Name)
+			return
+		}
+		if !option.HasValue(value) {
+			err = failure.BadRequestFromString(value + " is not a value of option " + option.Name)
+			return
+		}
+	}
+	return
+}
+
+// Key identifies the variant's combination of option values, so two
+// variants of a product cannot share one.
+func (v *Variant) Key() string {
+	names := make([]string, 0, len(v.Options))
+	for name := range v.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, name+"="+v.Options[name])
+	}
+	return strings.Join(parts, ";")
+}
+
+func (v *Variant) Validate() error {
+	validator := shared.GetValidator()
+	return validator.Struct(v)
+}
+
+func (v Variant) ToResponseFormat() VariantResponseFormat {
+	return VariantResponseFormat(v)
+}
+
+func (v Variant) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.ToResponseFormat())
+}
+
+// normalizeOptions lowercases option names the way options are stored.
+func normalizeOptions(options map[string]string) (res map[string]string) {
+	res = map[string]string{}
+	for name, value := range options {
+		res[strings.ToLower(name)] = value
+	}
+	return
+}
@@ -0,0 +1,39 @@
+package variant_test
+
+import (
+	"testing"
+
+	"github.com/evermos/boilerplate-go/internal/domain/variant"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVariantOptions(t *testing.T) {
+	options := []variant.Option{
+		{Name: "size", Values: []string{"S", "M", "L"}},
+		{Name: "color", Values: []string{"red", "blue"}},
+	}
+
+	t.Run("MatchOptions", func(t *testing.T) {
+		v := variant.Variant{Options: map[string]string{"size": "M", "color": "red"}}
+		assert.NoError(t, v.MatchOptions(options))
+
+		for _, opts := range []map[string]string{
+			{"size": "M"},
+			{"size": "XL", "color": "red"},
+			{"size": "M", "material": "cotton"},
+			{"size": "M", "color": "red", "material": "cotton"},
+		} {
+			v := variant.Variant{Options: opts}
+			assert.Error(t, v.MatchOptions(options), opts)
+		}
+	})
+
+	t.Run("Key", func(t *testing.T) {
+		a := variant.Variant{Options: map[string]string{"size": "M", "color": "red"}}
+		b := variant.Variant{Options: map[string]string{"color": "red", "size": "M"}}
+		c := variant.Variant{Options: map[string]string{"color": "blue", "size": "M"}}
+		assert.Equal(t, "color=red;size=M", a.Key())
+		assert.Equal(t, a.Key(), b.Key())
+		assert.NotEqual(t, a.Key(), c.Key())
+	})
+}
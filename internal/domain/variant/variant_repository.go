@@ -0,0 +1,307 @@
+package variant
+
+import (
+	"database/sql"
+
+	"github.com/evermos/boilerplate-go/infras"
+	"github.com/evermos/boilerplate-go/internal/domain/inventory"
+	"github.com/evermos/boilerplate-go/internal/domain/product"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/evermos/boilerplate-go/shared/nuuid"
+	"github.com/guregu/null"
+	"github.com/jmoiron/sqlx"
+)
+
+type VariantRepository interface {
+	GetOptions(productId string) (res []Option, err error)
+	SetOptions(productId string, load []Option) (err error)
+	Create(load Variant) (err error)
+	Update(load Variant) (err error)
+	UpdateWithStock(load Variant) (err error)
+	GetByID(orgId, id string) (res Variant, err error)
+	GetByProductID(productId string) (res []Variant, err error)
+	ExistsBySku(orgId, sku, excludeId string) (exists bool, err error)
+	CountByProductID(productId string) (count int, err error)
+}
+
+type VariantRepositoryMySQL struct {
+	DB *infras.MySQLConn
+}
+
+func ProvideVariantRepositoryMySQL(db *infras.MySQLConn) *VariantRepositoryMySQL {
+	return &VariantRepositoryMySQL{DB: db}
+}
+
+func (r *VariantRepositoryMySQL) GetOptions(productId string) (res []Option, err error) {
+	err = r.DB.Read.Select(&res, "SELECT * FROM product_option WHERE product_id = ? ORDER BY position", productId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	if len(res) == 0 {
+		res = []Option{}
+		return
+	}
+	ids := make([]string, 0, len(res))
+	for i := range res {
+		res[i].Values = []string{}
+		ids = append(ids, res[i].Id.String())
+	}
+	query, args, err := sqlx.In("SELECT * FROM product_option_value WHERE option_id IN (?) ORDER BY position", ids)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	var values []OptionValue
+	err = r.DB.Read.Select(&values, r.DB.Read.Rebind(query), args...)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	for _, v := range values {
+		for i := range res {
+			if res[i].Id == v.OptionId {
+				res[i].Values = append(res[i].Values, v.Value)
+			}
+		}
+	}
+	return
+}
+
+// SetOptions replaces the options of the product and their values.
+func (r *VariantRepositoryMySQL) SetOptions(productId string, load []Option) (err error) {
+	return r.DB.WithTransaction(func(db *sqlx.Tx, c chan error) {
+		if _, err := db.Exec("DELETE FROM product_option WHERE product_id = ?", productId); err != nil {
+			logger.ErrorWithStack(err)
+			c <- err
+			return
+		}
+		for _, option := range load {
+			query := `INSERT INTO product_option (id,product_id,name,position,created_at,created_by)
+			VALUES (:id,:product_id,:name,:position,:created_at,:created_by)`
+			if _, err := db.NamedExec(query, option); err != nil {
+				logger.ErrorWithStack(err)
+				c <- err
+				return
+			}
+			for position, value := range option.Values {
+				_, err := db.Exec("INSERT INTO product_option_value (option_id,value,position) VALUES (?,?,?)", option.Id.String(), value, position)
+				if err != nil {
+					logger.ErrorWithStack(err)
+					c <- err
+					return
+				}
+			}
+		}
+		c <- nil
+	})
+}
+
+// Create inserts the variant without stock and records its initial stock as
+// a movement and its initial price in the price history.
+func (r *VariantRepositoryMySQL) Create(load Variant) (err error) {
+	return r.DB.WithTransaction(func(db *sqlx.Tx, c chan error) {
+		query := `INSERT INTO product_variant (id,organization_id,product_id,sku,price,stock,created_at,created_by,updated_at,updated_by)
+		VALUES (:id,:organization_id,:product_id,:sku,:price,0,:created_at,:created_by,:updated_at,:updated_by)`
+		if _, err := db.NamedExec(query, load); err != nil {
+			logger.ErrorWithStack(err)
+			c <- err
+			return
+		}
+		change, err := product.NewPriceChange(load.OrganizationId, load.ProductId, nuuid.From(load.Id), load.Price, null.Float{}, null.Float{}, nuuid.NUUID{}, load.CreatedBy)
+		if err != nil {
+			c <- err
+			return
+		}
+		if err := product.TxRecordPrice(db, change); err != nil {
+			c <- err
+			return
+		}
+		if err := r.txSetVariantOptions(db, load); err != nil {
+			c <- err
+			return
+		}
+		if err := r.txSetStock(db, load); err != nil {
+			c <- err
+			return
+		}
+		c <- nil
+	})
+}
+
+// Update writes the variant except for its stock, which only changes
+// through stock movements. A change of price is recorded in the price
+// history of the product.
+func (r *VariantRepositoryMySQL) Update(load Variant) (err error) {
+	return r.update(load, false)
+}
+
+// UpdateWithStock writes the variant and records the change of its stock as
+// an adjustment.
+func (r *VariantRepositoryMySQL) UpdateWithStock(load Variant) (err error) {
+	return r.update(load, true)
+}
+
+func (r *VariantRepositoryMySQL) update(load Variant, withStock bool) (err error) {
+	return r.DB.WithTransaction(func(db *sqlx.Tx, c chan error) {
+		if err := r.txRecordPrice(db, load); err != nil {
+			c <- err
+			return
+		}
+		query := `
+		UPDATE product_variant
+		SET
+			sku = :sku,
+			price = :price,
+			updated_at = :updated_at,
+			updated_by = :updated_by,
+			deleted_at = :deleted_at,
+			deleted_by = :deleted_by
+		WHERE id = :id`
+		if _, err := db.NamedExec(query, load); err != nil {
+			logger.ErrorWithStack(err)
+			c <- err
+			return
+		}
+		if err := r.txSetVariantOptions(db, load); err != nil {
+			c <- err
+			return
+		}
+		if withStock {
+			if err := r.txSetStock(db, load); err != nil {
+				c <- err
+				return
+			}
+		}
+		c <- nil
+	})
+}
+
+// txRecordPrice records the price of the variant when it differs from the
+// stored one, which stays locked until tx ends.
+func (r *VariantRepositoryMySQL) txRecordPrice(tx *sqlx.Tx, load Variant) (err error) {
+	var stored float64
+	err = tx.Get(&stored, "SELECT price FROM product_variant WHERE id = ? FOR UPDATE", load.Id.String())
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	if stored == load.Price {
+		return
+	}
+	change, err := product.NewPriceChange(load.OrganizationId, load.ProductId, nuuid.From(load.Id), load.Price, null.Float{}, null.FloatFrom(stored), nuuid.NUUID{}, load.UpdatedBy)
+	if err != nil {
+		return
+	}
+	return product.TxRecordPrice(tx, change)
+}
+
+// txSetStock brings the stock to the variant's, see inventory.TxSet.
+func (r *VariantRepositoryMySQL) txSetStock(tx *sqlx.Tx, load Variant) (err error) {
+	m, err := inventory.NewMovement(load.OrganizationId, load.ProductId, nuuid.From(load.Id), 0, inventory.ReasonAdjustment, null.String{}, load.UpdatedBy)
+	if err != nil {
+		return
+	}
+	_, err = inventory.TxSet(tx, m, load.Stock)
+	return
+}
+
+func (r *VariantRepositoryMySQL) txSetVariantOptions(tx *sqlx.Tx, load Variant) (err error) {
+	_, err = tx.Exec("DELETE FROM product_variant_option WHERE variant_id = ?", load.Id.String())
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	for name, value := range load.Options {
+		_, err = tx.Exec("INSERT INTO product_variant_option (variant_id,name,value) VALUES (?,?,?)", load.Id.String(), name, value)
+		if err != nil {
+			logger.ErrorWithStack(err)
+			return
+		}
+	}
+	return
+}
+
+// GetByID returns the variant, including soft deleted ones.
+func (r *VariantRepositoryMySQL) GetByID(orgId, id string) (res Variant, err error) {
+	err = r.DB.Read.Get(&res, "SELECT * FROM product_variant WHERE id = ? AND organization_id = ?", id, orgId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err = failure.NotFound("Variant")
+			return
+		}
+		logger.ErrorWithStack(err)
+		return
+	}
+	variants, err := r.attachOptions([]Variant{res})
+	if err != nil {
+		return
+	}
+	res = variants[0]
+	return
+}
+
+// GetByProductID returns the variants of the product that are not deleted.
+func (r *VariantRepositoryMySQL) GetByProductID(productId string) (res []Variant, err error) {
+	err = r.DB.Read.Select(&res, "SELECT * FROM product_variant WHERE product_id = ? AND deleted_at IS NULL ORDER BY sku", productId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	res, err = r.attachOptions(res)
+	return
+}
+
+// attachOptions loads the option values of all variants with a single
+// query.
+func (r *VariantRepositoryMySQL) attachOptions(variants []Variant) (res []Variant, err error) {
+	res = variants
+	if len(variants) == 0 {
+		res = []Variant{}
+		return
+	}
+	ids := make([]string, 0, len(variants))
+	for i := range res {
+		res[i].Options = map[string]string{}
+		ids = append(ids, res[i].Id.String())
+	}
+	query, args, err := sqlx.In("SELECT * FROM product_variant_option WHERE variant_id IN (?)", ids)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	var options []VariantOption
+	err = r.DB.Read.Select(&options, r.DB.Read.Rebind(query), args...)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	for _, o := range options {
+		for i := range res {
+			if res[i].Id == o.VariantId {
+				res[i].Options[o.Name] = o.Value
+			}
+		}
+	}
+	return
+}
+
+func (r *VariantRepositoryMySQL) ExistsBySku(orgId, sku, excludeId string) (exists bool, err error) {
+	err = r.DB.Read.Get(&exists, "SELECT COUNT(id) FROM product_variant WHERE organization_id = ? AND sku = ? AND id <> ?", orgId, sku, excludeId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
+
+// CountByProductID counts the variants of the product that are not deleted.
+func (r *VariantRepositoryMySQL) CountByProductID(productId string) (count int, err error) {
+	err = r.DB.Read.Get(&count, "SELECT COUNT(id) FROM product_variant WHERE product_id = ? AND deleted_at IS NULL", productId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+		return
+	}
+	return
+}
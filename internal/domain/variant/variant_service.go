@@ -0,0 +1,210 @@
+package variant
+
+import (
+	"github.com/evermos/boilerplate-go/internal/domain/product"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/gofrs/uuid"
+)
+
+type VariantService interface {
+	GetOptions(orgId, productId uuid.UUID) (res []Option, err error)
+	SetOptions(load OptionsPayload, orgId, productId, userId uuid.UUID) (res []Option, err error)
+	Create(load VariantPayload, orgId, productId, userId uuid.UUID) (res Variant, err error)
+	Update(load VariantPayload, orgId, productId, variantId, userId uuid.UUID) (res Variant, err error)
+	Delete(orgId, productId, variantId, userId uuid.UUID) (res Variant, err error)
+	GetByID(orgId, variantId uuid.UUID) (res Variant, err error)
+	GetByProductID(orgId, productId uuid.UUID) (res []Variant, err error)
+	HasVariants(productId uuid.UUID) (has bool, err error)
+}
+
+type VariantServiceImpl struct {
+	Repo           VariantRepository
+	ProductService product.ProductService
+}
+
+func ProvideVariantServiceImpl(repo VariantRepository, productService product.ProductService) *VariantServiceImpl {
+	return &VariantServiceImpl{Repo: repo, ProductService: productService}
+}
+
+func (s *VariantServiceImpl) GetOptions(orgId, productId uuid.UUID) (res []Option, err error) {
+	_, err = s.ProductService.GetByID(productId, orgId)
+	if err != nil {
+		return
+	}
+	return s.Repo.GetOptions(productId.String())
+}
+
+// SetOptions replaces the options of the product. Existing variants have to
+// remain valid under the new options.
+func (s *VariantServiceImpl) SetOptions(load OptionsPayload, orgId, productId, userId uuid.UUID) (res []Option, err error) {
+	_, err = s.ProductService.GetByID(productId, orgId)
+	if err != nil {
+		return
+	}
+	res = []Option{}
+	seen := map[string]bool{}
+	for position, optionLoad := range load.Options {
+		option, err := Option{}.NewFromPayload(optionLoad, productId, userId, position)
+		if err != nil {
+			return res, failure.BadRequest(err)
+		}
+		if seen[option.Name] {
+			return res, failure.BadRequestFromString("duplicate option " + option.Name)
+		}
+		seen[option.Name] = true
+		res = append(res, option)
+	}
+	variants, err := s.Repo.GetByProductID(productId.String())
+	if err != nil {
+		return
+	}
+	for _, v := range variants {
+		if v.MatchOptions(res) != nil {
+			err = failure.Conflict("update", "options", "variant "+v.Sku+" would no longer match them")
+			return
+		}
+	}
+	err = s.Repo.SetOptions(productId.String(), res)
+	return
+}
+
+func (s *VariantServiceImpl) Create(load VariantPayload, orgId, productId, userId uuid.UUID) (res Variant, err error) {
+	_, err = s.ProductService.GetByID(productId, orgId)
+	if err != nil {
+		return
+	}
+	res, err = res.NewFromPayload(load, orgId, productId, userId)
+	if err != nil {
+		return
+	}
+	err = s.ensureValid(res)
+	if err != nil {
+		return
+	}
+	err = s.Repo.Create(res)
+	return
+}
+
+// Update replaces the variant. Its stock is set to the given level, with the
+// difference recorded as an adjustment.
+func (s *VariantServiceImpl) Update(load VariantPayload, orgId, productId, variantId, userId uuid.UUID) (res Variant, err error) {
+	res, err = s.getForProduct(orgId, productId, variantId)
+	if err != nil {
+		return
+	}
+	err = res.Update(load, userId)
+	if err != nil {
+		return
+	}
+	err = s.ensureValid(res)
+	if err != nil {
+		return
+	}
+	err = s.Repo.UpdateWithStock(res)
+	return
+}
+
+// Delete soft deletes the variant. Orders keep referring to it, but it can
+// no longer be added to carts or checked out.
+func (s *VariantServiceImpl) Delete(orgId, productId, variantId, userId uuid.UUID) (res Variant, err error) {
+	res, err = s.getForProduct(orgId, productId, variantId)
+	if err != nil {
+		return
+	}
+	err = res.SoftDelete(userId)
+	if err != nil {
+		return
+	}
+	err = s.Repo.Update(res)
+	return
+}
+
+// GetByID returns a variant that can be sold: neither it nor its product is
+// deleted.
+func (s *VariantServiceImpl) GetByID(orgId, variantId uuid.UUID) (res Variant, err error) {
+	res, err = s.Repo.GetByID(orgId.String(), variantId.String())
+	if err != nil {
+		return
+	}
+	if res.IsDeleted() {
+		err = failure.NotFound("Variant")
+		return
+	}
+	exists, err := s.ProductService.ExistsByID(res.ProductId, orgId)
+	if err != nil {
+		return
+	}
+	if !exists {
+		err = failure.NotFound("Variant")
+	}
+	return
+}
+
+func (s *VariantServiceImpl) GetByProductID(orgId, productId uuid.UUID) (res []Variant, err error) {
+	_, err = s.ProductService.GetByID(productId, orgId)
+	if err != nil {
+		return
+	}
+	return s.Repo.GetByProductID(productId.String())
+}
+
+// HasVariants reports whether the product is sold through variants rather
+// than on its own.
+func (s *VariantServiceImpl) HasVariants(productId uuid.UUID) (has bool, err error) {
+	count, err := s.Repo.CountByProductID(productId.String())
+	if err != nil {
+		return
+	}
+	has = count > 0
+	return
+}
+
+func (s *VariantServiceImpl) getForProduct(orgId, productId, variantId uuid.UUID) (res Variant, err error) {
+	_, err = s.ProductService.GetByID(productId, orgId)
+	if err != nil {
+		return
+	}
+	res, err = s.Repo.GetByID(orgId.String(), variantId.String())
+	if err != nil {
+		return
+	}
+	if res.ProductId != productId || res.IsDeleted() {
+		err = failure.NotFound("Variant")
+	}
+	return
+}
+
+// ensureValid checks the variant against the product's options, its
+// siblings' option combinations and the organization's SKUs.
+func (s *VariantServiceImpl) ensureValid(load Variant) (err error) {
+	options, err := s.Repo.GetOptions(load.ProductId.String())
+	if err != nil {
+		return
+	}
+	if len(options) == 0 {
+		err = failure.BadRequestFromString("define the product's options before adding variants")
+		return
+	}
+	err = load.MatchOptions(options)
+	if err != nil {
+		return
+	}
+	siblings, err := s.Repo.GetByProductID(load.ProductId.String())
+	if err != nil {
+		return
+	}
+	for _, sibling := range siblings {
+		if sibling.Id != load.Id && sibling.Key() == load.Key() {
+			err = failure.Conflict("save", "variant", "another variant has the same options")
+			return
+		}
+	}
+	exists, err := s.Repo.ExistsBySku(load.OrganizationId.String(), load.Sku, load.Id.String())
+	if err != nil {
+		return
+	}
+	if exists {
+		err = failure.Conflict("save", "variant", "already exists with that SKU")
+	}
+	return
+}
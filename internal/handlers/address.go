@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/evermos/boilerplate-go/internal/domain/address"
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/transport/http/response"
+	"github.com/go-chi/chi"
+	"github.com/gofrs/uuid"
+)
+
+type AddressHandler struct {
+	Service address.AddressService
+}
+
+func ProvideAddressHandler(service address.AddressService) AddressHandler {
+	return AddressHandler{Service: service}
+}
+
+// Router mounts the address book routes under /users/{userId}.
+func (h *AddressHandler) Router(r chi.Router) {
+	r.Route("/addresses", func(r chi.Router) {
+		r.Get("/", h.HandleGetAll)
+		r.Post("/", h.HandleCreate)
+		r.Route("/{addressId}", func(r chi.Router) {
+			r.Get("/", h.HandleGetByID)
+			r.Put("/", h.HandleUpdate)
+			r.Delete("/", h.HandleDelete)
+		})
+	})
+}
+
+// HandleCreate creates a new Address.
+// @Summary creates a new Address for a User.
+// @Description This endpoint adds an address to the user's address book. The first address becomes the default shipping and billing address.
+// @Tags v1/Address
+// @Security JWTToken
+// @Param userId path string true "the user id"
+// @Param Address body address.AddressPayload true "The address to be created"
+// @Produce json
+// @Success 201 {object} response.Base{data=address.AddressResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/users/{userId}/addresses [post]
+func (h *AddressHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	userId, err := uuid.FromString(chi.URLParam(r, "userId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	var payload address.AddressPayload
+	err = decoder.Decode(&payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	err = shared.GetValidator().Struct(payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	creatorId, ok := callerId(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.Create(payload, userId, creatorId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusCreated, res)
+}
+
+// HandleGetAll Gets all Addresses of a User.
+// @Summary Gets all Addresses of a User.
+// @Description This endpoint gets the user's address book.
+// @Tags v1/Address
+// @Security JWTToken
+// @Param userId path string true "the user id"
+// @Produce json
+// @Success 200 {object} response.Base{data=[]address.AddressResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/users/{userId}/addresses [get]
+func (h *AddressHandler) HandleGetAll(w http.ResponseWriter, r *http.Request) {
+	userId, err := uuid.FromString(chi.URLParam(r, "userId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	res, err := h.Service.GetAllByUserID(userId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleGetByID Gets an Address.
+// @Summary Gets an Address of a User.
+// @Description This endpoint gets a single address of the user.
+// @Tags v1/Address
+// @Security JWTToken
+// @Param userId path string true "the user id"
+// @Param addressId path string true "the address id"
+// @Produce json
+// @Success 200 {object} response.Base{data=address.AddressResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/users/{userId}/addresses/{addressId} [get]
+func (h *AddressHandler) HandleGetByID(w http.ResponseWriter, r *http.Request) {
+	userId, err := uuid.FromString(chi.URLParam(r, "userId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	addressId, err := uuid.FromString(chi.URLParam(r, "addressId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	res, err := h.Service.GetByID(addressId, userId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleUpdate updates an Address.
+// @Summary updates an Address of a User.
+// @Description This endpoint replaces the fields of an address.
+// @Tags v1/Address
+// @Security JWTToken
+// @Param userId path string true "the user id"
+// @Param addressId path string true "the address id"
+// @Param Address body address.AddressPayload true "The new address fields"
+// @Produce json
+// @Success 200 {object} response.Base{data=address.AddressResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/users/{userId}/addresses/{addressId} [put]
+func (h *AddressHandler) HandleUpdate(w http.ResponseWriter, r *http.Request) {
+	userId, err := uuid.FromString(chi.URLParam(r, "userId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	addressId, err := uuid.FromString(chi.URLParam(r, "addressId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	var payload address.AddressPayload
+	err = decoder.Decode(&payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	err = shared.GetValidator().Struct(payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	updaterId, ok := callerId(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.Update(payload, addressId, userId, updaterId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleDelete Deletes an Address.
+// @Summary soft deletes an Address of a User.
+// @Description This endpoint soft deletes an address. Orders that used it keep referencing it.
+// @Tags v1/Address
+// @Security JWTToken
+// @Param userId path string true "the user id"
+// @Param addressId path string true "the address id"
+// @Produce json
+// @Success 200 {object} response.Base{data=address.AddressResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/users/{userId}/addresses/{addressId} [delete]
+func (h *AddressHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	userId, err := uuid.FromString(chi.URLParam(r, "userId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	addressId, err := uuid.FromString(chi.URLParam(r, "addressId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	deleterId, ok := callerId(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.DeleteByID(addressId, userId, deleterId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
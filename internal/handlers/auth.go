@@ -5,9 +5,9 @@ import (
 	"net/http"
 
 	"github.com/evermos/boilerplate-go/internal/domain/auth"
+	"github.com/evermos/boilerplate-go/internal/domain/group"
 	"github.com/evermos/boilerplate-go/shared"
 	"github.com/evermos/boilerplate-go/shared/failure"
-	"github.com/evermos/boilerplate-go/shared/jwt"
 	"github.com/evermos/boilerplate-go/transport/http/middleware"
 	"github.com/evermos/boilerplate-go/transport/http/response"
 	"github.com/go-chi/chi"
@@ -27,25 +27,27 @@ func (h *AuthHandler) Router(r chi.Router) {
 		r.Post("/login", h.HandleLogin)
 		r.Group(func(r chi.Router) {
 			r.Use(h.JwtAuth.Validate)
-			r.Use(h.JwtAuth.AdminOnly)
+			r.Use(h.JwtAuth.RequirePermission(group.PermissionUsersWrite))
 			r.Post("/register", h.HandleRegister)
 		})
 		r.Group(func(r chi.Router) {
 			r.Use(h.JwtAuth.Validate)
 			r.Get("/validate", h.HandleValidate)
+			r.Post("/switch", h.HandleSwitchOrganization)
 		})
 	})
 }
 
 // HandleRegister creates a new User.
 // @Summary Create a new User / register a user.
-// @Description This endpoint creates a new User.
+// @Description This endpoint creates a new User in the caller's organization. Only admins may give the user a role above their own.
 // @Tags v1/Auth
 // @Security JWTToken
 // @Param User body auth.AuthPayload true "The User to be created."
 // @Produce json
 // @Success 200 {object} response.Base{data=auth.JwtResponseFormat}
 // @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
 // @Failure 409 {object} response.Base
 // @Failure 500 {object} response.Base
 // @Router /v1/auth/register [post]
@@ -63,7 +65,17 @@ func (h *AuthHandler) HandleRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	res, err := h.Service.Register(payload)
+	claims, ok := claimsOf(w, r)
+	if !ok {
+		return
+	}
+	orgId, err := tenantOf(claims)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+
+	res, err := h.Service.Register(payload, orgId, claims.Role)
 	if err != nil {
 		response.WithError(w, err)
 		return
@@ -73,7 +85,7 @@ func (h *AuthHandler) HandleRegister(w http.ResponseWriter, r *http.Request) {
 
 // HandleLogin Login a user.
 // @Summary Login a user.
-// @Description This endpoint Logs in a User.
+// @Description This endpoint Logs in a User. The token is for the given organization, or the user's home organization when none is given.
 // @Tags v1/Auth
 // @Param User body auth.LoginPayload true "The User to be logged in."
 // @Produce json
@@ -106,6 +118,45 @@ func (h *AuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 	response.WithJSON(w, http.StatusOK, res)
 }
 
+// HandleSwitchOrganization issues a token for another organization.
+// @Summary Switches the organization of the current User.
+// @Description This endpoint issues a new token for another organization the user is a member of, with the role and cart the user has there.
+// @Tags v1/Auth
+// @Security JWTToken
+// @Param Organization body auth.SwitchOrganizationPayload true "The organization to switch to."
+// @Produce json
+// @Success 200 {object} response.Base{data=auth.JwtResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/auth/switch [post]
+func (h *AuthHandler) HandleSwitchOrganization(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var payload auth.SwitchOrganizationPayload
+	err := decoder.Decode(&payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	err = shared.GetValidator().Struct(payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	userId, ok := callerId(w, r)
+	if !ok {
+		return
+	}
+
+	res, err := h.Service.SwitchOrganization(payload, userId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+
+	response.WithJSON(w, http.StatusOK, res)
+}
+
 // HandleValidate validates a JWT Token.
 // @Summary Validates the given Jwt Token.
 // @Description This endpoint validates a jwt token.
@@ -118,9 +169,8 @@ func (h *AuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 // @Failure 500 {object} response.Base
 // @Router /v1/auth/validate [get]
 func (h *AuthHandler) HandleValidate(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value(middleware.ClaimsKey("claims")).(*jwt.Claims)
+	claims, ok := claimsOf(w, r)
 	if !ok {
-		response.WithMessage(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
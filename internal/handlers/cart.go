@@ -5,9 +5,9 @@ import (
 	"net/http"
 
 	"github.com/evermos/boilerplate-go/internal/domain/cart"
+	"github.com/evermos/boilerplate-go/internal/domain/group"
 	"github.com/evermos/boilerplate-go/shared"
 	"github.com/evermos/boilerplate-go/shared/failure"
-	"github.com/evermos/boilerplate-go/shared/jwt"
 	"github.com/evermos/boilerplate-go/shared/pagination"
 	"github.com/evermos/boilerplate-go/transport/http/middleware"
 	"github.com/evermos/boilerplate-go/transport/http/response"
@@ -35,7 +35,7 @@ func (h *CartHandler) Router(r chi.Router) {
 			r.Post("/checkout", h.HandleCheckout)
 		})
 		r.Group(func(r chi.Router) {
-			r.Use(h.JwtAuth.AdminOnly)
+			r.Use(h.JwtAuth.RequirePermission(group.PermissionCartsRead))
 			r.Get("/", h.HandleGetAllCarts)
 		})
 	})
@@ -43,7 +43,7 @@ func (h *CartHandler) Router(r chi.Router) {
 
 // HandleRegister Adds a product into a cart.
 // @Summary Adds a product into a users cart.
-// @Description This endpoint Creates a cart item and put it into a users cart.
+// @Description This endpoint Creates a cart item and put it into a users cart. Stock held in other carts is not available; when holds are on, the item is held for this cart for a while and its holdStatus shows whether the hold is still active. Products taking backorders or pre-orders can be added beyond their stock, up to their backorderLimit.
 // @Tags v1/Cart
 // @Security JWTToken
 // @Param cartId path string true "the cart id"
@@ -73,19 +73,11 @@ func (h *CartHandler) HandleAddToCart(w http.ResponseWriter, r *http.Request) {
 		response.WithError(w, failure.BadRequest(err))
 		return
 	}
-	claims, ok := r.Context().Value(middleware.ClaimsKey("claims")).(*jwt.Claims)
+	orgId, userId, ok := caller(w, r)
 	if !ok {
-		response.WithMessage(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
-	userId, err := uuid.FromString(claims.UserId)
-
-	if err != nil {
-		response.WithError(w, err)
-		return
-	}
-
-	res, err := h.Service.AddToCart(payload, userId, cartId)
+	res, err := h.Service.AddToCart(payload, userId, cartId, orgId)
 	if err != nil {
 		response.WithError(w, err)
 		return
@@ -112,7 +104,11 @@ func (h *CartHandler) HandleGetCart(w http.ResponseWriter, r *http.Request) {
 		response.WithError(w, failure.BadRequest(err))
 		return
 	}
-	res, err := h.Service.GetCart(id)
+	orgId, _, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.GetCart(id, orgId)
 	if err != nil {
 		response.WithError(w, err)
 		return
@@ -139,7 +135,11 @@ func (h *CartHandler) HandleGetCartItems(w http.ResponseWriter, r *http.Request)
 		response.WithError(w, failure.BadRequest(err))
 		return
 	}
-	res, err := h.Service.GetCart(id)
+	orgId, _, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.GetCart(id, orgId)
 	if err != nil {
 		response.WithError(w, err)
 		return
@@ -149,7 +149,7 @@ func (h *CartHandler) HandleGetCartItems(w http.ResponseWriter, r *http.Request)
 
 // HandleCheckout checkout a list of cart items.
 // @Summary checkout a list of cart items.
-// @Description This endpoint checkout the list of cart item ids given.
+// @Description This endpoint checkout the list of cart item ids given. Their quantities are taken out of stock together with placing the order; when any item has too little stock left nothing is ordered and the 409 names every item that is short. Short items of products taking backorders or pre-orders are ordered with status backordered or preordered instead and are taken out of stock as it arrives.
 // @Tags v1/Cart
 // @Security JWTToken
 // @Param cartId path string true "the cart id"
@@ -179,17 +179,11 @@ func (h *CartHandler) HandleCheckout(w http.ResponseWriter, r *http.Request) {
 		response.WithError(w, failure.BadRequest(err))
 		return
 	}
-	claims, ok := r.Context().Value(middleware.ClaimsKey("claims")).(*jwt.Claims)
+	orgId, userId, ok := caller(w, r)
 	if !ok {
-		response.WithMessage(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
-	userId, err := uuid.FromString(claims.UserId)
-	if err != nil {
-		response.WithError(w, err)
-		return
-	}
-	res, err := h.Service.Checkout(payload, cartId, userId)
+	res, err := h.Service.Checkout(payload, cartId, userId, orgId)
 	if err != nil {
 		response.WithError(w, err)
 		return
@@ -199,7 +193,7 @@ func (h *CartHandler) HandleCheckout(w http.ResponseWriter, r *http.Request) {
 
 // HandleGetAll Gets all carts.
 // @Summary Gets all carts.
-// @Description This endpoint Gets all carts of users.
+// @Description This endpoint Gets all carts of the users in the caller's organization.
 // @Tags v1/Cart
 // @Security JWTToken
 // @Param page query int true "current page number"
@@ -218,7 +212,11 @@ func (h *CartHandler) HandleGetAllCarts(w http.ResponseWriter, r *http.Request)
 		response.WithError(w, err)
 		return
 	}
-	res, err := h.Service.GetAllCarts(pg.Limit, pg.Offset, pg.Sort, pg.Field)
+	orgId, _, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.GetAllCarts(orgId, pg.Limit, pg.Offset, pg.Sort, pg.Field)
 	totalPage := pg.GetTotalPages(res)
 
 	if err != nil {
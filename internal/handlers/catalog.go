@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/evermos/boilerplate-go/configs"
+	"github.com/evermos/boilerplate-go/internal/domain/catalog"
+	"github.com/evermos/boilerplate-go/internal/domain/group"
+	"github.com/evermos/boilerplate-go/shared/bulk"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/pagination"
+	"github.com/evermos/boilerplate-go/transport/http/middleware"
+	"github.com/evermos/boilerplate-go/transport/http/response"
+	"github.com/go-chi/chi"
+	"github.com/gofrs/uuid"
+)
+
+type CatalogHandler struct {
+	Service catalog.CatalogService
+	Config  *configs.Config
+	JwtAuth *middleware.JwtAuthentication
+}
+
+func ProvideCatalogHandler(service catalog.CatalogService, config *configs.Config, jwtAuth *middleware.JwtAuthentication) CatalogHandler {
+	return CatalogHandler{Service: service, Config: config, JwtAuth: jwtAuth}
+}
+
+// ProductRouter mounts the product import jobs. It is mounted under
+// /products, which already validates the token.
+func (h *CatalogHandler) ProductRouter(r chi.Router) {
+	r.Group(func(r chi.Router) {
+		r.Use(h.JwtAuth.RequirePermission(group.PermissionProductsWrite))
+		r.Post("/import", h.HandleImport)
+		r.Get("/import/{jobId}", h.HandleGetImport)
+	})
+}
+
+// HandleImport imports Products from a file.
+// @Summary imports Products from a CSV or NDJSON file.
+// @Description This endpoint queues an import job and returns it right away; poll the job for its progress and report. Every row creates a product or replaces the product with the same SKU, so rows have the columns of an export: sku, name, description, stock, price, slug, seoTitle, metaDescription, status, publishAt and attributes written as "brand=acme;material=linen". Invalid rows are reported and skipped.
+// @Tags v1/Product
+// @Security JWTToken
+// @Accept multipart/form-data
+// @Param file formData file true "the products to import"
+// @Param format query string false "file format" Enums(csv, ndjson)
+// @Param dryRun query bool false "only validate the file"
+// @Produce json
+// @Success 202 {object} response.Base{data=catalog.ImportJobResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/import [post]
+func (h *CatalogHandler) HandleImport(w http.ResponseWriter, r *http.Request) {
+	format, err := bulk.ParseFormat(pagination.ParseQueryParams(r, "format"))
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	dryRun, err := pagination.ParseBoolParam(r, "dryRun")
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	orgId, callerId, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	data, err := readUpload(w, r, "file", h.Config.Storage.MaxUploadBytes)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	res, err := h.Service.StartImport(data, format, dryRun != nil && *dryRun, orgId, callerId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusAccepted, res)
+}
+
+// HandleGetImport gets a Product import.
+// @Summary gets a Product import.
+// @Description This endpoint gets the status, progress and report of an import job. At most the first 1000 rejected rows are listed in errors; failed counts all of them.
+// @Tags v1/Product
+// @Security JWTToken
+// @Param jobId path string true "the import job id"
+// @Produce json
+// @Success 200 {object} response.Base{data=catalog.ImportJobResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/import/{jobId} [get]
+func (h *CatalogHandler) HandleGetImport(w http.ResponseWriter, r *http.Request) {
+	jobId, err := uuid.FromString(chi.URLParam(r, "jobId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	orgId, _, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.GetImport(jobId, orgId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/evermos/boilerplate-go/internal/domain/category"
+	"github.com/evermos/boilerplate-go/internal/domain/group"
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/transport/http/middleware"
+	"github.com/evermos/boilerplate-go/transport/http/response"
+	"github.com/go-chi/chi"
+	"github.com/gofrs/uuid"
+)
+
+type CategoryHandler struct {
+	Service category.CategoryService
+	JwtAuth *middleware.JwtAuthentication
+}
+
+func ProvideCategoryHandler(service category.CategoryService, jwtAuth *middleware.JwtAuthentication) CategoryHandler {
+	return CategoryHandler{Service: service, JwtAuth: jwtAuth}
+}
+
+// Router mounts the category tree of the caller's organization. Everyone
+// can browse it, changing it takes the products.write permission.
+func (h *CategoryHandler) Router(r chi.Router) {
+	r.Route("/categories", func(r chi.Router) {
+		r.Use(h.JwtAuth.Validate)
+
+		r.Group(func(r chi.Router) {
+			r.Get("/", h.HandleGetTree)
+			r.Get("/{categoryId}", h.HandleGetByID)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(h.JwtAuth.RequirePermission(group.PermissionProductsWrite))
+			r.Post("/", h.HandleCreate)
+			r.Put("/{categoryId}", h.HandleUpdate)
+			r.Delete("/{categoryId}", h.HandleDelete)
+		})
+	})
+}
+
+// ProductRouter mounts the categories of a product. It is mounted under
+// /products, which already validates the token.
+func (h *CategoryHandler) ProductRouter(r chi.Router) {
+	r.Get("/{productId}/categories", h.HandleGetByProductID)
+	r.Group(func(r chi.Router) {
+		r.Use(h.JwtAuth.RequirePermission(group.PermissionProductsWrite))
+		r.Put("/{productId}/categories", h.HandleAssignProduct)
+	})
+}
+
+// HandleCreate creates a new Category.
+// @Summary creates a new Category.
+// @Description This endpoint creates a category in the caller's organization, optionally under a parent category. The slug is derived from the name when left empty.
+// @Tags v1/Category
+// @Security JWTToken
+// @Param Category body category.CategoryPayload true "The category to be created"
+// @Produce json
+// @Success 201 {object} response.Base{data=category.CategoryResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/categories [post]
+func (h *CategoryHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	payload, ok := h.decodePayload(w, r)
+	if !ok {
+		return
+	}
+	orgId, callerId, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.Create(payload, orgId, callerId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusCreated, res)
+}
+
+// HandleGetTree gets the category tree.
+// @Summary gets the category tree.
+// @Description This endpoint gets the root categories of the caller's organization with their subcategories nested under them.
+// @Tags v1/Category
+// @Security JWTToken
+// @Produce json
+// @Success 200 {object} response.Base{data=[]category.CategoryResponseFormat}
+// @Failure 401 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/categories [get]
+func (h *CategoryHandler) HandleGetTree(w http.ResponseWriter, r *http.Request) {
+	orgId, _, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.GetTree(orgId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleGetByID gets a Category.
+// @Summary gets a Category by id or slug.
+// @Description This endpoint gets a category of the caller's organization.
+// @Tags v1/Category
+// @Security JWTToken
+// @Param categoryId path string true "the category id or slug"
+// @Produce json
+// @Success 200 {object} response.Base{data=category.CategoryResponseFormat}
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/categories/{categoryId} [get]
+func (h *CategoryHandler) HandleGetByID(w http.ResponseWriter, r *http.Request) {
+	orgId, _, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.Resolve(orgId, chi.URLParam(r, "categoryId"))
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleUpdate replaces a Category.
+// @Summary replaces a Category.
+// @Description This endpoint renames, reorders or moves a category. A category cannot be moved under one of its own subcategories.
+// @Tags v1/Category
+// @Security JWTToken
+// @Param categoryId path string true "the category id"
+// @Param Category body category.CategoryPayload true "The category's new values"
+// @Produce json
+// @Success 200 {object} response.Base{data=category.CategoryResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/categories/{categoryId} [put]
+func (h *CategoryHandler) HandleUpdate(w http.ResponseWriter, r *http.Request) {
+	categoryId, err := uuid.FromString(chi.URLParam(r, "categoryId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	payload, ok := h.decodePayload(w, r)
+	if !ok {
+		return
+	}
+	orgId, callerId, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.Update(payload, orgId, categoryId, callerId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleDelete deletes a Category.
+// @Summary deletes a Category.
+// @Description This endpoint deletes a category without subcategories. Its products are unassigned from it.
+// @Tags v1/Category
+// @Security JWTToken
+// @Param categoryId path string true "the category id"
+// @Produce json
+// @Success 200 {object} response.Base{data=category.CategoryResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/categories/{categoryId} [delete]
+func (h *CategoryHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	categoryId, err := uuid.FromString(chi.URLParam(r, "categoryId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	orgId, _, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.Delete(orgId, categoryId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleGetByProductID gets the categories of a Product.
+// @Summary gets the categories of a Product.
+// @Description This endpoint lists the categories a product is assigned to.
+// @Tags v1/Product
+// @Security JWTToken
+// @Param productId path string true "the product id"
+// @Produce json
+// @Success 200 {object} response.Base{data=[]category.CategoryResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{productId}/categories [get]
+func (h *CategoryHandler) HandleGetByProductID(w http.ResponseWriter, r *http.Request) {
+	productId, err := uuid.FromString(chi.URLParam(r, "productId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	orgId, _, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.GetByProductID(orgId, productId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleAssignProduct replaces the categories of a Product.
+// @Summary replaces the categories of a Product.
+// @Description This endpoint assigns the product to exactly the given categories. An empty list removes it from every category.
+// @Tags v1/Product
+// @Security JWTToken
+// @Param productId path string true "the product id"
+// @Param Assignment body category.AssignmentPayload true "the product's categories"
+// @Produce json
+// @Success 200 {object} response.Base{data=[]category.CategoryResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{productId}/categories [put]
+func (h *CategoryHandler) HandleAssignProduct(w http.ResponseWriter, r *http.Request) {
+	productId, err := uuid.FromString(chi.URLParam(r, "productId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	var payload category.AssignmentPayload
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	err = shared.GetValidator().Struct(payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	orgId, callerId, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.AssignProduct(payload, orgId, productId, callerId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+func (h *CategoryHandler) decodePayload(w http.ResponseWriter, r *http.Request) (payload category.CategoryPayload, ok bool) {
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	err = shared.GetValidator().Struct(payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	ok = true
+	return
+}
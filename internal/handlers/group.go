@@ -0,0 +1,382 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/evermos/boilerplate-go/internal/domain/group"
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/pagination"
+	"github.com/evermos/boilerplate-go/transport/http/middleware"
+	"github.com/evermos/boilerplate-go/transport/http/response"
+	"github.com/go-chi/chi"
+	"github.com/gofrs/uuid"
+)
+
+type GroupHandler struct {
+	Service group.GroupService
+	JwtAuth *middleware.JwtAuthentication
+}
+
+func ProvideGroupHandler(service group.GroupService, jwtAuth *middleware.JwtAuthentication) GroupHandler {
+	return GroupHandler{Service: service, JwtAuth: jwtAuth}
+}
+
+// Router mounts group management. Groups always belong to the organization
+// of the caller's token.
+func (h *GroupHandler) Router(r chi.Router) {
+	r.Route("/groups", func(r chi.Router) {
+		r.Use(h.JwtAuth.Validate)
+		r.Use(h.JwtAuth.RequirePermission(group.PermissionGroupsManage))
+		r.Get("/", h.HandleGetAll)
+		r.Post("/", h.HandleCreate)
+		r.Get("/permissions", h.HandleGetPermissions)
+		r.Route("/{groupId}", func(r chi.Router) {
+			r.Get("/", h.HandleGetByID)
+			r.Put("/", h.HandleUpdate)
+			r.Delete("/", h.HandleDelete)
+			r.Route("/members", func(r chi.Router) {
+				r.Get("/", h.HandleGetMembers)
+				r.Put("/{userId}", h.HandleAddMember)
+				r.Delete("/{userId}", h.HandleRemoveMember)
+			})
+		})
+	})
+}
+
+// UserRouter mounts the user's own groups under /users/{userId}.
+func (h *GroupHandler) UserRouter(r chi.Router) {
+	r.Get("/groups", h.HandleGetByUserID)
+}
+
+// HandleCreate creates a new Group.
+// @Summary creates a new Group.
+// @Description This endpoint creates a group in the caller's organization with the given permissions.
+// @Tags v1/Group
+// @Security JWTToken
+// @Param Group body group.GroupPayload true "The group to be created"
+// @Produce json
+// @Success 201 {object} response.Base{data=group.GroupResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/groups [post]
+func (h *GroupHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	payload, ok := h.decodePayload(w, r)
+	if !ok {
+		return
+	}
+	orgId, callerId, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.Create(payload, orgId, callerId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusCreated, res)
+}
+
+// HandleGetAll lists Groups.
+// @Summary gets the Groups of the organization.
+// @Description This endpoint lists the groups of the caller's organization with their permissions.
+// @Tags v1/Group
+// @Security JWTToken
+// @Param page query int true "current page number"
+// @Param limit query int true "limit of groups per page"
+// @Produce json
+// @Success 200 {object} response.Pagination{data=[]group.GroupResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/groups [get]
+func (h *GroupHandler) HandleGetAll(w http.ResponseWriter, r *http.Request) {
+	orgId, _, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	pg, err := pagination.GetPagination(r)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	res, err := h.Service.GetAll(orgId, pg.Limit, pg.Offset)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithPagination(w, http.StatusOK, res, pg.Page, pg.Limit, pg.GetTotalPages(res))
+}
+
+// HandleGetPermissions lists the permissions groups can grant.
+// @Summary gets the grantable permissions.
+// @Description This endpoint lists every permission that can be granted to a group.
+// @Tags v1/Group
+// @Security JWTToken
+// @Produce json
+// @Success 200 {object} response.Base{data=[]string}
+// @Failure 401 {object} response.Base
+// @Router /v1/groups/permissions [get]
+func (h *GroupHandler) HandleGetPermissions(w http.ResponseWriter, r *http.Request) {
+	response.WithJSON(w, http.StatusOK, group.Permissions)
+}
+
+// HandleGetByID gets a Group.
+// @Summary gets a Group by id.
+// @Description This endpoint gets a group of the caller's organization.
+// @Tags v1/Group
+// @Security JWTToken
+// @Param groupId path string true "the group id"
+// @Produce json
+// @Success 200 {object} response.Base{data=group.GroupResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/groups/{groupId} [get]
+func (h *GroupHandler) HandleGetByID(w http.ResponseWriter, r *http.Request) {
+	groupId, err := uuid.FromString(chi.URLParam(r, "groupId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	orgId, _, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.GetByID(orgId, groupId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleUpdate updates a Group.
+// @Summary updates a Group.
+// @Description This endpoint renames a group and replaces its permissions.
+// @Tags v1/Group
+// @Security JWTToken
+// @Param groupId path string true "the group id"
+// @Param Group body group.GroupPayload true "The new group"
+// @Produce json
+// @Success 200 {object} response.Base{data=group.GroupResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/groups/{groupId} [put]
+func (h *GroupHandler) HandleUpdate(w http.ResponseWriter, r *http.Request) {
+	groupId, err := uuid.FromString(chi.URLParam(r, "groupId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	payload, ok := h.decodePayload(w, r)
+	if !ok {
+		return
+	}
+	orgId, callerId, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.Update(payload, orgId, groupId, callerId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleDelete deletes a Group.
+// @Summary deletes a Group.
+// @Description This endpoint deletes a group. Its members lose the permissions it granted.
+// @Tags v1/Group
+// @Security JWTToken
+// @Param groupId path string true "the group id"
+// @Produce json
+// @Success 200 {object} response.Base{data=group.GroupResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/groups/{groupId} [delete]
+func (h *GroupHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	groupId, err := uuid.FromString(chi.URLParam(r, "groupId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	orgId, _, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.Delete(orgId, groupId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleGetMembers lists the members of a Group.
+// @Summary gets the members of a Group.
+// @Description This endpoint lists the users in a group.
+// @Tags v1/Group
+// @Security JWTToken
+// @Param groupId path string true "the group id"
+// @Param page query int true "current page number"
+// @Param limit query int true "limit of members per page"
+// @Produce json
+// @Success 200 {object} response.Pagination{data=[]group.MemberResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/groups/{groupId}/members [get]
+func (h *GroupHandler) HandleGetMembers(w http.ResponseWriter, r *http.Request) {
+	groupId, err := uuid.FromString(chi.URLParam(r, "groupId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	orgId, _, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	pg, err := pagination.GetPagination(r)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	res, err := h.Service.GetMembers(orgId, groupId, pg.Limit, pg.Offset)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithPagination(w, http.StatusOK, res, pg.Page, pg.Limit, pg.GetTotalPages(res))
+}
+
+// HandleAddMember adds a User to a Group.
+// @Summary adds a User to a Group.
+// @Description This endpoint adds a member of the organization to the group. Adding an existing member does nothing.
+// @Tags v1/Group
+// @Security JWTToken
+// @Param groupId path string true "the group id"
+// @Param userId path string true "the user id"
+// @Produce json
+// @Success 200 {object} response.Base{data=group.MemberResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/groups/{groupId}/members/{userId} [put]
+func (h *GroupHandler) HandleAddMember(w http.ResponseWriter, r *http.Request) {
+	groupId, err := uuid.FromString(chi.URLParam(r, "groupId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	userId, err := uuid.FromString(chi.URLParam(r, "userId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	orgId, callerId, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.AddMember(orgId, groupId, userId, callerId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleRemoveMember removes a User from a Group.
+// @Summary removes a User from a Group.
+// @Description This endpoint removes the user from the group.
+// @Tags v1/Group
+// @Security JWTToken
+// @Param groupId path string true "the group id"
+// @Param userId path string true "the user id"
+// @Produce json
+// @Success 200 {object} response.Base
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/groups/{groupId}/members/{userId} [delete]
+func (h *GroupHandler) HandleRemoveMember(w http.ResponseWriter, r *http.Request) {
+	groupId, err := uuid.FromString(chi.URLParam(r, "groupId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	userId, err := uuid.FromString(chi.URLParam(r, "userId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	orgId, _, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	err = h.Service.RemoveMember(orgId, groupId, userId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithMessage(w, http.StatusOK, "member removed")
+}
+
+// HandleGetByUserID lists the Groups of a User.
+// @Summary gets the Groups of a User.
+// @Description This endpoint lists the groups the user belongs to in the caller's organization.
+// @Tags v1/Group
+// @Security JWTToken
+// @Param userId path string true "the user id"
+// @Produce json
+// @Success 200 {object} response.Base{data=[]group.GroupResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/users/{userId}/groups [get]
+func (h *GroupHandler) HandleGetByUserID(w http.ResponseWriter, r *http.Request) {
+	userId, err := uuid.FromString(chi.URLParam(r, "userId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	orgId, _, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.GetByUserID(orgId, userId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+func (h *GroupHandler) decodePayload(w http.ResponseWriter, r *http.Request) (payload group.GroupPayload, ok bool) {
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	err = shared.GetValidator().Struct(payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	ok = true
+	return
+}
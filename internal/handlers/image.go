@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/evermos/boilerplate-go/configs"
+	"github.com/evermos/boilerplate-go/internal/domain/group"
+	"github.com/evermos/boilerplate-go/internal/domain/media"
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/transport/http/middleware"
+	"github.com/evermos/boilerplate-go/transport/http/response"
+	"github.com/go-chi/chi"
+	"github.com/gofrs/uuid"
+)
+
+type ImageHandler struct {
+	Service media.MediaService
+	Config  *configs.Config
+	JwtAuth *middleware.JwtAuthentication
+}
+
+func ProvideImageHandler(service media.MediaService, config *configs.Config, jwtAuth *middleware.JwtAuthentication) ImageHandler {
+	return ImageHandler{Service: service, Config: config, JwtAuth: jwtAuth}
+}
+
+// ProductRouter mounts the image gallery of a product. It is mounted under
+// /products, which already validates the token.
+func (h *ImageHandler) ProductRouter(r chi.Router) {
+	r.Get("/{productId}/images", h.HandleGetByProductID)
+
+	r.Group(func(r chi.Router) {
+		r.Use(h.JwtAuth.RequirePermission(group.PermissionProductsWrite))
+		r.Post("/{productId}/images", h.HandleUpload)
+		r.Put("/{productId}/images/order", h.HandleReorder)
+		r.Patch("/{productId}/images/{imageId}", h.HandleUpdate)
+		r.Delete("/{productId}/images/{imageId}", h.HandleDelete)
+	})
+}
+
+// HandleGetByProductID gets the images of a Product.
+// @Summary gets the images of a Product.
+// @Description This endpoint lists the gallery of a product in display order, with the URLs of the resized renditions.
+// @Tags v1/Product
+// @Security JWTToken
+// @Param productId path string true "the product id"
+// @Produce json
+// @Success 200 {object} response.Base{data=[]media.ImageResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{productId}/images [get]
+func (h *ImageHandler) HandleGetByProductID(w http.ResponseWriter, r *http.Request) {
+	productId, err := uuid.FromString(chi.URLParam(r, "productId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	orgId, _, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.GetByProductID(orgId, productId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleUpload uploads an image of a Product.
+// @Summary uploads an image of a Product.
+// @Description This endpoint adds a JPEG, PNG or GIF image to the end of the product's gallery and generates resized renditions. The first image of a product becomes its primary image.
+// @Tags v1/Product
+// @Security JWTToken
+// @Accept multipart/form-data
+// @Param productId path string true "the product id"
+// @Param image formData file true "the image"
+// @Param altText formData string false "a description of the image for screen readers"
+// @Produce json
+// @Success 201 {object} response.Base{data=media.ImageResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{productId}/images [post]
+func (h *ImageHandler) HandleUpload(w http.ResponseWriter, r *http.Request) {
+	productId, err := uuid.FromString(chi.URLParam(r, "productId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	orgId, callerId, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	data, err := readUpload(w, r, "image", h.Config.Storage.MaxUploadBytes)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	res, err := h.Service.Upload(data, r.FormValue("altText"), orgId, productId, callerId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusCreated, res)
+}
+
+// HandleReorder reorders the images of a Product.
+// @Summary reorders the images of a Product.
+// @Description This endpoint puts the product's gallery in the given order. It has to list every image of the product.
+// @Tags v1/Product
+// @Security JWTToken
+// @Param productId path string true "the product id"
+// @Param Order body media.OrderPayload true "the image ids in their new order"
+// @Produce json
+// @Success 200 {object} response.Base{data=[]media.ImageResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{productId}/images/order [put]
+func (h *ImageHandler) HandleReorder(w http.ResponseWriter, r *http.Request) {
+	productId, err := uuid.FromString(chi.URLParam(r, "productId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	var payload media.OrderPayload
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	err = shared.GetValidator().Struct(payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	orgId, callerId, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.Reorder(payload, orgId, productId, callerId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleUpdate updates an image of a Product.
+// @Summary updates an image of a Product.
+// @Description This endpoint changes the alt text of an image or makes it the product's primary image.
+// @Tags v1/Product
+// @Security JWTToken
+// @Param productId path string true "the product id"
+// @Param imageId path string true "the image id"
+// @Param Image body media.ImagePatchPayload true "the fields to change"
+// @Produce json
+// @Success 200 {object} response.Base{data=media.ImageResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{productId}/images/{imageId} [patch]
+func (h *ImageHandler) HandleUpdate(w http.ResponseWriter, r *http.Request) {
+	productId, imageId, ok := h.pathIds(w, r)
+	if !ok {
+		return
+	}
+	var payload media.ImagePatchPayload
+	err := json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	err = shared.GetValidator().Struct(payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	orgId, callerId, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.Update(payload, orgId, productId, imageId, callerId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleDelete deletes an image of a Product.
+// @Summary deletes an image of a Product.
+// @Description This endpoint removes an image and its renditions. When it was the primary image, the first remaining image takes over.
+// @Tags v1/Product
+// @Security JWTToken
+// @Param productId path string true "the product id"
+// @Param imageId path string true "the image id"
+// @Produce json
+// @Success 200 {object} response.Base{data=media.ImageResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{productId}/images/{imageId} [delete]
+func (h *ImageHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	productId, imageId, ok := h.pathIds(w, r)
+	if !ok {
+		return
+	}
+	orgId, callerId, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.Delete(orgId, productId, imageId, callerId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+func (h *ImageHandler) pathIds(w http.ResponseWriter, r *http.Request) (productId, imageId uuid.UUID, ok bool) {
+	productId, err := uuid.FromString(chi.URLParam(r, "productId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	imageId, err = uuid.FromString(chi.URLParam(r, "imageId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	ok = true
+	return
+}
@@ -0,0 +1,373 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/evermos/boilerplate-go/internal/domain/group"
+	"github.com/evermos/boilerplate-go/internal/domain/inventory"
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/nuuid"
+	"github.com/evermos/boilerplate-go/shared/pagination"
+	"github.com/evermos/boilerplate-go/transport/http/middleware"
+	"github.com/evermos/boilerplate-go/transport/http/response"
+	"github.com/go-chi/chi"
+	"github.com/gofrs/uuid"
+)
+
+type InventoryHandler struct {
+	Service inventory.InventoryService
+	JwtAuth *middleware.JwtAuthentication
+}
+
+func ProvideInventoryHandler(service inventory.InventoryService, jwtAuth *middleware.JwtAuthentication) InventoryHandler {
+	return InventoryHandler{Service: service, JwtAuth: jwtAuth}
+}
+
+// ProductRouter mounts the stock ledger of a product. It is mounted under
+// /products, which already validates the token. Any user can ask to be
+// notified when a product is back in stock.
+func (h *InventoryHandler) ProductRouter(r chi.Router) {
+	r.Post("/{productId}/stock/subscription", h.HandleSubscribe)
+	r.Delete("/{productId}/stock/subscription", h.HandleUnsubscribe)
+	r.Group(func(r chi.Router) {
+		r.Use(h.JwtAuth.RequirePermission(group.PermissionProductsWrite))
+		r.Get("/{productId}/stock", h.HandleGetLevels)
+		r.Get("/{productId}/stock/movements", h.HandleGetHistory)
+		r.Post("/{productId}/stock/adjustments", h.HandleAdjust)
+		r.Post("/{productId}/stock/transfers", h.HandleTransfer)
+		r.Get("/{productId}/stock/threshold", h.HandleGetThreshold)
+		r.Put("/{productId}/stock/threshold", h.HandleSetThreshold)
+	})
+}
+
+// HandleGetHistory gets the stock history of a Product.
+// @Summary gets the stock movements of a Product.
+// @Description This endpoint lists every change of the stock of a product and its variants, newest first, with the reason, the user who made it and what caused it, such as the order.
+// @Tags v1/Product
+// @Security JWTToken
+// @Param productId path string true "the product id"
+// @Param page query int true "current page number"
+// @Param limit query int true "limit of movements per page"
+// @Param variantId query string false "only the movements of this variant"
+// @Param locationId query string false "only the movements at this location"
+// @Param reason query string false "filter by reason" Enums(order_placed, order_cancelled, adjustment, restock, return, transfer)
+// @Produce json
+// @Success 200 {object} response.Base{data=[]inventory.MovementResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{productId}/stock/movements [get]
+func (h *InventoryHandler) HandleGetHistory(w http.ResponseWriter, r *http.Request) {
+	productId, err := uuid.FromString(chi.URLParam(r, "productId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	pg, err := pagination.GetPagination(r)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	filter := inventory.HistoryFilter{Reason: pagination.ParseQueryParams(r, "reason")}
+	if param := pagination.ParseQueryParams(r, "variantId"); param != "" {
+		variantId, err := uuid.FromString(param)
+		if err != nil {
+			response.WithError(w, failure.BadRequest(err))
+			return
+		}
+		filter.VariantId = nuuid.From(variantId)
+	}
+	if param := pagination.ParseQueryParams(r, "locationId"); param != "" {
+		locationId, err := uuid.FromString(param)
+		if err != nil {
+			response.WithError(w, failure.BadRequest(err))
+			return
+		}
+		filter.LocationId = nuuid.From(locationId)
+	}
+	orgId, _, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.GetHistory(orgId, productId, filter, pg.Limit, pg.Offset)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithPagination(w, http.StatusOK, res, pg.Page, pg.Limit, pg.GetTotalPages(res))
+}
+
+// HandleAdjust adjusts the stock of a Product.
+// @Summary adjusts the stock of a Product.
+// @Description This endpoint records a stock movement entered by staff: a correction, goods received or a customer return. The quantity is added to the stock at the location, the default location when locationId is left out, or taken out when negative; stock cannot go below zero. Products with variants keep their stock per variant, so variantId is required for them.
+// @Tags v1/Product
+// @Security JWTToken
+// @Param productId path string true "the product id"
+// @Param Adjustment body inventory.AdjustmentPayload true "the movement"
+// @Produce json
+// @Success 201 {object} response.Base{data=inventory.MovementResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{productId}/stock/adjustments [post]
+func (h *InventoryHandler) HandleAdjust(w http.ResponseWriter, r *http.Request) {
+	productId, err := uuid.FromString(chi.URLParam(r, "productId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	var payload inventory.AdjustmentPayload
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	err = shared.GetValidator().Struct(payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	orgId, callerId, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.Adjust(payload, orgId, productId, callerId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusCreated, res)
+}
+
+// HandleGetLevels gets the stock of a Product per location.
+// @Summary gets the stock of a Product per location.
+// @Description This endpoint lists the stock of the product, and of each of its variants, at every location of the caller's organization. The stock of the product itself is the total over all locations.
+// @Tags v1/Product
+// @Security JWTToken
+// @Param productId path string true "the product id"
+// @Produce json
+// @Success 200 {object} response.Base{data=[]inventory.LevelResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{productId}/stock [get]
+func (h *InventoryHandler) HandleGetLevels(w http.ResponseWriter, r *http.Request) {
+	productId, err := uuid.FromString(chi.URLParam(r, "productId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	orgId, _, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.GetLevels(orgId, productId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleTransfer transfers stock of a Product between locations.
+// @Summary transfers stock of a Product between locations.
+// @Description This endpoint moves stock from one location to another, recorded as two movements that share a reference: the given one or the id of the outgoing movement. The source location cannot go below zero.
+// @Tags v1/Product
+// @Security JWTToken
+// @Param productId path string true "the product id"
+// @Param Transfer body inventory.TransferPayload true "the transfer"
+// @Produce json
+// @Success 201 {object} response.Base{data=[]inventory.MovementResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{productId}/stock/transfers [post]
+func (h *InventoryHandler) HandleTransfer(w http.ResponseWriter, r *http.Request) {
+	productId, err := uuid.FromString(chi.URLParam(r, "productId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	var payload inventory.TransferPayload
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	err = shared.GetValidator().Struct(payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	orgId, callerId, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.Transfer(payload, orgId, productId, callerId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusCreated, res)
+}
+
+// HandleGetThreshold gets the reorder level of a Product.
+// @Summary gets the reorder level of a Product.
+// @Description This endpoint gets the reorder level of the product. Staff are alerted when the stock of the product, or of one of its variants, falls to it; products without one alert when they run out.
+// @Tags v1/Product
+// @Security JWTToken
+// @Param productId path string true "the product id"
+// @Produce json
+// @Success 200 {object} response.Base{data=inventory.ThresholdResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{productId}/stock/threshold [get]
+func (h *InventoryHandler) HandleGetThreshold(w http.ResponseWriter, r *http.Request) {
+	productId, err := uuid.FromString(chi.URLParam(r, "productId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	orgId, _, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.GetThreshold(orgId, productId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleSetThreshold sets the reorder level of a Product.
+// @Summary sets the reorder level of a Product.
+// @Description This endpoint sets the reorder level of the product. When the stock of the product, or of one of its variants, falls to it, its admins and the members of groups with products.write are alerted by email unless they turned low stock notifications off.
+// @Tags v1/Product
+// @Security JWTToken
+// @Param productId path string true "the product id"
+// @Param Threshold body inventory.ThresholdPayload true "the reorder level"
+// @Produce json
+// @Success 200 {object} response.Base{data=inventory.ThresholdResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{productId}/stock/threshold [put]
+func (h *InventoryHandler) HandleSetThreshold(w http.ResponseWriter, r *http.Request) {
+	productId, err := uuid.FromString(chi.URLParam(r, "productId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	var payload inventory.ThresholdPayload
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	err = shared.GetValidator().Struct(payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	orgId, callerId, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.SetThreshold(payload, orgId, productId, callerId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleSubscribe asks to be notified when a Product is back in stock.
+// @Summary asks to be notified when a Product is back in stock.
+// @Description This endpoint subscribes the caller to the product, or to one of its variants, which is required for products with variants. The caller is emailed once when it is back in stock, unless they turned back in stock notifications off; subscribing again renews the subscription.
+// @Tags v1/Product
+// @Security JWTToken
+// @Param productId path string true "the product id"
+// @Param Subscription body inventory.SubscriptionPayload true "the variant, if any"
+// @Produce json
+// @Success 201 {object} response.Base{data=inventory.SubscriptionResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{productId}/stock/subscription [post]
+func (h *InventoryHandler) HandleSubscribe(w http.ResponseWriter, r *http.Request) {
+	productId, err := uuid.FromString(chi.URLParam(r, "productId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	var payload inventory.SubscriptionPayload
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	orgId, callerId, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.Subscribe(payload, orgId, productId, callerId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusCreated, res)
+}
+
+// HandleUnsubscribe stops waiting for a Product to be back in stock.
+// @Summary stops waiting for a Product to be back in stock.
+// @Description This endpoint deletes the caller's subscription to the product, or to the variant.
+// @Tags v1/Product
+// @Security JWTToken
+// @Param productId path string true "the product id"
+// @Param variantId query string false "the variant subscribed to"
+// @Produce json
+// @Success 200 {object} response.Base
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{productId}/stock/subscription [delete]
+func (h *InventoryHandler) HandleUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	productId, err := uuid.FromString(chi.URLParam(r, "productId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	var variantId nuuid.NUUID
+	if param := pagination.ParseQueryParams(r, "variantId"); param != "" {
+		id, err := uuid.FromString(param)
+		if err != nil {
+			response.WithError(w, failure.BadRequest(err))
+			return
+		}
+		variantId = nuuid.From(id)
+	}
+	orgId, callerId, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	err = h.Service.Unsubscribe(orgId, productId, variantId, callerId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithMessage(w, http.StatusOK, "subscription deleted")
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/evermos/boilerplate-go/internal/domain/group"
+	"github.com/evermos/boilerplate-go/internal/domain/inventory"
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/transport/http/middleware"
+	"github.com/evermos/boilerplate-go/transport/http/response"
+	"github.com/go-chi/chi"
+	"github.com/gofrs/uuid"
+)
+
+type LocationHandler struct {
+	Service inventory.InventoryService
+	JwtAuth *middleware.JwtAuthentication
+}
+
+func ProvideLocationHandler(service inventory.InventoryService, jwtAuth *middleware.JwtAuthentication) LocationHandler {
+	return LocationHandler{Service: service, JwtAuth: jwtAuth}
+}
+
+// Router mounts the stock locations of the caller's organization, which
+// take the products.write permission.
+func (h *LocationHandler) Router(r chi.Router) {
+	r.Route("/locations", func(r chi.Router) {
+		r.Use(h.JwtAuth.Validate)
+		r.Use(h.JwtAuth.RequirePermission(group.PermissionProductsWrite))
+		r.Get("/", h.HandleGetAll)
+		r.Post("/", h.HandleCreate)
+		r.Get("/{locationId}", h.HandleGetByID)
+		r.Put("/{locationId}", h.HandleUpdate)
+		r.Delete("/{locationId}", h.HandleDelete)
+	})
+}
+
+// HandleCreate creates a new Location.
+// @Summary creates a new Location.
+// @Description This endpoint adds a warehouse, store or other place stock is kept. The first location of an organization becomes its default, which takes stock changes that do not name a location; making another location the default takes that role from it.
+// @Tags v1/Location
+// @Security JWTToken
+// @Param Location body inventory.LocationPayload true "The location to be created"
+// @Produce json
+// @Success 201 {object} response.Base{data=inventory.LocationResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/locations [post]
+func (h *LocationHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	payload, ok := h.decodePayload(w, r)
+	if !ok {
+		return
+	}
+	orgId, callerId, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.CreateLocation(payload, orgId, callerId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusCreated, res)
+}
+
+// HandleGetAll gets the Locations.
+// @Summary gets the Locations.
+// @Description This endpoint lists the locations of the caller's organization by priority.
+// @Tags v1/Location
+// @Security JWTToken
+// @Produce json
+// @Success 200 {object} response.Base{data=[]inventory.LocationResponseFormat}
+// @Failure 401 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/locations [get]
+func (h *LocationHandler) HandleGetAll(w http.ResponseWriter, r *http.Request) {
+	orgId, _, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.GetLocations(orgId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleGetByID gets a Location.
+// @Summary gets a Location.
+// @Description This endpoint gets a location of the caller's organization.
+// @Tags v1/Location
+// @Security JWTToken
+// @Param locationId path string true "the location id"
+// @Produce json
+// @Success 200 {object} response.Base{data=inventory.LocationResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/locations/{locationId} [get]
+func (h *LocationHandler) HandleGetByID(w http.ResponseWriter, r *http.Request) {
+	locationId, err := uuid.FromString(chi.URLParam(r, "locationId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	orgId, _, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.GetLocation(orgId, locationId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleUpdate replaces a Location.
+// @Summary replaces a Location.
+// @Description This endpoint renames, reprioritizes or moves a location. The default location stays the default until another location is made the default.
+// @Tags v1/Location
+// @Security JWTToken
+// @Param locationId path string true "the location id"
+// @Param Location body inventory.LocationPayload true "The location's new values"
+// @Produce json
+// @Success 200 {object} response.Base{data=inventory.LocationResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/locations/{locationId} [put]
+func (h *LocationHandler) HandleUpdate(w http.ResponseWriter, r *http.Request) {
+	locationId, err := uuid.FromString(chi.URLParam(r, "locationId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	payload, ok := h.decodePayload(w, r)
+	if !ok {
+		return
+	}
+	orgId, callerId, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.UpdateLocation(payload, orgId, locationId, callerId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleDelete deletes a Location.
+// @Summary deletes a Location.
+// @Description This endpoint deletes a location without stock; transfer what is left first. The default location cannot be deleted. Its movements stay in the stock history.
+// @Tags v1/Location
+// @Security JWTToken
+// @Param locationId path string true "the location id"
+// @Produce json
+// @Success 200 {object} response.Base{data=inventory.LocationResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/locations/{locationId} [delete]
+func (h *LocationHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	locationId, err := uuid.FromString(chi.URLParam(r, "locationId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	orgId, callerId, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.DeleteLocation(orgId, locationId, callerId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+func (h *LocationHandler) decodePayload(w http.ResponseWriter, r *http.Request) (payload inventory.LocationPayload, ok bool) {
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	err = shared.GetValidator().Struct(payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	ok = true
+	return
+}
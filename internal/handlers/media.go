@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/evermos/boilerplate-go/configs"
+	"github.com/evermos/boilerplate-go/shared/storage"
+	"github.com/go-chi/chi"
+)
+
+// MediaHandler serves uploaded files when they are kept on the local
+// filesystem. Other storage drivers serve their files themselves.
+type MediaHandler struct {
+	Config *configs.Config
+}
+
+func ProvideMediaHandler(config *configs.Config) MediaHandler {
+	return MediaHandler{Config: config}
+}
+
+func (h *MediaHandler) Router(r chi.Router) {
+	if h.Config.Storage.Driver != "" && h.Config.Storage.Driver != storage.DriverLocal {
+		return
+	}
+	files := http.StripPrefix("/v1/media/", http.FileServer(http.Dir(h.Config.Storage.Local.Path)))
+	r.Get("/media/*", func(w http.ResponseWriter, r *http.Request) {
+		// do not expose directory listings
+		if strings.HasSuffix(r.URL.Path, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		files.ServeHTTP(w, r)
+	})
+}
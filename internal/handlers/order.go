@@ -5,7 +5,6 @@ import (
 
 	"github.com/evermos/boilerplate-go/internal/domain/order"
 	"github.com/evermos/boilerplate-go/shared/failure"
-	"github.com/evermos/boilerplate-go/shared/jwt"
 	"github.com/evermos/boilerplate-go/shared/pagination"
 	"github.com/evermos/boilerplate-go/transport/http/middleware"
 	"github.com/evermos/boilerplate-go/transport/http/response"
@@ -58,9 +57,8 @@ func (h *OrderHandler) HandleGetAll(w http.ResponseWriter, r *http.Request) {
 	}
 	status := pagination.ParseQueryParams(r, "status")
 	cancelled := pagination.GetCancelled(pagination.ParseQueryParams(r, "cancelled"))
-	claims, ok := r.Context().Value(middleware.ClaimsKey("claims")).(*jwt.Claims)
+	claims, ok := claimsOf(w, r)
 	if !ok {
-		response.WithMessage(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 	userId, err := uuid.FromString(claims.UserId)
@@ -68,7 +66,12 @@ func (h *OrderHandler) HandleGetAll(w http.ResponseWriter, r *http.Request) {
 		response.WithError(w, err)
 		return
 	}
-	res, err := h.Service.GetAll(pg.Limit, pg.Offset, pg.Sort, pg.Field, status, userId, claims.Role, cancelled)
+	orgId, err := tenantOf(claims)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	res, err := h.Service.GetAll(orgId, pg.Limit, pg.Offset, pg.Sort, pg.Field, status, userId, claims.Role, cancelled)
 	totalPage := pg.GetTotalPages(res)
 	if err != nil {
 		response.WithError(w, err)
@@ -96,9 +99,8 @@ func (h *OrderHandler) HandleCancel(w http.ResponseWriter, r *http.Request) {
 		response.WithError(w, failure.BadRequest(err))
 		return
 	}
-	claims, ok := r.Context().Value(middleware.ClaimsKey("claims")).(*jwt.Claims)
+	claims, ok := claimsOf(w, r)
 	if !ok {
-		response.WithMessage(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 	userId, err := uuid.FromString(claims.UserId)
@@ -106,7 +108,12 @@ func (h *OrderHandler) HandleCancel(w http.ResponseWriter, r *http.Request) {
 		response.WithError(w, err)
 		return
 	}
-	res, err := h.Service.CancelOrder(id, userId, claims.Role)
+	orgId, err := tenantOf(claims)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	res, err := h.Service.CancelOrder(id, userId, orgId, claims.Role)
 	if err != nil {
 		response.WithError(w, err)
 		return
@@ -133,17 +140,11 @@ func (h *OrderHandler) HandleGetByID(w http.ResponseWriter, r *http.Request) {
 		response.WithError(w, failure.BadRequest(err))
 		return
 	}
-	claims, ok := r.Context().Value(middleware.ClaimsKey("claims")).(*jwt.Claims)
+	orgId, userId, ok := caller(w, r)
 	if !ok {
-		response.WithMessage(w, http.StatusUnauthorized, "Unauthorized")
-		return
-	}
-	userId, err := uuid.FromString(claims.UserId)
-	if err != nil {
-		response.WithError(w, err)
 		return
 	}
-	res, err := h.Service.GetByID(id, userId)
+	res, err := h.Service.GetByID(id, userId, orgId)
 	if err != nil {
 		response.WithError(w, err)
 		return
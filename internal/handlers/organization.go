@@ -0,0 +1,442 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/evermos/boilerplate-go/internal/domain/organization"
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/pagination"
+	"github.com/evermos/boilerplate-go/transport/http/middleware"
+	"github.com/evermos/boilerplate-go/transport/http/response"
+	"github.com/go-chi/chi"
+	"github.com/gofrs/uuid"
+)
+
+type OrganizationHandler struct {
+	Service organization.OrganizationService
+	JwtAuth *middleware.JwtAuthentication
+}
+
+func ProvideOrganizationHandler(service organization.OrganizationService, jwtAuth *middleware.JwtAuthentication) OrganizationHandler {
+	return OrganizationHandler{Service: service, JwtAuth: jwtAuth}
+}
+
+func (h *OrganizationHandler) Router(r chi.Router) {
+	r.Route("/organizations", func(r chi.Router) {
+		r.Use(h.JwtAuth.Validate)
+		r.Get("/", h.HandleGetMine)
+		r.Post("/", h.HandleCreate)
+		r.Route("/invites", func(r chi.Router) {
+			r.Get("/", h.HandleGetMyInvites)
+			r.Post("/{orgId}/accept", h.HandleAcceptInvite)
+			r.Delete("/{orgId}", h.HandleDeclineInvite)
+		})
+		r.Route("/{orgId}", func(r chi.Router) {
+			r.Get("/", h.HandleGetByID)
+			r.Route("/members", func(r chi.Router) {
+				r.Get("/", h.HandleGetMembers)
+				r.Put("/{userId}", h.HandleSetMember)
+				r.Delete("/{userId}", h.HandleRemoveMember)
+			})
+			r.Route("/invites", func(r chi.Router) {
+				r.Put("/{userId}", h.HandleInvite)
+				r.Delete("/{userId}", h.HandleRevokeInvite)
+			})
+		})
+	})
+}
+
+// HandleCreate creates a new Organization.
+// @Summary creates a new Organization.
+// @Description This endpoint creates an organization with the caller as its admin. Use /v1/auth/switch to get a token for it.
+// @Tags v1/Organization
+// @Security JWTToken
+// @Param Organization body organization.OrganizationPayload true "The organization to be created"
+// @Produce json
+// @Success 201 {object} response.Base{data=organization.OrganizationResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/organizations [post]
+func (h *OrganizationHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var payload organization.OrganizationPayload
+	err := decoder.Decode(&payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	err = shared.GetValidator().Struct(payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	callerId, ok := callerId(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.Create(payload, callerId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusCreated, res)
+}
+
+// HandleGetMine lists the caller's Organizations.
+// @Summary gets the Organizations of the current User.
+// @Description This endpoint lists every organization the caller is a member of, with the caller's role there.
+// @Tags v1/Organization
+// @Security JWTToken
+// @Produce json
+// @Success 200 {object} response.Base{data=[]organization.MemberResponseFormat}
+// @Failure 500 {object} response.Base
+// @Router /v1/organizations [get]
+func (h *OrganizationHandler) HandleGetMine(w http.ResponseWriter, r *http.Request) {
+	callerId, ok := callerId(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.GetMembershipsByUserID(callerId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleGetByID gets an Organization.
+// @Summary gets an Organization by id.
+// @Description This endpoint gets an organization the caller is a member of.
+// @Tags v1/Organization
+// @Security JWTToken
+// @Param orgId path string true "the organization id"
+// @Produce json
+// @Success 200 {object} response.Base{data=organization.OrganizationResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/organizations/{orgId} [get]
+func (h *OrganizationHandler) HandleGetByID(w http.ResponseWriter, r *http.Request) {
+	orgId, err := uuid.FromString(chi.URLParam(r, "orgId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	callerId, ok := callerId(w, r)
+	if !ok {
+		return
+	}
+	_, err = h.Service.GetMember(orgId, callerId)
+	if err != nil {
+		response.WithError(w, failure.NotFound("Organization"))
+		return
+	}
+	res, err := h.Service.GetByID(orgId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleGetMembers lists the members of an Organization.
+// @Summary gets the members of an Organization.
+// @Description This endpoint lists the members of an organization. Only its admins can see them.
+// @Tags v1/Organization
+// @Security JWTToken
+// @Param orgId path string true "the organization id"
+// @Param page query int true "current page number"
+// @Param limit query int true "limit of members per page"
+// @Produce json
+// @Success 200 {object} response.Pagination{data=[]organization.MemberResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/organizations/{orgId}/members [get]
+func (h *OrganizationHandler) HandleGetMembers(w http.ResponseWriter, r *http.Request) {
+	orgId, ok := h.adminOf(w, r)
+	if !ok {
+		return
+	}
+	pg, err := pagination.GetPagination(r)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	res, err := h.Service.GetMembers(orgId, pg.Limit, pg.Offset)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithPagination(w, http.StatusOK, res, pg.Page, pg.Limit, pg.GetTotalPages(res))
+}
+
+// HandleSetMember changes a member's role.
+// @Summary changes the role of a member of an Organization.
+// @Description This endpoint changes the role of an existing member. Other users join by accepting an invite. Only admins of the organization can do this.
+// @Tags v1/Organization
+// @Security JWTToken
+// @Param orgId path string true "the organization id"
+// @Param userId path string true "the user id"
+// @Param Member body organization.MemberPayload true "The member's role"
+// @Produce json
+// @Success 200 {object} response.Base{data=organization.MemberResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/organizations/{orgId}/members/{userId} [put]
+func (h *OrganizationHandler) HandleSetMember(w http.ResponseWriter, r *http.Request) {
+	orgId, ok := h.adminOf(w, r)
+	if !ok {
+		return
+	}
+	userId, err := uuid.FromString(chi.URLParam(r, "userId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	var payload organization.MemberPayload
+	err = decoder.Decode(&payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	err = shared.GetValidator().Struct(payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	callerId, ok := callerId(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.SetMember(payload, orgId, userId, callerId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleRemoveMember removes a member from an Organization.
+// @Summary removes a User from an Organization.
+// @Description This endpoint removes the user from the organization together with their cart there. Users cannot be removed from their home organization.
+// @Tags v1/Organization
+// @Security JWTToken
+// @Param orgId path string true "the organization id"
+// @Param userId path string true "the user id"
+// @Produce json
+// @Success 200 {object} response.Base{data=organization.MemberResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/organizations/{orgId}/members/{userId} [delete]
+func (h *OrganizationHandler) HandleRemoveMember(w http.ResponseWriter, r *http.Request) {
+	orgId, ok := h.adminOf(w, r)
+	if !ok {
+		return
+	}
+	userId, err := uuid.FromString(chi.URLParam(r, "userId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	res, err := h.Service.RemoveMember(orgId, userId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleInvite invites a User to an Organization.
+// @Summary invites a User to an Organization.
+// @Description This endpoint invites the user to the organization with the given role, or renews their pending invite. The user becomes a member once they accept it. Only admins of the organization can do this.
+// @Tags v1/Organization
+// @Security JWTToken
+// @Param orgId path string true "the organization id"
+// @Param userId path string true "the user id"
+// @Param Member body organization.MemberPayload true "The role to invite the user with"
+// @Produce json
+// @Success 200 {object} response.Base{data=organization.InviteResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/organizations/{orgId}/invites/{userId} [put]
+func (h *OrganizationHandler) HandleInvite(w http.ResponseWriter, r *http.Request) {
+	orgId, ok := h.adminOf(w, r)
+	if !ok {
+		return
+	}
+	userId, err := uuid.FromString(chi.URLParam(r, "userId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	var payload organization.MemberPayload
+	err = decoder.Decode(&payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	err = shared.GetValidator().Struct(payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	callerId, ok := callerId(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.Invite(payload, orgId, userId, callerId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleRevokeInvite revokes a pending invite.
+// @Summary revokes the invite of a User to an Organization.
+// @Description This endpoint revokes the user's pending invite to the organization. Only admins of the organization can do this.
+// @Tags v1/Organization
+// @Security JWTToken
+// @Param orgId path string true "the organization id"
+// @Param userId path string true "the user id"
+// @Produce json
+// @Success 200 {object} response.Base{data=organization.InviteResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/organizations/{orgId}/invites/{userId} [delete]
+func (h *OrganizationHandler) HandleRevokeInvite(w http.ResponseWriter, r *http.Request) {
+	orgId, ok := h.adminOf(w, r)
+	if !ok {
+		return
+	}
+	userId, err := uuid.FromString(chi.URLParam(r, "userId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	res, err := h.Service.DeleteInvite(orgId, userId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleGetMyInvites lists the caller's pending invites.
+// @Summary gets the pending invites of the current User.
+// @Description This endpoint lists the organizations the caller has been invited to and not answered yet.
+// @Tags v1/Organization
+// @Security JWTToken
+// @Produce json
+// @Success 200 {object} response.Base{data=[]organization.InviteResponseFormat}
+// @Failure 500 {object} response.Base
+// @Router /v1/organizations/invites [get]
+func (h *OrganizationHandler) HandleGetMyInvites(w http.ResponseWriter, r *http.Request) {
+	callerId, ok := callerId(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.GetInvitesByUserID(callerId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleAcceptInvite accepts an invite.
+// @Summary accepts the current User's invite to an Organization.
+// @Description This endpoint makes the caller a member of the organization with the role they were invited with. Use /v1/auth/switch to get a token for it.
+// @Tags v1/Organization
+// @Security JWTToken
+// @Param orgId path string true "the organization id"
+// @Produce json
+// @Success 201 {object} response.Base{data=organization.MemberResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/organizations/invites/{orgId}/accept [post]
+func (h *OrganizationHandler) HandleAcceptInvite(w http.ResponseWriter, r *http.Request) {
+	orgId, err := uuid.FromString(chi.URLParam(r, "orgId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	callerId, ok := callerId(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.AcceptInvite(orgId, callerId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusCreated, res)
+}
+
+// HandleDeclineInvite declines an invite.
+// @Summary declines the current User's invite to an Organization.
+// @Description This endpoint drops the caller's pending invite to the organization.
+// @Tags v1/Organization
+// @Security JWTToken
+// @Param orgId path string true "the organization id"
+// @Produce json
+// @Success 200 {object} response.Base{data=organization.InviteResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/organizations/invites/{orgId} [delete]
+func (h *OrganizationHandler) HandleDeclineInvite(w http.ResponseWriter, r *http.Request) {
+	orgId, err := uuid.FromString(chi.URLParam(r, "orgId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	callerId, ok := callerId(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.DeleteInvite(orgId, callerId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// adminOf returns the organization in the path if the caller is one of its
+// admins. The role is looked up rather than taken from the token, so admins
+// can manage any of their organizations without switching.
+func (h *OrganizationHandler) adminOf(w http.ResponseWriter, r *http.Request) (orgId uuid.UUID, ok bool) {
+	orgId, err := uuid.FromString(chi.URLParam(r, "orgId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	callerId, ok := callerId(w, r)
+	if !ok {
+		return
+	}
+	member, err := h.Service.GetMember(orgId, callerId)
+	if err != nil || !member.IsAdmin() {
+		response.WithError(w, failure.Unauthorized("only admins of the organization are allowed"))
+		return orgId, false
+	}
+	return
+}
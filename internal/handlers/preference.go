@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/evermos/boilerplate-go/internal/domain/preference"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/transport/http/middleware"
+	"github.com/evermos/boilerplate-go/transport/http/response"
+	"github.com/go-chi/chi"
+	"github.com/gofrs/uuid"
+)
+
+type PreferenceHandler struct {
+	Service preference.PreferenceService
+	JwtAuth *middleware.JwtAuthentication
+}
+
+func ProvidePreferenceHandler(service preference.PreferenceService, jwtAuth *middleware.JwtAuthentication) PreferenceHandler {
+	return PreferenceHandler{Service: service, JwtAuth: jwtAuth}
+}
+
+// Router mounts the preference schema.
+func (h *PreferenceHandler) Router(r chi.Router) {
+	r.Route("/preferences", func(r chi.Router) {
+		r.Use(h.JwtAuth.Validate)
+		r.Get("/schema", h.HandleGetSchema)
+	})
+}
+
+// UserRouter mounts the user's preferences under /users/{userId}.
+func (h *PreferenceHandler) UserRouter(r chi.Router) {
+	r.Route("/preferences", func(r chi.Router) {
+		r.Get("/", h.HandleGet)
+		r.Patch("/", h.HandleUpdate)
+	})
+}
+
+// HandleGetSchema lists the allowed preferences.
+// @Summary gets the preference schema.
+// @Description This endpoint lists every preference key with its type, default and allowed values.
+// @Tags v1/Preference
+// @Security JWTToken
+// @Produce json
+// @Success 200 {object} response.Base{data=[]preference.Definition}
+// @Failure 401 {object} response.Base
+// @Router /v1/preferences/schema [get]
+func (h *PreferenceHandler) HandleGetSchema(w http.ResponseWriter, r *http.Request) {
+	response.WithJSON(w, http.StatusOK, h.Service.GetSchema())
+}
+
+// HandleGet gets a User's preferences.
+// @Summary gets the preferences of a User.
+// @Description This endpoint returns the value of every preference, defaults included.
+// @Tags v1/Preference
+// @Security JWTToken
+// @Param userId path string true "the user id"
+// @Produce json
+// @Success 200 {object} response.Base{data=preference.Preferences}
+// @Failure 400 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/users/{userId}/preferences [get]
+func (h *PreferenceHandler) HandleGet(w http.ResponseWriter, r *http.Request) {
+	userId, err := uuid.FromString(chi.URLParam(r, "userId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	res, err := h.Service.Get(userId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleUpdate updates a User's preferences.
+// @Summary updates the preferences of a User.
+// @Description This endpoint sets the preferences in the request body. Keys that are left out keep their value, null resets a key to its default.
+// @Tags v1/Preference
+// @Security JWTToken
+// @Param userId path string true "the user id"
+// @Param Preferences body preference.Preferences true "the preferences to change"
+// @Produce json
+// @Success 200 {object} response.Base{data=preference.Preferences}
+// @Failure 400 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/users/{userId}/preferences [patch]
+func (h *PreferenceHandler) HandleUpdate(w http.ResponseWriter, r *http.Request) {
+	userId, err := uuid.FromString(chi.URLParam(r, "userId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	var payload map[string]json.RawMessage
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	updaterId, ok := callerId(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.Update(payload, userId, updaterId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
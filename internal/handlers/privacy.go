@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/evermos/boilerplate-go/internal/domain/privacy"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/evermos/boilerplate-go/shared/pagination"
+	"github.com/evermos/boilerplate-go/transport/http/response"
+	"github.com/go-chi/chi"
+	"github.com/gofrs/uuid"
+)
+
+type PrivacyHandler struct {
+	Service privacy.PrivacyService
+}
+
+func ProvidePrivacyHandler(service privacy.PrivacyService) PrivacyHandler {
+	return PrivacyHandler{Service: service}
+}
+
+// Router mounts the per-user privacy routes under /users/{userId}.
+func (h *PrivacyHandler) Router(r chi.Router) {
+	r.Get("/data-export", h.HandleExport)
+	r.Route("/erasure", func(r chi.Router) {
+		r.Get("/", h.HandleGetErasures)
+		r.Post("/", h.HandleRequestErasure)
+		r.Delete("/", h.HandleCancelErasure)
+	})
+}
+
+// AdminRouter mounts the routes admins use to follow erasure jobs.
+func (h *PrivacyHandler) AdminRouter(r chi.Router) {
+	r.Get("/erasures", h.HandleGetAllErasures)
+}
+
+// HandleExport exports a User's data.
+// @Summary exports all data stored about a User.
+// @Description This endpoint returns the user's profile, addresses, cart, orders and erasure requests as a downloadable JSON document.
+// @Tags v1/Privacy
+// @Security JWTToken
+// @Param userId path string true "the user id"
+// @Produce json
+// @Success 200 {object} privacy.DataExport
+// @Failure 400 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/users/{userId}/data-export [get]
+func (h *PrivacyHandler) HandleExport(w http.ResponseWriter, r *http.Request) {
+	userId, err := uuid.FromString(chi.URLParam(r, "userId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	res, err := h.Service.Export(userId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=user-%s.json", userId))
+	w.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(res)
+	if err != nil {
+		// the status line is already sent, all we can do is stop and log
+		logger.ErrorWithStack(err)
+	}
+}
+
+// HandleRequestErasure schedules a User's data for erasure.
+// @Summary schedules the erasure of a User's personal data.
+// @Description This endpoint schedules the user's personal data to be anonymized after the retention delay. Orders are kept. The request can be cancelled until it runs.
+// @Tags v1/Privacy
+// @Security JWTToken
+// @Param userId path string true "the user id"
+// @Produce json
+// @Success 201 {object} response.Base{data=privacy.ErasureRequestResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/users/{userId}/erasure [post]
+func (h *PrivacyHandler) HandleRequestErasure(w http.ResponseWriter, r *http.Request) {
+	userId, err := uuid.FromString(chi.URLParam(r, "userId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	requesterId, ok := callerId(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.RequestErasure(userId, requesterId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusCreated, res)
+}
+
+// HandleCancelErasure cancels a scheduled erasure.
+// @Summary cancels the scheduled erasure of a User's data.
+// @Description This endpoint cancels the user's pending erasure request.
+// @Tags v1/Privacy
+// @Security JWTToken
+// @Param userId path string true "the user id"
+// @Produce json
+// @Success 200 {object} response.Base{data=privacy.ErasureRequestResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/users/{userId}/erasure [delete]
+func (h *PrivacyHandler) HandleCancelErasure(w http.ResponseWriter, r *http.Request) {
+	userId, err := uuid.FromString(chi.URLParam(r, "userId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	requesterId, ok := callerId(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.CancelErasure(userId, requesterId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleGetErasures lists a User's erasure requests.
+// @Summary gets the erasure requests of a User.
+// @Description This endpoint lists every erasure request of the user with its status.
+// @Tags v1/Privacy
+// @Security JWTToken
+// @Param userId path string true "the user id"
+// @Produce json
+// @Success 200 {object} response.Base{data=[]privacy.ErasureRequestResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/users/{userId}/erasure [get]
+func (h *PrivacyHandler) HandleGetErasures(w http.ResponseWriter, r *http.Request) {
+	userId, err := uuid.FromString(chi.URLParam(r, "userId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	res, err := h.Service.GetErasuresByUserID(userId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleGetAllErasures lists the erasure requests of the caller's organization.
+// @Summary gets all erasure requests of the Organization.
+// @Description This endpoint lists the erasure jobs of the members of the caller's organization so admins can follow their status.
+// @Tags v1/Privacy
+// @Security JWTToken
+// @Param page query int true "page"
+// @Param limit query int true "limit"
+// @Param sort query string false "sort direction" Enums(ASC, DESC)
+// @Param field query string false "sort field" Enums(id, status, scheduled_for, created_at, updated_at)
+// @Param status query string false "filter by status" Enums(scheduled, completed, cancelled, failed)
+// @Produce json
+// @Success 200 {object} response.Base{data=[]privacy.ErasureRequestResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/users/erasures [get]
+func (h *PrivacyHandler) HandleGetAllErasures(w http.ResponseWriter, r *http.Request) {
+	pg, err := pagination.GetPagination(r)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	err = pg.ValidateField(privacy.SortFields...)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	status := pagination.ParseQueryParams(r, "status")
+	err = privacy.ValidateStatus(status)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	orgId, _, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.GetAllErasures(orgId, status, pg.Limit, pg.Offset, pg.Sort, pg.Field)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithPagination(w, http.StatusOK, res, pg.Page, pg.Limit, pg.GetTotalPages(res))
+}
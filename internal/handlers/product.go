@@ -2,26 +2,37 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/evermos/boilerplate-go/internal/domain/group"
 	"github.com/evermos/boilerplate-go/internal/domain/product"
 	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/bulk"
 	"github.com/evermos/boilerplate-go/shared/failure"
-	"github.com/evermos/boilerplate-go/shared/jwt"
+	"github.com/evermos/boilerplate-go/shared/logger"
 	"github.com/evermos/boilerplate-go/shared/pagination"
 	"github.com/evermos/boilerplate-go/transport/http/middleware"
 	"github.com/evermos/boilerplate-go/transport/http/response"
 	"github.com/go-chi/chi"
 	"github.com/gofrs/uuid"
+	"github.com/guregu/null"
 )
 
 type ProductHandler struct {
-	Service product.ProductService
-	JwtAuth *middleware.JwtAuthentication
+	Service          product.ProductService
+	CategoryHandler  CategoryHandler
+	VariantHandler   VariantHandler
+	ImageHandler     ImageHandler
+	CatalogHandler   CatalogHandler
+	InventoryHandler InventoryHandler
+	JwtAuth          *middleware.JwtAuthentication
 }
 
-func ProvideProductHandler(service product.ProductService, jwtAuth *middleware.JwtAuthentication) ProductHandler {
-	return ProductHandler{Service: service, JwtAuth: jwtAuth}
+func ProvideProductHandler(service product.ProductService, categoryHandler CategoryHandler, variantHandler VariantHandler, imageHandler ImageHandler, catalogHandler CatalogHandler, inventoryHandler InventoryHandler, jwtAuth *middleware.JwtAuthentication) ProductHandler {
+	return ProductHandler{Service: service, CategoryHandler: categoryHandler, VariantHandler: variantHandler, ImageHandler: imageHandler, CatalogHandler: catalogHandler, InventoryHandler: inventoryHandler, JwtAuth: jwtAuth}
 }
 
 func (h *ProductHandler) Router(r chi.Router) {
@@ -30,18 +41,35 @@ func (h *ProductHandler) Router(r chi.Router) {
 
 		r.Group(func(r chi.Router) {
 			r.Get("/", h.HandleGetAll)
+			r.Get("/search", h.HandleSearch)
+			r.Get("/{productId}", h.HandleGetProduct)
 		})
 
 		r.Group(func(r chi.Router) {
-			r.Use(h.JwtAuth.AdminOnly)
+			r.Use(h.JwtAuth.RequirePermission(group.PermissionProductsWrite))
 			r.Post("/", h.HandleCreateProduct)
+			r.Get("/export", h.HandleExport)
+			r.Put("/{productId}", h.HandleUpdateProduct)
+			r.Patch("/{productId}", h.HandlePatchProduct)
+			r.Delete("/{productId}", h.HandleDeleteProduct)
+			r.Post("/{productId}/restore", h.HandleRestoreProduct)
+			r.Get("/{productId}/prices", h.HandleGetPriceHistory)
+			r.Get("/{productId}/prices/schedules", h.HandleGetPriceSchedules)
+			r.Post("/{productId}/prices/schedules", h.HandleSchedulePrice)
+			r.Delete("/{productId}/prices/schedules/{scheduleId}", h.HandleCancelPriceSchedule)
 		})
+
+		h.CategoryHandler.ProductRouter(r)
+		h.VariantHandler.ProductRouter(r)
+		h.ImageHandler.ProductRouter(r)
+		h.CatalogHandler.ProductRouter(r)
+		h.InventoryHandler.ProductRouter(r)
 	})
 }
 
 // HandleCheckout Insert a product.
 // @Summary create a new product.
-// @Description This endpoint inserts a new product.
+// @Description This endpoint inserts a new product as a draft unless a status is given. The slug is generated from the name unless given, and the description is sanitized.
 // @Tags v1/Product
 // @Security JWTToken
 // @Param CartItemIds body product.ProductPayload true "product to be created"
@@ -64,19 +92,12 @@ func (h *ProductHandler) HandleCreateProduct(w http.ResponseWriter, r *http.Requ
 		response.WithError(w, failure.BadRequest(err))
 		return
 	}
-	claims, ok := r.Context().Value(middleware.ClaimsKey("claims")).(*jwt.Claims)
+	orgId, userId, ok := caller(w, r)
 	if !ok {
-		response.WithMessage(w, http.StatusUnauthorized, "Unauthorized")
-		return
-	}
-	userId, err := uuid.FromString(claims.UserId)
-
-	if err != nil {
-		response.WithError(w, err)
 		return
 	}
 
-	res, err := h.Service.Create(payload, userId)
+	res, err := h.Service.Create(payload, userId, orgId)
 
 	if err != nil {
 		response.WithError(w, err)
@@ -87,16 +108,23 @@ func (h *ProductHandler) HandleCreateProduct(w http.ResponseWriter, r *http.Requ
 
 // HandleGetAll Gets all products.
 // @Summary Gets all products.
-// @Description This endpoint Gets all products available.
+// @Description This endpoint Gets all products available in the caller's organization, along with facet counts for refining the listing. Each facet ignores its own filter, so the counts show what choosing another value would give.
 // @Tags v1/Product
 // @Security JWTToken
 // @Param page query int true "current page number"
 // @Param limit query int true "limit of products per page"
 // @Param sort query string false "sort direction"
 // @Param field query string false "field to sort by"
-// @Param product_title query string false "filter by product name"
+// @Param q query string false "search query; results then come most relevant first"
+// @Param product_title query string false "deprecated, same as q"
+// @Param category query string false "filter by category id or slug, including its subcategories"
+// @Param min_price query number false "lowest price"
+// @Param max_price query number false "highest price"
+// @Param in_stock query bool false "only products that can be bought right now"
+// @Param attr.brand query string false "filter by an attribute, here brand; repeat to match any of several values"
+// @Param status query string false "filter by publication state: draft, published or archived. Callers who do not manage products only ever see live products"
 // @Produce json
-// @Success 200 {object} response.Base{data=[]product.ProductResponseFormat}
+// @Success 200 {object} response.Pagination{data=[]product.ProductResponseFormat,facets=product.Facets}
 // @Failure 400 {object} response.Base
 // @Failure 409 {object} response.Base
 // @Failure 500 {object} response.Base
@@ -107,13 +135,521 @@ func (h *ProductHandler) HandleGetAll(w http.ResponseWriter, r *http.Request) {
 		response.WithError(w, err)
 		return
 	}
-	productTitle := pagination.ParseQueryParams(r, "product_title")
-	res, err := h.Service.GetAll(pg.Limit, pg.Offset, pg.Sort, pg.Field, productTitle)
-	totalPage := pg.GetTotalPages(res)
+	err = pg.ValidateField(product.SortFields...)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	filter, ok := h.filter(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.GetAll(filter, pg.Limit, pg.Offset, pg.Sort, pg.Field)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	facets, err := h.Service.GetFacets(filter)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	totalPage := pg.GetTotalPagesFromCount(facets.Total)
+	response.WithPaginationFacets(w, http.StatusOK, res, pg.Page, pg.Limit, totalPage, facets.Total, facets)
+}
+
+// HandleSearch Searches products.
+// @Summary Searches products.
+// @Description This endpoint searches the names and descriptions of the caller's organization's products, most relevant first. Words match as prefixes, "quoted text" as a phrase, and a leading minus excludes a word or phrase. Matches are highlighted with <em> tags.
+// @Tags v1/Product
+// @Security JWTToken
+// @Param q query string true "search query, e.g. red shirt -polo"
+// @Param page query int true "current page number"
+// @Param limit query int true "limit of products per page"
+// @Param category query string false "filter by category id or slug, including its subcategories"
+// @Param min_price query number false "lowest price"
+// @Param max_price query number false "highest price"
+// @Param in_stock query bool false "only products that can be bought right now"
+// @Param attr.brand query string false "filter by an attribute, here brand; repeat to match any of several values"
+// @Param status query string false "filter by publication state: draft, published or archived. Callers who do not manage products only ever see live products"
+// @Produce json
+// @Success 200 {object} response.Base{data=[]product.SearchHit}
+// @Failure 400 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/search [get]
+func (h *ProductHandler) HandleSearch(w http.ResponseWriter, r *http.Request) {
+	pg, err := pagination.GetPagination(r)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	filter, ok := h.filter(w, r)
+	if !ok {
+		return
+	}
+	if filter.Query == "" {
+		response.WithError(w, failure.BadRequestFromString("q is required"))
+		return
+	}
+	res, total, err := h.Service.Search(filter, pg.Limit, pg.Offset)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithPaginationTotal(w, http.StatusOK, res, pg.Page, pg.Limit, pg.GetTotalPagesFromCount(total), total)
+}
+
+// HandleExport exports Products to a file.
+// @Summary exports Products as CSV or NDJSON.
+// @Description This endpoint streams every product matching the filters, deleted ones excluded, in the format accepted by the import.
+// @Tags v1/Product
+// @Security JWTToken
+// @Param format query string false "file format" Enums(csv, ndjson)
+// @Param category query string false "filter by category id or slug, including its subcategories"
+// @Param min_price query number false "lowest price"
+// @Param max_price query number false "highest price"
+// @Param in_stock query bool false "only products that can be bought right now"
+// @Param attr.brand query string false "filter by an attribute, here brand; repeat to match any of several values"
+// @Param status query string false "filter by publication state: draft, published or archived"
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Success 200 {file} file
+// @Failure 400 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/export [get]
+func (h *ProductHandler) HandleExport(w http.ResponseWriter, r *http.Request) {
+	format, err := bulk.ParseFormat(pagination.ParseQueryParams(r, "format"))
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	filter, ok := h.filter(w, r)
+	if !ok {
+		return
+	}
+	err = filter.Validate()
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=products.%s", format))
+	encoder := bulk.NewEncoder(w, format)
+	err = h.Service.Export(filter, func(prod product.Product) error {
+		return encoder.Encode(prod.ToRow())
+	})
+	if err == nil {
+		err = encoder.Flush()
+	}
+	if err != nil {
+		// the status line is already sent, all we can do is stop and log
+		logger.ErrorWithStack(err)
+	}
+}
+
+// filter reads the product filter from the query string.
+func (h *ProductHandler) filter(w http.ResponseWriter, r *http.Request) (filter product.Filter, ok bool) {
+	orgId, _, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	manager, ok := h.canManage(w, r)
+	if !ok {
+		return
+	}
+	filter = product.Filter{
+		OrganizationId: orgId,
+		Query:          r.URL.Query().Get("q"),
+		Status:         r.URL.Query().Get("status"),
+		PublishedOnly:  !manager,
+	}
+	if filter.Query == "" {
+		filter.Query = r.URL.Query().Get("product_title")
+	}
+	if categoryParam := r.URL.Query().Get("category"); categoryParam != "" {
+		cat, err := h.CategoryHandler.Service.Resolve(orgId, categoryParam)
+		if err != nil {
+			response.WithError(w, err)
+			return filter, false
+		}
+		filter.CategoryIds, err = h.CategoryHandler.Service.GetDescendantIDs(orgId, cat.Id)
+		if err != nil {
+			response.WithError(w, err)
+			return filter, false
+		}
+	}
+	minPrice, err := pagination.ParseFloatParam(r, "min_price")
+	if err != nil {
+		response.WithError(w, err)
+		return filter, false
+	}
+	filter.MinPrice = null.FloatFromPtr(minPrice)
+	maxPrice, err := pagination.ParseFloatParam(r, "max_price")
+	if err != nil {
+		response.WithError(w, err)
+		return filter, false
+	}
+	filter.MaxPrice = null.FloatFromPtr(maxPrice)
+	inStock, err := pagination.ParseBoolParam(r, "in_stock")
+	if err != nil {
+		response.WithError(w, err)
+		return filter, false
+	}
+	filter.InStock = inStock != nil && *inStock
+	filter.Attributes = map[string][]string{}
+	for key, values := range r.URL.Query() {
+		if name := strings.TrimPrefix(key, attributeParamPrefix); name != key && name != "" {
+			filter.Attributes[name] = values
+		}
+	}
+	return
+}
+
+// attributeParamPrefix marks the query parameters filtering on an attribute,
+// e.g. attr.brand=acme.
+const attributeParamPrefix = "attr."
+
+// HandleGetProduct Gets a product.
+// @Summary Gets a product.
+// @Description This endpoint gets a product of the caller's organization by id or slug. Deleted products are not found, and neither are products that are not live unless the caller manages products.
+// @Tags v1/Product
+// @Security JWTToken
+// @Param productId path string true "the product's id or slug"
+// @Produce json
+// @Success 200 {object} response.Base{data=product.ProductResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{productId} [get]
+func (h *ProductHandler) HandleGetProduct(w http.ResponseWriter, r *http.Request) {
+	orgId, _, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	manager, ok := h.canManage(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.Resolve(chi.URLParam(r, "productId"), orgId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	if !manager && !res.IsPublished(time.Now().UTC()) {
+		response.WithError(w, failure.NotFound("Product"))
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleUpdateProduct Replaces a product.
+// @Summary replaces a product.
+// @Description This endpoint replaces the content, stock and price of a product. The slug and status are kept when left out. A change of price is recorded in the price history and carts holding the product are repriced by the worker.
+// @Tags v1/Product
+// @Security JWTToken
+// @Param productId path string true "the product's id"
+// @Param Product body product.ProductPayload true "the product's new values"
+// @Produce json
+// @Success 200 {object} response.Base{data=product.ProductResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{productId} [put]
+func (h *ProductHandler) HandleUpdateProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(chi.URLParam(r, "productId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	var payload product.ProductPayload
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	err = shared.GetValidator().Struct(payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	orgId, userId, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.Update(payload, id, userId, orgId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandlePatchProduct Partially updates a product.
+// @Summary partially updates a product.
+// @Description This endpoint changes only the fields present in the request.
+// @Tags v1/Product
+// @Security JWTToken
+// @Param productId path string true "the product's id"
+// @Param Product body product.ProductPatchPayload true "the fields to change"
+// @Produce json
+// @Success 200 {object} response.Base{data=product.ProductResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{productId} [patch]
+func (h *ProductHandler) HandlePatchProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(chi.URLParam(r, "productId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	var payload product.ProductPatchPayload
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	err = shared.GetValidator().Struct(payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	orgId, userId, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.Patch(payload, id, userId, orgId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleDeleteProduct Deletes a product.
+// @Summary soft deletes a product.
+// @Description This endpoint hides a product from listings, carts and checkout. Past orders keep referring to it and it can be restored.
+// @Tags v1/Product
+// @Security JWTToken
+// @Param productId path string true "the product's id"
+// @Produce json
+// @Success 200 {object} response.Base{data=product.ProductResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{productId} [delete]
+func (h *ProductHandler) HandleDeleteProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(chi.URLParam(r, "productId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	orgId, userId, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.Delete(id, userId, orgId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleRestoreProduct Restores a deleted product.
+// @Summary restores a soft deleted product.
+// @Description This endpoint makes a deleted product available again.
+// @Tags v1/Product
+// @Security JWTToken
+// @Param productId path string true "the product's id"
+// @Produce json
+// @Success 200 {object} response.Base{data=product.ProductResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{productId}/restore [post]
+func (h *ProductHandler) HandleRestoreProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(chi.URLParam(r, "productId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	orgId, userId, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.Restore(id, userId, orgId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleGetPriceHistory gets the price history of a Product.
+// @Summary gets the price history of a Product.
+// @Description This endpoint lists every change of the price of a product and its variants, newest first, with the previous price, the user who made it and the schedule that made it, if any. repricedAt is set once open carts have been brought to the price.
+// @Tags v1/Product
+// @Security JWTToken
+// @Param productId path string true "the product id"
+// @Param page query int true "current page number"
+// @Param limit query int true "limit of changes per page"
+// @Produce json
+// @Success 200 {object} response.Base{data=[]product.PriceChangeResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{productId}/prices [get]
+func (h *ProductHandler) HandleGetPriceHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(chi.URLParam(r, "productId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	pg, err := pagination.GetPagination(r)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	orgId, _, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, total, err := h.Service.GetPriceHistory(id, orgId, pg.Limit, pg.Offset)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithPaginationTotal(w, http.StatusOK, res, pg.Page, pg.Limit, pg.GetTotalPagesFromCount(total), total)
+}
+
+// HandleGetPriceSchedules gets the price schedules of a Product.
+// @Summary gets the price schedules of a Product.
+// @Description This endpoint lists the price schedules of the product by start, including those that ended or were cancelled.
+// @Tags v1/Product
+// @Security JWTToken
+// @Param productId path string true "the product id"
+// @Produce json
+// @Success 200 {object} response.Base{data=[]product.PriceScheduleResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{productId}/prices/schedules [get]
+func (h *ProductHandler) HandleGetPriceSchedules(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(chi.URLParam(r, "productId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	orgId, _, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.GetPriceSchedules(id, orgId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleSchedulePrice schedules a price for a Product.
+// @Summary schedules a price for a Product.
+// @Description This endpoint schedules a price, and optionally a compare-at price shown struck through, for the product. The worker sets it at startsAt and, when endsAt is given, restores the previous prices at endsAt unless the price was changed in the meantime. Windows of a product cannot overlap.
+// @Tags v1/Product
+// @Security JWTToken
+// @Param productId path string true "the product id"
+// @Param Schedule body product.PriceSchedulePayload true "the price and its window"
+// @Produce json
+// @Success 201 {object} response.Base{data=product.PriceScheduleResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{productId}/prices/schedules [post]
+func (h *ProductHandler) HandleSchedulePrice(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(chi.URLParam(r, "productId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	var payload product.PriceSchedulePayload
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	err = shared.GetValidator().Struct(payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	orgId, userId, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.SchedulePrice(payload, id, userId, orgId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusCreated, res)
+}
 
+// HandleCancelPriceSchedule cancels a price schedule of a Product.
+// @Summary cancels a price schedule of a Product.
+// @Description This endpoint cancels a price schedule that has not started yet.
+// @Tags v1/Product
+// @Security JWTToken
+// @Param productId path string true "the product id"
+// @Param scheduleId path string true "the schedule id"
+// @Produce json
+// @Success 200 {object} response.Base{data=product.PriceScheduleResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{productId}/prices/schedules/{scheduleId} [delete]
+func (h *ProductHandler) HandleCancelPriceSchedule(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.FromString(chi.URLParam(r, "productId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	scheduleId, err := uuid.FromString(chi.URLParam(r, "scheduleId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	orgId, userId, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.CancelPriceSchedule(scheduleId, id, userId, orgId)
 	if err != nil {
 		response.WithError(w, err)
 		return
 	}
-	response.WithPagination(w, http.StatusOK, res, pg.Page, pg.Limit, totalPage)
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// canManage reports whether the caller manages products, and so also sees
+// drafts, scheduled and archived products.
+func (h *ProductHandler) canManage(w http.ResponseWriter, r *http.Request) (manager, ok bool) {
+	claims, ok := claimsOf(w, r)
+	if !ok {
+		return
+	}
+	manager, err := h.JwtAuth.Allows(claims, group.PermissionProductsWrite)
+	if err != nil {
+		response.WithError(w, err)
+		return false, false
+	}
+	return
 }
@@ -0,0 +1,434 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/evermos/boilerplate-go/internal/domain/scim"
+	"github.com/evermos/boilerplate-go/transport/http/middleware"
+	"github.com/evermos/boilerplate-go/transport/http/response"
+	"github.com/go-chi/chi"
+	"github.com/gofrs/uuid"
+)
+
+type ScimHandler struct {
+	Service  scim.ScimService
+	ScimAuth *middleware.ScimAuthentication
+}
+
+func ProvideScimHandler(service scim.ScimService, scimAuth *middleware.ScimAuthentication) ScimHandler {
+	return ScimHandler{Service: service, ScimAuth: scimAuth}
+}
+
+// Router mounts the SCIM 2.0 endpoints. They sit outside /v1 as identity
+// providers expect the standard paths, and answer in SCIM's own format.
+func (h *ScimHandler) Router(r chi.Router) {
+	r.Route("/scim/v2", func(r chi.Router) {
+		r.Use(h.ScimAuth.Validate)
+		r.Get("/ServiceProviderConfig", h.HandleServiceProviderConfig)
+		r.Route("/Users", func(r chi.Router) {
+			r.Get("/", h.HandleGetUsers)
+			r.Post("/", h.HandleCreateUser)
+			r.Get("/{id}", h.HandleGetUser)
+			r.Put("/{id}", h.HandleReplaceUser)
+			r.Patch("/{id}", h.HandlePatchUser)
+			r.Delete("/{id}", h.HandleDeleteUser)
+		})
+		r.Route("/Groups", func(r chi.Router) {
+			r.Get("/", h.HandleGetGroups)
+			r.Post("/", h.HandleCreateGroup)
+			r.Get("/{id}", h.HandleGetGroup)
+			r.Put("/{id}", h.HandleReplaceGroup)
+			r.Patch("/{id}", h.HandlePatchGroup)
+			r.Delete("/{id}", h.HandleDeleteGroup)
+		})
+	})
+}
+
+// HandleServiceProviderConfig describes the supported SCIM features.
+// @Summary gets the SCIM service provider configuration.
+// @Description This endpoint tells identity providers which SCIM features are supported.
+// @Tags scim/v2
+// @Security SCIMToken
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /scim/v2/ServiceProviderConfig [get]
+func (h *ScimHandler) HandleServiceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	response.WithSCIM(w, http.StatusOK, map[string]interface{}{
+		"schemas":        []string{"urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"},
+		"patch":          map[string]bool{"supported": true},
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": scim.MaxCount},
+		"changePassword": map[string]bool{"supported": false},
+		"sort":           map[string]bool{"supported": false},
+		"etag":           map[string]bool{"supported": false},
+		"authenticationSchemes": []map[string]string{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer token",
+			"description": "The token configured in SCIM.TOKEN",
+		}},
+	})
+}
+
+// HandleGetUsers lists users.
+// @Summary lists Users through SCIM.
+// @Description This endpoint lists the users of the provisioned organization. filter supports userName, emails, name.formatted and displayName with eq or co, and active eq, joined by and.
+// @Tags scim/v2
+// @Security SCIMToken
+// @Param filter query string false "SCIM filter"
+// @Param startIndex query int false "1-based index of the first result"
+// @Param count query int false "maximum number of results"
+// @Produce json
+// @Success 200 {object} scim.ListResponse
+// @Failure 400 {object} scim.Error
+// @Failure 401 {object} scim.Error
+// @Router /scim/v2/Users [get]
+func (h *ScimHandler) HandleGetUsers(w http.ResponseWriter, r *http.Request) {
+	startIndex, count, ok := h.page(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.GetUsers(r.URL.Query().Get("filter"), startIndex, count)
+	if err != nil {
+		h.withError(w, err)
+		return
+	}
+	response.WithSCIM(w, http.StatusOK, res)
+}
+
+// HandleGetUser gets a user.
+// @Summary gets a User through SCIM.
+// @Description This endpoint gets a user of the provisioned organization.
+// @Tags scim/v2
+// @Security SCIMToken
+// @Param id path string true "the user id"
+// @Produce json
+// @Success 200 {object} scim.User
+// @Failure 401 {object} scim.Error
+// @Failure 404 {object} scim.Error
+// @Router /scim/v2/Users/{id} [get]
+func (h *ScimHandler) HandleGetUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.GetUser(id)
+	if err != nil {
+		h.withError(w, err)
+		return
+	}
+	response.WithSCIM(w, http.StatusOK, res)
+}
+
+// HandleCreateUser provisions a user.
+// @Summary creates a User through SCIM.
+// @Description This endpoint provisions a user in the organization. Users sent without a password get a random one.
+// @Tags scim/v2
+// @Security SCIMToken
+// @Param User body scim.User true "the user"
+// @Produce json
+// @Success 201 {object} scim.User
+// @Failure 400 {object} scim.Error
+// @Failure 401 {object} scim.Error
+// @Failure 409 {object} scim.Error
+// @Router /scim/v2/Users [post]
+func (h *ScimHandler) HandleCreateUser(w http.ResponseWriter, r *http.Request) {
+	var payload scim.User
+	if !h.decode(w, r, &payload) {
+		return
+	}
+	res, err := h.Service.CreateUser(payload)
+	if err != nil {
+		h.withError(w, err)
+		return
+	}
+	response.WithSCIM(w, http.StatusCreated, res)
+}
+
+// HandleReplaceUser replaces a user.
+// @Summary replaces a User through SCIM.
+// @Description This endpoint updates the user to match the request. Setting active to false soft deletes the user, setting it back to true restores it.
+// @Tags scim/v2
+// @Security SCIMToken
+// @Param id path string true "the user id"
+// @Param User body scim.User true "the user"
+// @Produce json
+// @Success 200 {object} scim.User
+// @Failure 400 {object} scim.Error
+// @Failure 401 {object} scim.Error
+// @Failure 404 {object} scim.Error
+// @Failure 409 {object} scim.Error
+// @Router /scim/v2/Users/{id} [put]
+func (h *ScimHandler) HandleReplaceUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	var payload scim.User
+	if !h.decode(w, r, &payload) {
+		return
+	}
+	res, err := h.Service.ReplaceUser(id, payload)
+	if err != nil {
+		h.withError(w, err)
+		return
+	}
+	response.WithSCIM(w, http.StatusOK, res)
+}
+
+// HandlePatchUser patches a user.
+// @Summary patches a User through SCIM.
+// @Description This endpoint applies add, replace and remove operations to the user.
+// @Tags scim/v2
+// @Security SCIMToken
+// @Param id path string true "the user id"
+// @Param PatchOp body scim.PatchRequest true "the operations"
+// @Produce json
+// @Success 200 {object} scim.User
+// @Failure 400 {object} scim.Error
+// @Failure 401 {object} scim.Error
+// @Failure 404 {object} scim.Error
+// @Failure 409 {object} scim.Error
+// @Router /scim/v2/Users/{id} [patch]
+func (h *ScimHandler) HandlePatchUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	var payload scim.PatchRequest
+	if !h.decode(w, r, &payload) {
+		return
+	}
+	res, err := h.Service.PatchUser(id, payload)
+	if err != nil {
+		h.withError(w, err)
+		return
+	}
+	response.WithSCIM(w, http.StatusOK, res)
+}
+
+// HandleDeleteUser deprovisions a user.
+// @Summary deletes a User through SCIM.
+// @Description This endpoint soft deletes the user.
+// @Tags scim/v2
+// @Security SCIMToken
+// @Param id path string true "the user id"
+// @Success 204
+// @Failure 401 {object} scim.Error
+// @Failure 404 {object} scim.Error
+// @Router /scim/v2/Users/{id} [delete]
+func (h *ScimHandler) HandleDeleteUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	err := h.Service.DeleteUser(id)
+	if err != nil {
+		h.withError(w, err)
+		return
+	}
+	response.NoContent(w)
+}
+
+// HandleGetGroups lists groups.
+// @Summary lists Groups through SCIM.
+// @Description This endpoint lists the groups of the provisioned organization. filter supports displayName eq.
+// @Tags scim/v2
+// @Security SCIMToken
+// @Param filter query string false "SCIM filter"
+// @Param startIndex query int false "1-based index of the first result"
+// @Param count query int false "maximum number of results"
+// @Produce json
+// @Success 200 {object} scim.ListResponse
+// @Failure 400 {object} scim.Error
+// @Failure 401 {object} scim.Error
+// @Router /scim/v2/Groups [get]
+func (h *ScimHandler) HandleGetGroups(w http.ResponseWriter, r *http.Request) {
+	startIndex, count, ok := h.page(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.GetGroups(r.URL.Query().Get("filter"), startIndex, count)
+	if err != nil {
+		h.withError(w, err)
+		return
+	}
+	response.WithSCIM(w, http.StatusOK, res)
+}
+
+// HandleGetGroup gets a group.
+// @Summary gets a Group through SCIM.
+// @Description This endpoint gets a group with its members.
+// @Tags scim/v2
+// @Security SCIMToken
+// @Param id path string true "the group id"
+// @Produce json
+// @Success 200 {object} scim.Group
+// @Failure 401 {object} scim.Error
+// @Failure 404 {object} scim.Error
+// @Router /scim/v2/Groups/{id} [get]
+func (h *ScimHandler) HandleGetGroup(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.GetGroup(id)
+	if err != nil {
+		h.withError(w, err)
+		return
+	}
+	response.WithSCIM(w, http.StatusOK, res)
+}
+
+// HandleCreateGroup creates a group.
+// @Summary creates a Group through SCIM.
+// @Description This endpoint creates a group without permissions and adds the given members.
+// @Tags scim/v2
+// @Security SCIMToken
+// @Param Group body scim.Group true "the group"
+// @Produce json
+// @Success 201 {object} scim.Group
+// @Failure 400 {object} scim.Error
+// @Failure 401 {object} scim.Error
+// @Failure 409 {object} scim.Error
+// @Router /scim/v2/Groups [post]
+func (h *ScimHandler) HandleCreateGroup(w http.ResponseWriter, r *http.Request) {
+	var payload scim.Group
+	if !h.decode(w, r, &payload) {
+		return
+	}
+	res, err := h.Service.CreateGroup(payload)
+	if err != nil {
+		h.withError(w, err)
+		return
+	}
+	response.WithSCIM(w, http.StatusCreated, res)
+}
+
+// HandleReplaceGroup replaces a group.
+// @Summary replaces a Group through SCIM.
+// @Description This endpoint renames the group and replaces its members. Permissions are kept.
+// @Tags scim/v2
+// @Security SCIMToken
+// @Param id path string true "the group id"
+// @Param Group body scim.Group true "the group"
+// @Produce json
+// @Success 200 {object} scim.Group
+// @Failure 400 {object} scim.Error
+// @Failure 401 {object} scim.Error
+// @Failure 404 {object} scim.Error
+// @Failure 409 {object} scim.Error
+// @Router /scim/v2/Groups/{id} [put]
+func (h *ScimHandler) HandleReplaceGroup(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	var payload scim.Group
+	if !h.decode(w, r, &payload) {
+		return
+	}
+	res, err := h.Service.ReplaceGroup(id, payload)
+	if err != nil {
+		h.withError(w, err)
+		return
+	}
+	response.WithSCIM(w, http.StatusOK, res)
+}
+
+// HandlePatchGroup patches a group.
+// @Summary patches a Group through SCIM.
+// @Description This endpoint renames the group or adds and removes members.
+// @Tags scim/v2
+// @Security SCIMToken
+// @Param id path string true "the group id"
+// @Param PatchOp body scim.PatchRequest true "the operations"
+// @Produce json
+// @Success 200 {object} scim.Group
+// @Failure 400 {object} scim.Error
+// @Failure 401 {object} scim.Error
+// @Failure 404 {object} scim.Error
+// @Router /scim/v2/Groups/{id} [patch]
+func (h *ScimHandler) HandlePatchGroup(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	var payload scim.PatchRequest
+	if !h.decode(w, r, &payload) {
+		return
+	}
+	res, err := h.Service.PatchGroup(id, payload)
+	if err != nil {
+		h.withError(w, err)
+		return
+	}
+	response.WithSCIM(w, http.StatusOK, res)
+}
+
+// HandleDeleteGroup deletes a group.
+// @Summary deletes a Group through SCIM.
+// @Description This endpoint deletes the group.
+// @Tags scim/v2
+// @Security SCIMToken
+// @Param id path string true "the group id"
+// @Success 204
+// @Failure 401 {object} scim.Error
+// @Failure 404 {object} scim.Error
+// @Router /scim/v2/Groups/{id} [delete]
+func (h *ScimHandler) HandleDeleteGroup(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	err := h.Service.DeleteGroup(id)
+	if err != nil {
+		h.withError(w, err)
+		return
+	}
+	response.NoContent(w)
+}
+
+func (h *ScimHandler) id(w http.ResponseWriter, r *http.Request) (id uuid.UUID, ok bool) {
+	id, err := uuid.FromString(chi.URLParam(r, "id"))
+	if err != nil {
+		// ids that are not uuids cannot exist
+		h.withError(w, scim.NewError(http.StatusNotFound, "", "resource not found"))
+		return
+	}
+	return id, true
+}
+
+// page reads startIndex and count, defaulting to the first page.
+func (h *ScimHandler) page(w http.ResponseWriter, r *http.Request) (startIndex, count int, ok bool) {
+	startIndex, count = 1, scim.DefaultCount
+	var err error
+	if v := r.URL.Query().Get("startIndex"); v != "" {
+		if startIndex, err = strconv.Atoi(v); err != nil {
+			h.withError(w, scim.NewError(http.StatusBadRequest, "invalidValue", "startIndex must be a number"))
+			return
+		}
+	}
+	if v := r.URL.Query().Get("count"); v != "" {
+		if count, err = strconv.Atoi(v); err != nil {
+			h.withError(w, scim.NewError(http.StatusBadRequest, "invalidValue", "count must be a number"))
+			return
+		}
+	}
+	return startIndex, count, true
+}
+
+func (h *ScimHandler) decode(w http.ResponseWriter, r *http.Request, target interface{}) bool {
+	err := json.NewDecoder(r.Body).Decode(target)
+	if err != nil {
+		h.withError(w, scim.NewError(http.StatusBadRequest, "invalidSyntax", err.Error()))
+		return false
+	}
+	return true
+}
+
+func (h *ScimHandler) withError(w http.ResponseWriter, err error) {
+	scimErr := scim.ErrorFrom(err)
+	response.WithSCIM(w, scimErr.Code(), scimErr)
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/shared/jwt"
+	"github.com/evermos/boilerplate-go/transport/http/middleware"
+	"github.com/evermos/boilerplate-go/transport/http/response"
+	"github.com/gofrs/uuid"
+)
+
+// tenantOf returns the organization the caller's token is scoped to. Tokens
+// issued before organizations existed have none and must be renewed.
+func tenantOf(claims *jwt.Claims) (orgId uuid.UUID, err error) {
+	orgId, err = uuid.FromString(claims.OrgId)
+	if err != nil {
+		err = failure.Unauthorized("token has no organization, please log in again")
+	}
+	return
+}
+
+// claimsOf returns the claims of the caller's token.
+func claimsOf(w http.ResponseWriter, r *http.Request) (claims *jwt.Claims, ok bool) {
+	claims, ok = r.Context().Value(middleware.ClaimsKey("claims")).(*jwt.Claims)
+	if !ok {
+		response.WithMessage(w, http.StatusUnauthorized, "Unauthorized")
+	}
+	return
+}
+
+// callerId returns the user of the caller's token, which need not be scoped
+// to an organization.
+func callerId(w http.ResponseWriter, r *http.Request) (userId uuid.UUID, ok bool) {
+	claims, ok := claimsOf(w, r)
+	if !ok {
+		return
+	}
+	userId, err := uuid.FromString(claims.UserId)
+	if err != nil {
+		response.WithError(w, err)
+		return userId, false
+	}
+	return
+}
+
+// caller returns the organization and user of the caller's token.
+func caller(w http.ResponseWriter, r *http.Request) (orgId, userId uuid.UUID, ok bool) {
+	claims, ok := claimsOf(w, r)
+	if !ok {
+		return
+	}
+	orgId, err := tenantOf(claims)
+	if err != nil {
+		response.WithError(w, err)
+		return orgId, userId, false
+	}
+	userId, err = uuid.FromString(claims.UserId)
+	if err != nil {
+		response.WithError(w, err)
+		return orgId, userId, false
+	}
+	return
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/evermos/boilerplate-go/shared/failure"
+)
+
+// defaultMaxUploadBytes is used when STORAGE.MAX_UPLOAD_BYTES is not set.
+const defaultMaxUploadBytes = 5 << 20
+
+// readUpload reads the file sent in the given multipart form field, refusing
+// bodies larger than maxBytes.
+func readUpload(w http.ResponseWriter, r *http.Request, field string, maxBytes int64) (data []byte, err error) {
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxUploadBytes
+	}
+	// leave some room for the multipart headers around the file
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes+1<<20)
+	err = r.ParseMultipartForm(maxBytes)
+	if err != nil {
+		err = failure.BadRequest(err)
+		return
+	}
+	file, header, err := r.FormFile(field)
+	if err != nil {
+		err = failure.BadRequest(err)
+		return
+	}
+	defer file.Close()
+	if header.Size > maxBytes {
+		err = failure.BadRequestFromString(fmt.Sprintf("file is larger than %d bytes", maxBytes))
+		return
+	}
+	data, err = ioutil.ReadAll(file)
+	if err != nil {
+		err = failure.BadRequest(err)
+		return
+	}
+	return
+}
@@ -1,13 +1,18 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 
+	"github.com/evermos/boilerplate-go/configs"
+	"github.com/evermos/boilerplate-go/internal/domain/group"
 	"github.com/evermos/boilerplate-go/internal/domain/user"
 	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/bulk"
 	"github.com/evermos/boilerplate-go/shared/failure"
-	"github.com/evermos/boilerplate-go/shared/jwt"
+	"github.com/evermos/boilerplate-go/shared/logger"
 	"github.com/evermos/boilerplate-go/shared/pagination"
 	"github.com/evermos/boilerplate-go/transport/http/middleware"
 	"github.com/evermos/boilerplate-go/transport/http/response"
@@ -16,12 +21,17 @@ import (
 )
 
 type UserHandler struct {
-	Service user.UserService
-	jwtAuth *middleware.JwtAuthentication
+	Service           user.UserService
+	AddressHandler    AddressHandler
+	PrivacyHandler    PrivacyHandler
+	GroupHandler      GroupHandler
+	PreferenceHandler PreferenceHandler
+	Config            *configs.Config
+	jwtAuth           *middleware.JwtAuthentication
 }
 
-func ProvideUserHandler(service user.UserService, jwtAuth *middleware.JwtAuthentication) UserHandler {
-	return UserHandler{Service: service, jwtAuth: jwtAuth}
+func ProvideUserHandler(service user.UserService, addressHandler AddressHandler, privacyHandler PrivacyHandler, groupHandler GroupHandler, preferenceHandler PreferenceHandler, config *configs.Config, jwtAuth *middleware.JwtAuthentication) UserHandler {
+	return UserHandler{Service: service, AddressHandler: addressHandler, PrivacyHandler: privacyHandler, GroupHandler: groupHandler, PreferenceHandler: preferenceHandler, Config: config, jwtAuth: jwtAuth}
 }
 
 func (h *UserHandler) Router(r chi.Router) {
@@ -29,16 +39,36 @@ func (h *UserHandler) Router(r chi.Router) {
 		r.Use(h.jwtAuth.Validate)
 
 		r.Group(func(r chi.Router) {
+			// IsUser lets through the user themselves and admins signed in
+			// to the organization the user was created in, so the routes
+			// mounted here need no further ownership check.
 			r.Use(h.jwtAuth.IsUser)
 			r.Route("/{userId}", func(r chi.Router) {
 				r.Get("/", h.HandleGetUser)
 				r.Put("/", h.HandleUpdateUser)
+				r.Patch("/", h.HandleUpdateProfile)
+				r.Post("/email/verify", h.HandleVerifyEmail)
+				r.Put("/avatar", h.HandleUpdateAvatar)
+				r.Delete("/avatar", h.HandleDeleteAvatar)
+				h.AddressHandler.Router(r)
+				h.PrivacyHandler.Router(r)
+				h.GroupHandler.UserRouter(r)
+				h.PreferenceHandler.UserRouter(r)
 				r.Delete("/", h.HandleDeleteUser)
 			})
 		})
 		r.Group(func(r chi.Router) {
-			r.Use(h.jwtAuth.AdminOnly)
+			r.Use(h.jwtAuth.RequirePermission(group.PermissionUsersRead))
 			r.Get("/", h.HandleGetAll)
+			r.Get("/export", h.HandleExport)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(h.jwtAuth.RequirePermission(group.PermissionUsersWrite))
+			r.Post("/import", h.HandleImport)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(h.jwtAuth.RequirePermission(group.PermissionPrivacyRead))
+			h.PrivacyHandler.AdminRouter(r)
 		})
 	})
 }
@@ -62,9 +92,8 @@ func (h *UserHandler) HandleGetUser(w http.ResponseWriter, r *http.Request) {
 		response.WithError(w, failure.BadRequest(err))
 		return
 	}
-	claims, ok := r.Context().Value(middleware.ClaimsKey("claims")).(*jwt.Claims)
+	claims, ok := claimsOf(w, r)
 	if !ok {
-		response.WithMessage(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -123,6 +152,163 @@ func (h *UserHandler) HandleUpdateUser(w http.ResponseWriter, r *http.Request) {
 	response.WithJSON(w, http.StatusOK, res)
 }
 
+// HandleUpdateProfile partially updates a User's profile.
+// @Summary partially updates a User's profile.
+// @Description This endpoint updates only the given profile fields. updatedAt must match the current value of the user.
+// @Tags v1/User
+// @Security JWTToken
+// @Param userId path string true "the user id"
+// @Param User body user.ProfilePayload true "The profile fields to be changed"
+// @Produce json
+// @Success 200 {object} response.Base{data=user.UserResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/users/{userId} [patch]
+func (h *UserHandler) HandleUpdateProfile(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "userId")
+	userId, err := uuid.FromString(id)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	var payload user.ProfilePayload
+	err = decoder.Decode(&payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	err = shared.GetValidator().Struct(payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	updaterId, ok := callerId(w, r)
+	if !ok {
+		return
+	}
+
+	res, err := h.Service.UpdateProfile(payload, userId, updaterId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleVerifyEmail verifies a User's email.
+// @Summary verifies a User's email.
+// @Description This endpoint marks the user's email as verified using the token sent to it.
+// @Tags v1/User
+// @Security JWTToken
+// @Param userId path string true "the user id"
+// @Param Token body user.VerifyEmailPayload true "The verification token"
+// @Produce json
+// @Success 200 {object} response.Base{data=user.UserResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/users/{userId}/email/verify [post]
+func (h *UserHandler) HandleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "userId")
+	userId, err := uuid.FromString(id)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	var payload user.VerifyEmailPayload
+	err = decoder.Decode(&payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	err = shared.GetValidator().Struct(payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+
+	res, err := h.Service.VerifyEmail(payload, userId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleUpdateAvatar uploads a User's avatar.
+// @Summary uploads a User's avatar.
+// @Description This endpoint replaces the user's avatar with a JPEG, PNG or GIF image and generates a thumbnail.
+// @Tags v1/User
+// @Security JWTToken
+// @Accept multipart/form-data
+// @Param userId path string true "the user id"
+// @Param avatar formData file true "the avatar image"
+// @Produce json
+// @Success 200 {object} response.Base{data=user.UserResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/users/{userId}/avatar [put]
+func (h *UserHandler) HandleUpdateAvatar(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "userId")
+	userId, err := uuid.FromString(id)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	data, err := readUpload(w, r, "avatar", h.Config.Storage.MaxUploadBytes)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	updaterId, ok := callerId(w, r)
+	if !ok {
+		return
+	}
+
+	res, err := h.Service.UpdateAvatar(data, userId, updaterId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleDeleteAvatar removes a User's avatar.
+// @Summary removes a User's avatar.
+// @Description This endpoint removes the user's avatar and its thumbnail.
+// @Tags v1/User
+// @Security JWTToken
+// @Param userId path string true "the user id"
+// @Produce json
+// @Success 200 {object} response.Base{data=user.UserResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/users/{userId}/avatar [delete]
+func (h *UserHandler) HandleDeleteAvatar(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "userId")
+	userId, err := uuid.FromString(id)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	updaterId, ok := callerId(w, r)
+	if !ok {
+		return
+	}
+
+	res, err := h.Service.DeleteAvatar(userId, updaterId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
 // HandleDeleteUser Deletes a User.
 // @Summary soft deletes a User.
 // @Description This endpoint soft deletes a User.
@@ -142,14 +328,8 @@ func (h *UserHandler) HandleDeleteUser(w http.ResponseWriter, r *http.Request) {
 		response.WithError(w, failure.BadRequest(err))
 		return
 	}
-	claims, ok := r.Context().Value(middleware.ClaimsKey("claims")).(*jwt.Claims)
+	deleterId, ok := callerId(w, r)
 	if !ok {
-		response.WithMessage(w, http.StatusUnauthorized, "Unauthorized")
-		return
-	}
-	deleterId, err := uuid.FromString(claims.UserId)
-	if err != nil {
-		response.WithError(w, err)
 		return
 	}
 	res, err := h.Service.DeleteByID(userId, deleterId)
@@ -162,15 +342,24 @@ func (h *UserHandler) HandleDeleteUser(w http.ResponseWriter, r *http.Request) {
 
 // HandleGetAll Gets all Users.
 // @Summary Gets all Users.
-// @Description This endpoint Gets all Users available.
+// @Description This endpoint Gets all Users of the caller's organization matching the given filters.
 // @Tags v1/User
 // @Security JWTToken
 // @Param page query int true "current page number"
 // @Param limit query int true "limit of Users per page"
 // @Param sort query string false "sort direction"
-// @Param field query string false "field to sort by"
+// @Param field query string false "field to sort by" Enums(id, email, username, name, role, created_at, updated_at)
+// @Param q query string false "search email, username and name"
+// @Param email query string false "filter by email substring"
+// @Param username query string false "filter by username substring"
+// @Param name query string false "filter by name substring"
+// @Param role query string false "filter by role" Enums(trainee, admin)
+// @Param status query string false "filter by deletion status" Enums(active, deleted)
+// @Param verified query bool false "filter by verified email"
+// @Param createdFrom query string false "created at or after, YYYY-MM-DD or RFC3339"
+// @Param createdTo query string false "created before, YYYY-MM-DD or RFC3339"
 // @Produce json
-// @Success 200 {object} response.Base{data=[]user.UserResponseFormat}
+// @Success 200 {object} response.Pagination{data=[]user.UserResponseFormat}
 // @Failure 400 {object} response.Base
 // @Failure 409 {object} response.Base
 // @Failure 500 {object} response.Base
@@ -181,11 +370,151 @@ func (h *UserHandler) HandleGetAll(w http.ResponseWriter, r *http.Request) {
 		response.WithError(w, err)
 		return
 	}
-	res, err := h.Service.GetAll(pg.Limit, pg.Offset, pg.Sort, pg.Field)
-	totalPage := pg.GetTotalPages(res)
+	err = pg.ValidateField(user.SortFields...)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	orgId, _, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	filter, err := parseUserFilter(r, orgId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	res, total, err := h.Service.GetAll(filter, pg.Limit, pg.Offset, pg.Sort, pg.Field)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithPaginationTotal(w, http.StatusOK, res, pg.Page, pg.Limit, pg.GetTotalPagesFromCount(total), total)
+}
+
+// HandleImport imports Users from a file.
+// @Summary imports Users from a CSV or NDJSON file.
+// @Description This endpoint creates a user in the caller's organization for every valid row of the file. Each row has email, userName, name, password and role. Invalid rows are reported and skipped.
+// @Tags v1/User
+// @Security JWTToken
+// @Accept multipart/form-data
+// @Param file formData file true "the users to import"
+// @Param format query string false "file format" Enums(csv, ndjson)
+// @Param dryRun query bool false "only validate the file"
+// @Produce json
+// @Success 200 {object} response.Base{data=user.ImportReport}
+// @Failure 400 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/users/import [post]
+func (h *UserHandler) HandleImport(w http.ResponseWriter, r *http.Request) {
+	format, err := bulk.ParseFormat(pagination.ParseQueryParams(r, "format"))
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	dryRun, err := pagination.ParseBoolParam(r, "dryRun")
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	data, err := readUpload(w, r, "file", h.Config.Storage.MaxUploadBytes)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	claims, ok := claimsOf(w, r)
+	if !ok {
+		return
+	}
+	orgId, err := tenantOf(claims)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	res, err := h.Service.Import(bytes.NewReader(data), format, dryRun != nil && *dryRun, orgId, claims.Role)
 	if err != nil {
 		response.WithError(w, err)
 		return
 	}
-	response.WithPagination(w, http.StatusOK, res, pg.Page, pg.Limit, totalPage)
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleExport exports Users to a file.
+// @Summary exports Users as CSV or NDJSON.
+// @Description This endpoint streams every user matching the filters. Passwords are never exported.
+// @Tags v1/User
+// @Security JWTToken
+// @Param format query string false "file format" Enums(csv, ndjson)
+// @Param q query string false "search email, username and name"
+// @Param role query string false "filter by role" Enums(trainee, admin)
+// @Param status query string false "filter by deletion status" Enums(active, deleted)
+// @Param verified query bool false "filter by verified email"
+// @Param createdFrom query string false "created at or after, YYYY-MM-DD or RFC3339"
+// @Param createdTo query string false "created before, YYYY-MM-DD or RFC3339"
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Success 200 {file} file
+// @Failure 400 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/users/export [get]
+func (h *UserHandler) HandleExport(w http.ResponseWriter, r *http.Request) {
+	format, err := bulk.ParseFormat(pagination.ParseQueryParams(r, "format"))
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	orgId, _, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	filter, err := parseUserFilter(r, orgId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=users.%s", format))
+	encoder := bulk.NewEncoder(w, format)
+	started := false
+	err = h.Service.Export(filter, func(u user.User) error {
+		started = true
+		return encoder.Encode(u.ToExportRow())
+	})
+	if err != nil && !started {
+		// nothing is sent yet, e.g. the filter is invalid
+		w.Header().Del("Content-Disposition")
+		response.WithError(w, err)
+		return
+	}
+	if err == nil {
+		err = encoder.Flush()
+	}
+	if err != nil {
+		// the status line is already sent, all we can do is stop and log
+		logger.ErrorWithStack(err)
+	}
+}
+
+// parseUserFilter reads the filters from the query string and limits them to
+// the organization orgId.
+func parseUserFilter(r *http.Request, orgId uuid.UUID) (filter user.Filter, err error) {
+	filter = user.Filter{
+		OrganizationId: orgId,
+		Search:         r.URL.Query().Get("q"),
+		Email:          r.URL.Query().Get("email"),
+		UserName:       r.URL.Query().Get("username"),
+		Name:           r.URL.Query().Get("name"),
+		Role:           pagination.ParseQueryParams(r, "role"),
+		Status:         pagination.ParseQueryParams(r, "status"),
+	}
+	filter.Verified, err = pagination.ParseBoolParam(r, "verified")
+	if err != nil {
+		return
+	}
+	filter.CreatedFrom, err = pagination.ParseTimeParam(r, "createdFrom")
+	if err != nil {
+		return
+	}
+	filter.CreatedTo, err = pagination.ParseTimeParam(r, "createdTo")
+	return
 }
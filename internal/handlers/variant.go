@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/evermos/boilerplate-go/internal/domain/group"
+	"github.com/evermos/boilerplate-go/internal/domain/variant"
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/failure"
+	"github.com/evermos/boilerplate-go/transport/http/middleware"
+	"github.com/evermos/boilerplate-go/transport/http/response"
+	"github.com/go-chi/chi"
+	"github.com/gofrs/uuid"
+)
+
+type VariantHandler struct {
+	Service variant.VariantService
+	JwtAuth *middleware.JwtAuthentication
+}
+
+func ProvideVariantHandler(service variant.VariantService, jwtAuth *middleware.JwtAuthentication) VariantHandler {
+	return VariantHandler{Service: service, JwtAuth: jwtAuth}
+}
+
+// ProductRouter mounts the options and variants of a product. It is mounted
+// under /products, which already validates the token.
+func (h *VariantHandler) ProductRouter(r chi.Router) {
+	r.Get("/{productId}/options", h.HandleGetOptions)
+	r.Get("/{productId}/variants", h.HandleGetByProductID)
+
+	r.Group(func(r chi.Router) {
+		r.Use(h.JwtAuth.RequirePermission(group.PermissionProductsWrite))
+		r.Put("/{productId}/options", h.HandleSetOptions)
+		r.Post("/{productId}/variants", h.HandleCreate)
+		r.Put("/{productId}/variants/{variantId}", h.HandleUpdate)
+		r.Delete("/{productId}/variants/{variantId}", h.HandleDelete)
+	})
+}
+
+// HandleGetOptions gets the options of a Product.
+// @Summary gets the options of a Product.
+// @Description This endpoint lists the options a product comes in, such as size or color, with their values.
+// @Tags v1/Product
+// @Security JWTToken
+// @Param productId path string true "the product id"
+// @Produce json
+// @Success 200 {object} response.Base{data=[]variant.OptionResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{productId}/options [get]
+func (h *VariantHandler) HandleGetOptions(w http.ResponseWriter, r *http.Request) {
+	productId, err := uuid.FromString(chi.URLParam(r, "productId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	orgId, _, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.GetOptions(orgId, productId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleSetOptions replaces the options of a Product.
+// @Summary replaces the options of a Product.
+// @Description This endpoint sets the options a product comes in. Existing variants have to keep matching the new options.
+// @Tags v1/Product
+// @Security JWTToken
+// @Param productId path string true "the product id"
+// @Param Options body variant.OptionsPayload true "the product's options"
+// @Produce json
+// @Success 200 {object} response.Base{data=[]variant.OptionResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{productId}/options [put]
+func (h *VariantHandler) HandleSetOptions(w http.ResponseWriter, r *http.Request) {
+	productId, err := uuid.FromString(chi.URLParam(r, "productId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	var payload variant.OptionsPayload
+	err = json.NewDecoder(r.Body).Decode(&payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	err = shared.GetValidator().Struct(payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	orgId, callerId, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.SetOptions(payload, orgId, productId, callerId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleGetByProductID gets the variants of a Product.
+// @Summary gets the variants of a Product.
+// @Description This endpoint lists the variants of a product that are for sale.
+// @Tags v1/Product
+// @Security JWTToken
+// @Param productId path string true "the product id"
+// @Produce json
+// @Success 200 {object} response.Base{data=[]variant.VariantResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{productId}/variants [get]
+func (h *VariantHandler) HandleGetByProductID(w http.ResponseWriter, r *http.Request) {
+	productId, err := uuid.FromString(chi.URLParam(r, "productId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	orgId, _, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.GetByProductID(orgId, productId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleCreate creates a new Variant.
+// @Summary creates a new Variant of a Product.
+// @Description This endpoint adds a variant with its own SKU, price and stock. It needs a value for each of the product's options.
+// @Tags v1/Product
+// @Security JWTToken
+// @Param productId path string true "the product id"
+// @Param Variant body variant.VariantPayload true "the variant to be created"
+// @Produce json
+// @Success 201 {object} response.Base{data=variant.VariantResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{productId}/variants [post]
+func (h *VariantHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	productId, err := uuid.FromString(chi.URLParam(r, "productId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	payload, ok := h.decodePayload(w, r)
+	if !ok {
+		return
+	}
+	orgId, callerId, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.Create(payload, orgId, productId, callerId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusCreated, res)
+}
+
+// HandleUpdate replaces a Variant.
+// @Summary replaces a Variant of a Product.
+// @Description This endpoint replaces the SKU, price, stock and options of a variant.
+// @Tags v1/Product
+// @Security JWTToken
+// @Param productId path string true "the product id"
+// @Param variantId path string true "the variant id"
+// @Param Variant body variant.VariantPayload true "the variant's new values"
+// @Produce json
+// @Success 200 {object} response.Base{data=variant.VariantResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 409 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{productId}/variants/{variantId} [put]
+func (h *VariantHandler) HandleUpdate(w http.ResponseWriter, r *http.Request) {
+	productId, variantId, ok := h.pathIds(w, r)
+	if !ok {
+		return
+	}
+	payload, ok := h.decodePayload(w, r)
+	if !ok {
+		return
+	}
+	orgId, callerId, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.Update(payload, orgId, productId, variantId, callerId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+// HandleDelete deletes a Variant.
+// @Summary soft deletes a Variant of a Product.
+// @Description This endpoint takes a variant off sale. Past orders keep referring to it.
+// @Tags v1/Product
+// @Security JWTToken
+// @Param productId path string true "the product id"
+// @Param variantId path string true "the variant id"
+// @Produce json
+// @Success 200 {object} response.Base{data=variant.VariantResponseFormat}
+// @Failure 400 {object} response.Base
+// @Failure 401 {object} response.Base
+// @Failure 404 {object} response.Base
+// @Failure 500 {object} response.Base
+// @Router /v1/products/{productId}/variants/{variantId} [delete]
+func (h *VariantHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	productId, variantId, ok := h.pathIds(w, r)
+	if !ok {
+		return
+	}
+	orgId, callerId, ok := caller(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.Service.Delete(orgId, productId, variantId, callerId)
+	if err != nil {
+		response.WithError(w, err)
+		return
+	}
+	response.WithJSON(w, http.StatusOK, res)
+}
+
+func (h *VariantHandler) pathIds(w http.ResponseWriter, r *http.Request) (productId, variantId uuid.UUID, ok bool) {
+	productId, err := uuid.FromString(chi.URLParam(r, "productId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	variantId, err = uuid.FromString(chi.URLParam(r, "variantId"))
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	ok = true
+	return
+}
+
+func (h *VariantHandler) decodePayload(w http.ResponseWriter, r *http.Request) (payload variant.VariantPayload, ok bool) {
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	err = shared.GetValidator().Struct(payload)
+	if err != nil {
+		response.WithError(w, failure.BadRequest(err))
+		return
+	}
+	ok = true
+	return
+}
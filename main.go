@@ -13,6 +13,9 @@ var config *configs.Config
 // @securityDefinitions.apikey JWTToken
 // @in header
 // @name Authorization
+// @securityDefinitions.apikey SCIMToken
+// @in header
+// @name Authorization
 func main() {
 	// Initialize logger
 	logger.InitLogger()
@@ -26,6 +29,10 @@ func main() {
 	// Wire everything up
 	http := InitializeService()
 
+	// Start background jobs
+	worker := InitializeWorker()
+	worker.Start()
+
 	// consumers := InitializeEvent()
 
 	// Start consumers
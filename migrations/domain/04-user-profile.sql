@@ -0,0 +1,9 @@
+ALTER TABLE `user`
+  ADD COLUMN `phone_number` varchar(20) NULL DEFAULT NULL AFTER `cart_id`,
+  ADD COLUMN `locale` varchar(10) NOT NULL DEFAULT 'en' AFTER `phone_number`,
+  ADD COLUMN `timezone` varchar(64) NOT NULL DEFAULT 'UTC' AFTER `locale`,
+  ADD COLUMN `email_verified_at` timestamp NULL DEFAULT NULL AFTER `timezone`,
+  ADD COLUMN `email_verification_token` char(64) NULL DEFAULT NULL AFTER `email_verified_at`;
+
+-- accounts created before verification existed are trusted
+UPDATE `user` SET `email_verified_at` = `created_at`;
@@ -0,0 +1,31 @@
+CREATE TABLE `address` (
+  `id` char(36) PRIMARY KEY,
+  `user_id` char(36) NOT NULL,
+  `label` varchar(50) NOT NULL,
+  `recipient_name` varchar(255) NOT NULL,
+  `phone_number` varchar(20) NOT NULL,
+  `line1` varchar(255) NOT NULL,
+  `line2` varchar(255) NULL DEFAULT NULL,
+  `city` varchar(100) NOT NULL,
+  `province` varchar(100) NOT NULL,
+  `postal_code` varchar(10) NOT NULL,
+  `country_code` char(2) NOT NULL,
+  `is_default_shipping` boolean NOT NULL DEFAULT FALSE,
+  `is_default_billing` boolean NOT NULL DEFAULT FALSE,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `deleted_at` timestamp NULL DEFAULT NULL,
+  `created_by` char(36) NOT NULL,
+  `updated_by` char(36) NOT NULL,
+  `deleted_by` char(36) NULL DEFAULT NULL,
+  INDEX `idx_address_user` (`user_id`, `deleted_at`)
+);
+
+ALTER TABLE `address` ADD FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE;
+
+ALTER TABLE `atc_order`
+  ADD COLUMN `shipping_address_id` char(36) NULL DEFAULT NULL AFTER `status`,
+  ADD COLUMN `billing_address_id` char(36) NULL DEFAULT NULL AFTER `shipping_address_id`;
+
+ALTER TABLE `atc_order` ADD FOREIGN KEY (`shipping_address_id`) REFERENCES `address` (`id`);
+ALTER TABLE `atc_order` ADD FOREIGN KEY (`billing_address_id`) REFERENCES `address` (`id`);
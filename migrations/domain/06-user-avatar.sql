@@ -0,0 +1,5 @@
+ALTER TABLE `user`
+  ADD COLUMN `avatar_key` varchar(255) NULL DEFAULT NULL AFTER `email_verification_token`,
+  ADD COLUMN `avatar_thumbnail_key` varchar(255) NULL DEFAULT NULL AFTER `avatar_key`,
+  ADD COLUMN `avatar_url` varchar(1000) NULL DEFAULT NULL AFTER `avatar_thumbnail_key`,
+  ADD COLUMN `avatar_thumbnail_url` varchar(1000) NULL DEFAULT NULL AFTER `avatar_url`;
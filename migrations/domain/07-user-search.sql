@@ -0,0 +1,5 @@
+ALTER TABLE `user`
+  ADD INDEX `idx_user_role` (`role`),
+  ADD INDEX `idx_user_created_at` (`created_at`),
+  ADD INDEX `idx_user_deleted_at` (`deleted_at`),
+  ADD INDEX `idx_user_email_verified_at` (`email_verified_at`);
@@ -0,0 +1,16 @@
+CREATE TABLE `erasure_request` (
+  `id` char(36) PRIMARY KEY,
+  `user_id` char(36) NOT NULL,
+  `status` varchar(20) NOT NULL,
+  `scheduled_for` timestamp NOT NULL,
+  `processed_at` timestamp NULL DEFAULT NULL,
+  `error` text NULL DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `created_by` char(36) NOT NULL,
+  `updated_by` char(36) NOT NULL,
+  INDEX `idx_erasure_request_user` (`user_id`, `status`),
+  INDEX `idx_erasure_request_due` (`status`, `scheduled_for`)
+);
+
+ALTER TABLE `erasure_request` ADD FOREIGN KEY (`user_id`) REFERENCES `user` (`id`);
@@ -0,0 +1,56 @@
+CREATE TABLE `organization` (
+  `id` char(36) PRIMARY KEY,
+  `name` varchar(255) NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `deleted_at` timestamp NULL DEFAULT NULL,
+  `created_by` char(36) NOT NULL,
+  `updated_by` char(36) NOT NULL,
+  `deleted_by` char(36) NULL DEFAULT NULL
+);
+
+-- Existing data is moved into a default organization.
+INSERT INTO `organization` (`id`, `name`, `created_by`, `updated_by`)
+VALUES ('00000000-0000-0000-0000-000000000001', 'Default', '00000000-0000-0000-0000-000000000000', '00000000-0000-0000-0000-000000000000');
+
+CREATE TABLE `organization_member` (
+  `organization_id` char(36) NOT NULL,
+  `user_id` char(36) NOT NULL,
+  `role` ENUM ('trainee', 'admin') NOT NULL,
+  `cart_id` char(36) NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `created_by` char(36) NOT NULL,
+  `updated_by` char(36) NOT NULL,
+  PRIMARY KEY (`organization_id`, `user_id`),
+  INDEX `idx_organization_member_user` (`user_id`)
+);
+
+ALTER TABLE `user` ADD COLUMN `organization_id` char(36) NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' AFTER `cart_id`;
+ALTER TABLE `cart` ADD COLUMN `organization_id` char(36) NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' AFTER `user_id`;
+ALTER TABLE `product` ADD COLUMN `organization_id` char(36) NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' AFTER `id`;
+ALTER TABLE `atc_order` ADD COLUMN `organization_id` char(36) NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' AFTER `id`;
+
+INSERT INTO `organization_member` (`organization_id`, `user_id`, `role`, `cart_id`, `created_at`, `updated_at`, `created_by`, `updated_by`)
+SELECT `organization_id`, `id`, `role`, `cart_id`, `created_at`, `updated_at`, `created_by`, `updated_by` FROM `user`;
+
+ALTER TABLE `organization_member` ADD FOREIGN KEY (`organization_id`) REFERENCES `organization` (`id`) ON DELETE CASCADE;
+ALTER TABLE `organization_member` ADD FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE;
+ALTER TABLE `organization_member` ADD FOREIGN KEY (`cart_id`) REFERENCES `cart` (`id`);
+ALTER TABLE `user` ADD FOREIGN KEY (`organization_id`) REFERENCES `organization` (`id`);
+ALTER TABLE `cart` ADD FOREIGN KEY (`organization_id`) REFERENCES `organization` (`id`);
+ALTER TABLE `product` ADD FOREIGN KEY (`organization_id`) REFERENCES `organization` (`id`);
+ALTER TABLE `atc_order` ADD FOREIGN KEY (`organization_id`) REFERENCES `organization` (`id`);
+
+DROP TRIGGER IF EXISTS `create_cart_on_user_insert`;
+
+DELIMITER |
+
+CREATE TRIGGER `create_cart_on_user_insert` AFTER INSERT ON `user`
+FOR EACH ROW
+BEGIN
+  INSERT INTO `cart` (`id`, `user_id`, `organization_id`, `created_at`, `updated_at`, `deleted_at`, `created_by`, `updated_by`, `deleted_by`)
+  VALUES (NEW.cart_id, NEW.id, NEW.organization_id, NEW.created_at, NEW.updated_at, NEW.deleted_at, NEW.id, NEW.id, NEW.deleted_by);
+END;
+|
+DELIMITER ;
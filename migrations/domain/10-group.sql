@@ -0,0 +1,31 @@
+CREATE TABLE `user_group` (
+  `id` char(36) PRIMARY KEY,
+  `organization_id` char(36) NOT NULL,
+  `name` varchar(100) NOT NULL,
+  `description` varchar(255) NULL DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `created_by` char(36) NOT NULL,
+  `updated_by` char(36) NOT NULL,
+  UNIQUE KEY `uq_user_group_name` (`organization_id`, `name`)
+);
+
+CREATE TABLE `user_group_permission` (
+  `group_id` char(36) NOT NULL,
+  `permission` varchar(50) NOT NULL,
+  PRIMARY KEY (`group_id`, `permission`)
+);
+
+CREATE TABLE `user_group_member` (
+  `group_id` char(36) NOT NULL,
+  `user_id` char(36) NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `created_by` char(36) NOT NULL,
+  PRIMARY KEY (`group_id`, `user_id`),
+  INDEX `idx_user_group_member_user` (`user_id`)
+);
+
+ALTER TABLE `user_group` ADD FOREIGN KEY (`organization_id`) REFERENCES `organization` (`id`) ON DELETE CASCADE;
+ALTER TABLE `user_group_permission` ADD FOREIGN KEY (`group_id`) REFERENCES `user_group` (`id`) ON DELETE CASCADE;
+ALTER TABLE `user_group_member` ADD FOREIGN KEY (`group_id`) REFERENCES `user_group` (`id`) ON DELETE CASCADE;
+ALTER TABLE `user_group_member` ADD FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE;
@@ -0,0 +1,10 @@
+CREATE TABLE `user_preference` (
+  `user_id` char(36) NOT NULL,
+  `preference_key` varchar(100) NOT NULL,
+  `value` text NOT NULL,
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `updated_by` char(36) NOT NULL,
+  PRIMARY KEY (`user_id`, `preference_key`)
+);
+
+ALTER TABLE `user_preference` ADD FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE;
@@ -0,0 +1,28 @@
+CREATE TABLE `category` (
+  `id` char(36) PRIMARY KEY,
+  `organization_id` char(36) NOT NULL,
+  `parent_id` char(36) NULL DEFAULT NULL,
+  `name` varchar(100) NOT NULL,
+  `slug` varchar(100) NOT NULL,
+  `position` int NOT NULL DEFAULT 0,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `created_by` char(36) NOT NULL,
+  `updated_by` char(36) NOT NULL,
+  UNIQUE KEY `uq_category_slug` (`organization_id`, `slug`),
+  INDEX `idx_category_parent` (`parent_id`)
+);
+
+CREATE TABLE `product_category` (
+  `product_id` char(36) NOT NULL,
+  `category_id` char(36) NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `created_by` char(36) NOT NULL,
+  PRIMARY KEY (`product_id`, `category_id`),
+  INDEX `idx_product_category_category` (`category_id`)
+);
+
+ALTER TABLE `category` ADD FOREIGN KEY (`organization_id`) REFERENCES `organization` (`id`) ON DELETE CASCADE;
+ALTER TABLE `category` ADD FOREIGN KEY (`parent_id`) REFERENCES `category` (`id`);
+ALTER TABLE `product_category` ADD FOREIGN KEY (`product_id`) REFERENCES `product` (`id`) ON DELETE CASCADE;
+ALTER TABLE `product_category` ADD FOREIGN KEY (`category_id`) REFERENCES `category` (`id`) ON DELETE CASCADE;
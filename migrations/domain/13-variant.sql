@@ -0,0 +1,110 @@
+CREATE TABLE `product_option` (
+  `id` char(36) PRIMARY KEY,
+  `product_id` char(36) NOT NULL,
+  `name` varchar(50) NOT NULL,
+  `position` int NOT NULL DEFAULT 0,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `created_by` char(36) NOT NULL,
+  UNIQUE KEY `uq_product_option_name` (`product_id`, `name`)
+);
+
+CREATE TABLE `product_option_value` (
+  `option_id` char(36) NOT NULL,
+  `value` varchar(50) NOT NULL,
+  `position` int NOT NULL DEFAULT 0,
+  PRIMARY KEY (`option_id`, `value`)
+);
+
+CREATE TABLE `product_variant` (
+  `id` char(36) PRIMARY KEY,
+  `organization_id` char(36) NOT NULL,
+  `product_id` char(36) NOT NULL,
+  `sku` varchar(64) NOT NULL,
+  `price` decimal(10,2) NOT NULL,
+  `stock` int NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `deleted_at` timestamp NULL DEFAULT NULL,
+  `created_by` char(36) NOT NULL,
+  `updated_by` char(36) NOT NULL,
+  `deleted_by` char(36) NULL DEFAULT NULL,
+  UNIQUE KEY `uq_product_variant_sku` (`organization_id`, `sku`),
+  INDEX `idx_product_variant_product` (`product_id`)
+);
+
+CREATE TABLE `product_variant_option` (
+  `variant_id` char(36) NOT NULL,
+  `name` varchar(50) NOT NULL,
+  `value` varchar(50) NOT NULL,
+  PRIMARY KEY (`variant_id`, `name`)
+);
+
+ALTER TABLE `product_option` ADD FOREIGN KEY (`product_id`) REFERENCES `product` (`id`) ON DELETE CASCADE;
+ALTER TABLE `product_option_value` ADD FOREIGN KEY (`option_id`) REFERENCES `product_option` (`id`) ON DELETE CASCADE;
+ALTER TABLE `product_variant` ADD FOREIGN KEY (`organization_id`) REFERENCES `organization` (`id`);
+ALTER TABLE `product_variant` ADD FOREIGN KEY (`product_id`) REFERENCES `product` (`id`) ON DELETE CASCADE;
+ALTER TABLE `product_variant_option` ADD FOREIGN KEY (`variant_id`) REFERENCES `product_variant` (`id`) ON DELETE CASCADE;
+
+-- Items without a variant are products that are sold on their own.
+ALTER TABLE `cart_item` ADD COLUMN `variant_id` char(36) NULL DEFAULT NULL AFTER `product_id`;
+ALTER TABLE `cart_item` ADD FOREIGN KEY (`variant_id`) REFERENCES `product_variant` (`id`) ON DELETE CASCADE;
+ALTER TABLE `order_item` ADD COLUMN `variant_id` char(36) NULL DEFAULT NULL AFTER `product_id`;
+ALTER TABLE `order_item` ADD FOREIGN KEY (`variant_id`) REFERENCES `product_variant` (`id`);
+
+-- Stock and cart prices come from the variant when an item has one.
+DROP TRIGGER IF EXISTS `update_cart_items_on_product_price_update`;
+DROP TRIGGER IF EXISTS `update_stock_product_on_insert`;
+DROP TRIGGER IF EXISTS `update_stock_product_on_delete`;
+DROP TRIGGER IF EXISTS `before_order_item_update`;
+
+DELIMITER |
+
+CREATE TRIGGER `update_cart_items_on_product_price_update` AFTER UPDATE ON `product`
+FOR EACH ROW
+BEGIN
+  UPDATE `cart_item`
+  SET `price` = NEW.price * `quantity`
+  WHERE `product_id` = NEW.id AND `variant_id` IS NULL;
+END;
+
+CREATE TRIGGER `update_cart_items_on_variant_price_update` AFTER UPDATE ON `product_variant`
+FOR EACH ROW
+BEGIN
+  UPDATE `cart_item`
+  SET `price` = NEW.price * `quantity`
+  WHERE `variant_id` = NEW.id;
+END;
+
+CREATE TRIGGER `update_stock_product_on_insert` AFTER INSERT ON `order_item`
+FOR EACH ROW
+BEGIN
+  IF NEW.variant_id IS NULL THEN
+    UPDATE `product` SET `stock` = `stock` - NEW.quantity WHERE `id` = NEW.product_id;
+  ELSE
+    UPDATE `product_variant` SET `stock` = `stock` - NEW.quantity WHERE `id` = NEW.variant_id;
+  END IF;
+END;
+
+CREATE TRIGGER `update_stock_product_on_delete` AFTER DELETE ON `order_item`
+FOR EACH ROW
+BEGIN
+  IF OLD.variant_id IS NULL THEN
+    UPDATE `product` SET `stock` = `stock` + OLD.quantity WHERE `id` = OLD.product_id;
+  ELSE
+    UPDATE `product_variant` SET `stock` = `stock` + OLD.quantity WHERE `id` = OLD.variant_id;
+  END IF;
+END;
+
+CREATE TRIGGER `before_order_item_update` BEFORE UPDATE ON `order_item`
+FOR EACH ROW
+BEGIN
+  IF NEW.deleted_at IS NOT NULL AND NEW.deleted_by IS NOT NULL THEN
+    IF NEW.variant_id IS NULL THEN
+      UPDATE `product` SET `stock` = `stock` + NEW.quantity WHERE `id` = NEW.product_id;
+    ELSE
+      UPDATE `product_variant` SET `stock` = `stock` + NEW.quantity WHERE `id` = NEW.variant_id;
+    END IF;
+  END IF;
+END;
+|
+DELIMITER ;
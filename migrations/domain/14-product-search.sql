@@ -0,0 +1,4 @@
+ALTER TABLE `product` ADD COLUMN `description` text NULL DEFAULT NULL AFTER `name`;
+
+-- Searched in boolean mode by ProductSearchMySQL, replacing LIKE on the name.
+ALTER TABLE `product` ADD FULLTEXT INDEX `ft_product_search` (`name`, `description`);
@@ -0,0 +1,11 @@
+CREATE TABLE `product_attribute` (
+  `product_id` char(36) NOT NULL,
+  `name` varchar(50) NOT NULL,
+  `value` varchar(100) NOT NULL,
+  PRIMARY KEY (`product_id`, `name`),
+  INDEX `idx_product_attribute_value` (`name`, `value`)
+);
+
+ALTER TABLE `product_attribute` ADD FOREIGN KEY (`product_id`) REFERENCES `product` (`id`) ON DELETE CASCADE;
+
+CREATE INDEX `idx_product_price` ON `product` (`organization_id`, `price`);
@@ -0,0 +1,33 @@
+CREATE TABLE `product_image` (
+  `id` char(36) PRIMARY KEY,
+  `organization_id` char(36) NOT NULL,
+  `product_id` char(36) NOT NULL,
+  `position` int NOT NULL DEFAULT 0,
+  `is_primary` tinyint(1) NOT NULL DEFAULT 0,
+  `alt_text` varchar(255) NULL DEFAULT NULL,
+  `storage_key` varchar(255) NOT NULL,
+  `url` varchar(1024) NOT NULL,
+  `content_type` varchar(50) NOT NULL,
+  `width` int NOT NULL,
+  `height` int NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `created_by` char(36) NOT NULL,
+  `updated_by` char(36) NOT NULL,
+  INDEX `idx_product_image_product` (`product_id`, `position`)
+);
+
+CREATE TABLE `product_image_rendition` (
+  `image_id` char(36) NOT NULL,
+  `size` varchar(20) NOT NULL,
+  `storage_key` varchar(255) NOT NULL,
+  `url` varchar(1024) NOT NULL,
+  `content_type` varchar(50) NOT NULL,
+  `width` int NOT NULL,
+  `height` int NOT NULL,
+  PRIMARY KEY (`image_id`, `size`)
+);
+
+ALTER TABLE `product_image` ADD FOREIGN KEY (`organization_id`) REFERENCES `organization` (`id`) ON DELETE CASCADE;
+ALTER TABLE `product_image` ADD FOREIGN KEY (`product_id`) REFERENCES `product` (`id`) ON DELETE CASCADE;
+ALTER TABLE `product_image_rendition` ADD FOREIGN KEY (`image_id`) REFERENCES `product_image` (`id`) ON DELETE CASCADE;
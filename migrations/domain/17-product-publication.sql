@@ -0,0 +1,15 @@
+ALTER TABLE `product`
+  ADD COLUMN `slug` varchar(255) NULL DEFAULT NULL AFTER `price`,
+  ADD COLUMN `seo_title` varchar(255) NULL DEFAULT NULL AFTER `slug`,
+  ADD COLUMN `meta_description` varchar(500) NULL DEFAULT NULL AFTER `seo_title`,
+  ADD COLUMN `status` varchar(20) NOT NULL DEFAULT 'published' AFTER `meta_description`,
+  ADD COLUMN `publish_at` timestamp NULL DEFAULT NULL AFTER `status`;
+
+-- existing products stay live, with their id as slug until one is chosen
+UPDATE `product` SET `slug` = `id`, `publish_at` = `created_at`;
+
+ALTER TABLE `product`
+  MODIFY `slug` varchar(255) NOT NULL,
+  MODIFY `status` varchar(20) NOT NULL DEFAULT 'draft',
+  ADD UNIQUE KEY `uq_product_slug` (`organization_id`, `slug`),
+  ADD INDEX `idx_product_publication` (`organization_id`, `status`, `publish_at`);
@@ -0,0 +1,29 @@
+ALTER TABLE `product`
+  ADD COLUMN `sku` varchar(64) NULL DEFAULT NULL AFTER `price`,
+  ADD UNIQUE KEY `uq_product_sku` (`organization_id`, `sku`);
+
+CREATE TABLE `product_import` (
+  `id` char(36) PRIMARY KEY,
+  `organization_id` char(36) NOT NULL,
+  `status` varchar(20) NOT NULL,
+  `format` varchar(10) NOT NULL,
+  `dry_run` tinyint(1) NOT NULL DEFAULT 0,
+  `data` mediumblob NULL DEFAULT NULL,
+  `total` int NOT NULL DEFAULT 0,
+  `processed` int NOT NULL DEFAULT 0,
+  `valid` int NOT NULL DEFAULT 0,
+  `created` int NOT NULL DEFAULT 0,
+  `updated` int NOT NULL DEFAULT 0,
+  `failed` int NOT NULL DEFAULT 0,
+  `errors` json NOT NULL,
+  `error` text NULL DEFAULT NULL,
+  `started_at` timestamp NULL DEFAULT NULL,
+  `finished_at` timestamp NULL DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `created_by` char(36) NOT NULL,
+  `updated_by` char(36) NOT NULL,
+  INDEX `idx_product_import_status` (`status`, `created_at`)
+);
+
+ALTER TABLE `product_import` ADD FOREIGN KEY (`organization_id`) REFERENCES `organization` (`id`) ON DELETE CASCADE;
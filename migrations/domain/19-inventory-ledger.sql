@@ -0,0 +1,35 @@
+-- Every change of stock is recorded in the ledger by the application, so the
+-- triggers that changed stock behind its back are gone.
+DROP TRIGGER IF EXISTS `update_stock_product_on_insert`;
+DROP TRIGGER IF EXISTS `update_stock_product_on_delete`;
+DROP TRIGGER IF EXISTS `before_order_item_update`;
+DROP TRIGGER IF EXISTS `after_order_cancel`;
+
+CREATE TABLE `stock_movement` (
+  `id` char(36) PRIMARY KEY,
+  `seq` bigint NOT NULL AUTO_INCREMENT UNIQUE,
+  `organization_id` char(36) NOT NULL,
+  `product_id` char(36) NOT NULL,
+  `variant_id` char(36) NULL DEFAULT NULL,
+  `quantity` int NOT NULL,
+  `stock_after` int NOT NULL,
+  `reason` varchar(20) NOT NULL,
+  `reference` varchar(255) NULL DEFAULT NULL,
+  `note` varchar(500) NULL DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `created_by` char(36) NOT NULL,
+  INDEX `idx_stock_movement_product` (`product_id`, `created_at`)
+);
+
+ALTER TABLE `stock_movement` ADD FOREIGN KEY (`organization_id`) REFERENCES `organization` (`id`) ON DELETE CASCADE;
+ALTER TABLE `stock_movement` ADD FOREIGN KEY (`product_id`) REFERENCES `product` (`id`) ON DELETE CASCADE;
+ALTER TABLE `stock_movement` ADD FOREIGN KEY (`variant_id`) REFERENCES `product_variant` (`id`) ON DELETE CASCADE;
+
+-- The stock on hand when the ledger starts.
+INSERT INTO `stock_movement` (`id`, `organization_id`, `product_id`, `quantity`, `stock_after`, `reason`, `note`, `created_by`)
+SELECT UUID(), `organization_id`, `id`, `stock`, `stock`, 'adjustment', 'opening balance', `updated_by`
+FROM `product` WHERE `stock` <> 0;
+
+INSERT INTO `stock_movement` (`id`, `organization_id`, `product_id`, `variant_id`, `quantity`, `stock_after`, `reason`, `note`, `created_by`)
+SELECT UUID(), `organization_id`, `product_id`, `id`, `stock`, `stock`, 'adjustment', 'opening balance', `updated_by`
+FROM `product_variant` WHERE `stock` <> 0;
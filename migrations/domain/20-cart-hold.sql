@@ -0,0 +1,4 @@
+ALTER TABLE `cart_item`
+  ADD COLUMN `hold_status` varchar(10) NULL DEFAULT NULL AFTER `price`,
+  ADD COLUMN `held_until` timestamp NULL DEFAULT NULL AFTER `hold_status`,
+  ADD INDEX `idx_cart_item_hold` (`product_id`, `hold_status`, `held_until`);
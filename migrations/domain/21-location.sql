@@ -0,0 +1,63 @@
+CREATE TABLE `location` (
+  `id` char(36) PRIMARY KEY,
+  `organization_id` char(36) NOT NULL,
+  `code` varchar(50) NOT NULL,
+  `name` varchar(255) NOT NULL,
+  `priority` int NOT NULL DEFAULT 0,
+  `city` varchar(100) NULL DEFAULT NULL,
+  `province` varchar(100) NULL DEFAULT NULL,
+  `postal_code` varchar(20) NULL DEFAULT NULL,
+  `country_code` char(2) NULL DEFAULT NULL,
+  `is_default` tinyint(1) NOT NULL DEFAULT 0,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `deleted_at` timestamp NULL DEFAULT NULL,
+  `created_by` char(36) NOT NULL,
+  `updated_by` char(36) NOT NULL,
+  `deleted_by` char(36) NULL DEFAULT NULL,
+  UNIQUE KEY `uq_location_code` (`organization_id`, `code`)
+);
+
+ALTER TABLE `location` ADD FOREIGN KEY (`organization_id`) REFERENCES `organization` (`id`) ON DELETE CASCADE;
+
+-- The stock on hand so far is at each organization's default location.
+INSERT INTO `location` (`id`, `organization_id`, `code`, `name`, `is_default`, `created_by`, `updated_by`)
+SELECT UUID(), `id`, 'main', 'Main', 1, '00000000-0000-0000-0000-000000000000', '00000000-0000-0000-0000-000000000000'
+FROM `organization`;
+
+-- item_key stands in for variant_id in the unique key, which would not
+-- catch duplicates of products without variants as NULLs never collide.
+CREATE TABLE `location_stock` (
+  `location_id` char(36) NOT NULL,
+  `organization_id` char(36) NOT NULL,
+  `product_id` char(36) NOT NULL,
+  `variant_id` char(36) NULL DEFAULT NULL,
+  `item_key` char(36) AS (IFNULL(`variant_id`, '')) STORED,
+  `stock` int NOT NULL DEFAULT 0,
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  UNIQUE KEY `uq_location_stock` (`location_id`, `product_id`, `item_key`),
+  INDEX `idx_location_stock_product` (`product_id`, `item_key`)
+);
+
+ALTER TABLE `location_stock` ADD FOREIGN KEY (`location_id`) REFERENCES `location` (`id`) ON DELETE CASCADE;
+ALTER TABLE `location_stock` ADD FOREIGN KEY (`product_id`) REFERENCES `product` (`id`) ON DELETE CASCADE;
+ALTER TABLE `location_stock` ADD FOREIGN KEY (`variant_id`) REFERENCES `product_variant` (`id`) ON DELETE CASCADE;
+
+INSERT INTO `location_stock` (`location_id`, `organization_id`, `product_id`, `stock`)
+SELECT l.`id`, p.`organization_id`, p.`id`, p.`stock`
+FROM `product` p JOIN `location` l ON l.`organization_id` = p.`organization_id` AND l.`is_default` = 1
+WHERE p.`stock` <> 0;
+
+INSERT INTO `location_stock` (`location_id`, `organization_id`, `product_id`, `variant_id`, `stock`)
+SELECT l.`id`, v.`organization_id`, v.`product_id`, v.`id`, v.`stock`
+FROM `product_variant` v JOIN `location` l ON l.`organization_id` = v.`organization_id` AND l.`is_default` = 1
+WHERE v.`stock` <> 0;
+
+ALTER TABLE `stock_movement` ADD COLUMN `location_id` char(36) NULL DEFAULT NULL AFTER `variant_id`;
+
+UPDATE `stock_movement` m JOIN `location` l ON l.`organization_id` = m.`organization_id` AND l.`is_default` = 1
+SET m.`location_id` = l.`id`;
+
+ALTER TABLE `stock_movement` MODIFY `location_id` char(36) NOT NULL;
+ALTER TABLE `stock_movement` ADD FOREIGN KEY (`location_id`) REFERENCES `location` (`id`);
+ALTER TABLE `stock_movement` ADD INDEX `idx_stock_movement_reference` (`organization_id`, `reference`);
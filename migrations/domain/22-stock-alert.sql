@@ -0,0 +1,48 @@
+CREATE TABLE `stock_threshold` (
+  `product_id` char(36) PRIMARY KEY,
+  `organization_id` char(36) NOT NULL,
+  `reorder_level` int NOT NULL DEFAULT 0,
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `updated_by` char(36) NOT NULL
+);
+
+ALTER TABLE `stock_threshold` ADD FOREIGN KEY (`product_id`) REFERENCES `product` (`id`) ON DELETE CASCADE;
+
+-- Events are written with the movement that raised them and published by
+-- the worker; published_at is set once they have been.
+CREATE TABLE `stock_event` (
+  `id` char(36) PRIMARY KEY,
+  `organization_id` char(36) NOT NULL,
+  `product_id` char(36) NOT NULL,
+  `variant_id` char(36) NULL DEFAULT NULL,
+  `item` varchar(300) NOT NULL,
+  `topic` varchar(50) NOT NULL,
+  `reorder_level` int NOT NULL,
+  `stock_before` int NOT NULL,
+  `stock_after` int NOT NULL,
+  `movement_id` char(36) NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `published_at` timestamp NULL DEFAULT NULL,
+  INDEX `idx_stock_event_unpublished` (`published_at`, `created_at`)
+);
+
+ALTER TABLE `stock_event` ADD FOREIGN KEY (`product_id`) REFERENCES `product` (`id`) ON DELETE CASCADE;
+ALTER TABLE `stock_event` ADD FOREIGN KEY (`movement_id`) REFERENCES `stock_movement` (`id`) ON DELETE CASCADE;
+
+-- item_key stands in for variant_id in the unique key, see location_stock.
+CREATE TABLE `stock_subscription` (
+  `id` char(36) PRIMARY KEY,
+  `organization_id` char(36) NOT NULL,
+  `product_id` char(36) NOT NULL,
+  `variant_id` char(36) NULL DEFAULT NULL,
+  `item_key` char(36) AS (IFNULL(`variant_id`, '')) STORED,
+  `user_id` char(36) NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `notified_at` timestamp NULL DEFAULT NULL,
+  UNIQUE KEY `uq_stock_subscription` (`product_id`, `item_key`, `user_id`),
+  INDEX `idx_stock_subscription_user` (`user_id`)
+);
+
+ALTER TABLE `stock_subscription` ADD FOREIGN KEY (`product_id`) REFERENCES `product` (`id`) ON DELETE CASCADE;
+ALTER TABLE `stock_subscription` ADD FOREIGN KEY (`variant_id`) REFERENCES `product_variant` (`id`) ON DELETE CASCADE;
+ALTER TABLE `stock_subscription` ADD FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE;
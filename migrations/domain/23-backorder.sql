@@ -0,0 +1,11 @@
+ALTER TABLE `product`
+  ADD COLUMN `order_policy` varchar(10) NOT NULL DEFAULT 'stock',
+  ADD COLUMN `expected_at` timestamp NULL DEFAULT NULL,
+  ADD COLUMN `backorder_limit` int NULL DEFAULT NULL;
+
+-- Items ordered beyond stock wait as backordered or preordered until the
+-- worker takes them out of stock.
+ALTER TABLE `order_item`
+  ADD COLUMN `status` varchar(12) NOT NULL DEFAULT 'allocated',
+  ADD COLUMN `expected_at` timestamp NULL DEFAULT NULL,
+  ADD INDEX `idx_order_item_waiting` (`product_id`, `status`);
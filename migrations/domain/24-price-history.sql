@@ -0,0 +1,58 @@
+-- The cart triggers from 13-variant.sql rewrote the price of every cart item of
+-- a product or variant on any update of its row, even one that only touched
+-- the name or the stock, and left no record of it. Cart items are now repriced
+-- from the price_history rows written for actual price changes.
+DROP TRIGGER IF EXISTS `update_cart_items_on_product_price_update`;
+DROP TRIGGER IF EXISTS `update_cart_items_on_variant_price_update`;
+
+ALTER TABLE `product` ADD COLUMN `compare_at_price` decimal(10,2) NULL DEFAULT NULL AFTER `price`;
+
+CREATE TABLE `price_schedule` (
+  `id` char(36) PRIMARY KEY,
+  `organization_id` char(36) NOT NULL,
+  `product_id` char(36) NOT NULL,
+  `price` decimal(10,2) NOT NULL,
+  `compare_at_price` decimal(10,2) NULL DEFAULT NULL,
+  `starts_at` timestamp NOT NULL,
+  `ends_at` timestamp NULL DEFAULT NULL,
+  `status` varchar(10) NOT NULL DEFAULT 'scheduled',
+  `previous_price` decimal(10,2) NULL DEFAULT NULL,
+  `previous_compare_at_price` decimal(10,2) NULL DEFAULT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `created_by` char(36) NOT NULL,
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `updated_by` char(36) NOT NULL,
+  INDEX `idx_price_schedule_due` (`status`, `starts_at`, `ends_at`)
+);
+
+ALTER TABLE `price_schedule` ADD FOREIGN KEY (`product_id`) REFERENCES `product` (`id`) ON DELETE CASCADE;
+
+-- Changes are written with the price they record; repriced_at is set once
+-- open carts have been brought to it.
+CREATE TABLE `price_change` (
+  `id` char(36) PRIMARY KEY,
+  `seq` bigint NOT NULL AUTO_INCREMENT UNIQUE,
+  `organization_id` char(36) NOT NULL,
+  `product_id` char(36) NOT NULL,
+  `variant_id` char(36) NULL DEFAULT NULL,
+  `price` decimal(10,2) NOT NULL,
+  `compare_at_price` decimal(10,2) NULL DEFAULT NULL,
+  `previous_price` decimal(10,2) NULL DEFAULT NULL,
+  `schedule_id` char(36) NULL DEFAULT NULL,
+  `changed_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `changed_by` char(36) NOT NULL,
+  `repriced_at` timestamp NULL DEFAULT NULL,
+  INDEX `idx_price_change_product` (`product_id`, `seq`),
+  INDEX `idx_price_change_unrepriced` (`repriced_at`, `seq`)
+);
+
+ALTER TABLE `price_change` ADD FOREIGN KEY (`product_id`) REFERENCES `product` (`id`) ON DELETE CASCADE;
+ALTER TABLE `price_change` ADD FOREIGN KEY (`variant_id`) REFERENCES `product_variant` (`id`) ON DELETE CASCADE;
+ALTER TABLE `price_change` ADD FOREIGN KEY (`schedule_id`) REFERENCES `price_schedule` (`id`);
+
+-- The history starts with the prices products and variants have now.
+INSERT INTO `price_change` (`id`, `organization_id`, `product_id`, `price`, `changed_at`, `changed_by`, `repriced_at`)
+SELECT UUID(), `organization_id`, `id`, `price`, `updated_at`, `updated_by`, CURRENT_TIMESTAMP FROM `product`;
+
+INSERT INTO `price_change` (`id`, `organization_id`, `product_id`, `variant_id`, `price`, `changed_at`, `changed_by`, `repriced_at`)
+SELECT UUID(), `organization_id`, `product_id`, `id`, `price`, `updated_at`, `updated_by`, CURRENT_TIMESTAMP FROM `product_variant`;
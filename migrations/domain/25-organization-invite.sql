@@ -0,0 +1,11 @@
+CREATE TABLE `organization_invite` (
+  `organization_id` char(36) NOT NULL,
+  `user_id` char(36) NOT NULL,
+  `role` ENUM ('trainee', 'admin') NOT NULL,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `created_by` char(36) NOT NULL,
+  PRIMARY KEY (`organization_id`, `user_id`),
+  INDEX `idx_organization_invite_user` (`user_id`),
+  FOREIGN KEY (`organization_id`) REFERENCES `organization` (`id`) ON DELETE CASCADE,
+  FOREIGN KEY (`user_id`) REFERENCES `user` (`id`) ON DELETE CASCADE
+);
@@ -0,0 +1,300 @@
+// Package bulk reads and writes records as CSV or newline delimited JSON.
+// Struct fields are mapped to CSV columns and JSON keys by their json tag.
+package bulk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/evermos/boilerplate-go/shared/failure"
+)
+
+type Format string
+
+const (
+	CSV    Format = "csv"
+	NDJSON Format = "ndjson"
+)
+
+// ParseFormat parses a format name, defaulting to CSV when it is empty.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "", "csv":
+		return CSV, nil
+	case "ndjson", "jsonl":
+		return NDJSON, nil
+	default:
+		return "", failure.BadRequestFromString(fmt.Sprintf("unsupported format %s, expected csv or ndjson", s))
+	}
+}
+
+func (f Format) ContentType() string {
+	if f == NDJSON {
+		return "application/x-ndjson"
+	}
+	return "text/csv"
+}
+
+// RecordError is returned by Decode when a single record is malformed.
+// Decoding can continue with the next record.
+type RecordError struct {
+	Row int
+	Err error
+}
+
+func (e *RecordError) Error() string {
+	return e.Err.Error()
+}
+
+// Decoder reads one record at a time.
+type Decoder struct {
+	format  Format
+	csv     *csv.Reader
+	lines   *bufio.Scanner
+	header  map[string]int
+	row     int
+	started bool
+}
+
+func NewDecoder(r io.Reader, format Format) *Decoder {
+	d := &Decoder{format: format}
+	if format == CSV {
+		d.csv = csv.NewReader(r)
+		d.csv.TrimLeadingSpace = true
+		d.csv.FieldsPerRecord = -1
+	} else {
+		d.lines = bufio.NewScanner(r)
+		d.lines.Buffer(make([]byte, 64*1024), 1024*1024)
+	}
+	return d
+}
+
+// Row returns the 1-based position of the last record read, not counting
+// the CSV header and blank NDJSON lines.
+func (d *Decoder) Row() int {
+	return d.row
+}
+
+// Decode reads the next record into v, which must be a pointer to a struct.
+// It returns io.EOF when there are no more records and a *RecordError when
+// only the current record is broken. Any other error means the input as a
+// whole cannot be read.
+func (d *Decoder) Decode(v interface{}) error {
+	if d.format == NDJSON {
+		return d.decodeJSON(v)
+	}
+	return d.decodeCSV(v)
+}
+
+func (d *Decoder) decodeJSON(v interface{}) error {
+	for d.lines.Scan() {
+		line := bytes.TrimSpace(d.lines.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		d.row++
+		if err := json.Unmarshal(line, v); err != nil {
+			return &RecordError{Row: d.row, Err: err}
+		}
+		return nil
+	}
+	if err := d.lines.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+func (d *Decoder) decodeCSV(v interface{}) error {
+	if !d.started {
+		d.started = true
+		header, err := d.csv.Read()
+		if err != nil {
+			return err
+		}
+		d.header = map[string]int{}
+		for i, name := range header {
+			d.header[strings.TrimSpace(name)] = i
+		}
+	}
+	record, err := d.csv.Read()
+	if err == io.EOF {
+		return err
+	}
+	d.row++
+	if _, ok := err.(*csv.ParseError); ok {
+		return &RecordError{Row: d.row, Err: err}
+	}
+	if err != nil {
+		return err
+	}
+	rv := reflect.ValueOf(v).Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		name := fieldName(rt.Field(i))
+		if name == "" {
+			continue
+		}
+		col, ok := d.header[name]
+		if !ok || col >= len(record) || record[col] == "" {
+			continue
+		}
+		if err := setField(rv.Field(i), record[col]); err != nil {
+			return &RecordError{Row: d.row, Err: fmt.Errorf("%s: %v", name, err)}
+		}
+	}
+	return nil
+}
+
+// Encoder writes records of a single struct type.
+type Encoder struct {
+	format  Format
+	w       io.Writer
+	csv     *csv.Writer
+	started bool
+}
+
+func NewEncoder(w io.Writer, format Format) *Encoder {
+	e := &Encoder{format: format, w: w}
+	if format == CSV {
+		e.csv = csv.NewWriter(w)
+	}
+	return e
+}
+
+// Encode writes v, a struct or pointer to struct. The CSV header is taken
+// from the first record.
+func (e *Encoder) Encode(v interface{}) error {
+	if e.format == NDJSON {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = e.w.Write(append(data, '\n'))
+		return err
+	}
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	rt := rv.Type()
+	if !e.started {
+		e.started = true
+		header := []string{}
+		for i := 0; i < rt.NumField(); i++ {
+			if name := fieldName(rt.Field(i)); name != "" {
+				header = append(header, name)
+			}
+		}
+		if err := e.csv.Write(header); err != nil {
+			return err
+		}
+	}
+	record := []string{}
+	for i := 0; i < rt.NumField(); i++ {
+		if fieldName(rt.Field(i)) != "" {
+			record = append(record, formatField(rv.Field(i)))
+		}
+	}
+	return e.csv.Write(record)
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (e *Encoder) Flush() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		return e.csv.Error()
+	}
+	return nil
+}
+
+func fieldName(f reflect.StructField) string {
+	if f.PkgPath != "" {
+		return ""
+	}
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+func setField(f reflect.Value, value string) error {
+	if f.Kind() == reflect.Ptr {
+		ptr := reflect.New(f.Type().Elem())
+		if err := setField(ptr.Elem(), value); err != nil {
+			return err
+		}
+		f.Set(ptr)
+		return nil
+	}
+	if u, ok := f.Addr().Interface().(interface{ UnmarshalText([]byte) error }); ok {
+		return u.UnmarshalText([]byte(value))
+	}
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		f.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		f.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported field type %s", f.Type())
+	}
+	return nil
+}
+
+func formatField(f reflect.Value) string {
+	if f.Kind() == reflect.Ptr {
+		if f.IsNil() {
+			return ""
+		}
+		f = f.Elem()
+	}
+	switch v := f.Interface().(type) {
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.UTC().Format(time.RFC3339)
+	case encoding.TextMarshaler:
+		if text, err := v.MarshalText(); err == nil {
+			return string(text)
+		}
+	case fmt.Stringer:
+		return v.String()
+	}
+	switch f.Kind() {
+	case reflect.String:
+		return f.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(f.Int(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(f.Float(), 'f', -1, 64)
+	case reflect.Bool:
+		return strconv.FormatBool(f.Bool())
+	}
+	return fmt.Sprint(f.Interface())
+}
@@ -0,0 +1,68 @@
+package bulk_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/evermos/boilerplate-go/shared/bulk"
+	"github.com/stretchr/testify/assert"
+)
+
+type row struct {
+	Name  string  `json:"name"`
+	Stock int     `json:"stock"`
+	Price float64 `json:"price"`
+}
+
+func decodeAll(t *testing.T, input string, format bulk.Format) (rows []row, errRows []int) {
+	decoder := bulk.NewDecoder(strings.NewReader(input), format)
+	for {
+		var r row
+		err := decoder.Decode(&r)
+		if err == io.EOF {
+			return
+		}
+		if _, ok := err.(*bulk.RecordError); ok {
+			errRows = append(errRows, decoder.Row())
+			continue
+		}
+		if !assert.NoError(t, err) {
+			return
+		}
+		rows = append(rows, r)
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	t.Run("CSV", func(t *testing.T) {
+		rows, errRows := decodeAll(t, "price,name,stock\n1.5,Shirt,3\nx,Hat,1\n2,Sock,\n", bulk.CSV)
+		assert.Equal(t, []row{{Name: "Shirt", Stock: 3, Price: 1.5}, {Name: "Sock", Price: 2}}, rows)
+		assert.Equal(t, []int{2}, errRows)
+	})
+
+	t.Run("NDJSON", func(t *testing.T) {
+		rows, errRows := decodeAll(t, "{\"name\":\"Shirt\",\"stock\":3}\n\nnot json\n{\"name\":\"Hat\"}\n", bulk.NDJSON)
+		assert.Equal(t, []row{{Name: "Shirt", Stock: 3}, {Name: "Hat"}}, rows)
+		assert.Equal(t, []int{2}, errRows)
+	})
+}
+
+func TestEncoder(t *testing.T) {
+	t.Run("CSV", func(t *testing.T) {
+		var buf bytes.Buffer
+		encoder := bulk.NewEncoder(&buf, bulk.CSV)
+		assert.NoError(t, encoder.Encode(row{Name: "Shirt, blue", Stock: 3, Price: 1.5}))
+		assert.NoError(t, encoder.Encode(&row{Name: "Hat"}))
+		assert.NoError(t, encoder.Flush())
+		assert.Equal(t, "name,stock,price\n\"Shirt, blue\",3,1.5\nHat,0,0\n", buf.String())
+	})
+
+	t.Run("NDJSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		encoder := bulk.NewEncoder(&buf, bulk.NDJSON)
+		assert.NoError(t, encoder.Encode(row{Name: "Hat"}))
+		assert.Equal(t, "{\"name\":\"Hat\",\"stock\":0,\"price\":0}\n", buf.String())
+	})
+}
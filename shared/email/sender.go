@@ -0,0 +1,23 @@
+package email
+
+import "github.com/rs/zerolog/log"
+
+// Sender delivers an email to a single recipient.
+type Sender interface {
+	Send(to, subject, body string) error
+}
+
+// LogSender is a Sender that writes the email to the log instead of
+// delivering it. It is used until a mail provider is configured.
+type LogSender struct{}
+
+// ProvideLogSender is the provider for LogSender.
+func ProvideLogSender() *LogSender {
+	return &LogSender{}
+}
+
+// Send logs the email.
+func (s *LogSender) Send(to, subject, body string) error {
+	log.Info().Str("to", to).Str("subject", subject).Msg(body)
+	return nil
+}
@@ -0,0 +1,155 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // register the GIF decoder
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"github.com/evermos/boilerplate-go/shared/failure"
+)
+
+const (
+	ContentTypeJPEG = "image/jpeg"
+	ContentTypePNG  = "image/png"
+	ContentTypeGIF  = "image/gif"
+)
+
+// maxPixels guards against decompression bombs, small files that decode into
+// huge images.
+const maxPixels = 40000000
+
+// extensions maps the accepted content types to a file extension.
+var extensions = map[string]string{
+	ContentTypeJPEG: "jpg",
+	ContentTypePNG:  "png",
+	ContentTypeGIF:  "gif",
+}
+
+// Image is an uploaded image that has been sniffed and decoded.
+type Image struct {
+	Data        []byte
+	ContentType string
+	Image       image.Image
+}
+
+// Extension returns the file extension matching the image's content type.
+func (i Image) Extension() string {
+	return extensions[i.ContentType]
+}
+
+// Decode sniffs the content type of data, rejecting anything but JPEG, PNG
+// and GIF, and decodes it. The client supplied content type is not trusted.
+func Decode(data []byte) (res Image, err error) {
+	contentType := http.DetectContentType(data)
+	if _, ok := extensions[contentType]; !ok {
+		err = failure.BadRequestFromString(fmt.Sprintf("unsupported image type %s", contentType))
+		return
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		err = failure.BadRequest(err)
+		return
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		err = failure.BadRequestFromString("image dimensions are too large")
+		return
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		err = failure.BadRequest(err)
+		return
+	}
+	res = Image{Data: data, ContentType: contentType, Image: img}
+	return
+}
+
+// Thumbnail crops the center square of img and scales it to size x size.
+func Thumbnail(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+	x0 := b.Min.X + (b.Dx()-side)/2
+	y0 := b.Min.Y + (b.Dy()-side)/2
+	return resize(img, image.Rect(x0, y0, x0+side, y0+side), size, size)
+}
+
+// Fit scales img down so it fits in a maxWidth x maxHeight box keeping its
+// aspect ratio. Images that already fit are returned unchanged.
+func Fit(img image.Image, maxWidth, maxHeight int) image.Image {
+	b := img.Bounds()
+	if b.Dx() <= maxWidth && b.Dy() <= maxHeight {
+		return img
+	}
+	width, height := maxWidth, b.Dy()*maxWidth/b.Dx()
+	if height > maxHeight {
+		width, height = b.Dx()*maxHeight/b.Dy(), maxHeight
+	}
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+	return resize(img, b, width, height)
+}
+
+// resize scales the src rectangle of img to width x height by averaging the
+// source pixels that fall into each destination pixel.
+func resize(img image.Image, src image.Rectangle, width, height int) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		sy0 := src.Min.Y + y*src.Dy()/height
+		sy1 := src.Min.Y + (y+1)*src.Dy()/height
+		if sy1 <= sy0 {
+			sy1 = sy0 + 1
+		}
+		for x := 0; x < width; x++ {
+			sx0 := src.Min.X + x*src.Dx()/width
+			sx1 := src.Min.X + (x+1)*src.Dx()/width
+			if sx1 <= sx0 {
+				sx1 = sx0 + 1
+			}
+			var r, g, b, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)})
+		}
+	}
+	return dst
+}
+
+// Encode encodes img in the given format. GIFs are re-encoded as PNG since
+// only the first frame is kept.
+func Encode(img image.Image, contentType string) (data []byte, resContentType string, err error) {
+	var buf bytes.Buffer
+	switch contentType {
+	case ContentTypeJPEG:
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+		resContentType = ContentTypeJPEG
+	case ContentTypePNG, ContentTypeGIF:
+		err = png.Encode(&buf, img)
+		resContentType = ContentTypePNG
+	default:
+		err = errors.New("unsupported image type " + contentType)
+	}
+	data = buf.Bytes()
+	return
+}
+
+// ExtensionOf returns the file extension for a content type.
+func ExtensionOf(contentType string) string {
+	return extensions[contentType]
+}
@@ -13,6 +13,7 @@ type Claims struct {
 	UserName string `json:"userName"`
 	Role     string `json:"role"`
 	CartId   string `json:"cartId"`
+	OrgId    string `json:"orgId"`
 	jwt.StandardClaims
 }
 
@@ -24,12 +25,15 @@ func NewJWT(secret string) *JWT {
 	return &JWT{secret: secret}
 }
 
-func (j *JWT) GenerateJwt(userId, userName, role, cartId string) (string, error) {
+// GenerateJwt issues a token for the user acting inside the organization
+// orgId, with the role and cart the user has there.
+func (j *JWT) GenerateJwt(userId, userName, role, cartId, orgId string) (string, error) {
 	claims := Claims{
 		UserId:   userId,
 		UserName: userName,
 		Role:     role,
 		CartId:   cartId,
+		OrgId:    orgId,
 		StandardClaims: jwt.StandardClaims{
 			ExpiresAt: time.Now().Add(time.Hour + 1).Unix(),
 			Issuer:    "Bootcamp-auth",
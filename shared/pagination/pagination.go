@@ -1,11 +1,13 @@
 package pagination
 
 import (
+	"fmt"
 	"math"
 	"net/http"
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/evermos/boilerplate-go/shared/failure"
 )
@@ -82,6 +84,74 @@ func GetPagination(r *http.Request) (pg *Pagination, err error) {
 	return
 }
 
+// GetTotalPagesFromCount returns the number of pages needed for total rows.
+func (p *Pagination) GetTotalPagesFromCount(total int) int {
+	if p.Limit <= 0 {
+		return 0
+	}
+	return int(math.Ceil(float64(total) / float64(p.Limit)))
+}
+
+// ParseTimeParam parses a date (2006-01-02) or RFC3339 timestamp query
+// parameter. A missing parameter gives a zero time.
+func ParseTimeParam(r *http.Request, key string) (t time.Time, err error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return
+	}
+	t, err = time.Parse(time.RFC3339, value)
+	if err != nil {
+		t, err = time.Parse("2006-01-02", value)
+	}
+	if err != nil {
+		err = failure.BadRequestFromString(fmt.Sprintf("invalid %s, expected YYYY-MM-DD or RFC3339", key))
+	}
+	return
+}
+
+// ParseBoolParam parses an optional boolean query parameter, returning nil
+// when it is missing.
+func ParseBoolParam(r *http.Request, key string) (b *bool, err error) {
+	value := ParseQueryParams(r, key)
+	if value == "" {
+		return
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		err = failure.BadRequestFromString(fmt.Sprintf("invalid %s, expected true or false", key))
+		return
+	}
+	b = &parsed
+	return
+}
+
+// ParseFloatParam parses an optional number query parameter, returning nil
+// when it is missing.
+func ParseFloatParam(r *http.Request, key string) (f *float64, err error) {
+	value := ParseQueryParams(r, key)
+	if value == "" {
+		return
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		err = failure.BadRequestFromString(fmt.Sprintf("invalid %s, expected a number", key))
+		return
+	}
+	f = &parsed
+	return
+}
+
+// ValidateField checks that the sort field is one of the allowed columns, as
+// it is written into the query as is.
+func (p *Pagination) ValidateField(allowed ...string) error {
+	for _, field := range allowed {
+		if p.Field == field {
+			return nil
+		}
+	}
+	return failure.BadRequestFromString(fmt.Sprintf("cannot sort by %s", p.Field))
+}
+
 func (p *Pagination) GetTotalPages(res interface{}) int {
 	val := reflect.ValueOf(res)
 	if val.Kind() != reflect.Slice {
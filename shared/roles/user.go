@@ -30,3 +30,9 @@ func GetStringFromRole(r Role) string {
 		return "trainee"
 	}
 }
+
+// CanGrant reports whether a user with the grantor role may give role to
+// another user. Admins grant any role, others none above their own.
+func CanGrant(grantor, role string) bool {
+	return GetRoleFromString(grantor) == Admin || GetRoleFromString(role) <= GetRoleFromString(grantor)
+}
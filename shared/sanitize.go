@@ -0,0 +1,91 @@
+package shared
+
+import (
+	"io"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// allowedTags are the formatting tags kept by SanitizeHTML.
+var allowedTags = map[string]bool{
+	"p": true, "br": true, "hr": true, "strong": true, "b": true, "em": true, "i": true, "u": true,
+	"h2": true, "h3": true, "h4": true, "ul": true, "ol": true, "li": true,
+	"blockquote": true, "code": true, "pre": true, "a": true,
+}
+
+// droppedTags are removed together with their content.
+var droppedTags = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true, "embed": true, "template": true,
+}
+
+// SanitizeHTML cleans up rich text such as a product description, written in
+// markdown, HTML or a mix of both. Formatting tags are kept without their
+// attributes, links keep http, https and mailto targets only, and every
+// other tag is dropped while its text is kept. Scripts and styles are
+// dropped with their content. Text is left alone apart from escaping "<",
+// so markdown keeps working.
+func SanitizeHTML(input string) string {
+	var b strings.Builder
+	tokenizer := html.NewTokenizer(strings.NewReader(input))
+	skipping := ""
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			if tokenizer.Err() != io.EOF {
+				return ""
+			}
+			return strings.TrimSpace(b.String())
+		}
+		token := tokenizer.Token()
+		if skipping != "" {
+			if tokenType == html.EndTagToken && token.Data == skipping {
+				skipping = ""
+			}
+			continue
+		}
+		switch tokenType {
+		case html.TextToken:
+			b.WriteString(strings.Replace(token.Data, "<", "&lt;", -1))
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if droppedTags[token.Data] {
+				if tokenType == html.StartTagToken {
+					skipping = token.Data
+				}
+				continue
+			}
+			if !allowedTags[token.Data] {
+				continue
+			}
+			b.WriteString("<" + token.Data)
+			if token.Data == "a" {
+				if href, ok := safeHref(token.Attr); ok {
+					b.WriteString(` href="` + html.EscapeString(href) + `" rel="nofollow noopener"`)
+				}
+			}
+			b.WriteString(">")
+		case html.EndTagToken:
+			if allowedTags[token.Data] {
+				b.WriteString("</" + token.Data + ">")
+			}
+		}
+	}
+}
+
+func safeHref(attributes []html.Attribute) (href string, ok bool) {
+	for _, attribute := range attributes {
+		if attribute.Key != "href" {
+			continue
+		}
+		u, err := url.Parse(strings.TrimSpace(attribute.Val))
+		if err != nil {
+			return
+		}
+		switch strings.ToLower(u.Scheme) {
+		case "http", "https", "mailto":
+			return u.String(), true
+		}
+	}
+	return
+}
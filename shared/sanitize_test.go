@@ -0,0 +1,24 @@
+package shared_test
+
+import (
+	"testing"
+
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeHTML(t *testing.T) {
+	for input, expected := range map[string]string{
+		"## Soft cotton\n\n> breathable, *light*":                   "## Soft cotton\n\n> breathable, *light*",
+		`<p onclick="x()">Hello <b>world</b></p>`:                   "<p>Hello <b>world</b></p>",
+		`<script>alert(1)</script><em>ok</em>`:                      "<em>ok</em>",
+		`<a href="https://example.com/a?b=1&c=2">shop</a>`:          `<a href="https://example.com/a?b=1&amp;c=2" rel="nofollow noopener">shop</a>`,
+		`<a href="javascript:alert(1)">x</a>`:                       "<a>x</a>",
+		`<div><img src=x onerror=alert(1)>text</div>`:               "text",
+		`&lt;script&gt;alert(1)&lt;/script&gt;`:                     "&lt;script>alert(1)&lt;/script>",
+		"<!-- note -->kept":                                         "kept",
+		`<style>p { color: red }</style><iframe src="x"></iframe>a`: "a",
+	} {
+		assert.Equal(t, expected, shared.SanitizeHTML(input), input)
+	}
+}
@@ -0,0 +1,16 @@
+package shared
+
+import (
+	"regexp"
+	"strings"
+)
+
+// slugRegex matches a lowercase URL slug such as "mens-shoes".
+var slugRegex = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Slugify turns a name into a URL slug, e.g. "Men's Shoes" into "men-s-shoes".
+func Slugify(name string) string {
+	return strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/evermos/boilerplate-go/shared/failure"
+)
+
+// LocalStorage stores objects as files below a root directory.
+type LocalStorage struct {
+	root    string
+	baseURL string
+}
+
+// NewLocalStorage creates a LocalStorage writing below root and serving
+// objects from baseURL.
+func NewLocalStorage(root, baseURL string) *LocalStorage {
+	return &LocalStorage{root: root, baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// Root returns the directory the objects are written to.
+func (s *LocalStorage) Root() string {
+	return s.root
+}
+
+func (s *LocalStorage) Put(key, contentType string, data []byte) (err error) {
+	p, err := s.path(key)
+	if err != nil {
+		return
+	}
+	err = os.MkdirAll(filepath.Dir(p), 0755)
+	if err != nil {
+		return
+	}
+	// write to a temporary file first so readers never see a partial object
+	tmp := p + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return
+	}
+	return os.Rename(tmp, p)
+}
+
+func (s *LocalStorage) Delete(key string) (err error) {
+	p, err := s.path(key)
+	if err != nil {
+		return
+	}
+	err = os.Remove(p)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return
+}
+
+func (s *LocalStorage) URL(key string) string {
+	return s.baseURL + "/" + strings.TrimLeft(path.Clean("/"+key), "/")
+}
+
+// path maps a key onto the filesystem, refusing keys that escape the root.
+func (s *LocalStorage) path(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if clean == "/" {
+		return "", failure.BadRequestFromString("empty storage key")
+	}
+	return filepath.Join(s.root, filepath.FromSlash(clean)), nil
+}
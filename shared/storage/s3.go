@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Storage stores objects in an S3 compatible bucket using path-style
+// requests signed with AWS Signature Version 4, so it also works against
+// local stand-ins such as MinIO.
+type S3Storage struct {
+	endpoint        string
+	region          string
+	bucket          string
+	accessKeyID     string
+	secretAccessKey string
+	publicURL       string
+	client          *http.Client
+	now             func() time.Time
+}
+
+// NewS3Storage creates an S3Storage. An empty endpoint means AWS itself, and
+// an empty publicURL serves objects straight from the bucket.
+func NewS3Storage(endpoint, region, bucket, accessKeyID, secretAccessKey, publicURL string) *S3Storage {
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+	}
+	endpoint = strings.TrimRight(endpoint, "/")
+	if publicURL == "" {
+		publicURL = endpoint + "/" + bucket
+	}
+	return &S3Storage{
+		endpoint:        endpoint,
+		region:          region,
+		bucket:          bucket,
+		accessKeyID:     accessKeyID,
+		secretAccessKey: secretAccessKey,
+		publicURL:       strings.TrimRight(publicURL, "/"),
+		client:          &http.Client{Timeout: 30 * time.Second},
+		now:             time.Now,
+	}
+}
+
+func (s *S3Storage) Put(key, contentType string, data []byte) (err error) {
+	req, err := s.newRequest(http.MethodPut, key, data)
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", contentType)
+	return s.do(req, data)
+}
+
+func (s *S3Storage) Delete(key string) (err error) {
+	req, err := s.newRequest(http.MethodDelete, key, nil)
+	if err != nil {
+		return
+	}
+	return s.do(req, nil)
+}
+
+func (s *S3Storage) URL(key string) string {
+	return s.publicURL + "/" + escapePath(strings.TrimLeft(key, "/"))
+}
+
+func (s *S3Storage) newRequest(method, key string, body []byte) (*http.Request, error) {
+	u := s.endpoint + "/" + s.bucket + "/" + escapePath(strings.TrimLeft(key, "/"))
+	return http.NewRequest(method, u, bytes.NewReader(body))
+}
+
+func (s *S3Storage) do(req *http.Request, body []byte) (err error) {
+	s.sign(req, body)
+	res, err := s.client.Do(req)
+	if err != nil {
+		return
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 && !(req.Method == http.MethodDelete && res.StatusCode == http.StatusNotFound) {
+		msg, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("s3 %s %s: %s: %s", req.Method, req.URL.Path, res.Status, msg)
+	}
+	return
+}
+
+// sign adds the AWS Signature Version 4 headers to req.
+func (s *S3Storage) sign(req *http.Request, body []byte) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	req.Header.Set("Host", req.URL.Host)
+
+	headerNames := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	if req.Header.Get("Content-Type") != "" {
+		headerNames = append(headerNames, "content-type")
+	}
+	sort.Strings(headerNames)
+
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	canonical := canonicalRequest(req, headerNames, payloadHash)
+	signature := signature(s.secretAccessKey, date, s.region, "s3", stringToSign(amzDate, scope, canonical))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKeyID, scope, strings.Join(headerNames, ";"), signature))
+}
+
+// canonicalRequest describes req the way it is signed. headerNames are the
+// lowercase names of the signed headers in sorted order.
+func canonicalRequest(req *http.Request, headerNames []string, payloadHash string) string {
+	var canonicalHeaders strings.Builder
+	for _, name := range headerNames {
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = req.URL.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	return strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		strings.Join(headerNames, ";"),
+		payloadHash,
+	}, "\n")
+}
+
+func stringToSign(amzDate, scope, canonicalRequest string) string {
+	return strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+}
+
+// signature signs stringToSign with the key derived from the secret for the
+// date, region and service.
+func signature(secretAccessKey, date, region, service, stringToSign string) string {
+	key := hmacSHA256([]byte("AWS4"+secretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// escapePath escapes every segment of an object key but keeps the slashes.
+func escapePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = strings.Replace(url.PathEscape(segment), "+", "%2B", -1)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"github.com/evermos/boilerplate-go/configs"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+)
+
+// Storage stores binary objects such as uploaded images under a key and
+// exposes them through a public URL.
+type Storage interface {
+	// Put stores data under key, replacing any existing object.
+	Put(key, contentType string, data []byte) (err error)
+	// Delete removes the object stored under key. Deleting a missing object
+	// is not an error.
+	Delete(key string) (err error)
+	// URL returns the public URL of the object stored under key.
+	URL(key string) string
+}
+
+// ProvideStorage is the provider for Storage. The backend is selected by
+// STORAGE.DRIVER and defaults to the local filesystem.
+func ProvideStorage(config *configs.Config) Storage {
+	switch config.Storage.Driver {
+	case DriverS3:
+		log.Info().Str("endpoint", config.Storage.S3.Endpoint).Str("bucket", config.Storage.S3.Bucket).Msg("Using S3 storage.")
+		return NewS3Storage(config.Storage.S3.Endpoint, config.Storage.S3.Region, config.Storage.S3.Bucket,
+			config.Storage.S3.AccessKeyID, config.Storage.S3.SecretAccessKey, config.Storage.S3.PublicURL)
+	default:
+		log.Info().Str("path", config.Storage.Local.Path).Msg("Using local storage.")
+		return NewLocalStorage(config.Storage.Local.Path, config.Storage.Local.URL)
+	}
+}
@@ -1,21 +1,45 @@
 package shared
 
 import (
+	"reflect"
+	"regexp"
 	"sync"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/guregu/null"
 	"github.com/rs/zerolog/log"
 )
 
 var once sync.Once
 var v *validator.Validate
 
+// localeRegex matches a language tag such as "en" or "id-ID".
+var localeRegex = regexp.MustCompile(`^[a-z]{2,3}(-[A-Z]{2})?$`)
+
 // GetValidator is responsible for returning a single instance of the validator.
 func GetValidator() *validator.Validate {
 	once.Do(func() {
 		log.Info().Msg("Validator initialized.")
 		v = validator.New()
+		registerCustomValidations(v)
 	})
 
 	return v
 }
+
+// registerCustomValidations registers the tags that are not built into the validator.
+func registerCustomValidations(v *validator.Validate) {
+	_ = v.RegisterValidation("locale", func(fl validator.FieldLevel) bool {
+		return localeRegex.MatchString(fl.Field().String())
+	})
+	_ = v.RegisterValidation("slug", func(fl validator.FieldLevel) bool {
+		return slugRegex.MatchString(fl.Field().String())
+	})
+	// validate the value of optional strings, so tags such as max apply
+	v.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		if s, ok := field.Interface().(null.String); ok && s.Valid {
+			return s.String
+		}
+		return nil
+	}, null.String{})
+}
@@ -7,17 +7,20 @@ import (
 	"github.com/evermos/boilerplate-go/configs"
 	"github.com/evermos/boilerplate-go/infras"
 	"github.com/evermos/boilerplate-go/internal/domain/auth"
+	"github.com/evermos/boilerplate-go/internal/domain/group"
 	"github.com/evermos/boilerplate-go/shared/failure"
 	"github.com/evermos/boilerplate-go/shared/jwt"
+	"github.com/evermos/boilerplate-go/shared/logger"
 	"github.com/evermos/boilerplate-go/transport/http/response"
 	"github.com/go-chi/chi"
 	"github.com/gofrs/uuid"
 )
 
 type JwtAuthentication struct {
-	conf *configs.Config
-	db   *infras.MySQLConn
-	jwt  *jwt.JWT
+	conf   *configs.Config
+	db     *infras.MySQLConn
+	jwt    *jwt.JWT
+	groups group.GroupService
 }
 
 type ClaimsKey string
@@ -26,12 +29,13 @@ const (
 	HeaderJwt = "Authorization"
 )
 
-func ProvideJwtAuthentication(conf *configs.Config, db *infras.MySQLConn) *JwtAuthentication {
+func ProvideJwtAuthentication(conf *configs.Config, db *infras.MySQLConn, groups group.GroupService) *JwtAuthentication {
 	jwt := jwt.NewJWT(conf.App.JWTSecret)
 	return &JwtAuthentication{
-		conf: conf,
-		db:   db,
-		jwt:  jwt,
+		conf:   conf,
+		db:     db,
+		jwt:    jwt,
+		groups: groups,
 	}
 }
 
@@ -81,6 +85,51 @@ func (a *JwtAuthentication) AdminOnly(next http.Handler) http.Handler {
 	})
 }
 
+// RequirePermission lets admins through, as well as users whose groups in
+// the token's organization grant permission.
+func (a *JwtAuthentication) RequirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := r.Context().Value(ClaimsKey("claims")).(*jwt.Claims)
+			if !ok {
+				response.WithMessage(w, http.StatusUnauthorized, "Unauthorized")
+				return
+			}
+			granted, err := a.Allows(claims, permission)
+			if err != nil {
+				response.WithError(w, err)
+				return
+			}
+			if !granted {
+				response.WithError(w, failure.Unauthorized("missing permission "+permission))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Allows reports whether the caller holds permission, for handlers that
+// show more to privileged callers rather than refusing the others.
+func (a *JwtAuthentication) Allows(claims *jwt.Claims, permission string) (granted bool, err error) {
+	if claims.Role == "admin" {
+		return true, nil
+	}
+	return a.hasPermission(claims, permission)
+}
+
+func (a *JwtAuthentication) hasPermission(claims *jwt.Claims, permission string) (granted bool, err error) {
+	orgId, err := uuid.FromString(claims.OrgId)
+	if err != nil {
+		return false, nil
+	}
+	userId, err := uuid.FromString(claims.UserId)
+	if err != nil {
+		return false, nil
+	}
+	return a.groups.HasPermission(orgId, userId, permission)
+}
+
 func (a *JwtAuthentication) CartAccess(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		idString := chi.URLParam(r, "cartId")
@@ -119,6 +168,28 @@ func (a *JwtAuthentication) IsUser(next http.Handler) http.Handler {
 			response.WithError(w, failure.Unauthorized("Unauthorized, invalid credentials "))
 			return
 		}
+		if userId.String() != claims.UserId {
+			// admins only manage users created in their organization, not
+			// members who joined it from another one
+			home, err := a.isHomeOf(claims.OrgId, userId.String())
+			if err != nil {
+				response.WithError(w, err)
+				return
+			}
+			if !home {
+				response.WithError(w, failure.NotFound("user"))
+				return
+			}
+		}
 		next.ServeHTTP(w, r)
 	})
 }
+
+// isHomeOf reports whether the user was created in the organization.
+func (a *JwtAuthentication) isHomeOf(orgId, userId string) (home bool, err error) {
+	err = a.db.Read.Get(&home, "SELECT COUNT(id) FROM user WHERE id = ? AND organization_id = ?", userId, orgId)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+	return
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/evermos/boilerplate-go/configs"
+	"github.com/evermos/boilerplate-go/internal/domain/scim"
+	"github.com/evermos/boilerplate-go/transport/http/response"
+)
+
+// ScimAuthentication checks the bearer token identity providers use for
+// SCIM provisioning. It is separate from user JWTs, and SCIM is disabled
+// while SCIM.TOKEN is empty.
+type ScimAuthentication struct {
+	token string
+}
+
+func ProvideScimAuthentication(conf *configs.Config) *ScimAuthentication {
+	return &ScimAuthentication{token: conf.Scim.Token}
+}
+
+func (a *ScimAuthentication) Validate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get(HeaderJwt), "Bearer ")
+		if a.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+			err := scim.NewError(http.StatusUnauthorized, "", "invalid SCIM token")
+			response.WithSCIM(w, err.Code(), err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
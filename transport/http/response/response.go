@@ -20,6 +20,8 @@ type Pagination struct {
 	Page      int         `json:"page"`
 	Limit     int         `json:"limit"`
 	TotalPage int         `json:"totalPage"`
+	Total     *int        `json:"total,omitempty"`
+	Facets    interface{} `json:"facets,omitempty"`
 }
 
 // NoContent sends a response without any content
@@ -41,6 +43,17 @@ func WithPagination(w http.ResponseWriter, code int, jsonPayload interface{}, pa
 	respond(w, code, Pagination{Data: jsonPayload, Page: page, Limit: limit, TotalPage: totalPage})
 }
 
+// WithPaginationTotal sends a page of results along with the total number of matching rows
+func WithPaginationTotal(w http.ResponseWriter, code int, jsonPayload interface{}, page, limit, totalPage, total int) {
+	respond(w, code, Pagination{Data: jsonPayload, Page: page, Limit: limit, TotalPage: totalPage, Total: &total})
+}
+
+// WithPaginationFacets sends a page of results along with the total number of
+// matching rows and the facet counts of the listing
+func WithPaginationFacets(w http.ResponseWriter, code int, jsonPayload interface{}, page, limit, totalPage, total int, facets interface{}) {
+	respond(w, code, Pagination{Data: jsonPayload, Page: page, Limit: limit, TotalPage: totalPage, Total: &total, Facets: facets})
+}
+
 // WithError sends a response with an error message
 func WithError(w http.ResponseWriter, err error) {
 	code := failure.GetCode(err)
@@ -48,6 +61,18 @@ func WithError(w http.ResponseWriter, err error) {
 	respond(w, code, Base{Error: &errMsg})
 }
 
+// WithSCIM sends a SCIM resource, list or error as is, without the Base
+// envelope
+func WithSCIM(w http.ResponseWriter, code int, payload interface{}) {
+	response, _ := json.Marshal(payload)
+	w.Header().Set("Content-Type", "application/scim+json")
+	w.WriteHeader(code)
+	_, err := w.Write(response)
+	if err != nil {
+		logger.ErrorWithStack(err)
+	}
+}
+
 // WithPreparingShutdown sends a default response for when the server is preparing to shut down
 func WithPreparingShutdown(w http.ResponseWriter) {
 	WithMessage(w, http.StatusServiceUnavailable, "SERVER PREPARING TO SHUT DOWN")
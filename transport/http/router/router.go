@@ -7,11 +7,18 @@ import (
 
 // DomainHandlers is a struct that contains all domain-specific handlers.
 type DomainHandlers struct {
-	AuthHandler    handlers.AuthHandler
-	ProductHandler handlers.ProductHandler
-	CartHandler    handlers.CartHandler
-	OrderHandler   handlers.OrderHandler
-	UserHandler    handlers.UserHandler
+	AuthHandler         handlers.AuthHandler
+	ProductHandler      handlers.ProductHandler
+	CartHandler         handlers.CartHandler
+	OrderHandler        handlers.OrderHandler
+	UserHandler         handlers.UserHandler
+	MediaHandler        handlers.MediaHandler
+	OrganizationHandler handlers.OrganizationHandler
+	GroupHandler        handlers.GroupHandler
+	ScimHandler         handlers.ScimHandler
+	PreferenceHandler   handlers.PreferenceHandler
+	CategoryHandler     handlers.CategoryHandler
+	LocationHandler     handlers.LocationHandler
 }
 
 // Router is the router struct containing handlers.
@@ -34,5 +41,12 @@ func (r *Router) SetupRoutes(mux *chi.Mux) {
 		r.DomainHandlers.CartHandler.Router(rc)
 		r.DomainHandlers.OrderHandler.Router(rc)
 		r.DomainHandlers.UserHandler.Router(rc)
+		r.DomainHandlers.MediaHandler.Router(rc)
+		r.DomainHandlers.OrganizationHandler.Router(rc)
+		r.DomainHandlers.GroupHandler.Router(rc)
+		r.DomainHandlers.PreferenceHandler.Router(rc)
+		r.DomainHandlers.CategoryHandler.Router(rc)
+		r.DomainHandlers.LocationHandler.Router(rc)
 	})
+	r.DomainHandlers.ScimHandler.Router(mux)
 }
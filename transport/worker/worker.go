@@ -0,0 +1,111 @@
+package worker
+
+import (
+	"time"
+
+	"github.com/evermos/boilerplate-go/configs"
+	"github.com/evermos/boilerplate-go/internal/domain/alert"
+	"github.com/evermos/boilerplate-go/internal/domain/cart"
+	"github.com/evermos/boilerplate-go/internal/domain/catalog"
+	"github.com/evermos/boilerplate-go/internal/domain/inventory"
+	"github.com/evermos/boilerplate-go/internal/domain/order"
+	"github.com/evermos/boilerplate-go/internal/domain/privacy"
+	"github.com/evermos/boilerplate-go/internal/domain/product"
+	"github.com/evermos/boilerplate-go/shared"
+	"github.com/evermos/boilerplate-go/shared/logger"
+	"github.com/rs/zerolog/log"
+)
+
+// defaultInterval is used when no interval is configured.
+const defaultInterval = time.Minute
+
+// Limits of the events handled in the background: events handled at the
+// same time, events waiting to be handled, and attempts per event with the
+// delay between them.
+const (
+	eventFlight     = 4
+	eventBuffer     = 100
+	eventRetry      = 3
+	eventRetryDelay = 10 * time.Second
+)
+
+// Job is a unit of background work that runs on a fixed interval.
+type Job struct {
+	Name string
+	Run  func() error
+}
+
+// Worker runs the background jobs of every domain, and handles the events
+// the jobs publish.
+type Worker struct {
+	Config   *configs.Config
+	Jobs     []Job
+	PubSub   shared.PubSub
+	interval time.Duration
+	stop     chan struct{}
+}
+
+// ProvideWorker is the provider for Worker.
+func ProvideWorker(config *configs.Config, privacyService privacy.PrivacyService, catalogService catalog.CatalogService, cartService cart.CartService, inventoryService inventory.InventoryService, alertService alert.AlertService, orderService order.OrderService, productService product.ProductService) *Worker {
+	interval := time.Duration(config.Worker.IntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	pubsub := shared.New(eventFlight, shared.SetMessageBuffer(eventBuffer))
+	pubsub.SubscriberRegistry(inventory.TopicStockLow, alertService.NotifyLowStock)
+	pubsub.SubscriberRegistry(inventory.TopicStockReplenished, alertService.NotifyRestock, shared.SetMaxRetry(eventRetry), shared.SetMaxDelayRetry(eventRetryDelay))
+	return &Worker{
+		Config:   config,
+		PubSub:   pubsub,
+		interval: interval,
+		stop:     make(chan struct{}),
+		Jobs: []Job{
+			{Name: "privacy.erasure", Run: privacyService.ProcessDueErasures},
+			{Name: "catalog.import", Run: catalogService.ProcessImports},
+			{Name: "cart.holds", Run: cartService.ExpireHolds},
+			{Name: "inventory.events", Run: func() error { return inventoryService.PublishEvents(pubsub) }},
+			{Name: "order.backorders", Run: orderService.AllocateWaitingItems},
+			{Name: "product.prices", Run: productService.ApplyPriceSchedules},
+			{Name: "cart.reprice", Run: cartService.RepriceCarts},
+		},
+	}
+}
+
+// Start runs every job in its own goroutine until Stop is called.
+func (w *Worker) Start() {
+	w.PubSub.Start()
+	for _, job := range w.Jobs {
+		go w.loop(job)
+	}
+	log.Info().Int("jobs", len(w.Jobs)).Dur("interval", w.interval).Msg("Background worker started.")
+}
+
+// Stop ends all running jobs after their current run.
+func (w *Worker) Stop() {
+	close(w.stop)
+}
+
+func (w *Worker) loop(job Job) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		w.run(job)
+		select {
+		case <-ticker.C:
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// run executes a job once, making sure a panic never takes the worker down.
+func (w *Worker) run(job Job) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error().Str("job", job.Name).Interface("panic", r).Msg("Background job panicked.")
+		}
+	}()
+	if err := job.Run(); err != nil {
+		logger.ErrorWithStack(err)
+	}
+}
@@ -6,15 +6,30 @@ package main
 import (
 	"github.com/evermos/boilerplate-go/configs"
 	"github.com/evermos/boilerplate-go/infras"
+	"github.com/evermos/boilerplate-go/internal/domain/address"
+	"github.com/evermos/boilerplate-go/internal/domain/alert"
 	"github.com/evermos/boilerplate-go/internal/domain/auth"
 	"github.com/evermos/boilerplate-go/internal/domain/cart"
+	"github.com/evermos/boilerplate-go/internal/domain/catalog"
+	"github.com/evermos/boilerplate-go/internal/domain/category"
+	"github.com/evermos/boilerplate-go/internal/domain/group"
+	"github.com/evermos/boilerplate-go/internal/domain/inventory"
+	"github.com/evermos/boilerplate-go/internal/domain/media"
 	"github.com/evermos/boilerplate-go/internal/domain/order"
+	"github.com/evermos/boilerplate-go/internal/domain/organization"
+	"github.com/evermos/boilerplate-go/internal/domain/preference"
+	"github.com/evermos/boilerplate-go/internal/domain/privacy"
 	"github.com/evermos/boilerplate-go/internal/domain/product"
+	"github.com/evermos/boilerplate-go/internal/domain/scim"
 	"github.com/evermos/boilerplate-go/internal/domain/user"
+	"github.com/evermos/boilerplate-go/internal/domain/variant"
 	"github.com/evermos/boilerplate-go/internal/handlers"
+	"github.com/evermos/boilerplate-go/shared/email"
+	"github.com/evermos/boilerplate-go/shared/storage"
 	"github.com/evermos/boilerplate-go/transport/http"
 	"github.com/evermos/boilerplate-go/transport/http/middleware"
 	"github.com/evermos/boilerplate-go/transport/http/router"
+	"github.com/evermos/boilerplate-go/transport/worker"
 	"github.com/google/wire"
 )
 
@@ -23,9 +38,16 @@ var configurations = wire.NewSet(
 	configs.Get,
 )
 
+// Wiring for outgoing notifications.
+var mailers = wire.NewSet(
+	email.ProvideLogSender,
+	wire.Bind(new(email.Sender), new(*email.LogSender)),
+)
+
 // Wiring for persistences.
 var persistences = wire.NewSet(
 	infras.ProvideMySQLConn,
+	storage.ProvideStorage,
 )
 
 var domainAuth = wire.NewSet(
@@ -40,6 +62,58 @@ var domainProduct = wire.NewSet(
 	wire.Bind(new(product.ProductService), new(*product.ProductServiceImpl)),
 	product.ProvideProductRepositoryMySQL,
 	wire.Bind(new(product.ProductRepository), new(*product.ProductRepositoryMySQL)),
+	product.ProvideProductSearchMySQL,
+	wire.Bind(new(product.ProductSearcher), new(*product.ProductSearchMySQL)),
+)
+
+var domainCategory = wire.NewSet(
+	category.ProvideCategoryServiceImpl,
+	wire.Bind(new(category.CategoryService), new(*category.CategoryServiceImpl)),
+	category.ProvideCategoryRepositoryMySQL,
+	wire.Bind(new(category.CategoryRepository), new(*category.CategoryRepositoryMySQL)),
+)
+
+var domainVariant = wire.NewSet(
+	variant.ProvideVariantServiceImpl,
+	wire.Bind(new(variant.VariantService), new(*variant.VariantServiceImpl)),
+	variant.ProvideVariantRepositoryMySQL,
+	wire.Bind(new(variant.VariantRepository), new(*variant.VariantRepositoryMySQL)),
+)
+
+var domainMedia = wire.NewSet(
+	media.ProvideMediaServiceImpl,
+	wire.Bind(new(media.MediaService), new(*media.MediaServiceImpl)),
+	media.ProvideMediaRepositoryMySQL,
+	wire.Bind(new(media.MediaRepository), new(*media.MediaRepositoryMySQL)),
+)
+
+var domainCatalog = wire.NewSet(
+	catalog.ProvideCatalogServiceImpl,
+	wire.Bind(new(catalog.CatalogService), new(*catalog.CatalogServiceImpl)),
+	catalog.ProvideCatalogRepositoryMySQL,
+	wire.Bind(new(catalog.CatalogRepository), new(*catalog.CatalogRepositoryMySQL)),
+)
+
+var domainInventory = wire.NewSet(
+	inventory.ProvideInventoryServiceImpl,
+	wire.Bind(new(inventory.InventoryService), new(*inventory.InventoryServiceImpl)),
+	inventory.ProvideInventoryRepositoryMySQL,
+	wire.Bind(new(inventory.InventoryRepository), new(*inventory.InventoryRepositoryMySQL)),
+	inventory.ProvideAllocator,
+)
+
+var domainAlert = wire.NewSet(
+	alert.ProvideAlertServiceImpl,
+	wire.Bind(new(alert.AlertService), new(*alert.AlertServiceImpl)),
+	alert.ProvideAlertRepositoryMySQL,
+	wire.Bind(new(alert.AlertRepository), new(*alert.AlertRepositoryMySQL)),
+)
+
+var domainAddress = wire.NewSet(
+	address.ProvideAddressServiceImpl,
+	wire.Bind(new(address.AddressService), new(*address.AddressServiceImpl)),
+	address.ProvideAddressRepositoryMySQL,
+	wire.Bind(new(address.AddressRepository), new(*address.AddressRepositoryMySQL)),
 )
 
 var domainCart = wire.NewSet(
@@ -63,24 +137,71 @@ var domainUser = wire.NewSet(
 	wire.Bind(new(user.UserRepository), new(*user.UserRepositoryMySQL)),
 )
 
+var domainOrganization = wire.NewSet(
+	organization.ProvideOrganizationServiceImpl,
+	wire.Bind(new(organization.OrganizationService), new(*organization.OrganizationServiceImpl)),
+	organization.ProvideOrganizationRepositoryMySQL,
+	wire.Bind(new(organization.OrganizationRepository), new(*organization.OrganizationRepositoryMySQL)),
+)
+
+var domainGroup = wire.NewSet(
+	group.ProvideGroupServiceImpl,
+	wire.Bind(new(group.GroupService), new(*group.GroupServiceImpl)),
+	group.ProvideGroupRepositoryMySQL,
+	wire.Bind(new(group.GroupRepository), new(*group.GroupRepositoryMySQL)),
+)
+
+var domainPreference = wire.NewSet(
+	preference.ProvidePreferenceServiceImpl,
+	wire.Bind(new(preference.PreferenceService), new(*preference.PreferenceServiceImpl)),
+	preference.ProvidePreferenceRepositoryMySQL,
+	wire.Bind(new(preference.PreferenceRepository), new(*preference.PreferenceRepositoryMySQL)),
+)
+
+var domainPrivacy = wire.NewSet(
+	privacy.ProvidePrivacyServiceImpl,
+	wire.Bind(new(privacy.PrivacyService), new(*privacy.PrivacyServiceImpl)),
+	privacy.ProvidePrivacyRepositoryMySQL,
+	wire.Bind(new(privacy.PrivacyRepository), new(*privacy.PrivacyRepositoryMySQL)),
+)
+
+var domainScim = wire.NewSet(
+	scim.ProvideScimServiceImpl,
+	wire.Bind(new(scim.ScimService), new(*scim.ScimServiceImpl)),
+)
+
 // Wiring for all domains.
 var domains = wire.NewSet(
-	domainAuth, domainProduct, domainCart, domainOrder, domainUser,
+	domainAuth, domainProduct, domainCategory, domainVariant, domainMedia, domainCatalog, domainInventory, domainAlert, domainCart, domainOrder, domainUser, domainAddress, domainOrganization, domainGroup, domainPreference, domainPrivacy, domainScim,
 )
 
 var authMiddleware = wire.NewSet(
 	middleware.ProvideAuthentication,
 	middleware.ProvideJwtAuthentication,
+	middleware.ProvideScimAuthentication,
 )
 
 // Wiring for HTTP routing.
 var routing = wire.NewSet(
-	wire.Struct(new(router.DomainHandlers), "AuthHandler", "ProductHandler", "CartHandler", "OrderHandler", "UserHandler"),
+	wire.Struct(new(router.DomainHandlers), "AuthHandler", "ProductHandler", "CartHandler", "OrderHandler", "UserHandler", "MediaHandler", "OrganizationHandler", "GroupHandler", "ScimHandler", "PreferenceHandler", "CategoryHandler"),
 	handlers.ProvideAuthHandler,
 	handlers.ProvideUserHandler,
+	handlers.ProvideAddressHandler,
+	handlers.ProvidePrivacyHandler,
+	handlers.ProvideMediaHandler,
+	handlers.ProvideOrganizationHandler,
+	handlers.ProvideGroupHandler,
+	handlers.ProvideScimHandler,
+	handlers.ProvidePreferenceHandler,
 	handlers.ProvideCartHandler,
 	handlers.ProvideOrderHandler,
 	handlers.ProvideProductHandler,
+	handlers.ProvideCategoryHandler,
+	handlers.ProvideVariantHandler,
+	handlers.ProvideImageHandler,
+	handlers.ProvideCatalogHandler,
+	handlers.ProvideInventoryHandler,
+	handlers.ProvideLocationHandler,
 	router.ProvideRouter,
 )
 
@@ -91,6 +212,8 @@ func InitializeService() *http.HTTP {
 		configurations,
 		// persistences
 		persistences,
+		// notifications
+		mailers,
 		// middleware
 		authMiddleware,
 		// domains
@@ -101,3 +224,19 @@ func InitializeService() *http.HTTP {
 		http.ProvideHTTP)
 	return &http.HTTP{}
 }
+
+// Wiring for background jobs.
+func InitializeWorker() *worker.Worker {
+	wire.Build(
+		// configurations
+		configurations,
+		// persistences
+		persistences,
+		// notifications
+		mailers,
+		// domains
+		domains,
+		// selected transport layer
+		worker.ProvideWorker)
+	return &worker.Worker{}
+}